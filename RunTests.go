@@ -2,15 +2,32 @@ package wasmtest
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"go/build/constraint"
+	"io"
 	"os"
+	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
 // RunTests provides a simplified variadic API for running WebAssembly tests.
-// It accepts optional arguments of types: string (directory), func(...any) (logger), time.Duration (timeout).
+// It accepts optional arguments of types: string (directory or ./... pattern), []string (several of them,
+// run one after the other and aggregated), func(...any) (logger), time.Duration (timeout),
+// ChangedSince (only runs the packages affected by the git changes since a revision),
+// CoverProfile (writes the merged Go coverage profile of the packages there),
+// ArtifactsDir (writes report.html and run-report.json there after the run,
+// and the WithCPUProfile profiles when no other directory is given), HistoryDir (records the run
+// there for the dashboard),
+// SlowestTests (prints the N slowest tests at the end of the run), Parallel (runs that many packages
+// at once), Shard (runs one part of the tests, splitting them over CI machines), Verbosity (what is logged:
+// Quiet, Normal (the default), Verbose or Debug),
+// Option (passed to New), ExecOptions (go test flags; its Dir is replaced by dir), func(ProgressEvent) (receives
+// every progress message as a typed event) and func(TestProgress) (receives "12/34 tests, 2 failed" progress
+// snapshots, counting the tests of the test files for the total). Arguments of any other type are rejected with an error.
 // Defaults: dir="wasm_tests", logger=fmt.Println, timeout=3*time.Minute
 //
 // Examples:
@@ -20,220 +37,567 @@ import (
 //	RunTests(myLogger)                  // sets custom logger
 //	RunTests(5 * time.Minute)           // sets custom timeout
 //	RunTests("./my_tests", myLogger)    // sets directory and logger
+//	RunTests(ExecOptions{Run: "TestDOM"}) // runs only TestDOM
+//	RunTests([]string{"./wasm_tests", "./ui/wasm_tests"}) // runs both directories
+//
+// When tests fail, the run ends with a summary repeating the name and the output of each
+// failed test (see RunResult.FailureOutput), so the details don't have to be looked for in
+// the whole output.
+//
+// Defaults are read from a wasmtest.yaml or .wasmtest.toml file at the module root when
+// present, then from the WASMTEST_* environment variables (see Config); the arguments
+// override both.
 //
 // Note: if dir is passed as an empty string "" or ".", it defaults to "wasm_tests".
+// The process working directory is never changed, so RunTests can be called concurrently.
 func RunTests(args ...any) error {
+	_, err := runTests(context.Background(), args...)
+	return err
+}
+
+// RunTestsContext is like RunTests but stops the run, including the go test
+// process and the browser it started, as soon as ctx is done, e.g. when a CI
+// job is aborted or a TUI user presses Esc. The timeout argument still
+// applies on top of ctx.
+func RunTestsContext(ctx context.Context, args ...any) error {
+	_, err := runTests(ctx, args...)
+	return err
+}
+
+// RunTestsResult is like RunTests but also returns the structured RunResult
+// of the run (passed, failed and skipped tests, durations, raw output and
+// exit code). The result is nil when the tests could not be started, e.g.
+// because the directory doesn't exist.
+func RunTestsResult(args ...any) (*RunResult, error) {
+	return runTests(context.Background(), args...)
+}
+
+// runTests implements RunTests, RunTestsContext and RunTestsResult.
+func runTests(parent context.Context, args ...any) (result *RunResult, err error) {
+	// Defaults come from the configuration file of the module, if any
+	cfg, err := LoadConfig(".")
+	if err != nil {
+		return nil, newRunError(ErrUsage, "❌💥 CONFIG ERROR: Failed to load the wasmtest configuration file\n🔴 Details: %w", err)
+	}
+	defaultDir := "wasm_tests"
+	if cfg.Dir != "" {
+		defaultDir = cfg.Dir
+	}
+	timeout := 3 * time.Minute
+	if cfg.Timeout > 0 {
+		timeout = cfg.Timeout
+	}
+	var opts []Option
+	execOpts := ExecOptions{Run: cfg.Run, Skip: cfg.Skip, Timeout: cfg.PackageTimeout, Tags: cfg.Tags, Args: cfg.Args, Env: cfg.environ()}
+
 	// Parse variadic arguments by type
-	dir := "wasm_tests"
+	dir := defaultDir
+	var dirs []string
+	var writers []ReportWriter
+	changedSince := ChangedSince(cfg.ChangedSince)
+	artifacts := ArtifactsDir(cfg.ArtifactsDir)
+	history := HistoryDir(cfg.History)
+	var coverage CoverProfile
+	slowest := SlowestTests(cfg.Slowest)
+	parallel := Parallel(cfg.Parallel)
+	shard := Shard{Index: cfg.ShardIndex, Total: cfg.ShardTotal}
+	verbosity := cfg.Verbosity
 	logger := func(a ...any) { fmt.Println(a...) }
-	timeout := 3 * time.Minute
+	var events func(ProgressEvent)
+	var progress func(TestProgress)
 	for _, arg := range args {
 		switch v := arg.(type) {
 		case string:
-			dir = v
+			dir, dirs = v, nil
+		case []string:
+			dirs = v
+		case ChangedSince:
+			changedSince = v
+		case ArtifactsDir:
+			artifacts = v
+		case HistoryDir:
+			history = v
+		case CoverProfile:
+			coverage = v
+		case SlowestTests:
+			slowest = v
+		case Parallel:
+			parallel = v
+		case Shard:
+			shard = v
+		case Verbosity:
+			verbosity = v
 		case func(...any):
 			logger = v
 		case time.Duration:
 			timeout = v
+		case Option:
+			opts = append(opts, v)
+		case ExecOptions:
+			// Flags and variables given later win over the file ones.
+			v.Args = append(slices.Clone(execOpts.Args), v.Args...)
+			v.Env = append(slices.Clone(execOpts.Env), v.Env...)
+			v.TestArgs = append(slices.Clone(execOpts.TestArgs), v.TestArgs...)
+			if v.Run == "" {
+				v.Run = execOpts.Run
+			}
+			if v.Skip == "" {
+				v.Skip = execOpts.Skip
+			}
+			if v.Timeout == 0 {
+				v.Timeout = execOpts.Timeout
+			}
+			if len(v.Tags) == 0 {
+				v.Tags = execOpts.Tags
+			}
+			execOpts = v
+		case func(ProgressEvent):
+			events = v
+		case func(TestProgress):
+			progress = v
+		case ReportWriter:
+			writers = append(writers, v)
+		default:
+			return nil, newRunError(ErrUsage, "❌💥 ARGUMENT ERROR: unsupported RunTests argument of type %T\n💡 Accepted types: string (directory), []string (directories), ChangedSince, ArtifactsDir, HistoryDir, CoverProfile, SlowestTests, Parallel, Shard, Verbosity, func(...any) (logger), time.Duration (timeout), Option and ExecOptions values, func(ProgressEvent), func(TestProgress) and ReportWriter", arg)
 		}
 	}
-	// Get current directory to restore later
-	originalDir, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("❌💥 CRITICAL ERROR: Failed to get current directory\n🔴 Details: %v", err)
+	if err := shard.validate(); err != nil {
+		return nil, err
 	}
-	defer os.Chdir(originalDir)
-
-	// Normalize dir: if empty or "." use "wasm_tests"
+	// Normalize dir: if empty or "." use "wasm_tests" (or the configured dir)
 	if dir == "" || dir == "." {
-		dir = "wasm_tests"
+		dir = defaultDir
 	}
 
-	// Check if directory exists before attempting to change
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		return fmt.Errorf("❌💥 DIRECTORY ERROR: Test directory %s does not exist\n🔴 Please ensure the test directory exists and contains WebAssembly test files", dir)
+	s := runSettings{logger: logger, events: events, timeout: timeout, config: cfg.Options(), opts: opts, exec: execOpts, verbosity: verbosity, progress: progress, parallel: int(parallel), shard: shard}
+	if len(dirs) > 0 {
+		dirs = slices.Clone(dirs)
+		for i, d := range dirs {
+			if d == "" || d == "." {
+				dirs[i] = defaultDir
+			}
+		}
+	} else {
+		dirs = []string{dir}
 	}
-
-	// Change to the test directory
-	if err := os.Chdir(dir); err != nil {
-		return fmt.Errorf("❌💥 DIRECTORY ERROR: Failed to change to directory %s\n🔴 Details: %v", dir, err)
+	if artifacts != "" {
+		// The options tell the browser and go recorded in run-report.json.
+		probe := &Wasmtest{}
+		for _, opt := range s.options() {
+			opt(probe)
+		}
+		for _, artifact := range []struct {
+			name string
+			rw   func(io.Writer) ReportWriter
+		}{
+			{"report.html", func(out io.Writer) ReportWriter { return &htmlReportWriter{out: out} }},
+			{"run-report.json", func(out io.Writer) ReportWriter {
+				return &jsonReportWriter{out: out, browser: probe.browser, toolchain: probe.toolchain}
+			}},
+		} {
+			f, err := artifacts.create(artifact.name)
+			if err != nil {
+				logger("report writer error:", err)
+				continue
+			}
+			defer f.Close()
+			writers = append(writers, artifact.rw(f))
+		}
+		// CPU profiles go with the other artifacts by default.
+		if probe.cpuProfile && probe.cpuProfileDir == "" {
+			s.opts = append(s.opts, WithCPUProfile(string(artifacts)))
+		}
 	}
-
-	// Check for WebAssembly test files
-	hasWasmTests := false
-	files, err := os.ReadDir(".")
-	if err == nil {
-		for _, file := range files {
-			if strings.HasSuffix(file.Name(), "_test.go") {
-				content, err := os.ReadFile(file.Name())
-				if err == nil && (strings.Contains(string(content), "//go:build js && wasm") ||
-					strings.Contains(string(content), "// +build js,wasm")) {
-					hasWasmTests = true
-					break
+	if history != "" {
+		if store, err := OpenHistory(string(history)); err != nil {
+			logger("history error:", err)
+		} else {
+			writers = append(writers, store.Recorder())
+		}
+	}
+	// The failures are repeated last, after the slowest tests.
+	defer func() {
+		if result != nil {
+			if summary := failureSummary(result); summary != "" {
+				logger("[WASMTEST]", "info", summary)
+			}
+		}
+	}()
+	if slowest > 0 {
+		defer func() {
+			if result != nil {
+				if summary := slowestSummary(result, int(slowest)); summary != "" {
+					logger("[WASMTEST]", "info", summary)
 				}
 			}
+		}()
+	}
+	if coverage != "" {
+		tmp, err := makeTempDir("wasmtest-cover-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(tmp)
+		s.coverDir = tmp
+		defer func() {
+			if result != nil {
+				s.mergeCoverage(result, string(coverage))
+			}
+		}()
+	}
+	if len(writers) == 0 {
+		return s.dispatch(parent, dirs, changedSince)
+	}
+
+	// Report writers see each package as a plan of an orchestrated run.
+	plans := make([]RunPlan, len(dirs))
+	for i, d := range dirs {
+		plans[i] = RunPlan{Name: d, Dir: d}
+	}
+	for _, rw := range writers {
+		if err := rw.Begin(plans); err != nil {
+			logger("report writer error:", err)
+		}
+	}
+	var mu sync.Mutex
+	report := &Report{}
+	s.emit = func(ev TestEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, rw := range writers {
+			if err := rw.TestEvent(ev); err != nil {
+				logger("report writer error:", err)
+			}
+		}
+	}
+	s.record = func(dir string, res *RunResult, err error) {
+		if res == nil {
+			res = newRunResult(dir)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		report.Results = append(report.Results, PlanResult{Plan: RunPlan{Name: dir, Dir: dir}, RunResult: *res, Err: err})
+	}
+
+	start := time.Now()
+	result, err = s.dispatch(parent, dirs, changedSince)
+	report.Duration = time.Since(start)
+	if len(report.Failed()) > 0 || (err != nil && len(report.Results) == 0) {
+		report.ExitCode = 1
+	}
+	for _, rw := range writers {
+		if err := rw.End(report); err != nil {
+			logger("report writer error:", err)
 		}
 	}
+	return result, err
+}
+
+// dispatch runs dirs, a single directory or several directories and ./...
+// patterns, optionally restricted to the packages changed since a revision.
+func (s runSettings) dispatch(parent context.Context, dirs []string, changedSince ChangedSince) (*RunResult, error) {
+	if changedSince != "" {
+		return s.runChanged(parent, string(changedSince), dirs)
+	}
+	if len(dirs) > 1 || isPackagePattern(dirs[0]) || s.shard.Total > 1 {
+		return s.runPackages(parent, dirs)
+	}
+	return s.run(parent, dirs[0])
+}
+
+// runSettings holds the parsed RunTests arguments, shared by the runs of
+// every package matched by a ./... pattern.
+type runSettings struct {
+	logger  func(...any)
+	events  func(ProgressEvent)
+	timeout time.Duration
+	// config holds the options of the configuration file, which opts and
+	// the other settings override.
+	config []Option
+	opts   []Option
+	exec   ExecOptions
+	// emit, when set, receives the test events of every package for the
+	// ReportWriter arguments; record receives the outcome of each package.
+	emit   func(TestEvent)
+	record func(dir string, res *RunResult, err error)
+	// verbosity selects the messages logged (see Verbosity).
+	verbosity Verbosity
+	// progress, when set, receives the TestProgress of each package.
+	progress func(TestProgress)
+	// parallel is the number of packages run at once (see Parallel).
+	parallel int
+	// shard is the part of the tests run (see Shard), and shardRuns the
+	// -run pattern of each of its packages.
+	shard     Shard
+	shardRuns map[string]string
+	// coverDir, when set, is where the coverage profile of each package
+	// is written (see CoverProfile).
+	coverDir string
+}
 
-	if !hasWasmTests {
-		return fmt.Errorf("❌💥 NO TEST FILES: No WebAssembly test files found in directory %s\n🔴 Required: Files must contain '//go:build js && wasm' or '// +build js,wasm'\n💡 Check that your test files have the correct build tags", dir)
+// options returns the options of the Wasmtest running the tests.
+func (s runSettings) options() []Option {
+	opts := append(slices.Clone(s.config), WithLogger(s.logger), WithTimeout(s.timeout), WithVerbosity(s.verbosity))
+	return append(opts, s.opts...)
+}
+
+// run runs the tests of the package in dir and records the outcome for the
+// report writers.
+func (s runSettings) run(parent context.Context, dir string) (*RunResult, error) {
+	res, err := s.runDir(parent, dir)
+	if s.record != nil {
+		s.record(dir, res, err)
+	}
+	return res, err
+}
+
+// runDir runs the tests of the package in dir.
+func (s runSettings) runDir(parent context.Context, dir string) (_ *RunResult, err error) {
+	logger, timeout := s.logger, s.timeout
+	var events func(...any)
+	if s.events != nil {
+		events = ProgressFunc(func(ev ProgressEvent) {
+			ev.Package = dir
+			s.events(ev)
+		})
+	}
+
+	// Check if directory exists. The process working directory is never
+	// changed: go test runs with dir as its working directory instead.
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, newRunError(ErrDirNotFound, "❌💥 DIRECTORY ERROR: Test directory %s does not exist\n🔴 Please ensure the test directory exists and contains WebAssembly test files", dir)
+	}
+
+	// Check for WebAssembly test files
+	target := s.target()
+	found, err := hasWasmTests(dir, target, s.exec.Tags...)
+	if err != nil {
+		return nil, fmt.Errorf("❌💥 DIRECTORY ERROR: Failed to read directory %s\n🔴 Details: %w", dir, err)
+	}
+
+	if !found {
+		return nil, newRunError(ErrNoWasmTests, "❌💥 NO TEST FILES: No %s test files found in directory %s\n🔴 Required: Files must have a build constraint selecting the target, e.g. '//go:build js && wasm' or '//go:build wasip1'\n💡 Check that your test files have the correct build tags, and pass the extra tags gating them in ExecOptions.Tags", target, dir)
 	}
 
 	// Create Wasmtest instance
-	w := New(logger)
+	w := New(s.options()...)
+	// A runner that is missing or doesn't start is most often a setup
+	// problem, which the doctor report pins down.
+	defer func() {
+		if errors.Is(err, ErrRunnerMissing) {
+			logger("[WASMTEST]", "doctor", w.Doctor(context.WithoutCancel(parent)))
+		}
+	}()
 
 	// Collect progress messages to determine success/failure
-	var messages [][]any
-	var lastMessage []any
-	var hasErrors bool
+	var received int
 	var errorMessages []string
-	var failedTests []string
+	var tracker testTracker
+	filter := logFilter{level: s.verbosity}
+	result := newRunResult(dir)
 
+	progress := progressTracker{progress: TestProgress{Dir: dir}, notify: s.progress}
 	progressFunc := func(msgs ...any) {
-		messages = append(messages, msgs)
-		lastMessage = msgs
-
-		// Log all messages
-		if len(msgs) > 1 && msgs[0] == "out" {
-			// For test output lines, log without [WASMTEST] prefix for cleaner display
-			logger(msgs[1:]...)
-		} else {
-			logger(append([]any{"[WASMTEST]"}, msgs...)...)
+		received++
+		result.collect(msgs...)
+		if s.progress != nil {
+			progress.update(msgs...)
+		}
+		if events != nil {
+			events(msgs...)
+		}
+		if ev, ok := tracker.event(msgs...); ok && s.emit != nil {
+			ev.Plan = dir
+			s.emit(ev)
+		}
+		if len(msgs) == 0 {
+			return
+		}
+		if msgType := fmt.Sprintf("%v", msgs[0]); (msgType == "error" || msgType == "err") && len(msgs) > 1 {
+			errorMessages = append(errorMessages, fmt.Sprintf("%v", msgs[1]))
 		}
 
-		// Check for errors
-		if len(msgs) > 0 {
-			msgType := fmt.Sprintf("%v", msgs[0])
-			if msgType == "error" || msgType == "err" {
-				hasErrors = true
-				if len(msgs) > 1 {
-					errorMessages = append(errorMessages, fmt.Sprintf("%v", msgs[1]))
-				}
-			}
-
-			// Parse test output to find failing tests
-			if msgType == "out" && len(msgs) > 1 {
-				output := fmt.Sprintf("%v", msgs[1])
-				// Look for test failure patterns
-				if strings.Contains(output, "--- FAIL:") {
-					// Extract test name from patterns like:
-					// "--- FAIL: TestFunctionName (0.00s)"
-					// "--- FAIL: TestFunctionName/SubTest (0.00s)"
-					if strings.HasPrefix(output, "--- FAIL: ") {
-						testName := strings.TrimPrefix(output, "--- FAIL: ")
-						// Remove timing info
-						if idx := strings.LastIndex(testName, " ("); idx > 0 {
-							testName = testName[:idx]
-						}
-						if testName != "" && !slices.Contains(failedTests, testName) {
-							failedTests = append(failedTests, testName)
-						}
-					}
-				}
+		// Log the messages selected by the verbosity, but the test state
+		// changes, which repeat the "--- PASS" lines of the output, and the
+		// test list.
+		for _, msgs := range filter.filter(msgs...) {
+			switch fmt.Sprintf("%v", msgs[0]) {
+			case "test", "list":
+			case "out":
+				// For test output lines, log without [WASMTEST] prefix for cleaner display
+				logger(msgs[1:]...)
+			default:
+				logger(append([]any{"[WASMTEST]"}, msgs...)...)
 			}
 		}
 	}
 
 	// Execute tests with timeout context
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(parent, timeout)
 	defer cancel()
 
-	// Run Execute in a goroutine so we can timeout
-	done := make(chan error, 1)
-	go func() {
-		// Note: Execute doesn't return error, it reports via progress callback
-		w.Execute(progressFunc)
-		done <- nil
-	}()
-
-	select {
-	case <-done:
-		// Execution completed
-	case <-ctx.Done():
-		return fmt.Errorf("⏰💥 TIMEOUT ERROR: Test execution timed out after %v in directory %s\n🔴 This usually means the WebAssembly tests are hanging or taking too long\n💡 Try increasing the timeout or check for infinite loops in your tests", timeout, dir)
+	// The context stops the go test process, and the browser it started, on
+	// timeout or cancellation.
+	start := time.Now()
+	execOpts := s.exec
+	execOpts.Dir = dir
+	if run, ok := s.shardRuns[dir]; ok {
+		execOpts.Run = run
 	}
-
-	// Analyze results from progress messages
-	if len(messages) == 0 {
-		return fmt.Errorf("❌💥 NO OUTPUT: No progress messages received from test execution in directory %s\n🔴 This indicates a serious problem with the test runner\n💡 Check that wasmbrowsertest is properly installed and accessible", dir)
+	if s.coverDir != "" {
+		profile, err := coverProfile(s.coverDir, dir)
+		if err != nil {
+			return nil, err
+		}
+		execOpts.Args = append(slices.Clone(execOpts.Args), "-coverprofile", profile)
+		// Tests that ran without writing a profile had no file system.
+		defer func() {
+			if info, statErr := os.Stat(profile); statErr == nil && info.Size() == 0 && len(result.PassedTests)+len(result.FailedTests) > 0 {
+				warning := coverageMissing
+				warning.Message = "no coverage profile was written for " + dir
+				progressFunc("warning", warning)
+			}
+		}()
 	}
-
-	// Check for errors in output
-	if hasErrors {
-		errorSummary := strings.Join(errorMessages, "; ")
-		if errorSummary == "" {
-			errorSummary = "unknown error occurred during test execution"
+	spec := execOpts.spec(w)
+	spec.list = s.progress != nil
+	err = w.execute(ctx, spec, progressFunc)
+	result.Duration = time.Since(start)
+	switch {
+	case errors.Is(context.Cause(parent), ErrTimeout):
+		return result, newRunError(ErrTimeout, "⏰💥 TIMEOUT ERROR: The %v was reached while running the tests in directory %s\n🔴 The remaining packages were not run\n💡 Increase the timeout, or set a per-package timeout (ExecOptions.Timeout) so a single slow package can't use it all", context.Cause(parent), dir)
+	case parent.Err() != nil:
+		return result, fmt.Errorf("🛑💥 CANCELED: Test execution in directory %s was canceled after %v\n🔴 Details: %w", dir, result.Duration.Round(time.Millisecond), context.Cause(parent))
+	case ctx.Err() != nil:
+		return result, newRunError(ErrTimeout, "⏰💥 TIMEOUT ERROR: Test execution timed out after %v in directory %s\n🔴 This usually means the WebAssembly tests are hanging or taking too long\n💡 Try increasing the timeout or check for infinite loops in your tests", timeout, dir)
+	}
+	result.setExit(err)
+	if s.verbosity <= Quiet {
+		mark := "✅"
+		if err != nil {
+			mark = "❌"
 		}
+		logger("[WASMTEST]", "info", fmt.Sprintf("%s %s: %d passed, %d failed, %d skipped in %v", mark, dir,
+			len(result.PassedTests), len(result.FailedTests), len(result.SkippedTests), result.Duration.Round(time.Millisecond)))
+	}
 
-		errorMsg := fmt.Sprintf("❌💥 WebAssembly test EXECUTION FAILED in directory %s\n🔴 Error: %s", dir, errorSummary)
-
-		// Add information about failing tests if any were found
-		if len(failedTests) > 0 {
-			errorMsg += fmt.Sprintf("\n🧪 Failing Tests: %s", strings.Join(failedTests, ", "))
+	// failure returns the error of a run that ended without passing.
+	failure := func(msg string) error {
+		if after, running, ok := testTimeout(result.RawOutput); ok {
+			return newRunError(ErrTimeout, "⏰💥 PACKAGE TIMEOUT: The tests in directory %s exceeded the per-package timeout of %s\n🔴 Still running: %s\n💡 Increase ExecOptions.Timeout or check for hanging tests", dir, after, strings.Join(running, ", "))
 		}
-
-		errorMsg += "\n💡 Check the test output above for detailed failure information"
-		return fmt.Errorf("%s", errorMsg)
+		if result.ShuffleSeed != "" {
+			msg += fmt.Sprintf("\n🔀 Shuffle seed: %s (replay this order with ExecOptions{Shuffle: %q})", result.ShuffleSeed, result.ShuffleSeed)
+		}
+		for _, line := range append(result.RawOutput, errorMessages...) {
+			if runnerMissing(line) {
+				return newRunError(ErrRunnerMissing, "%s", msg)
+			}
+		}
+		return &TestFailureError{Dir: dir, Failed: result.FailedTests, ExitCode: result.ExitCode,
+			BuildFailed: slices.ContainsFunc(result.RawOutput, buildFailed), msg: msg}
 	}
 
-	if len(lastMessage) >= 2 {
-		if lastMessage[0] == "exit" {
-			if lastMessage[1] == "error" {
-				errorDetail := "unknown error"
-				if len(lastMessage) > 2 {
-					errorDetail = fmt.Sprintf("%v", lastMessage[2])
-				}
-
-				errorMsg := fmt.Sprintf("❌💥 WebAssembly tests FAILED in directory %s\n🔴 Exit Error: %s", dir, errorDetail)
-
-				// Add information about failing tests if any were found
-				if len(failedTests) > 0 {
-					errorMsg += fmt.Sprintf("\n🧪 Failing Tests: %s", strings.Join(failedTests, ", "))
-				}
+	// Analyze results: the exit status of go test is authoritative, the
+	// per-test states come from its -json records.
+	if received == 0 {
+		return result, newRunError(ErrRunnerMissing, "❌💥 NO OUTPUT: No progress messages received from test execution in directory %s\n🔴 This indicates a serious problem with the test runner\n💡 Check that wasmbrowsertest is properly installed and accessible", dir)
+	}
+	if err == nil {
+		return result, nil // Success
+	}
+	if errors.Is(err, ErrRunnerMissing) {
+		return result, err
+	}
 
-				errorMsg += "\n💡 The test process exited with an error status"
-				return fmt.Errorf("%s", errorMsg)
-			} else if lastMessage[1] == "ok" {
-				// Check for PASS in output to confirm success
-				foundPass := false
-				for _, msg := range messages {
-					if len(msg) >= 2 && msg[0] == "out" {
-						output := fmt.Sprintf("%v", msg[1])
-						if strings.Contains(output, "PASS") {
-							foundPass = true
-							break
-						}
-					}
-				}
-				if foundPass {
-					return nil // Success
-				}
+	errorSummary := strings.Join(errorMessages, "; ")
+	if errorSummary == "" {
+		errorSummary = err.Error()
+	}
+	errorMsg := fmt.Sprintf("❌💥 WebAssembly tests FAILED in directory %s\n🔴 Error: %s", dir, errorSummary)
 
-				errorMsg := fmt.Sprintf("⚠️💥 PARTIAL SUCCESS: Tests completed in directory %s but no PASS found in output", dir)
+	// Add information about failing tests if any were found
+	if len(result.FailedTests) > 0 {
+		errorMsg += fmt.Sprintf("\n🧪 Failing Tests: %s", strings.Join(result.FailedTests, ", "))
+	}
 
-				// Add information about failing tests if any were found
-				if len(failedTests) > 0 {
-					errorMsg += fmt.Sprintf("\n🧪 Failing Tests: %s", strings.Join(failedTests, ", "))
-				}
+	errorMsg += "\n💡 Check the test output above for detailed failure information"
+	return result, failure(errorMsg)
+}
 
-				errorMsg += "\n🔴 This usually means your tests are not producing the expected output\n💡 Check that your test functions are named correctly (TestXxx) and contain proper assertions"
-				return fmt.Errorf("%s", errorMsg)
+// testTimeout reports whether lines hold the panic of a test binary that
+// exceeded its -timeout, returning the timeout and the tests still running.
+func testTimeout(lines []string) (after string, running []string, ok bool) {
+	for i, line := range lines {
+		after, found := strings.CutPrefix(strings.TrimSpace(line), "panic: test timed out after ")
+		if !found {
+			continue
+		}
+		for _, next := range lines[i+1:] {
+			next = strings.TrimSpace(next)
+			if next == "running tests:" {
+				continue
 			}
+			name, _, found := strings.Cut(next, " (")
+			if !found || !strings.HasSuffix(next, ")") || strings.Contains(name, " ") {
+				break
+			}
+			running = append(running, name)
 		}
+		return after, running, true
 	}
+	return "", nil, false
+}
 
-	// If we reach here, something unexpected happened
-	var debugInfo strings.Builder
-	debugInfo.WriteString(fmt.Sprintf("❌💥 UNEXPECTED ERROR: Test execution issue in directory %s\n", dir))
-	debugInfo.WriteString(fmt.Sprintf("🔴 Total messages received: %d\n", len(messages)))
-	if len(lastMessage) > 0 {
-		debugInfo.WriteString(fmt.Sprintf("🔴 Last message: %v\n", lastMessage))
+// hasWasmTests reports whether dir holds a _test.go file whose build
+// constraint selects it for target, TargetJS or TargetWASIP1, with the
+// extra build tags set, and not for other platforms.
+func hasWasmTests(dir, target string, tags ...string) (bool, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return false, err
 	}
-	debugInfo.WriteString("📋 All messages received:\n")
-	for i, msg := range messages {
-		debugInfo.WriteString(fmt.Sprintf("  %d: %v\n", i+1, msg))
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), "_test.go") {
+			content, err := os.ReadFile(filepath.Join(dir, file.Name()))
+			if err == nil && wasmOnly(content, target, tags) {
+				return true, nil
+			}
+		}
 	}
-	debugInfo.WriteString("💡 This usually indicates a problem with the test runner or environment setup")
+	return false, nil
+}
 
-	return fmt.Errorf("%s", debugInfo.String())
+// wasmOnly reports whether the build constraint of src, e.g.
+// "//go:build js && wasm && integration", is satisfied for target with tags
+// but not without its GOOS and GOARCH tags. An empty target is TargetJS.
+func wasmOnly(src []byte, target string, tags []string) bool {
+	if target == "" {
+		target = TargetJS
+	}
+	goos, _, _ := strings.Cut(target, "/")
+	for _, line := range strings.Split(string(src), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "//") {
+			// Constraints must appear before the package clause.
+			return false
+		}
+		expr, err := constraint.Parse(line)
+		if err != nil {
+			continue
+		}
+		has := func(wasm bool) func(string) bool {
+			return func(tag string) bool {
+				if tag == goos || tag == "wasm" {
+					return wasm
+				}
+				return slices.Contains(tags, tag)
+			}
+		}
+		return expr.Eval(has(true)) && !expr.Eval(has(false))
+	}
+	return false
 }
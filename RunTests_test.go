@@ -0,0 +1,131 @@
+package wasmtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunTestsContextCanceled(t *testing.T) {
+	node := nodeExec(t)
+	dir := writeModule(t, map[string]string{
+		"hang_test.go": "//go:build js && wasm\n\npackage tmp\n\nimport (\n\t\"testing\"\n\t\"time\"\n)\n\nfunc TestHang(t *testing.T) { time.Sleep(time.Hour) }\n",
+	})
+
+	// The process tree must be gone shortly after the context is done,
+	// well before the test itself would finish.
+	w := New(WithInstallDisabled(), WithLogger(func(...any) {}))
+	ctx, cancel := context.WithCancel(context.Background())
+	progress := func(msgs ...any) {
+		if len(msgs) > 1 && msgs[0] == "out" && strings.HasPrefix(fmt.Sprint(msgs[1]), "=== RUN") {
+			cancel()
+		}
+	}
+	start := time.Now()
+	if err := w.execute(ctx, execSpec{dir: dir, exec: node}, progress); err == nil {
+		t.Error("execute succeeded after cancel")
+	}
+	if d := time.Since(start); d > time.Minute {
+		t.Errorf("execute returned %v after start", d)
+	}
+
+	canceled, stop := context.WithCancel(context.Background())
+	stop()
+	err := RunTestsContext(canceled, dir, func(...any) {}, WithInstallDisabled())
+	if err == nil || !strings.Contains(err.Error(), "CANCELED") {
+		t.Errorf("RunTestsContext() = %v, want a CANCELED error", err)
+	}
+}
+
+func TestRunTestsKeepsWorkingDir(t *testing.T) {
+	node := nodeExec(t)
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Concurrent runs only work when none of them changes directory.
+	var wg sync.WaitGroup
+	for _, run := range []string{"TestMathHelper", "TestJSInterop"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			opts := ExecOptions{Run: run, Args: []string{"-exec", node}}
+			res, err := RunTestsResult("./example", func(...any) {}, opts, WithInstallDisabled())
+			if err != nil {
+				t.Errorf("RunTestsResult(%s) failed: %v", run, err)
+				return
+			}
+			if len(res.PassedTests) != 1 || res.PassedTests[0] != run {
+				t.Errorf("RunTestsResult(%s) passed %q", run, res.PassedTests)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if now, _ := os.Getwd(); now != cwd {
+		t.Errorf("working directory changed to %s", now)
+	}
+}
+
+func TestWasmOnly(t *testing.T) {
+	for _, tc := range []struct {
+		src    string
+		target string
+		tags   []string
+		want   bool
+	}{
+		{"//go:build js && wasm\n\npackage p\n", "", nil, true},
+		{"// +build js,wasm\n\npackage p\n", "", nil, true},
+		{"// Copyright\n\n//go:build wasm\n\npackage p\n", "", nil, true},
+		{"//go:build js && wasm && integration\n\npackage p\n", "", nil, false},
+		{"//go:build js && wasm && integration\n\npackage p\n", "", []string{"integration"}, true},
+		{"//go:build integration\n\npackage p\n", "", []string{"integration"}, false},
+		{"//go:build !js\n\npackage p\n", "", nil, false},
+		{"package p\n\n//go:build js && wasm\n", "", nil, false},
+		{"//go:build js && wasm\n\npackage p\n", TargetWASIP1, nil, false},
+		{"//go:build wasip1\n\npackage p\n", TargetWASIP1, nil, true},
+		{"//go:build wasip1\n\npackage p\n", TargetJS, nil, false},
+		{"//go:build wasm\n\npackage p\n", TargetWASIP1, nil, true},
+	} {
+		if got := wasmOnly([]byte(tc.src), tc.target, tc.tags); got != tc.want {
+			t.Errorf("wasmOnly(%q, %q, %q) = %v, want %v", tc.src, tc.target, tc.tags, got, tc.want)
+		}
+	}
+}
+
+func TestRunTestsTags(t *testing.T) {
+	node := nodeExec(t)
+	dir := writeModule(t, map[string]string{
+		"plain_test.go":       "//go:build js && wasm\n\npackage tmp\n\nimport \"testing\"\n\nfunc TestPlain(t *testing.T) {}\n",
+		"integration_test.go": "//go:build js && wasm && integration\n\npackage tmp\n\nimport \"testing\"\n\nfunc TestIntegration(t *testing.T) {}\n",
+	})
+	exec := ExecOptions{Args: []string{"-exec", node}}
+
+	res, err := RunTestsResult(dir, func(...any) {}, exec, WithInstallDisabled())
+	if err != nil || slices.Contains(res.PassedTests, "TestIntegration") {
+		t.Fatalf("untagged run = %v, %v", res, err)
+	}
+	exec.Tags = []string{"integration"}
+	res, err = RunTestsResult(dir, func(...any) {}, exec, WithInstallDisabled())
+	if err != nil || !slices.Equal(res.PassedTests, []string{"TestIntegration", "TestPlain"}) {
+		t.Errorf("tagged run passed %q, %v", res.PassedTests, err)
+	}
+
+	// A directory holding only tagged tests needs the tags to be found.
+	only := writeModule(t, map[string]string{
+		"integration_test.go": "//go:build js && wasm && integration\n\npackage tmp\n\nimport \"testing\"\n\nfunc TestIntegration(t *testing.T) {}\n",
+	})
+	if err := RunTests(only, func(...any) {}, WithInstallDisabled()); !errors.Is(err, ErrNoWasmTests) {
+		t.Errorf("untagged run of tagged tests = %v", err)
+	}
+	if err := RunTests(only, func(...any) {}, exec, WithInstallDisabled()); err != nil {
+		t.Errorf("tagged run of tagged tests = %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package wasmtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ArtifactsDir is a RunTests argument naming the directory where the files
+// describing a run are written once it ends: report.html (see the "html"
+// report writer) and run-report.json (see JSONReport). Each run overwrites
+// the files of the previous one. It can also be set with the
+// WASMTEST_ARTIFACTS_DIR environment variable or the artifacts_dir setting
+// of the configuration file.
+type ArtifactsDir string
+
+// create creates the artifact called name, creating the directory if
+// needed.
+func (d ArtifactsDir) create(name string) (*os.File, error) {
+	if err := os.MkdirAll(string(d), 0o755); err != nil {
+		return nil, fmt.Errorf("wasmtest: creating artifacts dir: %w", err)
+	}
+	return os.Create(filepath.Join(string(d), name))
+}
+
+// artifactName turns a package path or directory into a file name.
+func artifactName(name string) string {
+	return strings.Trim(strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(name), "._")
+}
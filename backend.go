@@ -0,0 +1,329 @@
+package wasmtest
+
+import (
+	"cmp"
+	"context"
+	_ "embed"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Backends run the test binaries of go test runs (see WithBackend).
+const (
+	// BackendBrowser runs the tests in a browser through wasmbrowsertest.
+	// It is the default.
+	BackendBrowser = "browser"
+	// BackendBuiltin runs the tests in a browser too, without
+	// wasmbrowsertest, so New installs nothing: the test binary is built
+	// with go test -c and served with the wasm_exec.js of the Go
+	// installation to a browser wasmtest launches itself (see WithBrowser
+	// and WithBrowserFlags, Firefox included), which posts the output and
+	// the exit code back; go tool test2json converts the output.
+	BackendBuiltin = "builtin"
+	// BackendNode runs the tests under node with the wasm_exec.js of the Go
+	// installation (go_js_wasm_exec). There is no DOM, but no browser
+	// either: it starts much faster and works on CI hosts without one.
+	BackendNode = "node"
+	// BackendDeno runs the tests under Deno, like BackendNode. deno is
+	// looked up in PATH, then in $DENO_INSTALL/bin and ~/.deno/bin. The
+	// tests may read and write files, use the environment and the network,
+	// but not run programs.
+	BackendDeno = "deno"
+	// BackendWasmtime builds the tests for wasip1 instead of js and runs
+	// them with wasmtime, through the go_wasip1_wasm_exec of the Go
+	// installation. The whole file system is mapped into the WASI one and
+	// the working directory is kept, so testdata files are found.
+	// syscall/js is not available.
+	BackendWasmtime = "wasmtime"
+	// BackendWasmer is BackendWasmtime with wasmer.
+	BackendWasmer = "wasmer"
+	// BackendDocker runs the tests with wasmbrowsertest in the Chrome of a
+	// container image (see WithDockerImage), for hosts without a browser
+	// or where none may be installed; docker must be in PATH. The
+	// container shares the host network, which only Docker on Linux
+	// supports. RunBundle launches its browser there too.
+	BackendDocker = "docker"
+	// BackendAuto picks the first backend able to run the tests: the
+	// browser when one is installed, else node, else Deno, else wasmtime
+	// or wasmer with the tests built for wasip1. A fallback is reported
+	// as a "backend-fallback" warning telling why the browser was not
+	// used. An explicit target (see WithTarget) limits the choice to the
+	// backends running it.
+	BackendAuto = "auto"
+)
+
+// denoExecScript loads the wasm_exec.js of the Go installation under Deno.
+//
+//go:embed deno_exec.mjs
+var denoExecScript []byte
+
+// denoPermissions are the Deno permissions granted to the tests.
+var denoPermissions = []string{"--allow-read", "--allow-write", "--allow-env", "--allow-net", "--allow-sys"}
+
+// WithBackend selects how go test runs execute the test binary: one of
+// BackendBrowser (the default), BackendBuiltin, BackendNode, BackendDeno,
+// BackendWasmtime, BackendWasmer, BackendDocker or BackendAuto. The
+// WASMTEST_BACKEND environment variable and the backend setting of the
+// configuration file set it too. Tests touching the DOM through syscall/js
+// need a browser. RunBundle always uses a browser.
+func WithBackend(backend string) Option {
+	return func(w *Wasmtest) { w.backend = backend }
+}
+
+// WithWASIRuntime sets the wasmtime or wasmer executable of the
+// BackendWasmtime and BackendWasmer backends, by default the one named
+// after the backend in PATH. The WASMTEST_WASI_RUNTIME environment variable
+// and the wasi_runtime setting of the configuration file set it too.
+func WithWASIRuntime(path string) Option {
+	return func(w *Wasmtest) { w.wasiRuntime = path }
+}
+
+// wasiBackend reports whether backend runs wasip1 test binaries.
+func wasiBackend(backend string) bool {
+	return backend == BackendWasmtime || backend == BackendWasmer
+}
+
+// autoBackend returns the backend BackendAuto picks for spec, with the
+// fallback warning to report when it is not the browser.
+func (w *Wasmtest) autoBackend(spec execSpec) (string, *Warning, error) {
+	target := cmp.Or(spec.target, w.target)
+	var reasons []string
+	if target != TargetWASIP1 {
+		_, err := lookupBrowser(w.browser)
+		if err == nil {
+			return BackendBrowser, nil, nil
+		}
+		reasons = append(reasons, err.Error())
+		if _, err = exec.LookPath("node"); err == nil {
+			return BackendNode, backendFallback(BackendNode, reasons), nil
+		}
+		reasons = append(reasons, "node: "+err.Error())
+		if _, err = findDeno(); err == nil {
+			return BackendDeno, backendFallback(BackendDeno, reasons), nil
+		}
+		reasons = append(reasons, err.Error())
+	}
+	if target != TargetJS {
+		for _, runtime := range []string{BackendWasmtime, BackendWasmer} {
+			_, err := exec.LookPath(cmp.Or(w.wasiRuntime, runtime))
+			if err == nil {
+				return runtime, backendFallback(runtime, reasons), nil
+			}
+			reasons = append(reasons, runtime+": "+err.Error())
+		}
+	}
+	return "", nil, fmt.Errorf("wasmtest: no backend can run the tests: %s", strings.Join(reasons, "; "))
+}
+
+// backendFallback is the warning of BackendAuto choosing backend because
+// of reasons.
+func backendFallback(backend string, reasons []string) *Warning {
+	message := "running the tests with the " + backend + " backend"
+	if len(reasons) > 0 {
+		message = strings.Join(reasons, "; ") + "; " + message
+	}
+	if wasiBackend(backend) {
+		message += ", built for " + TargetWASIP1
+	}
+	return &Warning{
+		Code:    "backend-fallback",
+		Message: message + ": tests using the DOM will fail",
+		Hint:    "install a browser for the tests needing one, or select a backend with WithBackend",
+	}
+}
+
+// setupBackend sets the -exec command running the test binaries of spec
+// with its backend, with the environment it needs; it is left empty for
+// wasmbrowsertest. remove deletes the files it needs once the run is over.
+func (w *Wasmtest) setupBackend(ctx context.Context, spec *execSpec) (remove func(), err error) {
+	remove = func() {}
+	switch spec.backend {
+	case "", BackendBrowser, BackendBuiltin:
+		// Browsers don't run wasip1 binaries: wasmtime does.
+		if spec.target == TargetWASIP1 {
+			return w.setupWASI(ctx, spec, BackendWasmtime)
+		}
+		return remove, nil
+	case BackendNode:
+		if _, err := exec.LookPath("node"); err != nil {
+			return remove, fmt.Errorf("wasmtest: the node backend needs node in PATH: %w", err)
+		}
+		spec.exec, err = goWasmFile(ctx, *spec, "go_js_wasm_exec")
+		return remove, err
+	case BackendDeno:
+		spec.exec, remove, err = denoExec(ctx, *spec)
+		return remove, err
+	case BackendWasmtime, BackendWasmer:
+		return w.setupWASI(ctx, spec, spec.backend)
+	case BackendDocker:
+		// wasmbrowsertest takes the first browser on PATH.
+		path, remove, err := w.dockerBrowserScript(browserShimName(), w.browserFlags)
+		if err != nil {
+			return func() {}, err
+		}
+		spec.env = append(spec.env, "PATH="+filepath.Dir(path)+string(os.PathListSeparator)+lookupEnv(spec.environ(), "PATH"))
+		return remove, nil
+	}
+	return remove, fmt.Errorf("wasmtest: unknown backend %q (want %s, %s, %s, %s, %s, %s, %s or %s)", spec.backend, BackendBrowser, BackendBuiltin, BackendNode, BackendDeno, BackendWasmtime, BackendWasmer, BackendDocker, BackendAuto)
+}
+
+// setupWASI runs the test binaries of spec with go_wasip1_wasm_exec, which
+// starts the GOWASIRUNTIME runtime, wasmtime or wasmer, found in PATH. The
+// configured runtime is linked under that name in a directory put first on
+// PATH, so that any executable name works. The script only passes PWD and
+// PATH to the tests: the extra env entries of spec are added to
+// GOWASIRUNTIMEARGS, except the ones holding spaces, which it can't quote.
+func (w *Wasmtest) setupWASI(ctx context.Context, spec *execSpec, runtime string) (func(), error) {
+	remove := func() {}
+	bin, err := exec.LookPath(cmp.Or(w.wasiRuntime, runtime))
+	if err != nil {
+		return remove, fmt.Errorf("wasmtest: %s tests need %s in PATH or WithWASIRuntime: %w", TargetWASIP1, runtime, err)
+	}
+	if bin, err = filepath.Abs(bin); err != nil {
+		return remove, err
+	}
+	script, err := goWasmFile(ctx, *spec, "go_wasip1_wasm_exec")
+	if err != nil {
+		return remove, err
+	}
+	dir, err := makeTempDir("wasmtest-wasi-")
+	if err != nil {
+		return remove, err
+	}
+	remove = func() { os.RemoveAll(dir) }
+	if err := os.Symlink(bin, filepath.Join(dir, runtime)); err != nil {
+		remove()
+		return func() {}, err
+	}
+
+	var args []string
+	for _, kv := range spec.env {
+		if !strings.ContainsAny(kv, " \t\n") {
+			args = append(args, "--env", kv)
+		}
+	}
+	path := dir + string(os.PathListSeparator) + lookupEnv(spec.environ(), "PATH")
+	spec.env = append(spec.env, "PATH="+path, "GOWASIRUNTIME="+runtime, "GOWASIRUNTIMEARGS="+strings.Join(args, " "))
+	spec.exec = script
+	return remove, nil
+}
+
+// denoExec returns the -exec command running js/wasm binaries under Deno
+// and removes its script.
+func denoExec(ctx context.Context, spec execSpec) (string, func(), error) {
+	deno, err := findDeno()
+	if err != nil {
+		return "", func() {}, err
+	}
+	wasmExec, err := goWasmFile(ctx, spec, "wasm_exec.js")
+	if err != nil {
+		return "", func() {}, err
+	}
+	script, err := os.CreateTemp("", "wasmtest-deno-*.mjs")
+	if err != nil {
+		return "", func() {}, err
+	}
+	_, err = script.Write(denoExecScript)
+	if cerr := script.Close(); err == nil {
+		err = cerr
+	}
+	remove := func() { os.Remove(script.Name()) }
+	if err != nil {
+		remove()
+		return "", func() {}, err
+	}
+	args := append([]string{deno, "run", "--quiet", "--no-prompt", "--no-config"}, denoPermissions...)
+	args = append(args, script.Name(), wasmExec)
+	for i, arg := range args {
+		args[i] = quoteExecArg(arg)
+	}
+	return strings.Join(args, " "), remove, nil
+}
+
+// findDeno returns the deno executable: from PATH, or else where the Deno
+// installer puts it.
+func findDeno() (string, error) {
+	if path, err := exec.LookPath("deno"); err == nil {
+		return path, nil
+	}
+	dirs := []string{os.Getenv("DENO_INSTALL")}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".deno"))
+	}
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(dir, "bin", "deno")
+		if runtime.GOOS == "windows" {
+			path += ".exe"
+		}
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("wasmtest: the deno backend needs deno, not found in PATH, $DENO_INSTALL/bin or ~/.deno/bin")
+}
+
+// quoteExecArg quotes arg for the go test -exec flag, which splits its
+// value on spaces.
+func quoteExecArg(arg string) string {
+	if !strings.ContainsAny(arg, " \t'\"") {
+		return arg
+	}
+	if !strings.Contains(arg, "'") {
+		return "'" + arg + "'"
+	}
+	return `"` + arg + `"`
+}
+
+// splitExecArgs splits an -exec value into words as go test does: on
+// spaces, with single or double quoted words.
+func splitExecArgs(s string) []string {
+	var args []string
+	for {
+		s = strings.TrimLeft(s, " \t\n\r")
+		if s == "" {
+			return args
+		}
+		if quote := s[0]; quote == '\'' || quote == '"' {
+			end := strings.IndexByte(s[1:], quote)
+			if end < 0 {
+				return append(args, s[1:])
+			}
+			args = append(args, s[1:end+1])
+			s = s[end+2:]
+			continue
+		}
+		end := strings.IndexAny(s, " \t\n\r")
+		if end < 0 {
+			return append(args, s)
+		}
+		args = append(args, s[:end])
+		s = s[end:]
+	}
+}
+
+// goWasmFile returns the path of name, a support file for js/wasm such as
+// go_js_wasm_exec, in the Go installation of spec.
+func goWasmFile(ctx context.Context, spec execSpec, name string) (string, error) {
+	cmd := exec.CommandContext(ctx, spec.toolchain.command(), "env", "GOROOT")
+	cmd.Env = spec.environ()
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("wasmtest: go env GOROOT: %w", err)
+	}
+	goroot := strings.TrimSpace(string(out))
+	// Go 1.24 moved the script from misc/wasm to lib/wasm.
+	for _, dir := range []string{"lib/wasm", "misc/wasm"} {
+		path := filepath.Join(goroot, dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("wasmtest: %s not found in %s/lib/wasm", name, goroot)
+}
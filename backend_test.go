@@ -0,0 +1,210 @@
+package wasmtest
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExecuteNodeBackend(t *testing.T) {
+	nodeExec(t)
+	w := New(WithInstallDisabled(), WithBackend(BackendNode))
+	progress, msgs := collectProgress()
+	if err := w.execute(t.Context(), execSpec{dir: writeModule(t, map[string]string{"p_test.go": wasmPassTest})}, progress); err != nil {
+		t.Fatalf("execute failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	if !strings.Contains(strings.Join(msgs(), "\n"), "running the js/wasm tests with the node backend") {
+		t.Errorf("backend not reported:\n%s", strings.Join(msgs(), "\n"))
+	}
+
+	progress, msgs = collectProgress()
+	w = New(WithInstallDisabled(), WithBackend(BackendNode))
+	if err := w.execute(t.Context(), execSpec{dir: writeModule(t, map[string]string{"f_test.go": wasmFailTest})}, progress); err == nil {
+		t.Errorf("failing tests passed under node:\n%s", strings.Join(msgs(), "\n"))
+	}
+
+	w = New(WithInstallDisabled(), WithBackend("lynx"))
+	if err := w.execute(t.Context(), execSpec{dir: "."}, func(...any) {}); err == nil || !strings.Contains(err.Error(), `unknown backend "lynx"`) {
+		t.Errorf("unknown backend error = %v", err)
+	}
+}
+
+func TestFindDeno(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("DENO_INSTALL", "")
+	if _, err := findDeno(); err == nil {
+		t.Errorf("findDeno found a deno in an empty PATH")
+	}
+
+	homeDeno := filepath.Join(home, ".deno", "bin", "deno")
+	os.MkdirAll(filepath.Dir(homeDeno), 0o755)
+	os.WriteFile(homeDeno, nil, 0o755)
+	if got, err := findDeno(); got != homeDeno {
+		t.Errorf("findDeno() = %q, %v; want %q", got, err, homeDeno)
+	}
+
+	install := t.TempDir()
+	t.Setenv("DENO_INSTALL", install)
+	installDeno := filepath.Join(install, "bin", "deno")
+	os.MkdirAll(filepath.Dir(installDeno), 0o755)
+	os.WriteFile(installDeno, nil, 0o755)
+	if got, err := findDeno(); got != installDeno {
+		t.Errorf("findDeno() = %q, %v; want %q", got, err, installDeno)
+	}
+}
+
+func TestQuoteExecArg(t *testing.T) {
+	for arg, want := range map[string]string{
+		"/usr/bin/deno":      "/usr/bin/deno",
+		"/opt/my tools/deno": "'/opt/my tools/deno'",
+		"/opt/it's/deno":     `"/opt/it's/deno"`,
+	} {
+		if got := quoteExecArg(arg); got != want {
+			t.Errorf("quoteExecArg(%q) = %q; want %q", arg, got, want)
+		}
+	}
+}
+
+func TestExecuteDenoBackend(t *testing.T) {
+	nodeExec(t)
+	// The fake deno records its arguments and runs the script under node,
+	// which provides the same node: modules.
+	dir := t.TempDir()
+	record := filepath.Join(dir, "args")
+	fake := "#!/bin/sh\necho \"$@\" > " + record + "\nshift\nwhile [ \"${1#-}\" != \"$1\" ]; do shift; done\nexec node \"$@\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "deno"), []byte(fake), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	w := New(WithInstallDisabled(), WithBackend(BackendDeno))
+	progress, msgs := collectProgress()
+	if err := w.execute(t.Context(), execSpec{dir: writeModule(t, map[string]string{"p_test.go": wasmPassTest})}, progress); err != nil {
+		t.Fatalf("execute failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	args, err := os.ReadFile(record)
+	if err != nil {
+		t.Fatalf("deno was not run: %v", err)
+	}
+	for _, want := range append([]string{"run", "--no-prompt", "wasm_exec.js"}, denoPermissions...) {
+		if !strings.Contains(string(args), want) {
+			t.Errorf("deno arguments %q miss %q", args, want)
+		}
+	}
+
+	progress, msgs = collectProgress()
+	if err := w.execute(t.Context(), execSpec{dir: writeModule(t, map[string]string{"f_test.go": wasmFailTest})}, progress); err == nil {
+		t.Errorf("failing tests passed under deno:\n%s", strings.Join(msgs(), "\n"))
+	}
+}
+
+// fakeWASIRuntime returns a fake runtime running the wasip1 binaries with
+// the WASI support of node; its name differs from the backend one, as
+// WithWASIRuntime allows.
+func fakeWASIRuntime(t *testing.T) string {
+	t.Helper()
+	nodeExec(t)
+	runtime := filepath.Join(t.TempDir(), "fake-runtime")
+	script, err := filepath.Abs("testdata/nodewasi.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(runtime, []byte("#!/bin/sh\nexec node --no-warnings "+script+" \"$@\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return runtime
+}
+
+func TestExecuteWASIBackends(t *testing.T) {
+	runtime := fakeWASIRuntime(t)
+	dir := writeModule(t, map[string]string{
+		"p_test.go": "package p\n\nimport (\n\t\"os\"\n\t\"runtime\"\n\t\"testing\"\n)\n\n" +
+			"func TestWASI(t *testing.T) {\n\tif runtime.GOOS != \"wasip1\" {\n\t\tt.Fatal(runtime.GOOS)\n\t}\n" +
+			"\tif data, err := os.ReadFile(\"testdata/in.txt\"); string(data) != \"hi\" {\n\t\tt.Fatal(string(data), err)\n\t}\n" +
+			"\tif os.Getenv(\"WASI_TEST\") != \"on\" {\n\t\tt.Fatal(\"env not passed\")\n\t}\n}\n",
+		"testdata/in.txt": "hi",
+	})
+	for _, backend := range []string{BackendWasmtime, BackendWasmer} {
+		w := New(WithInstallDisabled(), WithBackend(backend), WithWASIRuntime(runtime))
+		progress, msgs := collectProgress()
+		if err := w.execute(t.Context(), execSpec{dir: dir, env: []string{"WASI_TEST=on"}}, progress); err != nil {
+			t.Errorf("%s: execute failed: %v\n%s", backend, err, strings.Join(msgs(), "\n"))
+		}
+	}
+
+	w := New(WithInstallDisabled(), WithBackend(BackendWasmtime), WithWASIRuntime(filepath.Join(t.TempDir(), "wasmtime")))
+	if err := w.execute(t.Context(), execSpec{dir: dir}, func(...any) {}); err == nil || !strings.Contains(err.Error(), "need wasmtime") {
+		t.Errorf("missing runtime error = %v", err)
+	}
+}
+
+func TestRunTestsWASIBackends(t *testing.T) {
+	runtime := fakeWASIRuntime(t)
+	dir := writeModule(t, map[string]string{
+		"p_test.go": "//go:build wasip1\n\npackage p\n\nimport \"testing\"\n\nfunc TestWASI(t *testing.T) {}\n",
+	})
+	for _, backend := range []string{BackendWasmtime, BackendWasmer} {
+		res, err := RunTestsResult(dir, func(...any) {}, WithInstallDisabled(), WithBackend(backend), WithWASIRuntime(runtime))
+		if err != nil || !slices.Equal(res.PassedTests, []string{"TestWASI"}) {
+			t.Errorf("%s: RunTestsResult() = %+v, %v", backend, res, err)
+		}
+	}
+}
+
+func TestAutoBackend(t *testing.T) {
+	bin := t.TempDir()
+	t.Setenv("PATH", bin)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("DENO_INSTALL", "")
+	browserAppPaths = map[string][]string{}
+	defer func(paths map[string][]string) { browserAppPaths = paths }(browserAppPaths)
+	tool := func(name string) {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := New(WithInstallDisabled(), WithBackend(BackendAuto))
+	if _, _, err := w.autoBackend(execSpec{}); err == nil {
+		t.Errorf("autoBackend found a backend in an empty PATH")
+	}
+	tool("wasmtime")
+	if backend, warn, _ := w.autoBackend(execSpec{}); backend != BackendWasmtime || warn == nil || !strings.Contains(warn.Message, TargetWASIP1) {
+		t.Errorf("autoBackend() = %q, %+v; want wasmtime with a wasip1 warning", backend, warn)
+	}
+	if _, _, err := w.autoBackend(execSpec{target: TargetJS}); err == nil {
+		t.Errorf("autoBackend picked wasmtime for js/wasm tests")
+	}
+	tool("node")
+	backend, warn, _ := w.autoBackend(execSpec{})
+	if backend != BackendNode || warn == nil || warn.Code != "backend-fallback" || !strings.Contains(warn.Message, errNoBrowser.Error()) {
+		t.Errorf("autoBackend() = %q, %+v; want node with the missing browser warning", backend, warn)
+	}
+	if backend, _, _ := w.autoBackend(execSpec{target: TargetWASIP1}); backend != BackendWasmtime {
+		t.Errorf("autoBackend() for wasip1 tests = %q", backend)
+	}
+	tool("browser")
+	w = New(WithInstallDisabled(), WithBackend(BackendAuto), WithBrowser(filepath.Join(bin, "browser")))
+	if backend, warn, _ := w.autoBackend(execSpec{}); backend != BackendBrowser || warn != nil {
+		t.Errorf("autoBackend() = %q, %+v; want the browser", backend, warn)
+	}
+}
+
+func TestExecuteAutoBackend(t *testing.T) {
+	nodeExec(t)
+	if _, err := findBrowser(); err == nil {
+		t.Skip("a browser is installed; the auto backend would not fall back")
+	}
+	w := New(WithInstallDisabled(), WithBackend(BackendAuto))
+	progress, msgs := collectProgress()
+	if err := w.execute(t.Context(), execSpec{dir: writeModule(t, map[string]string{"p_test.go": wasmPassTest})}, progress); err != nil {
+		t.Fatalf("execute failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	if out := strings.Join(msgs(), "\n"); !strings.Contains(out, "backend-fallback") || !strings.Contains(out, "with the node backend") {
+		t.Errorf("fallback not reported:\n%s", out)
+	}
+}
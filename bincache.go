@@ -0,0 +1,218 @@
+package wasmtest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// binaryCacheMaxAge is how long an unused test binary stays in the binary
+// cache.
+const binaryCacheMaxAge = 7 * 24 * time.Hour
+
+// WithBinaryCache keeps the js/wasm test binaries compiled by go test runs,
+// Bundle and Serve in dir, keyed on a hash of their build inputs: the Go
+// files, embedded files and go.mod of the packages they are built from, the
+// build flags and the Go toolchain. When nothing changed since a binary was
+// compiled, go test -c is skipped and the cached binary runs directly. An
+// empty dir uses a wasmtest directory in the user cache directory. The
+// WASMTEST_BINARY_CACHE environment variable and the binary_cache setting
+// of the configuration file set it too.
+//
+// A go test run served from the cache runs its binary with go tool
+// test2json, like RunBinary: go vet is not run, and go test's cache of test
+// results is not used. Runs with coverage, CPU profiles, WithKeepBinary, the
+// docker backend, TinyGo or the wasip1 target are always compiled.
+func WithBinaryCache(dir string) Option {
+	return func(w *Wasmtest) {
+		if dir == "" {
+			if cache, err := os.UserCacheDir(); err == nil {
+				dir = filepath.Join(cache, "wasmtest", "binaries")
+			}
+		}
+		w.binaryCache = dir
+	}
+}
+
+// cacheableBoolFlags and cacheableValueFlags are the go test flags that
+// leave a test binary run from the binary cache the same as go test would
+// run it: the test flags turned into test binary flags by testFlagsOf, and
+// the build flags, which are part of the cache key.
+var (
+	cacheableBoolFlags  = []string{"-v", "-trimpath"}
+	cacheableValueFlags = []string{"-tags", "-ldflags", "-gcflags", "-asmflags", "-mod", "-modfile", "-overlay", "-pgo"}
+)
+
+// listedBuildFlags are the build flags changing which files go list reports.
+var listedBuildFlags = []string{"-tags", "-mod", "-modfile", "-overlay"}
+
+// buildFlagsOf returns the build flags of the go test flags args, and
+// whether every flag of args is one a cached binary can run with.
+func buildFlagsOf(args []string) ([]string, bool) {
+	var flags []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, _, hasValue := strings.Cut(arg, "=")
+		name = "-" + strings.TrimLeft(name, "-")
+		switch {
+		case strings.HasPrefix(name, "-test."), slices.Contains(testBoolFlags, name):
+		case slices.Contains(testValueFlags, name):
+			if !hasValue {
+				i++
+			}
+		case slices.Contains(cacheableBoolFlags, name):
+			if name != "-v" {
+				flags = append(flags, arg)
+			}
+		case slices.Contains(cacheableValueFlags, name):
+			flags = append(flags, arg)
+			if !hasValue && i+1 < len(args) {
+				i++
+				flags = append(flags, args[i])
+			}
+		default:
+			return nil, false
+		}
+	}
+	return flags, true
+}
+
+// binaryCacheEntry returns the directory of the binary cache holding the
+// test binary of spec, or "" when the cache is disabled or doesn't apply to
+// spec.
+func (w *Wasmtest) binaryCacheEntry(ctx context.Context, spec execSpec) (string, error) {
+	if w.binaryCache == "" || spec.native || spec.tinyGo != "" || spec.binary != nil || spec.target == TargetWASIP1 || spec.backend == BackendDocker || w.cpuProfile || w.keepBinary != "" {
+		return "", nil
+	}
+	key, err := buildKey(ctx, spec)
+	if err != nil || key == "" {
+		return "", err
+	}
+	return filepath.Join(w.binaryCache, key), nil
+}
+
+// buildKey returns a hash of the inputs of the test binary of spec: the
+// toolchain, the build flags and the names, sizes and modification times of
+// the files of the packages it is built from, as go list reports them. The
+// standard library is covered by the toolchain version. It returns "" when
+// spec.args hold flags a cached binary can't honour.
+func buildKey(ctx context.Context, spec execSpec) (string, error) {
+	flags, ok := buildFlagsOf(spec.args)
+	if !ok {
+		return "", nil
+	}
+	goEnv, err := goEnvVars(ctx, spec, "GOVERSION", "GOROOT", "GOOS", "GOARCH", "GOWASM", "GOFLAGS", "GOEXPERIMENT")
+	if err != nil {
+		return "", err
+	}
+
+	const format = `{{if not .Standard}}{{.ImportPath}}{{"\t"}}{{.Dir}}` +
+		`{{range .GoFiles}}{{"\t"}}{{.}}{{end}}{{range .CgoFiles}}{{"\t"}}{{.}}{{end}}` +
+		`{{range .TestGoFiles}}{{"\t"}}{{.}}{{end}}{{range .XTestGoFiles}}{{"\t"}}{{.}}{{end}}` +
+		`{{range .EmbedFiles}}{{"\t"}}{{.}}{{end}}{{range .TestEmbedFiles}}{{"\t"}}{{.}}{{end}}` +
+		`{{range .XTestEmbedFiles}}{{"\t"}}{{.}}{{end}}{{with .Module}}{{"\t"}}{{.GoMod}}{{end}}` +
+		`{{"\n"}}{{end}}`
+	args := []string{"list", "-deps", "-test", "-f", format}
+	for i := 0; i < len(flags); i++ {
+		name, _, hasValue := strings.Cut(flags[i], "=")
+		name = "-" + strings.TrimLeft(name, "-")
+		n := 1
+		if !hasValue && slices.Contains(cacheableValueFlags, name) {
+			n = 2
+		}
+		if slices.Contains(listedBuildFlags, name) {
+			args = append(args, flags[i:min(i+n, len(flags))]...)
+		}
+		i += n - 1
+	}
+	list := exec.CommandContext(ctx, spec.toolchain.command(), append(args, ".")...)
+	list.Dir = spec.dir
+	list.Env = spec.environ()
+	out, err := list.Output()
+	if err != nil {
+		return "", fmt.Errorf("wasmtest: go list failed: %w", err)
+	}
+
+	sum := sha256.New()
+	fmt.Fprintf(sum, "%s\n%q\n", spec.toolchain.command(), flags)
+	for _, name := range []string{"GOVERSION", "GOROOT", "GOOS", "GOARCH", "GOWASM", "GOFLAGS", "GOEXPERIMENT"} {
+		fmt.Fprintf(sum, "%s=%s\n", name, goEnv[name])
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		fmt.Fprintf(sum, "package %s\n", fields[0])
+		for _, file := range fields[2:] {
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(fields[1], file)
+			}
+			if info, err := os.Stat(file); err == nil {
+				fmt.Fprintf(sum, "%s %d %d\n", file, info.Size(), info.ModTime().UnixNano())
+			} else {
+				fmt.Fprintf(sum, "%s missing\n", file)
+			}
+		}
+	}
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// cachedBinary returns the test binary stored in the binary cache entry,
+// or nil when there is none. Using it keeps it in the cache.
+func cachedBinary(entry string) *testBinary {
+	if entry == "" {
+		return nil
+	}
+	path := filepath.Join(entry, "test.wasm")
+	pkg, err := os.ReadFile(filepath.Join(entry, "package"))
+	if err != nil {
+		return nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	now := time.Now()
+	os.Chtimes(entry, now, now)
+	return &testBinary{path: path, pkg: strings.TrimSpace(string(pkg))}
+}
+
+// storeBinary copies the test binary bin of pkg into the binary cache
+// entry, replacing it atomically, and removes the entries unused for
+// binaryCacheMaxAge.
+func storeBinary(entry, bin, pkg string) error {
+	cache := filepath.Dir(entry)
+	if err := os.MkdirAll(cache, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(cache, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	if err := copyFile(bin, filepath.Join(tmp, "test.wasm")); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "package"), []byte(pkg+"\n"), 0o644); err != nil {
+		return err
+	}
+	os.RemoveAll(entry)
+	if err := os.Rename(tmp, entry); err != nil {
+		return err
+	}
+
+	entries, _ := os.ReadDir(cache)
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil && e.IsDir() && time.Since(info.ModTime()) > binaryCacheMaxAge {
+			os.RemoveAll(filepath.Join(cache, e.Name()))
+		}
+	}
+	return nil
+}
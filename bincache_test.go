@@ -0,0 +1,81 @@
+package wasmtest
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBuildFlagsOf(t *testing.T) {
+	for _, tc := range []struct {
+		args  []string
+		flags []string
+		ok    bool
+	}{
+		{nil, nil, true},
+		{[]string{"-run", "TestA", "-count=1", "-v", "-short"}, nil, true},
+		{[]string{"-tags", "dev", "-run=X", "-ldflags=-X main.v=1", "-trimpath"}, []string{"-tags", "dev", "-ldflags=-X main.v=1", "-trimpath"}, true},
+		{[]string{"-test.run=X", "--mod", "vendor"}, []string{"--mod", "vendor"}, true},
+		{[]string{"-run", "X", "-coverprofile", "c.out"}, nil, false},
+		{[]string{"-cpuprofile=cpu.out"}, nil, false},
+	} {
+		flags, ok := buildFlagsOf(tc.args)
+		if !slices.Equal(flags, tc.flags) || ok != tc.ok {
+			t.Errorf("buildFlagsOf(%q) = %q, %v; want %q, %v", tc.args, flags, ok, tc.flags, tc.ok)
+		}
+	}
+}
+
+func TestExecuteBinaryCache(t *testing.T) {
+	nodeExec(t)
+	cache := t.TempDir()
+	w := New(WithInstallDisabled(), WithBackend(BackendNode), WithBinaryCache(cache))
+	dir := writeModule(t, map[string]string{
+		"p_test.go":  wasmPassTest,
+		"other/o.go": "package other\n",
+	})
+	run := func() (string, error) {
+		progress, msgs := collectProgress()
+		err := w.execute(t.Context(), execSpec{dir: dir, args: []string{"-run", "TestPass"}, list: true}, progress)
+		return strings.Join(msgs(), "\n"), err
+	}
+
+	// The first run compiles the tests and caches the binary.
+	out, err := run()
+	if err != nil {
+		t.Fatalf("execute failed: %v\n%s", err, out)
+	}
+	if strings.Contains(out, "reused") || !strings.Contains(out, "test pass TestPass") {
+		t.Errorf("unexpected first run progress:\n%s", out)
+	}
+	entries, err := os.ReadDir(cache)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("cache entries = %v, %v", entries, err)
+	}
+
+	// Unchanged sources, or changes to packages the tests don't import,
+	// reuse the binary.
+	if err := os.WriteFile(filepath.Join(dir, "other", "o.go"), []byte("package other\n\nvar X = 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err = run()
+	if err != nil {
+		t.Fatalf("cached execute failed: %v\n%s", err, out)
+	}
+	for _, want := range []string{"reused the example.com/tmp test binary", "list [TestPass]", "test pass TestPass"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("cached run progress lacks %q:\n%s", want, out)
+		}
+	}
+
+	// A change to the tests compiles them again.
+	if err := os.WriteFile(filepath.Join(dir, "p_test.go"), []byte(strings.ReplaceAll(wasmFailTest, "TestFail", "TestPass")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err = run()
+	if err == nil || strings.Contains(out, "reused") || !strings.Contains(out, "test fail TestPass") {
+		t.Errorf("execute after a change = %v:\n%s", err, out)
+	}
+}
@@ -0,0 +1,247 @@
+package wasmtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Browser names accepted by WithBrowser, WASMTEST_BROWSER and the browser
+// setting of the configuration file besides an executable path.
+const (
+	BrowserChrome   = "chrome"
+	BrowserChromium = "chromium"
+	BrowserEdge     = "edge"
+	BrowserFirefox  = "firefox"
+)
+
+// browserCandidates are the executables probed by findBrowser, in order of
+// preference. Blink based browsers come first because wasmbrowsertest and
+// most CI images use them.
+var browserCandidates = []string{
+	"google-chrome", "google-chrome-stable", "chromium", "chromium-browser", "chrome",
+	"headless-shell", "headless_shell", "microsoft-edge", "msedge", "firefox",
+}
+
+// browserAppPaths are well known install locations that are usually not on
+// PATH.
+var browserAppPaths = map[string][]string{
+	"darwin": {
+		"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+		"/Applications/Chromium.app/Contents/MacOS/Chromium",
+		"/Applications/Microsoft Edge.app/Contents/MacOS/Microsoft Edge",
+		"/Applications/Firefox.app/Contents/MacOS/firefox",
+	},
+	"windows": {
+		`C:\Program Files\Google\Chrome\Application\chrome.exe`,
+		`C:\Program Files (x86)\Google\Chrome\Application\chrome.exe`,
+		`C:\Program Files (x86)\Microsoft\Edge\Application\msedge.exe`,
+		`C:\Program Files\Mozilla Firefox\firefox.exe`,
+	},
+}
+
+// errNoBrowser is returned by findBrowser when no browser is installed.
+var errNoBrowser = errors.New("wasmtest: no supported browser (Chrome, Chromium, Edge or Firefox) found")
+
+// defaultLaunchRetries is the number of browser launch retries of New.
+const defaultLaunchRetries = 2
+
+// transientLaunchErrors are substrings of the errors reported when a
+// browser fails to start for reasons that usually go away on a retry, like
+// a slow start under CI load.
+var transientLaunchErrors = []string{
+	"DevToolsActivePort file doesn't exist",
+	"Chrome failed to start",
+	"websocket url timeout reached",
+	"failed to start browser",
+}
+
+// isTransientLaunchError reports whether line reports a transient browser
+// launch failure.
+func isTransientLaunchError(line string) bool {
+	for _, s := range transientLaunchErrors {
+		if strings.Contains(line, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// findBrowser returns the path of the first installed browser.
+func findBrowser() (string, error) {
+	return lookupBrowser("")
+}
+
+// lookupBrowser returns the path of the browser selected by name: the first
+// installed browser when empty, the first installed browser of a family for
+// one of the Browser names, or else the executable name, looked up in PATH,
+// or path.
+func lookupBrowser(name string) (string, error) {
+	family := strings.ToLower(name)
+	switch family {
+	case "", BrowserChrome, BrowserChromium, BrowserEdge, BrowserFirefox:
+	default:
+		if strings.ContainsAny(name, `/\`) {
+			if _, err := os.Stat(name); err != nil {
+				return "", fmt.Errorf("wasmtest: browser %s: %w", name, err)
+			}
+			return name, nil
+		}
+		p, err := exec.LookPath(name)
+		if err != nil {
+			return "", fmt.Errorf("wasmtest: browser %q not found: %w", name, err)
+		}
+		return p, nil
+	}
+	for _, candidate := range browserCandidates {
+		if family != "" && browserFamily(candidate) != family {
+			continue
+		}
+		if p, err := exec.LookPath(candidate); err == nil {
+			return p, nil
+		}
+	}
+	for _, p := range browserAppPaths[runtime.GOOS] {
+		if family != "" && browserFamily(p) != family {
+			continue
+		}
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	if family != "" {
+		return "", fmt.Errorf("wasmtest: no %s browser found", family)
+	}
+	return "", errNoBrowser
+}
+
+// browserFamily returns the Browser name of the executable at path, or ""
+// when it is not recognized.
+func browserFamily(path string) string {
+	base := strings.ToLower(filepath.Base(path))
+	switch {
+	case strings.Contains(base, "firefox"):
+		return BrowserFirefox
+	case strings.Contains(base, "edge"):
+		return BrowserEdge
+	case strings.Contains(base, "chromium"), strings.Contains(base, "headless"):
+		return BrowserChromium
+	case strings.Contains(base, "chrome"):
+		return BrowserChrome
+	}
+	return ""
+}
+
+// browserShimName is the executable name found first by the chromedp
+// browser lookup of wasmbrowsertest on PATH.
+func browserShimName() string {
+	if runtime.GOOS == "windows" {
+		return "chrome.exe"
+	}
+	return "headless_shell"
+}
+
+// browserShim makes wasmbrowsertest use the browser at path with the extra
+// command line flags: wasmbrowsertest has no browser option and takes the
+// first browser it finds on PATH, so the returned PATH entry puts first,
+// before pathList, a temporary directory holding, under the name looked up
+// first, a link to path or, with flags, a script running it with them.
+// remove deletes the directory.
+func browserShim(path string, flags []string, pathList string) (entry string, remove func(), err error) {
+	if isFirefox(path) {
+		return "", nil, fmt.Errorf("wasmtest: wasmbrowsertest only drives Chromium based browsers, not %s; use RunBundle to run the tests in Firefox", path)
+	}
+	dir, err := makeTempDir("wasmtest-browser-")
+	if err != nil {
+		return "", nil, err
+	}
+	remove = func() { os.RemoveAll(dir) }
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	shim := filepath.Join(dir, browserShimName())
+	switch {
+	case len(flags) == 0:
+		err = os.Symlink(path, shim)
+	case runtime.GOOS == "windows":
+		err = errors.New("wasmtest: browser flags need a POSIX shell, they are only supported by RunBundle on Windows")
+	default:
+		script := "#!/bin/sh\nexec " + shellQuote(path) + ` "$@"`
+		for _, flag := range flags {
+			script += " " + shellQuote(flag)
+		}
+		err = os.WriteFile(shim, []byte(script+"\n"), 0o755)
+	}
+	if err != nil {
+		remove()
+		return "", nil, err
+	}
+	entry = "PATH=" + dir
+	if pathList != "" {
+		entry += string(os.PathListSeparator) + pathList
+	}
+	return entry, remove, nil
+}
+
+// shellQuote quotes s as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// isFirefox reports whether the browser executable is Firefox, which takes
+// different command line flags than Blink based browsers.
+func isFirefox(path string) bool {
+	return strings.Contains(strings.ToLower(filepath.Base(path)), "firefox")
+}
+
+// launchBrowser starts a browser with a throwaway profile loading url,
+// headless unless headful is set, in which case the devtools are opened
+// too, with the extra command line flags. The browser is killed and its
+// profile removed when ctx is done; the returned channel receives the
+// browser exit error.
+func launchBrowser(ctx context.Context, path, url string, headful bool, flags []string) (<-chan error, error) {
+	profile, err := makeTempDir("wasmtest-profile-")
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+	if isFirefox(path) {
+		args = []string{"-headless", "-no-remote", "-profile", profile}
+		if headful {
+			args[0] = "-devtools"
+		}
+	} else {
+		args = []string{
+			"--headless=new", "--disable-gpu", "--no-first-run", "--no-default-browser-check",
+			"--user-data-dir=" + profile,
+		}
+		if headful {
+			args[0] = "--auto-open-devtools-for-tabs"
+		}
+		// Chrome refuses to start as root without disabling its sandbox,
+		// which is the norm inside CI containers.
+		if os.Geteuid() == 0 {
+			args = append(args, "--no-sandbox")
+		}
+	}
+	// The page comes last: some launchers take the last argument as it.
+	args = append(append(args, flags...), url)
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(profile)
+		return nil, err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+		os.RemoveAll(profile)
+	}()
+	return done, nil
+}
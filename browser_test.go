@@ -0,0 +1,162 @@
+package wasmtest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flakyLauncher returns a script that fails like a browser that couldn't
+// start on its first `failures` invocations, printing failure to stderr,
+// and then runs real with the same arguments.
+func flakyLauncher(t *testing.T, failures int, failure, real string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the flaky launcher is a shell script")
+	}
+	dir := t.TempDir()
+	count := filepath.Join(dir, "count")
+	script := filepath.Join(dir, "launcher")
+	content := fmt.Sprintf(`#!/bin/sh
+n=$(cat %[1]q 2>/dev/null || echo 0)
+echo $((n+1)) > %[1]q
+if [ "$n" -lt %[2]d ]; then
+	echo %[3]q >&2
+	exit 1
+fi
+exec %[4]q "$@"
+`, count, failures, failure, real)
+	if err := os.WriteFile(script, []byte(content), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
+
+func TestIsTransientLaunchError(t *testing.T) {
+	for line, want := range map[string]bool{
+		"panic: DevToolsActivePort file doesn't exist": true,
+		"websocket url timeout reached":                true,
+		"--- FAIL: TestDOM (0.01s)":                    false,
+	} {
+		if got := isTransientLaunchError(line); got != want {
+			t.Errorf("isTransientLaunchError(%q) = %v, want %v", line, got, want)
+		}
+	}
+}
+
+func TestExecuteRetriesBrowserLaunch(t *testing.T) {
+	node := nodeExec(t)
+	launchErr := "chrome: DevToolsActivePort file doesn't exist"
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	spec := execSpec{dir: "./example", args: []string{"-run", "TestMathHelper"}}
+
+	spec.exec = flakyLauncher(t, 1, launchErr, node)
+	progress, msgs := collectProgress()
+	w := New(WithInstallDisabled(), WithLogger(func(...any) {}))
+	if err := w.execute(ctx, spec, progress); err != nil {
+		t.Fatalf("execute failed after a transient launch error: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	got := msgs()
+	if !slices.ContainsFunc(got, func(m string) bool { return strings.Contains(m, "browser-launch-retry") }) {
+		t.Errorf("no retry warning in progress:\n%s", strings.Join(got, "\n"))
+	}
+	if slices.ContainsFunc(got, func(m string) bool { return strings.HasSuffix(m, " "+launchErr) }) {
+		t.Errorf("retried launch error was reported as output:\n%s", strings.Join(got, "\n"))
+	}
+
+	// Without retries the launch error is the outcome of the run.
+	spec.exec = flakyLauncher(t, 1, launchErr, node)
+	progress, msgs = collectProgress()
+	w = New(WithInstallDisabled(), WithLogger(func(...any) {}), WithLaunchRetries(0))
+	if err := w.execute(ctx, spec, progress); err == nil {
+		t.Error("execute succeeded without retries")
+	}
+	if !slices.ContainsFunc(msgs(), func(m string) bool { return strings.HasSuffix(m, " "+launchErr) }) {
+		t.Errorf("launch error not reported:\n%s", strings.Join(msgs(), "\n"))
+	}
+}
+
+func TestLookupBrowser(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("installed applications could be found outside of PATH")
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"chromium", "firefox", "my-build"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+
+	for name, want := range map[string]string{
+		"":                             "chromium",
+		BrowserChromium:                "chromium",
+		"Firefox":                      "firefox",
+		"my-build":                     "my-build",
+		filepath.Join(dir, "my-build"): "my-build",
+		filepath.Join(dir, "missing"):  "",
+		BrowserEdge:                    "",
+		"not-installed":                "",
+	} {
+		got, err := lookupBrowser(name)
+		if want == "" {
+			if err == nil {
+				t.Errorf("lookupBrowser(%q) = %q, want an error", name, got)
+			}
+			continue
+		}
+		if err != nil || got != filepath.Join(dir, want) {
+			t.Errorf("lookupBrowser(%q) = %q, %v; want %s", name, got, err, want)
+		}
+	}
+}
+
+func TestBrowserShim(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need privileges on Windows")
+	}
+	browser := filepath.Join(t.TempDir(), "chromium")
+	entry, remove, err := browserShim(browser, nil, "/usr/bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, rest, _ := strings.Cut(strings.TrimPrefix(entry, "PATH="), string(os.PathListSeparator))
+	if target, err := os.Readlink(filepath.Join(dir, browserShimName())); err != nil || target != browser || rest != "/usr/bin" {
+		t.Errorf("entry %q links %q, %v", entry, target, err)
+	}
+	remove()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("shim directory left behind: %v", err)
+	}
+
+	if _, _, err := browserShim("/usr/bin/firefox", nil, ""); err == nil {
+		t.Error("wasmbrowsertest was given Firefox")
+	}
+}
+
+func TestBrowserShimFlags(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("browser flags need a POSIX shell")
+	}
+	browser := filepath.Join(t.TempDir(), "chromium")
+	if err := os.WriteFile(browser, []byte("#!/bin/sh\nfor a in \"$@\"; do echo \"$a\"; done\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	entry, remove, err := browserShim(browser, []string{"--lang=es", "--user-agent=it's me"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remove()
+	out, err := exec.Command(filepath.Join(strings.TrimPrefix(entry, "PATH="), browserShimName()), "--headless=new", "about:blank").Output()
+	if want := "--headless=new\nabout:blank\n--lang=es\n--user-agent=it's me\n"; err != nil || string(out) != want {
+		t.Errorf("shim ran the browser with %q, %v; want %q", out, err, want)
+	}
+}
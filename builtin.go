@@ -0,0 +1,187 @@
+package wasmtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// builtin reports whether the tests of spec run in a browser launched by
+// wasmtest itself (see BackendBuiltin).
+func (spec execSpec) builtin() bool {
+	return !spec.native && spec.backend == BackendBuiltin && spec.exec == "" && spec.tinyGo == ""
+}
+
+// testBoolFlags and testValueFlags are the go test flags passed on to the
+// test binary, the others being build flags.
+var (
+	testBoolFlags  = []string{"-short", "-failfast", "-benchmem"}
+	testValueFlags = []string{"-run", "-skip", "-count", "-bench", "-benchtime", "-shuffle", "-timeout", "-cpu", "-parallel"}
+)
+
+// buildValueFlags are the go test build flags taking a value, dropped with
+// it from the test binary flags.
+var buildValueFlags = []string{"-tags", "-ldflags", "-gcflags", "-asmflags", "-mod", "-modfile", "-overlay", "-pgo", "-toolexec", "-exec", "-o", "-p", "-coverpkg", "-covermode"}
+
+// testFlagsOf returns the test binary flags of the go test flags args,
+// e.g. -test.run X for -run X; build flags and -v are dropped.
+func testFlagsOf(args []string) []string {
+	var flags []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, _, hasValue := strings.Cut(arg, "=")
+		name = "-" + strings.TrimLeft(name, "-")
+		switch {
+		case strings.HasPrefix(name, "-test."):
+			flags = append(flags, arg)
+		case slices.Contains(testBoolFlags, name):
+			flags = append(flags, "-test."+strings.TrimPrefix(arg, "-"))
+		case slices.Contains(testValueFlags, name):
+			flags = append(flags, "-test."+strings.TrimLeft(arg, "-"))
+			if !hasValue && i+1 < len(args) {
+				i++
+				flags = append(flags, args[i])
+			}
+		case slices.Contains(buildValueFlags, name) && !hasValue:
+			i++
+		}
+	}
+	return flags
+}
+
+// compileBuiltin compiles the tests of spec with go test -c for the
+// builtin backend, into a temporary directory remove deletes. Build errors
+// are reported as "err" lines, as go test does.
+func (w *Wasmtest) compileBuiltin(ctx context.Context, spec execSpec, report func(msgs ...any)) (bin *testBinary, remove func(), err error) {
+	list := exec.CommandContext(ctx, spec.toolchain.command(), "list", "-f", "{{.ImportPath}}")
+	list.Dir = spec.dir
+	list.Env = spec.environ()
+	pkg, err := list.Output()
+	if err != nil {
+		return nil, nil, fmt.Errorf("wasmtest: go list failed: %w", err)
+	}
+	tmp, err := makeTempDir("wasmtest-builtin-")
+	if err != nil {
+		return nil, nil, err
+	}
+	remove = func() { os.RemoveAll(tmp) }
+
+	path := filepath.Join(tmp, "test.wasm")
+	build := exec.CommandContext(ctx, spec.toolchain.command(), append([]string{"test", "-c", "-o", path}, spec.args...)...)
+	build.Dir = spec.dir
+	build.Env = spec.environ()
+	if out, err := build.CombinedOutput(); err != nil {
+		for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+			report("err", line)
+		}
+		remove()
+		return nil, nil, fmt.Errorf("❌💥 BUILD ERROR: compiling the tests of %s failed: %v", spec.dir, err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		// go test -c writes nothing for a package without tests.
+		remove()
+		return nil, nil, fmt.Errorf("wasmtest: no test binary built for %s", spec.dir)
+	}
+	return &testBinary{path: path, pkg: strings.TrimSpace(string(pkg))}, remove, nil
+}
+
+// runInBrowser runs the test binary of spec in a browser launched with the
+// harness page, or in a new tab of the WithWarmBrowser browser, serving it
+// with the wasm_exec.js of the Go installation, and writes its standard
+// output to out, for test2json; its standard error and console calls are
+// reported. It returns the error of the binary exit code. When
+// holdLaunchErrors is set, a browser exiting before loading the page is
+// returned as a launch error instead, so the caller can retry.
+func (w *Wasmtest) runInBrowser(ctx context.Context, spec execSpec, out io.Writer, report func(msgs ...any), holdLaunchErrors bool) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	goEnv, err := goEnvVars(ctx, spec, "GOROOT")
+	if err != nil {
+		return nil, err
+	}
+	wasmExec, err := findWasmExecJS(goEnv["GOROOT"])
+	if err != nil {
+		return nil, err
+	}
+	browser := "the warm browser"
+	if w.warmBrowser == nil {
+		if browser, err = lookupBrowser(w.browser); err != nil {
+			return nil, err
+		}
+	}
+
+	env := map[string]string{}
+	for _, kv := range spec.env {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	manifest, err := json.Marshal(BundleManifest{
+		SchemaVersion: SchemaVersion,
+		Package:       spec.binary.pkg,
+		Wasm:          "test.wasm",
+		Args:          slices.Concat([]string{"-test.v=test2json", "-test.paniconexit0"}, spec.args, spec.testArgs),
+		Env:           env,
+	})
+	if err != nil {
+		return nil, err
+	}
+	files := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/wasm_exec.js":
+			http.ServeFile(rw, r, wasmExec)
+		case "/test.wasm":
+			http.ServeFile(rw, r, spec.binary.path)
+		default:
+			http.NotFound(rw, r)
+		}
+	})
+	h := newHarness(files, func() ([]byte, error) { return manifest, nil }, func(msgs ...any) {
+		if len(msgs) == 2 && msgs[0] == "out" {
+			fmt.Fprintln(out, msgs[1])
+			return
+		}
+		report(msgs...)
+	})
+	url, err := h.serve(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	var exited <-chan error
+	if w.warmBrowser != nil {
+		if exited, err = w.warmBrowser.openTab(ctx, url); err != nil {
+			return nil, err
+		}
+	} else if exited, err = launchBrowser(ctx, browser, url, w.headful, w.browserFlags); err != nil {
+		return nil, fmt.Errorf("wasmtest: failed to launch %s: %w", browser, err)
+	}
+	w.debugf(report, "builtin backend: %s opened %s", browser, url)
+
+	// A browser dying before the page reports is a failure, not a hang.
+	go func() {
+		var err error
+		select {
+		case err = <-exited:
+		case <-ctx.Done():
+			return
+		}
+		msg := fmt.Sprintf("browser exited before the tests finished: %v", err)
+		if !h.pageLoaded() {
+			msg = fmt.Sprintf("failed to start browser: %s exited before loading the tests: %v", filepath.Base(browser), err)
+		}
+		h.finish(harnessExit{Code: 1, Error: msg})
+	}()
+	err = h.wait(ctx)
+	if err != nil && holdLaunchErrors && !h.pageLoaded() && ctx.Err() == nil {
+		return []string{err.Error()}, err
+	}
+	return nil, err
+}
@@ -0,0 +1,54 @@
+package wasmtest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTestFlagsOf(t *testing.T) {
+	args := []string{"-run", "TestA", "-tags", "integration", "-count=2", "-v", "-ldflags=-s", "-short", "--skip", "TestB", "-test.benchtime=1x"}
+	want := []string{"-test.run", "TestA", "-test.count=2", "-test.short", "-test.skip", "TestB", "-test.benchtime=1x"}
+	if got := testFlagsOf(args); !reflect.DeepEqual(got, want) {
+		t.Errorf("testFlagsOf() = %q, want %q", got, want)
+	}
+}
+
+func TestBuiltinBackend(t *testing.T) {
+	browser := nodeBrowser(t)
+	// No wasmbrowsertest is installed nor looked up.
+	gopath := t.TempDir()
+	t.Setenv("GOPATH", gopath)
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	dir := writeModule(t, map[string]string{"pass_test.go": wasmPassTest, "fail_test.go": wasmFailTest})
+	w := New(WithBackend(BackendBuiltin), WithBrowser(browser), WithLogger(func(...any) {}))
+	if err := <-w.Ready(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(gopath, "bin")); err == nil {
+		t.Error("the builtin backend installed wasmbrowsertest")
+	}
+
+	progress, msgs := collectProgress()
+	if err := w.ExecuteWithOptions(ExecOptions{Dir: dir, Run: "TestPass"}, progress); err != nil {
+		t.Fatalf("passing run failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	out := strings.Join(msgs(), "\n")
+	if !strings.Contains(out, "test pass TestPass") || strings.Contains(out, "TestFail") || strings.Contains(out, "wasmbrowsertest") {
+		t.Errorf("unexpected progress:\n%s", out)
+	}
+	if entries, _ := filepath.Glob(filepath.Join(tmp, "wasmtest-builtin-*")); len(entries) > 0 {
+		t.Errorf("test binaries left behind: %q", entries)
+	}
+
+	res, err := RunTestsResult(dir, func(...any) {}, WithBackend(BackendBuiltin), WithBrowser(browser))
+	var failure *TestFailureError
+	if !errors.As(err, &failure) || !slices.Equal(res.FailedTests, []string{"TestFail"}) || !slices.Equal(res.PassedTests, []string{"TestPass"}) {
+		t.Errorf("failing run = %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,643 @@
+package wasmtest
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BundleManifest describes a self-contained test bundle created by Bundle.
+// It is stored as manifest.json next to the bundle files.
+type BundleManifest struct {
+	// SchemaVersion is the SchemaVersion the manifest was written with.
+	SchemaVersion int `json:"schemaVersion"`
+	// Package is the import path of the bundled test package.
+	Package   string    `json:"package"`
+	GoVersion string    `json:"goVersion"`
+	Created   time.Time `json:"created"`
+	// Wasm is the file name of the compiled test binary.
+	Wasm string `json:"wasm"`
+	// SHA256 is the hex encoded checksum of the test binary.
+	SHA256 string `json:"sha256"`
+	// GoWasm is the GOWASM feature set the binary was built with.
+	GoWasm string `json:"goWasm,omitempty"`
+	// Args are the arguments passed to the test binary, e.g. -test.v.
+	Args []string `json:"args"`
+	// Env holds environment variables visible to the test binary.
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// RunBundleOptions configures RunBundle. The zero value serves the bundle on
+// a random localhost port and opens it in the first browser found.
+type RunBundleOptions struct {
+	// Addr is the address the bundle is served on, e.g. ":8080" to let a
+	// browser on another host open it.
+	Addr string
+	// Browser is the browser, a Browser name or an executable; empty
+	// uses WithBrowser, or else the first browser found.
+	Browser string
+	// NoLaunch only serves the bundle and waits for a browser to open the
+	// URL reported through progress.
+	NoLaunch bool
+	// RemoteBrowser is the DevTools endpoint of a running browser to open
+	// the tests in instead of launching one; empty uses
+	// WithRemoteBrowser.
+	RemoteBrowser string
+	// WebDriver is the WebDriver server, such as a Selenium Grid, to open
+	// the tests through instead of launching a browser; empty uses
+	// WithWebDriver.
+	WebDriver string
+	// URL is the address of the served bundle given to the browser, when
+	// it differs from the serving address, e.g. http://runner:8080/ for a
+	// browser in another container.
+	URL string
+}
+
+// Bundle compiles the js/wasm tests of dir into out together with
+// wasm_exec.js, the runner page (index.html) and manifest.json, so they can
+// be run by RunBundle on a machine without a Go toolchain. testArgs are
+// passed to the test binary after -test.v (e.g. -test.run=TestDOM).
+func (w *Wasmtest) Bundle(ctx context.Context, dir, out string, testArgs ...string) (*BundleManifest, error) {
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		return nil, err
+	}
+	spec := w.bundleSpec(dir)
+
+	absOut, err := filepath.Abs(out)
+	if err != nil {
+		return nil, err
+	}
+	bin := filepath.Join(absOut, "test.wasm")
+	entry, err := w.binaryCacheEntry(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	if cached := cachedBinary(entry); cached != nil {
+		if err := copyFile(cached.path, bin); err != nil {
+			return nil, err
+		}
+		return writeBundleFiles(ctx, spec, out, cached.pkg, testArgs)
+	}
+
+	build := exec.CommandContext(ctx, spec.toolchain.command(), "test", "-c", "-o", bin)
+	build.Dir = dir
+	build.Env = spec.environ()
+	if output, err := build.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("❌💥 BUILD ERROR: compiling the tests of %s failed: %v\n%s", dir, err, output)
+	}
+
+	list := exec.CommandContext(ctx, spec.toolchain.command(), "list", "-f", "{{.ImportPath}}")
+	list.Dir = dir
+	list.Env = spec.environ()
+	listed, err := list.Output()
+	if err != nil {
+		return nil, err
+	}
+	pkg := strings.TrimSpace(string(listed))
+	// The bundle is built even when it can't be cached.
+	if entry != "" {
+		if err := storeBinary(entry, bin, pkg); err != nil {
+			w.safeLog(fmt.Errorf("%w: %v", errBinaryCache, err))
+		}
+	}
+	return writeBundleFiles(ctx, spec, out, pkg, testArgs)
+}
+
+// bundleSpec returns the execSpec of the Bundle build of the tests of dir.
+func (w *Wasmtest) bundleSpec(dir string) execSpec {
+	spec := execSpec{dir: dir, toolchain: w.toolchain}
+	if w.goWasm != "" {
+		spec.env = []string{"GOWASM=" + w.goWasm}
+	}
+	return spec
+}
+
+// writeBundleFiles writes the harness files and the manifest of the test
+// binary of pkg, already at out/test.wasm, making out a bundle. The wasip1
+// binaries get no harness page: they don't run in a browser.
+func writeBundleFiles(ctx context.Context, spec execSpec, out, pkg string, testArgs []string) (*BundleManifest, error) {
+	goEnv, err := goEnvVars(ctx, spec, "GOROOT", "GOVERSION")
+	if err != nil {
+		return nil, err
+	}
+	if spec.target != TargetWASIP1 {
+		wasmExec, err := findWasmExecJS(goEnv["GOROOT"])
+		if err != nil {
+			return nil, err
+		}
+		if err := copyFile(wasmExec, filepath.Join(out, "wasm_exec.js")); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(out, "index.html"), harnessPage, 0o644); err != nil {
+			return nil, err
+		}
+	}
+
+	sum, err := fileSHA256(filepath.Join(out, "test.wasm"))
+	if err != nil {
+		return nil, err
+	}
+	m := &BundleManifest{
+		SchemaVersion: SchemaVersion,
+		Package:       pkg,
+		GoVersion:     goEnv["GOVERSION"],
+		Created:       time.Now().UTC(),
+		Wasm:          "test.wasm",
+		SHA256:        sum,
+		GoWasm:        lookupEnv(spec.environ(), "GOWASM"),
+		Args:          append([]string{"-test.v"}, testArgs...),
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(out, "manifest.json"), data, 0o644); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// bundleOnlyOptions returns the names of the options set on w that only
+// RunBundle honours, as they need its harness page; go test runs reject
+// them.
+func (w *Wasmtest) bundleOnlyOptions() []string {
+	var names []string
+	if w.playwright != nil {
+		names = append(names, "WithPlaywright")
+	}
+	if w.videoDir != "" {
+		names = append(names, "WithVideo")
+	}
+	if w.harPath != "" {
+		names = append(names, "WithHAR")
+	}
+	if w.memoryAt != 0 {
+		names = append(names, "WithMemoryProfile")
+	}
+	if w.jsCoverageDir != "" {
+		names = append(names, "WithJSCoverage")
+	}
+	return names
+}
+
+// RunBundle serves a bundle created by Bundle and runs it in a browser,
+// reporting progress with the same messages as Execute. Only a browser is
+// needed on the executing host. The returned error is non-nil when the tests
+// failed or could not run.
+func (w *Wasmtest) RunBundle(ctx context.Context, dir string, opts RunBundleOptions, progress func(msgs ...any)) error {
+	if w.optionErr != nil {
+		return w.optionErr
+	}
+	m, data, err := readBundle(dir)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	h := newHarness(http.FileServer(http.Dir(dir)), func() ([]byte, error) { return data, nil }, progress)
+	url, err := h.serve(ctx, opts.Addr)
+	if err != nil {
+		progress("error", "failed to serve bundle:", err)
+		return err
+	}
+	if opts.URL != "" {
+		url = opts.URL
+	}
+	progress("info", fmt.Sprintf("serving %s tests on %s", m.Package, url))
+	if w.harPath != "" {
+		h.recordNetwork()
+		defer w.saveHAR(h, progress)
+	}
+
+	remote := cmp.Or(opts.RemoteBrowser, w.remoteBrowser)
+	webDriver := cmp.Or(opts.WebDriver, w.webDriver)
+	caps := w.webDriverCapabilities(cmp.Or(opts.Browser, w.browser))
+	if w.managedDriver != nil && webDriver == "" && w.cloudGrid == nil && !opts.NoLaunch && remote == "" {
+		var stop func()
+		if webDriver, caps, stop, err = w.startManagedDriver(ctx, cmp.Or(opts.Browser, w.browser), progress); err != nil {
+			return err
+		}
+		defer stop()
+	}
+	cloud := w.cloudGrid != nil && !opts.NoLaunch && remote == "" && webDriver == ""
+	playwright := w.playwright != nil && !cloud && !opts.NoLaunch && remote == "" && webDriver == ""
+	warm := w.warmBrowser != nil && !playwright && !cloud && opts.Browser == "" && !opts.NoLaunch && remote == "" && webDriver == ""
+	w.reportUnrecorded(playwright, progress)
+	if w.memoryAt != 0 {
+		h.recordMemory(w.memoryAt)
+		defer func() { w.saveMemoryReport(m.Package, h.memorySamples(), progress) }()
+	}
+
+	r := &bundleRun{w: w, opts: opts, pkg: m.Package, h: h, url: url, cancel: cancel, progress: progress, closed: make(chan struct{})}
+	stop := func() {}
+	switch {
+	case opts.NoLaunch:
+	case webDriver != "":
+		stop, err = r.openWebDriver(ctx, webDriver, caps)
+	case cloud:
+		stop, err = r.openCloudGrid(ctx)
+	case remote != "":
+		stop, err = openRemoteTab(ctx, remote, url)
+		if err != nil {
+			progress("error", "failed to open the tests in the remote browser:", err)
+		} else {
+			progress("info", "opened the tests in the remote browser "+remote)
+		}
+	case warm:
+		stop, err = r.openWarmTab(ctx)
+	case playwright:
+		stop, err = r.launchPlaywright(ctx)
+	default:
+		stop, err = r.launch(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	if err := h.wait(ctx); err != nil {
+		r.failed = true
+		progress("exit", "error", err.Error())
+		if w.headful && !opts.NoLaunch && remote == "" && webDriver == "" && !warm {
+			w.keepOpen(ctx, r.closed, progress)
+		}
+		return err
+	}
+	progress("exit", "ok")
+	return nil
+}
+
+// readBundle reads the manifest of the bundle in dir, returning it with its
+// raw data, and checks the test binary against it.
+func readBundle(dir string) (*BundleManifest, []byte, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("wasmtest: reading bundle manifest: %w", err)
+	}
+	var m BundleManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, nil, fmt.Errorf("wasmtest: invalid bundle manifest: %w", err)
+	}
+	if err := checkSchemaVersion("bundle manifest", m.SchemaVersion); err != nil {
+		return nil, nil, err
+	}
+	if sum, err := fileSHA256(filepath.Join(dir, m.Wasm)); err != nil || sum != m.SHA256 {
+		return nil, nil, fmt.Errorf("wasmtest: bundle test binary %s is missing or does not match the manifest checksum", m.Wasm)
+	}
+	return &m, data, nil
+}
+
+// saveHAR writes the network requests recorded by h to the WithHAR file.
+func (w *Wasmtest) saveHAR(h *harness, progress func(msgs ...any)) {
+	if err := writeHAR(w.harPath, h.requests()); err != nil {
+		progress("warning", Warning{Code: "har-failed", Message: fmt.Sprintf("failed to write the HAR file: %v", err)})
+		return
+	}
+	progress("info", "network requests saved to "+w.harPath)
+}
+
+// startManagedDriver starts the WebDriver server matching browser (see
+// WithManagedDriver), returning its endpoint and the capabilities of its
+// sessions.
+func (w *Wasmtest) startManagedDriver(ctx context.Context, browser string, progress func(msgs ...any)) (string, map[string]any, func(), error) {
+	var driver, endpoint string
+	var stop func()
+	path, err := lookupBrowser(browser)
+	if err == nil {
+		driver, err = w.findDriver(ctx, path, progress)
+	}
+	if err == nil {
+		endpoint, stop, err = startDriver(ctx, driver)
+	}
+	if err != nil {
+		progress("error", "failed to start the managed WebDriver:", err)
+		return "", nil, nil, err
+	}
+	progress("info", "started "+driver+" on "+endpoint)
+	return endpoint, w.managedDriverCapabilities(path), stop, nil
+}
+
+// reportUnrecorded warns about the recordings a bundle run can't make:
+// they need Playwright, and its Chromium engine but for videos.
+func (w *Wasmtest) reportUnrecorded(playwright bool, progress func(msgs ...any)) {
+	chromium := playwright && w.playwright.Engine == PlaywrightChromium
+	if w.videoDir != "" && !playwright {
+		progress("warning", videoUnsupported)
+	}
+	if w.cpuProfile && !chromium {
+		progress("warning", cpuProfileUnsupported)
+	}
+	if w.jsCoverageDir != "" && !chromium {
+		progress("warning", jsCoverageUnsupported)
+	}
+}
+
+// bundleRun is a RunBundle run opening its harness page in a browser.
+type bundleRun struct {
+	w        *Wasmtest
+	opts     RunBundleOptions
+	pkg      string
+	h        *harness
+	url      string
+	cancel   context.CancelFunc
+	progress func(msgs ...any)
+	// closed is closed when the launched browser is gone for good.
+	closed chan struct{}
+	// failed is set when the tests did not pass, to keep the video.
+	failed bool
+}
+
+// openWebDriver opens the tests in a new session of the WebDriver server
+// at endpoint. The returned function ends the session.
+func (r *bundleRun) openWebDriver(ctx context.Context, endpoint string, caps map[string]any) (func(), error) {
+	session, err := newWebDriverSession(ctx, endpoint, caps)
+	if err != nil {
+		r.progress("error", "failed to start a WebDriver session:", err)
+		return nil, err
+	}
+	if err := session.navigate(ctx, r.url); err != nil {
+		session.quit()
+		r.progress("error", "failed to open the tests through WebDriver:", err)
+		return nil, err
+	}
+	r.progress("info", "opened the tests in WebDriver session "+session.id+" of "+redactURL(endpoint))
+	return session.quit, nil
+}
+
+// openCloudGrid opens the tests in a session of the WithCloudGrid grid,
+// through its tunnel when it has one. The returned function ends the
+// session and the tunnel.
+func (r *bundleRun) openCloudGrid(ctx context.Context) (func(), error) {
+	grid := *r.w.cloudGrid
+	var tunnel string
+	stopTunnel := func() {}
+	page := r.url
+	if grid.Tunnel {
+		name, stop, err := grid.startTunnel(ctx)
+		if err != nil {
+			r.progress("error", "failed to start the cloud grid tunnel:", err)
+			return nil, err
+		}
+		stopTunnel = stop
+		tunnel = name
+		r.progress("info", "started the "+grid.Provider+" tunnel "+tunnel)
+		if r.opts.URL == "" {
+			page = grid.tunnelURL(r.url)
+		}
+	}
+	endpoint, caps, err := grid.session(r.pkg, tunnel)
+	if err != nil {
+		stopTunnel()
+		r.progress("error", err.Error())
+		return nil, err
+	}
+	session, err := newWebDriverSession(ctx, endpoint, caps)
+	if err != nil {
+		stopTunnel()
+		r.progress("error", "failed to start a "+grid.Provider+" session:", err)
+		return nil, err
+	}
+	stop := func() {
+		session.quit()
+		stopTunnel()
+	}
+	if err := session.navigate(ctx, page); err != nil {
+		stop()
+		r.progress("error", "failed to open the tests in the "+grid.Provider+" session:", err)
+		return nil, err
+	}
+	r.progress("info", "opened the tests in "+grid.Provider+" session "+session.id)
+	return stop, nil
+}
+
+// openWarmTab opens the tests in a new tab of the WithWarmBrowser browser,
+// starting it if needed. The returned function closes the tab.
+func (r *bundleRun) openWarmTab(ctx context.Context) (func(), error) {
+	endpoint, started, err := r.w.warmBrowser.devtools(ctx)
+	if err != nil {
+		r.progress("error", "failed to start the warm browser:", err)
+		return nil, err
+	}
+	if started {
+		r.progress("info", "started the warm browser, kept running for the next runs")
+	}
+	closeTab, err := openRemoteTab(ctx, endpoint, r.url)
+	if err != nil {
+		r.progress("error", "failed to open the tests in the warm browser:", err)
+		return nil, err
+	}
+	r.progress("info", "opened the tests in a new tab of the warm browser")
+	return closeTab, nil
+}
+
+// launchPlaywright opens the tests with WithPlaywright, recording what the
+// options ask for in a temporary directory. The returned function closes
+// the browser, saves the recordings and removes the directory.
+func (r *bundleRun) launchPlaywright(ctx context.Context) (func(), error) {
+	w := r.w
+	chromium := w.playwright.Engine == PlaywrightChromium
+	profiling := w.cpuProfile && chromium
+	covering := w.jsCoverageDir != "" && chromium
+	snapshotting := w.memoryAt&(MemoryAtEnd|MemoryOnFailure) != 0 && chromium
+	var rec playwrightRecording
+	remove := func() {}
+	if w.videoDir != "" || profiling || snapshotting || covering {
+		recording, err := makeTempDir("wasmtest-recording-")
+		if err != nil {
+			r.progress("error", "failed to create the recording directory:", err)
+			return nil, err
+		}
+		remove = func() { os.RemoveAll(recording) }
+		if w.videoDir != "" {
+			rec.video = filepath.Join(recording, "video")
+		}
+		if profiling {
+			rec.profile = filepath.Join(recording, "cpu.json")
+		}
+		if snapshotting {
+			rec.heapSnapshot = filepath.Join(recording, "heap.heapsnapshot")
+		}
+		if covering {
+			rec.jsCoverage = filepath.Join(recording, "js-coverage.json")
+		}
+	}
+	err := r.start(ctx, "Playwright "+w.playwright.Engine, func() (<-chan error, error) {
+		return w.launchPlaywright(ctx, r.url, rec)
+	})
+	if err != nil {
+		remove()
+		return nil, err
+	}
+	// Playwright saves the trace and the recordings while closing the
+	// browser.
+	return func() {
+		r.cancel()
+		<-r.closed
+		if r.failed && rec.video != "" {
+			w.saveVideo(rec.video, r.pkg, r.progress)
+		}
+		if rec.profile != "" {
+			w.saveCPUProfile(rec.profile, r.pkg, r.progress)
+		}
+		if rec.jsCoverage != "" {
+			w.saveJSCoverage(rec.jsCoverage, r.url, r.pkg, r.progress)
+		}
+		if rec.heapSnapshot != "" && (r.failed || w.memoryAt&MemoryAtEnd != 0) {
+			w.saveHeapSnapshot(rec.heapSnapshot, r.pkg, r.progress)
+		}
+		remove()
+	}, nil
+}
+
+// launch opens the tests in a browser of its own: the docker backend's
+// Chrome, or the RunBundleOptions.Browser or WithBrowser one. The returned
+// function removes the docker script.
+func (r *bundleRun) launch(ctx context.Context) (func(), error) {
+	w := r.w
+	if w.backend == BackendDocker && r.opts.Browser == "" {
+		image, _ := w.dockerImageBrowser()
+		path, remove, err := w.dockerBrowserScript("chrome", nil)
+		if err != nil {
+			r.progress("error", err.Error())
+			return nil, err
+		}
+		err = r.start(ctx, image+" (docker)", func() (<-chan error, error) {
+			return launchBrowser(ctx, path, r.url, w.headful, w.browserFlags)
+		})
+		if err != nil {
+			remove()
+			return nil, err
+		}
+		return remove, nil
+	}
+	browser, err := lookupBrowser(cmp.Or(r.opts.Browser, w.browser))
+	if err != nil {
+		r.progress("error", err.Error())
+		return nil, err
+	}
+	err = r.start(ctx, browser, func() (<-chan error, error) {
+		return launchBrowser(ctx, browser, r.url, w.headful, w.browserFlags)
+	})
+	return func() {}, err
+}
+
+// start launches browser with launch and watches it: a browser dying
+// before the page reports is a failure, not a hang, and one that dies
+// before even loading the page is relaunched. r.closed is closed when it
+// is gone for good.
+func (r *bundleRun) start(ctx context.Context, browser string, launch func() (<-chan error, error)) error {
+	exited, err := launch()
+	if err != nil {
+		r.progress("error", "failed to launch browser:", err)
+		return err
+	}
+	r.progress("info", "launched "+browser)
+	go func() {
+		defer close(r.closed)
+		for attempt := 1; ; attempt++ {
+			err := <-exited
+			if r.h.pageLoaded() || attempt > r.w.launchRetries || ctx.Err() != nil {
+				r.h.finish(harnessExit{Code: 1, Error: fmt.Sprintf("browser exited before the tests finished: %v", err)})
+				return
+			}
+			r.progress("warning", Warning{
+				Code:    "browser-launch-retry",
+				Message: fmt.Sprintf("the browser exited before loading the tests (%v); retrying (%d/%d)", err, attempt, r.w.launchRetries),
+				Hint:    "WithLaunchRetries sets the number of retries",
+			})
+			if exited, err = launch(); err != nil {
+				r.h.finish(harnessExit{Code: 1, Error: fmt.Sprintf("failed to relaunch browser: %v", err)})
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// keepOpen leaves a headful browser open after a failure, so the page can
+// be inspected, until it is closed, ctx is done or the WithFailurePause
+// delay elapsed.
+func (w *Wasmtest) keepOpen(ctx context.Context, closed <-chan struct{}, progress func(msgs ...any)) {
+	var timeout <-chan time.Time
+	msg := "the tests failed; the browser stays open for inspection, close it to finish"
+	if w.failurePause > 0 {
+		timeout = time.After(w.failurePause)
+		msg = fmt.Sprintf("the tests failed; the browser stays open for inspection for %v, close it to finish earlier", w.failurePause)
+	}
+	progress("info", msg)
+	select {
+	case <-closed:
+	case <-ctx.Done():
+	case <-timeout:
+	}
+}
+
+// goEnvVars returns the requested `go env` variables for spec.
+func goEnvVars(ctx context.Context, spec execSpec, names ...string) (map[string]string, error) {
+	cmd := exec.CommandContext(ctx, spec.toolchain.command(), append([]string{"env", "-json"}, names...)...)
+	cmd.Dir = spec.dir
+	cmd.Env = spec.environ()
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("wasmtest: go env failed: %w", err)
+	}
+	vars := map[string]string{}
+	if err := json.Unmarshal(out, &vars); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// findWasmExecJS returns the wasm_exec.js of the toolchain in goroot. It
+// moved from misc/wasm to lib/wasm in Go 1.24.
+func findWasmExecJS(goroot string) (string, error) {
+	for _, dir := range []string{"lib/wasm", "misc/wasm"} {
+		p := filepath.Join(goroot, dir, "wasm_exec.js")
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("wasmtest: wasm_exec.js not found in GOROOT %s", goroot)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
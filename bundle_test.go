@@ -0,0 +1,141 @@
+package wasmtest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// nodeBrowser returns an executable accepting browser style arguments that
+// loads the given page with node (see testdata/nodebrowser.js).
+func nodeBrowser(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the node browser shim is a shell script")
+	}
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node not found in PATH; skipping")
+	}
+	script, err := filepath.Abs("testdata/nodebrowser.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shim := filepath.Join(t.TempDir(), "nodebrowser")
+	content := fmt.Sprintf("#!/bin/sh\nexec node %q \"$@\"\n", script)
+	if err := os.WriteFile(shim, []byte(content), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return shim
+}
+
+// collectProgress returns a progress callback and a function returning the
+// messages received so far, formatted with fmt.Sprint.
+func collectProgress() (func(...any), func() []string) {
+	var mu sync.Mutex
+	var msgs []string
+	progress := func(a ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		msgs = append(msgs, strings.TrimSpace(fmt.Sprintln(a...)))
+	}
+	return progress, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(msgs)
+	}
+}
+
+func TestBundle(t *testing.T) {
+	browser := nodeBrowser(t)
+	w := New(WithInstallDisabled())
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	out := t.TempDir()
+	m, err := w.Bundle(ctx, "./example", out, "-test.run=TestMathHelper|TestJSInterop")
+	if err != nil {
+		t.Fatalf("Bundle failed: %v", err)
+	}
+	if m.Package != "github.com/cdvelop/wasmtest/example" || m.SHA256 == "" {
+		t.Errorf("unexpected manifest: %+v", m)
+	}
+	for _, f := range []string{"test.wasm", "wasm_exec.js", "index.html", "manifest.json"} {
+		if _, err := os.Stat(filepath.Join(out, f)); err != nil {
+			t.Errorf("bundle is missing %s", f)
+		}
+	}
+
+	progress, msgs := collectProgress()
+	if err := w.RunBundle(ctx, out, RunBundleOptions{Browser: browser}, progress); err != nil {
+		t.Fatalf("RunBundle failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	got := msgs()
+	for _, want := range []string{"out --- PASS: TestMathHelper", "out PASS", "exit ok"} {
+		if !slices.ContainsFunc(got, func(m string) bool { return strings.HasPrefix(m, want) }) {
+			t.Errorf("missing %q in progress:\n%s", want, strings.Join(got, "\n"))
+		}
+	}
+
+	// A tampered binary must be rejected before serving anything.
+	if err := os.WriteFile(filepath.Join(out, "test.wasm"), []byte("junk"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.RunBundle(ctx, out, RunBundleOptions{Browser: browser}, progress); err == nil {
+		t.Error("RunBundle accepted a binary not matching the manifest")
+	}
+}
+
+func TestRunBundleRelaunchesBrowser(t *testing.T) {
+	browser := flakyLauncher(t, 1, "DevToolsActivePort file doesn't exist", nodeBrowser(t))
+	w := New(WithInstallDisabled())
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	out := t.TempDir()
+	if _, err := w.Bundle(ctx, "./example", out, "-test.run=TestMathHelper"); err != nil {
+		t.Fatalf("Bundle failed: %v", err)
+	}
+	progress, msgs := collectProgress()
+	if err := w.RunBundle(ctx, out, RunBundleOptions{Browser: browser}, progress); err != nil {
+		t.Fatalf("RunBundle failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	if !slices.ContainsFunc(msgs(), func(m string) bool { return strings.Contains(m, "browser-launch-retry") }) {
+		t.Errorf("no retry warning in progress:\n%s", strings.Join(msgs(), "\n"))
+	}
+}
+
+func TestRunBundleHeadful(t *testing.T) {
+	real := nodeBrowser(t)
+	args := filepath.Join(t.TempDir(), "args")
+	browser := filepath.Join(t.TempDir(), "recorder")
+	content := fmt.Sprintf("#!/bin/sh\necho \"$@\" > %q\nexec %q \"$@\"\n", args, real)
+	if err := os.WriteFile(browser, []byte(content), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	w := New(WithInstallDisabled(), WithHeadful(), WithFailurePause(100*time.Millisecond), WithBrowserFlags("--lang=es"))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	out := t.TempDir()
+	if _, err := w.Bundle(ctx, writeModule(t, map[string]string{"h_test.go": wasmFailTest}), out); err != nil {
+		t.Fatalf("Bundle failed: %v", err)
+	}
+	progress, msgs := collectProgress()
+	if err := w.RunBundle(ctx, out, RunBundleOptions{Browser: browser}, progress); err == nil {
+		t.Fatal("RunBundle succeeded with a failing test")
+	}
+	if !slices.ContainsFunc(msgs(), func(m string) bool { return strings.Contains(m, "the browser stays open for inspection for 100ms") }) {
+		t.Errorf("no inspection message in progress:\n%s", strings.Join(msgs(), "\n"))
+	}
+	if data, err := os.ReadFile(args); err != nil || strings.Contains(string(data), "--headless") || !strings.Contains(string(data), "--auto-open-devtools-for-tabs") || !strings.Contains(string(data), "--lang=es http://") {
+		t.Errorf("browser arguments = %q, %v", data, err)
+	}
+}
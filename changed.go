@@ -0,0 +1,172 @@
+package wasmtest
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ChangedSince is a RunTests argument restricting the run to the packages
+// affected by the files changed since the given git revision (e.g.
+// "origin/main"); see ChangedPackages. It can also be set with the
+// WASMTEST_CHANGED_SINCE environment variable or the changed_since setting
+// of the configuration file.
+type ChangedSince string
+
+// ChangedPackages returns the directories of dirs whose js/wasm test build
+// uses a file changed since the git revision base: committed, staged and
+// unstaged changes are considered, as well as untracked files. A package is
+// affected when a file changed in its directory or in the directory of any
+// package it imports, test dependencies included, or when the go.mod or
+// go.sum of its module changed. Dependencies are resolved with the extra
+// build tags.
+func ChangedPackages(ctx context.Context, base string, dirs []string, tags ...string) ([]string, error) {
+	return changedPackages(ctx, goToolchain{}, base, dirs, tags)
+}
+
+// changedPackages implements ChangedPackages, resolving the dependencies
+// with the go of tc.
+func changedPackages(ctx context.Context, tc goToolchain, base string, dirs []string, tags []string) ([]string, error) {
+	if len(dirs) == 0 {
+		return nil, nil
+	}
+	top, err := gitOutput(ctx, dirs[0], "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	diff, err := gitOutput(ctx, dirs[0], "diff", "--name-only", base, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := gitOutput(ctx, top, "ls-files", "--others", "--exclude-standard", "--full-name")
+	if err != nil {
+		return nil, err
+	}
+
+	changed := map[string]bool{}
+	var modules []string
+	for _, name := range strings.Fields(diff + "\n" + untracked) {
+		path := realPath(filepath.Join(top, filepath.FromSlash(name)))
+		changed[filepath.Dir(path)] = true
+		if base := filepath.Base(path); base == "go.mod" || base == "go.sum" {
+			modules = append(modules, filepath.Dir(path))
+		}
+	}
+
+	var affected []string
+	for _, dir := range dirs {
+		deps, err := packageDirs(ctx, tc, dir, tags)
+		if err != nil {
+			return nil, err
+		}
+		for _, dep := range deps {
+			if changed[dep] || within(dep, modules) {
+				affected = append(affected, dir)
+				break
+			}
+		}
+	}
+	return affected, nil
+}
+
+// runChanged runs the packages matched by patterns that are affected by the
+// changes since the git revision base. Directories without js/wasm tests
+// are run anyway, so that run reports them.
+func (s runSettings) runChanged(parent context.Context, base string, patterns []string) (*RunResult, error) {
+	target := s.target()
+	dirs, err := expandPatterns(patterns, target, s.exec.Tags)
+	if err != nil {
+		return nil, err
+	}
+	var candidates, invalid []string
+	for _, dir := range dirs {
+		if ok, _ := hasWasmTests(dir, target, s.exec.Tags...); ok {
+			candidates = append(candidates, dir)
+		} else {
+			invalid = append(invalid, dir)
+		}
+	}
+	probe := &Wasmtest{}
+	for _, opt := range s.options() {
+		opt(probe)
+	}
+	affected, err := changedPackages(parent, probe.toolchain, base, candidates, s.exec.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("❌💥 GIT ERROR: Failed to find the packages changed since %s\n🔴 Details: %w", base, err)
+	}
+	s.logger("[WASMTEST]", "info", fmt.Sprintf("🔀 %d of %d packages changed since %s", len(affected), len(candidates), base))
+
+	dirs = append(invalid, affected...)
+	if len(dirs) == 0 {
+		result := newRunResult(strings.Join(patterns, " "))
+		result.ExitCode = 0
+		return result, nil
+	}
+	return s.runPackages(parent, dirs)
+}
+
+// packageDirs returns the directories of the package in dir and of all its
+// js/wasm dependencies, test dependencies included.
+func packageDirs(ctx context.Context, tc goToolchain, dir string, tags []string) ([]string, error) {
+	args := []string{"list", "-deps", "-test", "-f", "{{.Dir}}"}
+	if len(tags) > 0 {
+		args = append(args, "-tags", strings.Join(tags, ","))
+	}
+	spec := execSpec{dir: dir, toolchain: tc}
+	cmd := exec.CommandContext(ctx, spec.toolchain.command(), append(args, ".")...)
+	cmd.Dir = dir
+	cmd.Env = spec.environ()
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("go list in %s: %w: %s", dir, err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+	var dirs []string
+	for _, d := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if d != "" {
+			dirs = append(dirs, realPath(d))
+		}
+	}
+	return dirs, nil
+}
+
+// within reports whether path is one of roots or below one of them.
+func within(path string, roots []string) bool {
+	for _, root := range roots {
+		if rel, err := filepath.Rel(root, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// realPath returns path with symbolic links resolved, so paths reported by
+// git and go compare equal, or path itself when it can't be resolved (e.g.
+// a deleted file).
+func realPath(path string) string {
+	if p, err := filepath.EvalSymlinks(path); err == nil {
+		return p
+	}
+	if p, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		return filepath.Join(p, filepath.Base(path))
+	}
+	return path
+}
+
+// gitOutput runs git in dir and returns its trimmed output.
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
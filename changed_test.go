@@ -0,0 +1,88 @@
+package wasmtest
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// gitRepo commits the module written by writeModule to a new git repository.
+func gitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH; skipping")
+	}
+	root := writeModule(t, files)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	return root
+}
+
+func TestChangedPackages(t *testing.T) {
+	usesSrc := "//go:build js && wasm\n\npackage p\n\nimport (\n\t\"testing\"\n\n\t\"example.com/tmp/lib\"\n)\n\nfunc TestLib(t *testing.T) { _ = lib.Name }\n"
+	root := gitRepo(t, map[string]string{
+		"lib/lib.go":        "package lib\n\nconst Name = \"lib\"\n",
+		"uses/uses_test.go": usesSrc,
+		"solo/solo_test.go": wasmPassTest,
+	})
+	uses, solo := filepath.Join(root, "uses"), filepath.Join(root, "solo")
+	ctx := context.Background()
+
+	if got, err := ChangedPackages(ctx, "HEAD", []string{uses, solo}); err != nil || len(got) != 0 {
+		t.Fatalf("ChangedPackages() without changes = %q, %v", got, err)
+	}
+
+	// A dependency change affects the packages importing it.
+	if err := os.WriteFile(filepath.Join(root, "lib", "lib.go"), []byte("package lib\n\nconst Name = \"changed\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ChangedPackages(ctx, "HEAD", []string{uses, solo}); err != nil || !slices.Equal(got, []string{uses}) {
+		t.Errorf("ChangedPackages() after a lib change = %q, %v", got, err)
+	}
+
+	// Untracked files count too.
+	if err := os.WriteFile(filepath.Join(solo, "more_test.go"), []byte(wasmPassTest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ChangedPackages(ctx, "HEAD", []string{uses, solo}); err != nil || !slices.Equal(got, []string{uses, solo}) {
+		t.Errorf("ChangedPackages() with an untracked file = %q, %v", got, err)
+	}
+
+	if _, err := ChangedPackages(ctx, "no-such-revision", []string{uses}); err == nil {
+		t.Error("ChangedPackages accepted an unknown revision")
+	}
+}
+
+func TestRunTestsChangedSince(t *testing.T) {
+	node := nodeExec(t)
+	root := gitRepo(t, map[string]string{
+		"a/a_test.go": wasmPassTest,
+		"b/b_test.go": wasmFailTest,
+	})
+	opts := ExecOptions{Args: []string{"-exec", node}}
+
+	res, err := RunTestsResult(filepath.Join(root, "..."), ChangedSince("HEAD"), func(...any) {}, opts, WithInstallDisabled())
+	if err != nil || len(res.Packages) != 0 || !res.Passed() {
+		t.Fatalf("unchanged run = %+v, %v", res, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "a", "a_test.go"), []byte(wasmPassTest+"\n// changed\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res, err = RunTestsResult(filepath.Join(root, "..."), ChangedSince("HEAD"), func(...any) {}, opts, WithInstallDisabled())
+	if err != nil || len(res.Packages) != 1 || res.Packages[0].Dir != filepath.Join(root, "a") {
+		t.Errorf("changed run = %+v, %v", res, err)
+	}
+}
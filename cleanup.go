@@ -0,0 +1,150 @@
+package wasmtest
+
+import (
+	"context"
+	"debug/buildinfo"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// staleTempAge is how long a wasmtest-* temporary file or directory must
+// have been left untouched for Cleanup to remove it: younger ones may
+// belong to a run in progress in another process.
+const staleTempAge = time.Hour
+
+// Cleanup removes the global state left by the runs of w: the
+// go_js_wasm_exec linked or copied to wasmbrowsertest in the install
+// directory, the managed WebDriver downloads in the default cache (see
+// WithManagedWebDriver), and the wasmtest-* temporary directories and
+// scripts of the runs that were killed before removing them, once
+// untouched for an hour. Only the temporary directories marked as created
+// by a wasmtest process that is gone are removed. A go_js_wasm_exec that
+// is not wasmbrowsertest and a DriverOptions.CacheDir are left alone. It returns
+// the removed paths; the errors of the paths that could not be removed
+// are joined. The next run links go_js_wasm_exec again, so Cleanup must
+// not be called while runs are in progress.
+func (w *Wasmtest) Cleanup(ctx context.Context) ([]string, error) {
+	return w.cleanup(ctx, false)
+}
+
+// Uninstall is like Cleanup but also removes the wasmbrowsertest binary of
+// the install directory, installed by New or Install, and the record of
+// its hash (see the runner warnings of the troubleshooting guide).
+func (w *Wasmtest) Uninstall(ctx context.Context) ([]string, error) {
+	return w.cleanup(ctx, true)
+}
+
+// cleanup implements Cleanup and, with uninstall, Uninstall.
+func (w *Wasmtest) cleanup(ctx context.Context, uninstall bool) ([]string, error) {
+	// No install nor go_js_wasm_exec setup may run meanwhile.
+	w.installMu.Lock()
+	defer w.installMu.Unlock()
+	w.setupMu.Lock()
+	defer w.setupMu.Unlock()
+
+	var removed []string
+	var errs []error
+	remove := func(path string) {
+		if err := os.RemoveAll(path); err != nil {
+			errs = append(errs, err)
+			return
+		}
+		w.safeLog("cleanup", "removed "+path)
+		removed = append(removed, path)
+	}
+
+	if bin, err := w.installBinDir(ctx); err != nil {
+		errs = append(errs, err)
+	} else {
+		exe := ""
+		if runtime.GOOS == "windows" {
+			exe = ".exe"
+		}
+		goWasmExec := filepath.Join(bin, "go_js_wasm_exec"+exe)
+		if isWasmBrowserTest(goWasmExec) {
+			remove(goWasmExec)
+		}
+		if wasmBrowserTest := filepath.Join(bin, "wasmbrowsertest"+exe); uninstall && isWasmBrowserTest(wasmBrowserTest) {
+			remove(wasmBrowserTest)
+		}
+	}
+
+	// A cache directory of the user may hold other files.
+	if w.managedDriver == nil || w.managedDriver.CacheDir == "" {
+		if cache, err := (DriverOptions{}).driverCacheDir(); err == nil {
+			if _, err := os.Stat(cache); err == nil {
+				remove(cache)
+			}
+		}
+	}
+	if records, err := runnerRecordsPath(); err == nil && uninstall {
+		if _, err := os.Stat(records); err == nil {
+			remove(records)
+		}
+	}
+
+	temps, _ := filepath.Glob(filepath.Join(os.TempDir(), "wasmtest-*"))
+	for _, temp := range temps {
+		info, err := os.Lstat(temp)
+		if err != nil || time.Since(info.ModTime()) <= staleTempAge {
+			continue
+		}
+		if info.IsDir() && !abandonedTempDir(temp) || !info.IsDir() && !slices.ContainsFunc(tempScripts, func(pattern string) bool {
+			ok, _ := filepath.Match(pattern, info.Name())
+			return ok
+		}) {
+			continue
+		}
+		remove(temp)
+	}
+	return removed, errors.Join(errs...)
+}
+
+// tempMarker is the file marking a temporary directory created by
+// wasmtest. It holds the id of the process that created it.
+const tempMarker = ".wasmtest-pid"
+
+// tempScripts are the patterns of the temporary scripts written by
+// wasmtest, which Cleanup removes once stale.
+var tempScripts = []string{"wasmtest-deno-*.mjs", "wasmtest-playwright-*.js"}
+
+// makeTempDir is os.MkdirTemp in the default temporary directory, marking
+// the new directory as created by this process for Cleanup.
+func makeTempDir(pattern string) (string, error) {
+	dir, err := os.MkdirTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, tempMarker), []byte(strconv.Itoa(os.Getpid())), 0o644); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// abandonedTempDir reports whether dir was created by makeTempDir in a
+// process that is no longer running.
+func abandonedTempDir(dir string) bool {
+	data, err := os.ReadFile(filepath.Join(dir, tempMarker))
+	if err != nil {
+		return false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	return err == nil && pid != os.Getpid() && !processRunning(pid)
+}
+
+// isWasmBrowserTest reports whether path is wasmbrowsertest: a build of its
+// module, or a symlink to a file named wasmbrowsertest, even dangling.
+func isWasmBrowserTest(path string) bool {
+	if dest, err := os.Readlink(path); err == nil {
+		return strings.TrimSuffix(filepath.Base(dest), ".exe") == "wasmbrowsertest"
+	}
+	info, err := buildinfo.ReadFile(path)
+	return err == nil && info.Main.Path == wasmBrowserTestModule
+}
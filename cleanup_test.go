@@ -0,0 +1,108 @@
+package wasmtest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCleanup(t *testing.T) {
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache)
+	t.Setenv("HOME", t.TempDir())
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	bin := t.TempDir()
+	w := New(WithInstallDisabled(), WithInstallDir(bin), WithLogger(func(...any) {}))
+
+	// wasmbrowsertest is itself a link here, as the test can't build it.
+	installed := filepath.Join(t.TempDir(), "wasmbrowsertest")
+	os.WriteFile(installed, []byte("runner"), 0o755)
+	wasmBrowserTest := filepath.Join(bin, "wasmbrowsertest")
+	goWasmExec := filepath.Join(bin, "go_js_wasm_exec")
+	os.Symlink(installed, wasmBrowserTest)
+	os.Symlink(wasmBrowserTest, goWasmExec)
+	drivers := filepath.Join(cache, "wasmtest", "drivers")
+	os.MkdirAll(filepath.Join(drivers, "chromedriver", "120.0.1"), 0o755)
+	records := filepath.Join(cache, "wasmtest", "runners.json")
+	os.WriteFile(records, []byte("{}"), 0o644)
+	// The temporary directories are marked with the process that created
+	// them: one that exited, one still running, such as a long wasmtest
+	// serve, and this one.
+	exited := exec.Command(os.Args[0], "-test.run=^$")
+	if err := exited.Run(); err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(tmp, "wasmtest-profile-1")
+	fresh := filepath.Join(tmp, "wasmtest-profile-2")
+	live := filepath.Join(tmp, "wasmtest-serve-3")
+	own := filepath.Join(tmp, "wasmtest-compile-4")
+	unmarked := filepath.Join(tmp, "wasmtest-notes")
+	other := filepath.Join(tmp, "other-1")
+	for dir, pid := range map[string]int{stale: exited.Process.Pid, fresh: exited.Process.Pid, live: os.Getppid(), own: os.Getpid(), unmarked: 0, other: 0} {
+		os.Mkdir(dir, 0o755)
+		if pid != 0 {
+			os.WriteFile(filepath.Join(dir, tempMarker), []byte(strconv.Itoa(pid)), 0o644)
+		}
+	}
+	script := filepath.Join(tmp, "wasmtest-playwright-1.js")
+	userFile := filepath.Join(tmp, "wasmtest-report.txt")
+	os.WriteFile(script, nil, 0o644)
+	os.WriteFile(userFile, nil, 0o644)
+	old := time.Now().Add(-2 * staleTempAge)
+	for _, path := range []string{stale, live, own, unmarked, other, script, userFile} {
+		os.Chtimes(path, old, old)
+	}
+
+	removed, err := w.Cleanup(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(removed)
+	want := []string{goWasmExec, drivers, stale, script}
+	slices.Sort(want)
+	if !slices.Equal(removed, want) {
+		t.Errorf("Cleanup removed %q, want %q", removed, want)
+	}
+	for _, kept := range []string{wasmBrowserTest, records, fresh, live, own, unmarked, other, userFile} {
+		if _, err := os.Lstat(kept); err != nil {
+			t.Errorf("%s removed: %v", kept, err)
+		}
+	}
+
+	removed, err = w.Uninstall(t.Context())
+	if err != nil || !slices.Equal(removed, []string{wasmBrowserTest, records}) {
+		t.Errorf("Uninstall removed %q, %v", removed, err)
+	}
+
+	// A go_js_wasm_exec of another program and a driver cache directory
+	// of the user stay.
+	os.WriteFile(goWasmExec, []byte("#!/bin/sh\n"), 0o755)
+	userCache := t.TempDir()
+	os.MkdirAll(drivers, 0o755)
+	w = New(WithInstallDisabled(), WithInstallDir(bin), WithLogger(func(...any) {}), WithManagedWebDriver(DriverOptions{CacheDir: userCache}))
+	if removed, err := w.Cleanup(t.Context()); err != nil || len(removed) > 0 {
+		t.Errorf("Cleanup removed %q, %v", removed, err)
+	}
+	if _, err := os.Stat(userCache); err != nil {
+		t.Errorf("driver cache of the user removed: %v", err)
+	}
+}
+
+func TestMakeTempDir(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	dir, err := makeTempDir("wasmtest-x-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if abandonedTempDir(dir) {
+		t.Errorf("the directory of a running process is abandoned")
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, tempMarker)); err != nil || string(data) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("marker = %q, %v", data, err)
+	}
+}
@@ -0,0 +1,245 @@
+package wasmtest
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Cloud browser grid providers (see CloudGrid).
+const (
+	CloudBrowserStack = "browserstack"
+	CloudSauceLabs    = "saucelabs"
+)
+
+// tunnelStartTimeout bounds the start of a provider tunnel.
+const tunnelStartTimeout = 2 * time.Minute
+
+// CloudGrid configures WithCloudGrid.
+type CloudGrid struct {
+	// Provider is CloudBrowserStack or CloudSauceLabs.
+	Provider string
+	// Username and AccessKey are the account credentials. Empty ones are
+	// read from BROWSERSTACK_USERNAME and BROWSERSTACK_ACCESS_KEY, or
+	// SAUCE_USERNAME and SAUCE_ACCESS_KEY.
+	Username  string
+	AccessKey string
+	// Browser is the WebDriver browserName, e.g. "safari", "chrome",
+	// "firefox" or "MicrosoftEdge"; empty is "chrome". BrowserVersion is
+	// its version, empty for the latest.
+	Browser        string
+	BrowserVersion string
+	// OS and OSVersion select the platform: "OS X" and "Sonoma" or
+	// "Windows" and "11" on BrowserStack, "macOS" and "14" on Sauce Labs.
+	// With Device, they are the mobile platform, e.g. "iOS" and "17".
+	OS        string
+	OSVersion string
+	// Device selects a real mobile device, e.g. "iPhone 15" or "Samsung
+	// Galaxy S23", to run its own browser.
+	Device string
+	// Region is the Sauce Labs data center; empty is "us-west-1".
+	Region string
+	// Hub overrides the WebDriver endpoint of the provider, e.g. for a
+	// proxy; the credentials are added to it.
+	Hub string
+	// Tunnel starts the provider tunnel, BrowserStackLocal or Sauce
+	// Connect (sc) from PATH, for the run, so that the cloud browser
+	// reaches the bundle served on this host. Without it the bundle must
+	// be served on a public address (see RunBundleOptions.Addr and URL).
+	Tunnel bool
+}
+
+// WithCloudGrid makes RunBundle open the tests in a browser of a cloud
+// grid, BrowserStack or Sauce Labs, through their WebDriver hub, so that
+// the tests can be validated on real Safari, iOS and Android browsers that
+// can't run locally. The session is named after the tested package and
+// deleted at the end of the run. RunBundleOptions.WebDriver, when set,
+// takes precedence, and go test runs ignore it.
+func WithCloudGrid(grid CloudGrid) Option {
+	return func(w *Wasmtest) { w.cloudGrid = &grid }
+}
+
+// credentials returns the credentials of g.
+func (g CloudGrid) credentials() (user, key string, err error) {
+	userEnv, keyEnv := "BROWSERSTACK_USERNAME", "BROWSERSTACK_ACCESS_KEY"
+	if g.Provider == CloudSauceLabs {
+		userEnv, keyEnv = "SAUCE_USERNAME", "SAUCE_ACCESS_KEY"
+	}
+	user, key = cmp.Or(g.Username, os.Getenv(userEnv)), cmp.Or(g.AccessKey, os.Getenv(keyEnv))
+	if user == "" || key == "" {
+		return "", "", fmt.Errorf("wasmtest: %s credentials missing: set CloudGrid.Username and AccessKey, or %s and %s", g.Provider, userEnv, keyEnv)
+	}
+	return user, key, nil
+}
+
+// session returns the WebDriver endpoint, with the credentials, and the
+// capabilities of a session of g named name, using the tunnel tunnel when
+// not empty.
+func (g CloudGrid) session(name, tunnel string) (endpoint string, caps map[string]any, err error) {
+	user, key, err := g.credentials()
+	if err != nil {
+		return "", nil, err
+	}
+	caps = map[string]any{"browserName": cmp.Or(g.Browser, "chrome")}
+	if g.BrowserVersion != "" {
+		caps["browserVersion"] = g.BrowserVersion
+	}
+	var hub string
+	switch g.Provider {
+	case CloudBrowserStack:
+		hub = "https://hub-cloud.browserstack.com/wd/hub"
+		options := map[string]any{"sessionName": name}
+		if g.OS != "" {
+			options["os"] = g.OS
+		}
+		if g.OSVersion != "" {
+			options["osVersion"] = g.OSVersion
+		}
+		if g.Device != "" {
+			options["deviceName"] = g.Device
+			options["realMobile"] = "true"
+		}
+		if tunnel != "" {
+			options["local"] = "true"
+			options["localIdentifier"] = tunnel
+		}
+		caps["bstack:options"] = options
+	case CloudSauceLabs:
+		hub = "https://ondemand." + cmp.Or(g.Region, "us-west-1") + ".saucelabs.com/wd/hub"
+		options := map[string]any{"name": name}
+		if tunnel != "" {
+			options["tunnelName"] = tunnel
+		}
+		caps["sauce:options"] = options
+		if g.Device != "" {
+			caps["platformName"] = g.OS
+			caps["appium:deviceName"] = g.Device
+			caps["appium:platformVersion"] = g.OSVersion
+			caps["appium:automationName"] = "UiAutomator2"
+			if strings.EqualFold(g.OS, "iOS") {
+				caps["appium:automationName"] = "XCUITest"
+			}
+		} else if platform := strings.TrimSpace(g.OS + " " + g.OSVersion); platform != "" {
+			caps["platformName"] = platform
+		}
+	default:
+		return "", nil, fmt.Errorf("wasmtest: unknown cloud grid provider %q (want %s or %s)", g.Provider, CloudBrowserStack, CloudSauceLabs)
+	}
+	u, err := url.Parse(cmp.Or(g.Hub, hub))
+	if err != nil {
+		return "", nil, fmt.Errorf("wasmtest: invalid cloud grid hub: %w", err)
+	}
+	u.User = url.UserPassword(user, key)
+	return u.String(), caps, nil
+}
+
+// tunnelURL returns the address the cloud browser loads page at through
+// the tunnel: iOS Safari on BrowserStack can't load localhost, which is
+// reached as bs-local.com instead.
+func (g CloudGrid) tunnelURL(page string) string {
+	u, err := url.Parse(page)
+	if err != nil || g.Provider != CloudBrowserStack {
+		return page
+	}
+	if host := u.Hostname(); host == "localhost" || host == "127.0.0.1" {
+		u.Host = "bs-local.com:" + u.Port()
+	}
+	return u.String()
+}
+
+// startTunnel starts the tunnel of g and returns its name once it is up.
+// stop closes it.
+func (g CloudGrid) startTunnel(ctx context.Context) (name string, stop func(), err error) {
+	user, key, err := g.credentials()
+	if err != nil {
+		return "", nil, err
+	}
+	id := make([]byte, 6)
+	rand.Read(id)
+	name = "wasmtest-" + hex.EncodeToString(id)
+	var cmd *exec.Cmd
+	var ready string
+	switch g.Provider {
+	case CloudBrowserStack:
+		cmd = exec.Command("BrowserStackLocal", "--key", key, "--local-identifier", name)
+		ready = "You can now access your local server"
+	case CloudSauceLabs:
+		cmd = exec.Command("sc", "run", "--username", user, "--access-key", key, "--region", cmp.Or(g.Region, "us-west-1"), "--tunnel-name", name)
+		ready = "Sauce Connect is up"
+	default:
+		return "", nil, fmt.Errorf("wasmtest: unknown cloud grid provider %q (want %s or %s)", g.Provider, CloudBrowserStack, CloudSauceLabs)
+	}
+	pr, pw := io.Pipe()
+	cmd.Stdout, cmd.Stderr = pw, pw
+	if err := cmd.Start(); err != nil {
+		return "", nil, fmt.Errorf("wasmtest: %s tunnel: %w", g.Provider, err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		pw.Close()
+		close(exited)
+	}()
+	stop = func() {
+		// The tunnels close their remote end on an interrupt.
+		if runtime.GOOS == "windows" || cmd.Process.Signal(os.Interrupt) != nil {
+			cmd.Process.Kill()
+		}
+		select {
+		case <-exited:
+		case <-time.After(30 * time.Second):
+			cmd.Process.Kill()
+			<-exited
+		}
+	}
+
+	up := make(chan error, 1)
+	go func() {
+		var last string
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			if strings.Contains(scanner.Text(), ready) {
+				up <- nil
+				io.Copy(io.Discard, pr)
+				return
+			}
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				last = line
+			}
+		}
+		up <- fmt.Errorf("wasmtest: %s tunnel exited before being up: %s", g.Provider, last)
+	}()
+	timer := time.NewTimer(tunnelStartTimeout)
+	defer timer.Stop()
+	select {
+	case err = <-up:
+	case <-timer.C:
+		err = fmt.Errorf("wasmtest: %s tunnel not up after %v", g.Provider, tunnelStartTimeout)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		stop()
+		return "", nil, err
+	}
+	return name, stop, nil
+}
+
+// redactURL returns u with its password, if any, masked, for messages.
+func redactURL(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	return parsed.Redacted()
+}
@@ -26,27 +26,20 @@ func main() {
 		fmt.Printf("[PROGRESS] %s\n", message)
 	}
 
-	// Initialize Wasmtest
-	w := wasmtest.New(logger)
-
-	fmt.Printf("Handler Name: %s\n", w.Name())
-	fmt.Printf("Handler Label: %s\n", w.Label())
-
-	// Change to the example directory to run tests there
+	// Run the tests of the example directory without changing the working
+	// directory of the process
 	exampleDir := filepath.Join("..", "..", "example")
 	if _, err := os.Stat(exampleDir); os.IsNotExist(err) {
 		log.Fatalf("Example directory not found: %s", exampleDir)
 	}
 
-	// Change working directory
-	originalDir, _ := os.Getwd()
-	defer os.Chdir(originalDir)
+	// Initialize Wasmtest
+	w := wasmtest.New(wasmtest.WithLogger(logger), wasmtest.WithTestDir(exampleDir))
 
-	if err := os.Chdir(exampleDir); err != nil {
-		log.Fatalf("Failed to change to example directory: %v", err)
-	}
+	fmt.Printf("Handler Name: %s\n", w.Name())
+	fmt.Printf("Handler Label: %s\n", w.Label())
 
-	fmt.Printf("Changed to directory: %s\n", exampleDir)
+	fmt.Printf("Test directory: %s\n", exampleDir)
 	fmt.Println("Executing WASM tests...")
 
 	// Execute the WASM tests
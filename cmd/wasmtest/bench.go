@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/cdvelop/wasmtest"
+)
+
+func init() {
+	commands["bench"] = command{
+		summary: "run only the benchmarks, printing the go test benchmark lines for benchstat",
+		run:     runBench,
+	}
+}
+
+func runBench(args []string) int {
+	fs := newFlagSet("bench")
+	opts := wasmtest.ExecOptions{Run: "^$"}
+	fs.StringVar(&opts.Bench, "bench", ".", "run only the benchmarks matching this regexp")
+	fs.StringVar(&opts.BenchTime, "benchtime", "", "run each benchmark for this long, or this many times with Nx")
+	fs.BoolVar(&opts.Benchmem, "benchmem", true, "report the allocations of the benchmarks")
+	fs.IntVar(&opts.Count, "count", 0, "run each benchmark this many times, e.g. 10 for benchstat")
+	tags := fs.String("tags", "", "comma separated build tags")
+	timeout := fs.Duration("timeout", 0, "bound the whole run (default 3m, or the configuration file value)")
+	browser := fs.String("browser", "", "browser: chrome, chromium, edge, firefox or an executable (default: first one found)")
+	backend := fs.String("backend", "", "backend: browser, builtin, node, deno, wasmtime, wasmer, docker or auto (default browser)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: wasmtest bench [flags] [dirs or ./... patterns] [-- test binary flags]")
+		fs.PrintDefaults()
+	}
+	args, opts.TestArgs = splitPassThrough(args)
+	patterns, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if *tags != "" {
+		opts.Tags = strings.Split(*tags, ",")
+	}
+
+	// The go test output, benchmark lines included, is printed on the
+	// standard output as without -v, so that `wasmtest bench > new.txt` can
+	// be fed to benchstat; the log goes to the standard error.
+	runArgs := []any{opts, wasmtest.Quiet,
+		func(a ...any) { fmt.Fprintln(os.Stderr, a...) },
+		func(ev wasmtest.ProgressEvent) {
+			if ev.Kind == wasmtest.EventStdout && !verboseLine(ev.Message) {
+				fmt.Println(ev.Message)
+			}
+		},
+	}
+	switch len(patterns) {
+	case 0:
+	case 1:
+		runArgs = append(runArgs, patterns[0])
+	default:
+		runArgs = append(runArgs, patterns)
+	}
+	if *timeout > 0 {
+		runArgs = append(runArgs, *timeout)
+	}
+	if *browser != "" {
+		runArgs = append(runArgs, wasmtest.WithBrowser(*browser))
+	}
+	if *backend != "" {
+		runArgs = append(runArgs, wasmtest.WithBackend(*backend))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err = wasmtest.RunTestsContext(ctx, runArgs...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	return wasmtest.ExitCode(err)
+}
+
+// verboseLine reports whether line is only printed by go test -v, which
+// wasmtest runs with: a "=== RUN" line, or the name of a benchmark about to
+// run, which benchmark parsers would take for a result.
+func verboseLine(line string) bool {
+	fields := strings.Fields(line)
+	return strings.HasPrefix(line, "=== ") || (len(fields) == 1 && strings.HasPrefix(fields[0], "Benchmark"))
+}
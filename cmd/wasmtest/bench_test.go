@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestVerboseLine(t *testing.T) {
+	for line, want := range map[string]bool{
+		"=== RUN   BenchmarkSum":                 true,
+		"=== PAUSE TestX":                        true,
+		"BenchmarkSum":                           true,
+		"BenchmarkSum-8   \t 1000\t  1234 ns/op": false,
+		"goos: js":                               false,
+		"PASS":                                   false,
+		"ok  \texample.com/tmp\t0.1s":            false,
+		"    b_test.go:9: BenchmarkSum logged a line": false,
+	} {
+		if got := verboseLine(line); got != want {
+			t.Errorf("verboseLine(%q) = %v, want %v", line, got, want)
+		}
+	}
+}
+
+func TestBench(t *testing.T) {
+	needNode(t)
+	writeModule(t, map[string]string{
+		"wasm_tests/b_test.go": "//go:build js && wasm\n\npackage p\n\nimport \"testing\"\n\n" +
+			"func TestNotRun(t *testing.T) { t.Fatal(\"tests don't run\") }\n\n" +
+			"func BenchmarkSum(b *testing.B) {\n\tfor i := 0; i < b.N; i++ {\n\t\t_ = i + i\n\t}\n}\n",
+	})
+
+	// The benchmark lines go to the standard output, for benchstat.
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	code := runBench([]string{"./wasm_tests", "-backend", "node", "-benchtime", "10x"})
+	os.Stdout = stdout
+	w.Close()
+	out, _ := io.ReadAll(r)
+
+	if code != 0 {
+		t.Fatalf("bench exited with %d:\n%s", code, out)
+	}
+	if !strings.Contains(string(out), "BenchmarkSum") || !strings.Contains(string(out), "ns/op") || strings.Contains(string(out), "=== RUN") {
+		t.Errorf("unexpected bench output:\n%s", out)
+	}
+}
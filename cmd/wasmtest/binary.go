@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/cdvelop/wasmtest"
+)
+
+func init() {
+	commands["run-binary"] = command{
+		summary: "run a prebuilt test binary (go test -c) without compiling",
+		run:     runRunBinary,
+	}
+}
+
+func runRunBinary(args []string) int {
+	fs := newFlagSet("run-binary")
+	var opts wasmtest.ExecOptions
+	fs.StringVar(&opts.Dir, "dir", "", "directory the binary runs in, for its testdata files (default the current directory)")
+	fs.StringVar(&opts.Run, "run", "", "run only the tests matching this regexp")
+	fs.StringVar(&opts.Skip, "skip", "", "skip the tests matching this regexp")
+	backend := fs.String("backend", "", "backend: browser, node, deno, wasmtime, wasmer, docker or auto (default browser)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: wasmtest run-binary [flags] test.wasm [-- test binary flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	binary, testArgs := fs.Arg(0), fs.Args()[1:]
+	if len(testArgs) > 0 && testArgs[0] == "--" {
+		testArgs = testArgs[1:]
+	}
+	opts.Args = testArgs
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	w := wasmtest.New(wasmtest.WithInstallDisabled(), wasmtest.WithBackend(*backend))
+	err := w.RunBinary(ctx, binary, opts, printProgress)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	return wasmtest.ExitCode(err)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/cdvelop/wasmtest"
+)
+
+func init() {
+	commands["bundle"] = command{
+		summary: "compile the js/wasm tests of a directory into a self-contained bundle",
+		run:     runBundleCommand,
+	}
+	commands["run-bundle"] = command{
+		summary: "run a bundle created by `wasmtest bundle` in a browser",
+		run:     runRunBundle,
+	}
+}
+
+func runBundleCommand(args []string) int {
+	fs := newFlagSet("bundle")
+	out := fs.String("o", "wasmtest-bundle", "output directory")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: wasmtest bundle [-o dir] [package dir] [-- test binary flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	dir, testArgs := ".", fs.Args()
+	if len(testArgs) > 0 && testArgs[0] != "--" {
+		dir, testArgs = testArgs[0], testArgs[1:]
+	}
+	if len(testArgs) > 0 && testArgs[0] == "--" {
+		testArgs = testArgs[1:]
+	}
+
+	w := wasmtest.New(wasmtest.WithInstallDisabled())
+	m, err := w.Bundle(context.Background(), dir, *out, testArgs...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("bundled %s (%s) into %s\n", m.Package, m.GoVersion, *out)
+	return 0
+}
+
+func runRunBundle(args []string) int {
+	fs := newFlagSet("run-bundle")
+	var opts wasmtest.RunBundleOptions
+	fs.StringVar(&opts.Addr, "addr", "", "address to serve the bundle on (default a random localhost port)")
+	fs.StringVar(&opts.Browser, "browser", "", "browser: chrome, chromium, edge, firefox or an executable (default: first one found)")
+	fs.BoolVar(&opts.NoLaunch, "no-launch", false, "only serve the bundle and wait for a browser to open it")
+	fs.StringVar(&opts.RemoteBrowser, "remote", "", "DevTools endpoint (ws://host:9222/devtools/browser/<id>) of a running browser to use instead of launching one")
+	fs.StringVar(&opts.WebDriver, "webdriver", "", "WebDriver server, e.g. a Selenium Grid at http://grid:4444, to run the tests through instead of launching a browser")
+	fs.StringVar(&opts.URL, "url", "", "URL of the served bundle as seen by the browser (default the serving address)")
+	flags := fs.String("browser-flags", "", "extra space separated browser command line flags, e.g. --lang=es")
+	playwright := fs.String("playwright", "", "run the tests with Playwright, in chromium, firefox or webkit")
+	trace := fs.String("trace", "", "with -playwright, write a Playwright trace of the run to this file")
+	cpuProfile := fs.String("cpuprofile", "", "with -playwright chromium, write a pprof CPU profile of the run to this directory")
+	jsCoverage := fs.String("js-coverage", "", "with -playwright chromium, write the JS coverage of the run to this directory")
+	memory := fs.String("memory", "", "write memory samples, and with -playwright chromium heap snapshots, to this directory")
+	memoryAt := fs.String("memory-at", "end", "comma separated points sampled with -memory: end, failure and test")
+	har := fs.String("har", "", "write the network requests of the tests to this HAR file")
+	video := fs.String("video", "", "with -playwright, save a video of failed runs in this directory")
+	headful := fs.Bool("headful", false, "show the browser window and its devtools, and keep it open when the tests fail")
+	pause := fs.Duration("pause", 0, "with -headful, close the browser this long after a failure (default: when closed)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: wasmtest run-bundle [flags] [bundle dir]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	dir := "wasmtest-bundle"
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	wopts := []wasmtest.Option{wasmtest.WithInstallDisabled(), wasmtest.WithFailurePause(*pause), wasmtest.WithBrowserFlags(strings.Fields(*flags)...)}
+	if *headful {
+		wopts = append(wopts, wasmtest.WithHeadful())
+	}
+	if *playwright != "" {
+		wopts = append(wopts, wasmtest.WithPlaywright(wasmtest.PlaywrightOptions{Engine: *playwright, Trace: *trace}))
+	}
+	if *cpuProfile != "" {
+		wopts = append(wopts, wasmtest.WithCPUProfile(*cpuProfile))
+	}
+	if *jsCoverage != "" {
+		wopts = append(wopts, wasmtest.WithJSCoverage(*jsCoverage))
+	}
+	if *memory != "" {
+		at, err := memoryPoints(*memoryAt)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		wopts = append(wopts, wasmtest.WithMemoryProfile(*memory, at))
+	}
+	if *har != "" {
+		wopts = append(wopts, wasmtest.WithHAR(*har))
+	}
+	if *video != "" {
+		wopts = append(wopts, wasmtest.WithVideo(*video))
+	}
+	w := wasmtest.New(wopts...)
+	err := w.RunBundle(ctx, dir, opts, printProgress)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	return wasmtest.ExitCode(err)
+}
+
+// memoryPoints parses the -memory-at list.
+func memoryPoints(list string) (wasmtest.MemoryPoint, error) {
+	var at wasmtest.MemoryPoint
+	for _, name := range strings.Split(list, ",") {
+		switch strings.TrimSpace(name) {
+		case "end":
+			at |= wasmtest.MemoryAtEnd
+		case "failure":
+			at |= wasmtest.MemoryOnFailure
+		case "test":
+			at |= wasmtest.MemoryPerTest
+		default:
+			return 0, fmt.Errorf("wasmtest: unknown -memory-at point %q (want end, failure or test)", name)
+		}
+	}
+	return at, nil
+}
+
+// printProgress prints test output as is and other progress messages with
+// their kind.
+func printProgress(msgs ...any) {
+	if len(msgs) > 1 && (msgs[0] == "out" || msgs[0] == "err") {
+		fmt.Println(msgs[1:]...)
+		return
+	}
+	fmt.Println(append([]any{"[WASMTEST]"}, msgs...)...)
+}
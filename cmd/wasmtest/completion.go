@@ -0,0 +1,252 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/cdvelop/wasmtest"
+)
+
+func init() {
+	commands["completion"] = command{
+		summary: "print the shell completion script for bash, zsh, fish or powershell",
+		run:     runCompletion,
+	}
+}
+
+// flagSets are the flag sets created by the commands, by command name, so
+// that completion can list their flags.
+var flagSets = map[string]*flag.FlagSet{}
+
+// completing silences the usage the commands print for -h while their
+// flags are collected.
+var completing bool
+
+// newFlagSet returns the flag set of the command called name.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	if completing {
+		fs.SetOutput(io.Discard)
+	}
+	flagSets[name] = fs
+	return fs
+}
+
+// commandFlags returns the flag set of the command called name. The
+// commands define their flags before doing anything else, so running one
+// with -h only defines them.
+func commandFlags(name string) *flag.FlagSet {
+	cmd, ok := commands[name]
+	if !ok {
+		return nil
+	}
+	completing = true
+	defer func() { completing = false }()
+	cmd.run([]string{"-h"})
+	return flagSets[name]
+}
+
+// completionScripts are the completion scripts by shell. They complete
+// through the hidden __complete command, which prints the candidates for
+// the last of its arguments, one per line.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for wasmtest
+_wasmtest() {
+	local IFS=$'\n'
+	COMPREPLY=($(wasmtest __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _wasmtest wasmtest
+`,
+	"zsh": `#compdef wasmtest
+# zsh completion for wasmtest
+_wasmtest() {
+	local -a candidates
+	candidates=("${(@f)$(wasmtest __complete "${(@)words[2,$CURRENT]}" 2>/dev/null)}")
+	candidates=(${candidates:#})
+	if (( ${#candidates} )); then
+		compadd -a candidates
+	else
+		_files
+	fi
+}
+compdef _wasmtest wasmtest
+`,
+	"fish": `# fish completion for wasmtest
+function __wasmtest_complete
+	set -l candidates (wasmtest __complete (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)
+	if test (count $candidates) -gt 0
+		printf '%s\n' $candidates
+	else
+		__fish_complete_path (commandline -ct)
+	end
+end
+complete -c wasmtest -f -a '(__wasmtest_complete)'
+`,
+	"powershell": `# powershell completion for wasmtest
+Register-ArgumentCompleter -Native -CommandName wasmtest -ScriptBlock {
+	param($wordToComplete, $commandAst, $cursorPosition)
+	$words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+	if ($wordToComplete -eq '') { $words += '' }
+	wasmtest __complete @words 2>$null | ForEach-Object {
+		[System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+	}
+}
+`,
+}
+
+func runCompletion(args []string) int {
+	fs := newFlagSet("completion")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: wasmtest completion bash|zsh|fish|powershell")
+		fmt.Fprintln(fs.Output(), "\nFor bash, add this to ~/.bashrc:\n\n\tsource <(wasmtest completion bash)")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	script, ok := completionScripts[fs.Arg(0)]
+	if fs.NArg() != 1 || !ok {
+		fs.Usage()
+		return 2
+	}
+	fmt.Print(script)
+	return 0
+}
+
+// complete prints the completions of the last of args, the word being
+// completed, given the words before it.
+func complete(args []string) {
+	if len(args) == 0 {
+		return
+	}
+	word := args[len(args)-1]
+	for _, candidate := range completions(args[:len(args)-1], word) {
+		if strings.HasPrefix(candidate, word) {
+			fmt.Println(candidate)
+		}
+	}
+}
+
+// completions returns the candidates for word. The command line words
+// before it start with the command name.
+func completions(before []string, word string) []string {
+	if len(before) == 0 {
+		names := []string{"help"}
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	}
+	fs := commandFlags(before[0])
+	if fs == nil || slices.Contains(before, "--") {
+		// The test binary flags are left to the shell.
+		return nil
+	}
+	if before[0] == "install" && before[len(before)-1] == "-browser" {
+		return []string{wasmtest.PlaywrightChromium, wasmtest.PlaywrightFirefox, wasmtest.PlaywrightWebKit}
+	}
+	if before[0] == "completion" {
+		return []string{"bash", "fish", "powershell", "zsh"}
+	}
+
+	// The value of a flag, as in -run Test<tab>. The words before it are
+	// parsed for the packages and the -tags to discover the tests with.
+	if name, hasValue := flagName(before[len(before)-1]); name != "" && !hasValue {
+		if f := fs.Lookup(name); f != nil && !isBoolFlag(f) {
+			positional, _ := parseInterspersed(fs, before[1:len(before)-1])
+			return flagValues(before[0], name, positional, fs)
+		}
+	}
+
+	if strings.HasPrefix(word, "-") {
+		var names []string
+		fs.VisitAll(func(f *flag.Flag) {
+			names = append(names, "-"+f.Name)
+		})
+		return names
+	}
+	// Leave directories and files to the shell.
+	return nil
+}
+
+// flagName returns the name of the flag in arg, and whether arg also holds
+// its value, or "" when arg is not a flag.
+func flagName(arg string) (string, bool) {
+	if len(arg) < 2 || arg[0] != '-' || arg == "--" {
+		return "", false
+	}
+	name := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+	name, _, hasValue := strings.Cut(name, "=")
+	return name, hasValue
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// flagValues returns the candidate values of the flag called name of cmd,
+// given the positional arguments before it.
+func flagValues(cmd, name string, positional []string, fs *flag.FlagSet) []string {
+	switch name {
+	case "run", "skip":
+		return testNames(cmd, positional, fs, "test", "example", "fuzz")
+	case "bench":
+		return testNames(cmd, positional, fs, "benchmark")
+	case "reporter", "format":
+		return wasmtest.ReportWriters()
+	case "backend":
+		return []string{
+			wasmtest.BackendAuto, wasmtest.BackendBrowser, wasmtest.BackendBuiltin, wasmtest.BackendDeno,
+			wasmtest.BackendDocker, wasmtest.BackendNode, wasmtest.BackendWasmer, wasmtest.BackendWasmtime,
+		}
+	}
+	return nil
+}
+
+// testNames returns the names of the functions of the given kinds in the
+// packages of the command line.
+func testNames(cmd string, positional []string, fs *flag.FlagSet, kinds ...string) []string {
+	var patterns []string
+	switch cmd {
+	case "run", "bench", "coverage", "list":
+		patterns = positional
+	case "run-binary":
+		// The binary is not a package; -dir is where it was built from.
+		if f := fs.Lookup("dir"); f != nil && f.Value.String() != "" {
+			patterns = []string{f.Value.String()}
+		}
+	}
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	var tags []string
+	if f := fs.Lookup("tags"); f != nil && f.Value.String() != "" {
+		tags = strings.Split(f.Value.String(), ",")
+	}
+
+	seen := map[string]bool{}
+	var names []string
+	for _, pattern := range patterns {
+		pkgs, err := wasmtest.DiscoverTests(pattern, tags...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		for _, pkg := range pkgs {
+			for _, fn := range pkg.Funcs {
+				if !seen[fn.Name] && slices.Contains(kinds, fn.Kind) {
+					seen[fn.Name] = true
+					names = append(names, fn.Name)
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
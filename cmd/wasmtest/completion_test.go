@@ -0,0 +1,93 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/cdvelop/wasmtest"
+)
+
+func TestCommandFlags(t *testing.T) {
+	// Every command defines its flags when run with -h, without running.
+	for name := range commands {
+		if fs := commandFlags(name); fs == nil {
+			t.Errorf("no flags collected for %s", name)
+		}
+	}
+	if commandFlags("nope") != nil {
+		t.Error("flags of an unknown command")
+	}
+}
+
+func TestCompletions(t *testing.T) {
+	for _, tc := range []struct {
+		before   []string
+		word     string
+		want     []string
+		unwanted []string
+	}{
+		{nil, "", []string{"help", "run", "bench", "init", "completion"}, nil},
+		{[]string{"run"}, "-", []string{"-run", "-shard-index", "-backend", "-v"}, nil},
+		{[]string{"run", "./...", "-v"}, "-", []string{"-tags"}, nil},
+		{[]string{"run", "-backend"}, "", []string{wasmtest.BackendNode, wasmtest.BackendBuiltin}, nil},
+		{[]string{"run", "-reporter"}, "", wasmtest.ReportWriters(), nil},
+		{[]string{"install", "-browser"}, "", []string{wasmtest.PlaywrightChromium, wasmtest.PlaywrightFirefox, wasmtest.PlaywrightWebKit}, nil},
+		{[]string{"completion"}, "", []string{"bash", "fish", "powershell", "zsh"}, nil},
+		{[]string{"bench"}, "-", []string{"-bench", "-benchmem"}, []string{"-run"}},
+		{[]string{"init"}, "-", []string{"-config"}, nil},
+	} {
+		got := completions(tc.before, tc.word)
+		for _, want := range tc.want {
+			if !slices.Contains(got, want) {
+				t.Errorf("completions(%q, %q) = %q, lacks %q", tc.before, tc.word, got, want)
+			}
+		}
+		for _, unwanted := range tc.unwanted {
+			if slices.Contains(got, unwanted) {
+				t.Errorf("completions(%q, %q) = %q, holds %q", tc.before, tc.word, got, unwanted)
+			}
+		}
+	}
+
+	// The test binary flags after -- and the positional arguments are
+	// left to the shell.
+	for _, before := range [][]string{{"run", "--"}, {"run", "./wasm_tests", "--", "-test.v"}, {"nope"}} {
+		if got := completions(before, "-"); got != nil {
+			t.Errorf("completions(%q, \"-\") = %q, want none", before, got)
+		}
+	}
+	if got := completions([]string{"run"}, "./"); got != nil {
+		t.Errorf("completions of a directory = %q, want none", got)
+	}
+}
+
+func TestCompletionScripts(t *testing.T) {
+	for shell := range completionScripts {
+		if code := runCompletion([]string{shell}); code != 0 {
+			t.Errorf("completion %s exited with %d", shell, code)
+		}
+	}
+	for _, args := range [][]string{nil, {"tcsh"}, {"bash", "zsh"}} {
+		if code := runCompletion(args); code != 2 {
+			t.Errorf("completion %q exited with %d, want 2", args, code)
+		}
+	}
+}
+
+func TestFlagName(t *testing.T) {
+	for arg, want := range map[string]struct {
+		name     string
+		hasValue bool
+	}{
+		"-run":      {"run", false},
+		"--run":     {"run", false},
+		"-run=Test": {"run", true},
+		"--":        {"", false},
+		"-":         {"", false},
+		"./a":       {"", false},
+	} {
+		if name, hasValue := flagName(arg); name != want.name || hasValue != want.hasValue {
+			t.Errorf("flagName(%q) = %q, %v", arg, name, hasValue)
+		}
+	}
+}
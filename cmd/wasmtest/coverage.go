@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/cdvelop/wasmtest"
+)
+
+func init() {
+	commands["coverage"] = command{
+		summary: "run the tests with coverage, merging the profiles of the packages and printing the total",
+		run:     runCoverage,
+	}
+}
+
+func runCoverage(args []string) int {
+	fs := newFlagSet("coverage")
+	var opts wasmtest.ExecOptions
+	fs.StringVar(&opts.Run, "run", "", "run only the tests matching this regexp")
+	fs.StringVar(&opts.Skip, "skip", "", "skip the tests matching this regexp")
+	out := fs.String("o", "coverage.out", "write the merged coverage profile to this file")
+	coverpkg := fs.String("coverpkg", "", "comma separated package patterns to measure, instead of the tested packages")
+	html := fs.Bool("html", false, "open the profile in the browser with go tool cover -html")
+	tags := fs.String("tags", "", "comma separated build tags")
+	timeout := fs.Duration("timeout", 0, "bound the whole run (default 3m, or the configuration file value)")
+	browser := fs.String("browser", "", "browser: chrome, chromium, edge, firefox or an executable (default: first one found)")
+	backend := fs.String("backend", "", "backend: browser, node, deno, wasmtime, wasmer, docker or auto (default browser); builtin writes no profile")
+	parallel := fs.Int("p", 0, "run up to this many packages at once (default one after the other, or the configuration file value)")
+	verbose := fs.Bool("v", false, "log the output of every test, not only of the failed ones")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: wasmtest coverage [flags] [dirs or ./... patterns] [-- test binary flags]")
+		fs.PrintDefaults()
+	}
+	args, opts.TestArgs = splitPassThrough(args)
+	patterns, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if *tags != "" {
+		opts.Tags = strings.Split(*tags, ",")
+	}
+	if *coverpkg != "" {
+		opts.Args = append(opts.Args, "-coverpkg", *coverpkg)
+	}
+
+	runArgs := []any{opts, wasmtest.CoverProfile(*out)}
+	switch len(patterns) {
+	case 0:
+	case 1:
+		runArgs = append(runArgs, patterns[0])
+	default:
+		runArgs = append(runArgs, patterns)
+	}
+	if *timeout > 0 {
+		runArgs = append(runArgs, *timeout)
+	}
+	if *browser != "" {
+		runArgs = append(runArgs, wasmtest.WithBrowser(*browser))
+	}
+	if *backend != "" {
+		runArgs = append(runArgs, wasmtest.WithBackend(*backend))
+	}
+	if *parallel > 0 {
+		runArgs = append(runArgs, wasmtest.Parallel(*parallel))
+	}
+	if *verbose {
+		runArgs = append(runArgs, wasmtest.Verbose)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	start := time.Now()
+	err = wasmtest.RunTestsContext(ctx, runArgs...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	// The profile is opened even when tests failed, but not the one of an
+	// earlier run.
+	if info, statErr := os.Stat(*out); *html && statErr == nil && !info.ModTime().Before(start) {
+		cover := exec.Command("go", "tool", "cover", "-html="+*out)
+		cover.Stdout, cover.Stderr = os.Stdout, os.Stderr
+		if err := cover.Run(); err != nil {
+			fmt.Fprintln(os.Stderr, "wasmtest: go tool cover:", err)
+		}
+	}
+	return wasmtest.ExitCode(err)
+}
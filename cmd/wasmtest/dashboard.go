@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/cdvelop/wasmtest"
+)
+
+func init() {
+	commands["dashboard"] = command{
+		summary: "serve a local web UI over the recorded runs",
+		run:     runDashboard,
+	}
+}
+
+func runDashboard(args []string) int {
+	fs := newFlagSet("dashboard")
+	addr := fs.String("addr", "localhost:8090", "address to listen on")
+	dir := fs.String("history", "", "directory holding the recorded runs (default the configuration file value, or "+wasmtest.DefaultHistoryDir+")")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	// The runs are recorded where the configuration file says.
+	if *dir == "" {
+		if cfg, err := wasmtest.LoadConfig("."); err == nil {
+			*dir = cfg.History
+		}
+	}
+	history, err := wasmtest.OpenHistory(*dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("WasmTest dashboard for %s on http://%s\n", history.Dir(), ln.Addr())
+	if err := http.Serve(ln, wasmtest.DashboardHandler(history)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
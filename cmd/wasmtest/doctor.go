@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/cdvelop/wasmtest"
+)
+
+func init() {
+	commands["doctor"] = command{
+		summary: "check that this host can run js/wasm tests, exiting with 3 when it can't",
+		run:     runDoctor,
+	}
+}
+
+func runDoctor(args []string) int {
+	fs := newFlagSet("doctor")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	browser := fs.String("browser", "", "browser the tests will use: chrome, chromium, edge, firefox or an executable")
+	headful := fs.Bool("headful", false, "check for a display, as the tests will show the browser window")
+	goToolchain := fs.String("go", "", "go executable or GOROOT the tests will use (default the go in PATH)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: wasmtest doctor [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	// The checks follow the configuration of the project, without
+	// installing anything.
+	cfg, err := wasmtest.LoadConfig(".")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	opts := append(cfg.Options(), wasmtest.WithInstallDisabled(), wasmtest.WithLogger(func(...any) {}))
+	if *browser != "" {
+		opts = append(opts, wasmtest.WithBrowser(*browser))
+	}
+	if *headful {
+		opts = append(opts, wasmtest.WithHeadful())
+	}
+	if *goToolchain != "" {
+		opts = append(opts, wasmtest.WithGoToolchain(*goToolchain))
+	}
+	report := wasmtest.New(opts...).Doctor(context.Background())
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	} else {
+		fmt.Println(report)
+	}
+	if !report.Healthy() {
+		return wasmtest.ExitEnvironment
+	}
+	return 0
+}
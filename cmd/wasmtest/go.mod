@@ -0,0 +1,9 @@
+module github.com/cdvelop/wasmtest/cmd/wasmtest
+
+go 1.24.4
+
+require github.com/cdvelop/wasmtest v0.0.0
+
+// The command builds against the library of the same checkout: install it
+// with go install . from this directory.
+replace github.com/cdvelop/wasmtest => ../../
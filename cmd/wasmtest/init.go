@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/cdvelop/wasmtest"
+)
+
+func init() {
+	commands["init"] = command{
+		summary: "create a wasm_tests directory with an example js/wasm test",
+		run:     runInit,
+	}
+}
+
+func runInit(args []string) int {
+	fs := newFlagSet("init")
+	var opts wasmtest.ScaffoldOptions
+	fs.BoolVar(&opts.Config, "config", false, "also create a wasmtest.yaml pointing at the directory")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: wasmtest init [-config] [dir]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	dir := "wasm_tests"
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+
+	created, err := wasmtest.Scaffold(dir, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for _, path := range created {
+		fmt.Println("created", path)
+	}
+	// The tests only build within a module.
+	if out, err := exec.Command("go", "env", "GOMOD").Output(); err == nil {
+		if gomod := strings.TrimSpace(string(out)); gomod == "" || gomod == os.DevNull {
+			fmt.Println("no go.mod found: create the module with `go mod init <module path>` first")
+		}
+	}
+	fmt.Printf("run the tests with: wasmtest run %s\n", dir)
+	return 0
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInit(t *testing.T) {
+	dir := writeModule(t, map[string]string{})
+
+	if code := runInit([]string{"-config", "ui_tests"}); code != 0 {
+		t.Fatalf("init exited with %d", code)
+	}
+	for _, path := range []string{"ui_tests/dom.go", "ui_tests/dom_test.go", "wasmtest.yaml"} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Errorf("init didn't create %s: %v", path, err)
+		}
+	}
+
+	// Existing files are never overwritten.
+	if code := runInit([]string{"ui_tests"}); code != 1 {
+		t.Errorf("init over existing files exited with %d, want 1", code)
+	}
+	if code := runInit([]string{"-unknown"}); code != 2 {
+		t.Errorf("init with an unknown flag exited with %d, want 2", code)
+	}
+	if code := runInit(nil); code != 0 {
+		t.Errorf("init of the default directory exited with %d", code)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "wasm_tests", "dom_test.go")); err != nil {
+		t.Errorf("init didn't create wasm_tests: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+
+	"github.com/cdvelop/wasmtest"
+)
+
+func init() {
+	commands["install"] = command{
+		summary: "install wasmbrowsertest, the go_js_wasm_exec link and a managed browser ahead of the test runs",
+		run:     runInstall,
+	}
+}
+
+func runInstall(args []string) int {
+	fs := newFlagSet("install")
+	version := fs.String("version", "", "wasmbrowsertest version to install, e.g. v0.8.0 (default the configuration file one, or latest)")
+	browser := fs.String("browser", "", "managed browser to download with Playwright: chromium, firefox or webkit (default none)")
+	dir := fs.String("dir", "", "directory to install wasmbrowsertest in (default GOBIN, or GOPATH/bin)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: wasmtest install [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 || !slices.Contains([]string{"", wasmtest.PlaywrightChromium, wasmtest.PlaywrightFirefox, wasmtest.PlaywrightWebKit}, *browser) {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := wasmtest.LoadConfig(".")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	opts := append(cfg.Options(), wasmtest.WithAutoInstall(false), wasmtest.WithLogger(printProgress))
+	if *version != "" {
+		opts = append(opts, wasmtest.WithWasmBrowserTestVersion(*version))
+	}
+	if *dir != "" {
+		opts = append(opts, wasmtest.WithInstallDir(*dir))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := wasmtest.New(opts...).Prepare(ctx, *browser, printProgress); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return wasmtest.ExitEnvironment
+	}
+	return 0
+}
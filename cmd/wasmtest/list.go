@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cdvelop/wasmtest"
+)
+
+func init() {
+	commands["list"] = command{
+		summary: "list the js/wasm tests, benchmarks and examples without running them",
+		run:     runList,
+	}
+}
+
+func runList(args []string) int {
+	fs := newFlagSet("list")
+	asJSON := fs.Bool("json", false, "print the packages and their functions as JSON")
+	tags := fs.String("tags", "", "comma separated build tags")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: wasmtest list [flags] [dir or ./... pattern]")
+		fs.PrintDefaults()
+	}
+	patterns, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	pattern := "./..."
+	if len(patterns) > 0 {
+		pattern = patterns[0]
+	}
+	var tagList []string
+	if *tags != "" {
+		tagList = strings.Split(*tags, ",")
+	}
+
+	pkgs, err := wasmtest.DiscoverTests(pattern, tagList...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *asJSON {
+		if pkgs == nil {
+			pkgs = []wasmtest.PackageTests{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(pkgs); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, pkg := range pkgs {
+		fmt.Fprintf(tw, "%s (package %s)\n", pkg.Dir, pkg.Package)
+		for _, fn := range pkg.Funcs {
+			fmt.Fprintf(tw, "  %s\t%s\t%s:%d\n", fn.Name, fn.Kind, filepath.Base(fn.File), fn.Line)
+		}
+	}
+	tw.Flush()
+	return 0
+}
@@ -0,0 +1,59 @@
+// Command wasmtest is the command line companion of the wasmtest package.
+//
+// Usage:
+//
+//	wasmtest <command> [flags]
+//
+// Run `wasmtest help` for the list of commands.
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// command is a wasmtest subcommand. run receives the arguments following
+// the command name and returns the process exit code.
+type command struct {
+	summary string
+	run     func(args []string) int
+}
+
+var commands = map[string]command{}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	if name == "__complete" {
+		complete(os.Args[2:])
+		return
+	}
+	if name == "help" || name == "-h" || name == "--help" {
+		usage()
+		return
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "wasmtest: unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
+	os.Exit(cmd.run(os.Args[2:]))
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: wasmtest <command> [flags]\n\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun `wasmtest <command> -h` for the flags of a command.")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cdvelop/wasmtest"
+)
+
+func init() {
+	commands["report"] = command{
+		summary: "render a saved run-report.json in another format, e.g. html or junit",
+		run:     runReport,
+	}
+}
+
+func runReport(args []string) int {
+	fs := newFlagSet("report")
+	format := fs.String("format", "text", "output format: "+strings.Join(wasmtest.ReportWriters(), ", "))
+	output := fs.String("o", "", "write the report to this file instead of the standard output")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: wasmtest report [flags] run-report.json")
+		fs.PrintDefaults()
+	}
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(files) != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	in, err := os.Open(files[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer in.Close()
+	report, err := wasmtest.ReadJSONReport(in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	rw, err := wasmtest.NewReportWriter(*format, out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := report.Render(rw); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
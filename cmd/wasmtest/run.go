@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+
+	"github.com/cdvelop/wasmtest"
+)
+
+func init() {
+	commands["run"] = command{
+		summary: "run the js/wasm tests of directories or ./... patterns",
+		run:     runRun,
+	}
+}
+
+func runRun(args []string) int {
+	fs := newFlagSet("run")
+	var opts wasmtest.ExecOptions
+	fs.StringVar(&opts.Run, "run", "", "run only the tests matching this regexp")
+	fs.StringVar(&opts.Skip, "skip", "", "skip the tests matching this regexp")
+	fs.IntVar(&opts.Count, "count", 0, "run each test this many times")
+	fs.StringVar(&opts.Shuffle, "shuffle", "", "randomize the test order: on, or the seed of an order to replay")
+	tags := fs.String("tags", "", "comma separated build tags")
+	timeout := fs.Duration("timeout", 0, "bound the whole run (default 3m, or the configuration file value)")
+	reporter := fs.String("reporter", "", "also write a report in this format: "+strings.Join(wasmtest.ReportWriters(), ", "))
+	output := fs.String("o", "", "write the -reporter report to this file instead of the standard output")
+	browser := fs.String("browser", "", "browser: chrome, chromium, edge, firefox or an executable (default: first one found)")
+	backend := fs.String("backend", "", "backend: browser, builtin, node, deno, wasmtime, wasmer, docker or auto (default browser)")
+	headful := fs.Bool("headful", false, "show the browser window instead of running it headless")
+	verbose := fs.Bool("v", false, "log the output of every test, not only of the failed ones")
+	parallel := fs.Int("p", 0, "run up to this many packages at once (default one after the other, or the configuration file value)")
+	var shard wasmtest.Shard
+	fs.IntVar(&shard.Index, "shard-index", 0, "run the shard of this index, from 0 to -shard-total minus one")
+	fs.IntVar(&shard.Total, "shard-total", 0, "split the tests in this many shards, e.g. one per CI machine (default the WASMTEST_SHARD_TOTAL value)")
+	fs.BoolVar(&shard.Packages, "shard-packages", false, "split whole packages instead of tests between the shards")
+	changedSince := fs.String("changed-since", "", "only run the packages affected by the changes since this git revision")
+	artifacts := fs.String("artifacts", "", "write report.html and run-report.json to this directory")
+	history := fs.String("history", "", "record the run in this directory for wasmtest dashboard (default the configuration file value)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: wasmtest run [flags] [dirs or ./... patterns] [-- test binary flags]")
+		fs.PrintDefaults()
+	}
+	args, opts.TestArgs = splitPassThrough(args)
+	patterns, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+
+	var runArgs []any
+	switch len(patterns) {
+	case 0:
+	case 1:
+		runArgs = append(runArgs, patterns[0])
+	default:
+		runArgs = append(runArgs, patterns)
+	}
+	if *tags != "" {
+		opts.Tags = strings.Split(*tags, ",")
+	}
+	runArgs = append(runArgs, opts)
+	if *timeout > 0 {
+		runArgs = append(runArgs, *timeout)
+	}
+	// Unset flags leave the configuration file values.
+	if *browser != "" {
+		runArgs = append(runArgs, wasmtest.WithBrowser(*browser))
+	}
+	if *backend != "" {
+		runArgs = append(runArgs, wasmtest.WithBackend(*backend))
+	}
+	if *headful {
+		runArgs = append(runArgs, wasmtest.WithHeadful())
+	}
+	if *verbose {
+		runArgs = append(runArgs, wasmtest.Verbose)
+	}
+	if *parallel > 0 {
+		runArgs = append(runArgs, wasmtest.Parallel(*parallel))
+	}
+	if shard.Total > 0 {
+		runArgs = append(runArgs, shard)
+	}
+	if *changedSince != "" {
+		runArgs = append(runArgs, wasmtest.ChangedSince(*changedSince))
+	}
+	if *artifacts != "" {
+		runArgs = append(runArgs, wasmtest.ArtifactsDir(*artifacts))
+	}
+	if *history != "" {
+		runArgs = append(runArgs, wasmtest.HistoryDir(*history))
+	}
+
+	// The log goes to the standard error when the report takes the
+	// standard output.
+	var logOut io.Writer = os.Stdout
+	if *reporter != "" {
+		var out io.Writer = os.Stdout
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return wasmtest.ExitEnvironment
+			}
+			defer f.Close()
+			out = f
+		} else {
+			logOut = os.Stderr
+		}
+		rw, err := wasmtest.NewReportWriter(*reporter, out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		runArgs = append(runArgs, rw)
+	}
+	runArgs = append(runArgs, func(a ...any) { fmt.Fprintln(logOut, a...) })
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err = wasmtest.RunTestsContext(ctx, runArgs...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	return wasmtest.ExitCode(err)
+}
+
+// splitPassThrough splits args at the first "--": the arguments after it
+// are passed to the test binary untouched, as in `wasmtest run ./wasm_tests
+// -- -test.run TestFoo -myflag=bar`.
+func splitPassThrough(args []string) ([]string, []string) {
+	if i := slices.Index(args, "--"); i >= 0 {
+		return args[:i], args[i+1:]
+	}
+	return args, nil
+}
+
+// parseInterspersed parses the flags of args with fs, allowing them after
+// the positional arguments, as in `wasmtest run ./wasm_tests -run TestDOM`,
+// and returns the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/cdvelop/wasmtest"
+)
+
+// writeModule writes files into a new module in a temporary directory,
+// makes it the working directory and returns it.
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/tmp\n\ngo 1.21\n"
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(dir)
+	return dir
+}
+
+// needNode skips the test when the node backend can't run.
+func needNode(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node not found in PATH; skipping")
+	}
+	t.Setenv("WASMTEST_SKIP_INSTALL", "1")
+}
+
+func TestParseInterspersed(t *testing.T) {
+	for _, tc := range []struct {
+		args       []string
+		positional []string
+		run        string
+		verbose    bool
+	}{
+		{nil, nil, "", false},
+		{[]string{"./a", "./b"}, []string{"./a", "./b"}, "", false},
+		{[]string{"-run", "TestX", "./a"}, []string{"./a"}, "TestX", false},
+		{[]string{"./a", "-run", "TestX", "./b", "-v"}, []string{"./a", "./b"}, "TestX", true},
+		{[]string{"./...", "-run=TestX"}, []string{"./..."}, "TestX", false},
+	} {
+		fs := newFlagSet("test")
+		run := fs.String("run", "", "")
+		verbose := fs.Bool("v", false, "")
+		positional, err := parseInterspersed(fs, tc.args)
+		if err != nil || !slices.Equal(positional, tc.positional) || *run != tc.run || *verbose != tc.verbose {
+			t.Errorf("parseInterspersed(%q) = %q, %v with -run %q -v %v", tc.args, positional, err, *run, *verbose)
+		}
+	}
+
+	fs := newFlagSet("test")
+	fs.SetOutput(io.Discard)
+	if _, err := parseInterspersed(fs, []string{"./a", "-unknown"}); err == nil {
+		t.Error("parseInterspersed accepted an unknown flag")
+	}
+}
+
+func TestSplitPassThrough(t *testing.T) {
+	for _, tc := range []struct {
+		args, before, after []string
+	}{
+		{nil, nil, nil},
+		{[]string{"./a", "-run", "X"}, []string{"./a", "-run", "X"}, nil},
+		{[]string{"./a", "--", "-test.v", "-name=x"}, []string{"./a"}, []string{"-test.v", "-name=x"}},
+		{[]string{"--", "--"}, []string{}, []string{"--"}},
+	} {
+		before, after := splitPassThrough(tc.args)
+		if !slices.Equal(before, tc.before) || !slices.Equal(after, tc.after) {
+			t.Errorf("splitPassThrough(%q) = %q, %q; want %q, %q", tc.args, before, after, tc.before, tc.after)
+		}
+	}
+}
+
+func TestRunPassThrough(t *testing.T) {
+	needNode(t)
+	writeModule(t, map[string]string{
+		"wasm_tests/p_test.go": "//go:build js && wasm\n\npackage p\n\nimport (\n\t\"flag\"\n\t\"testing\"\n)\n\n" +
+			"var name = flag.String(\"name\", \"\", \"\")\n\n" +
+			"func TestName(t *testing.T) {\n\tif *name != \"gopher\" {\n\t\tt.Fatalf(\"-name = %q\", *name)\n\t}\n}\n",
+	})
+
+	// The flags after -- reach the test binary, not the run flags.
+	if code := runRun([]string{"./wasm_tests", "-backend", "node", "--", "-name=gopher"}); code != 0 {
+		t.Errorf("run with -- -name=gopher exited with %d", code)
+	}
+	if code := runRun([]string{"./wasm_tests", "-backend", "node", "--", "-name=other"}); code != 1 {
+		t.Errorf("run with -- -name=other exited with %d, want 1", code)
+	}
+	if code := runRun([]string{"./wasm_tests", "-name=gopher"}); code != 2 {
+		t.Errorf("run with a test binary flag before -- exited with %d, want 2", code)
+	}
+}
+
+func TestRunOutputError(t *testing.T) {
+	dir := writeModule(t, map[string]string{})
+	out := filepath.Join(dir, "missing", "report.xml")
+	if code := runRun([]string{"-reporter", "junit", "-o", out}); code != wasmtest.ExitEnvironment {
+		t.Errorf("run with an -o file that can't be created exited with %d, want %d", code, wasmtest.ExitEnvironment)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/cdvelop/wasmtest"
+)
+
+func init() {
+	commands["serve"] = command{
+		summary: "serve the test page of a directory, rebuilding on changes and running the tests on each reload",
+		run:     runServe,
+	}
+}
+
+func runServe(args []string) int {
+	fs := newFlagSet("serve")
+	var opts wasmtest.ServeOptions
+	fs.StringVar(&opts.Addr, "addr", wasmtest.DefaultServeAddr, "address to serve the test page on")
+	fs.StringVar(&opts.Exec.Run, "run", "", "run only the tests matching this regexp")
+	fs.StringVar(&opts.Exec.Skip, "skip", "", "skip the tests matching this regexp")
+	fs.DurationVar(&opts.Interval, "interval", 0, "how often to check the sources for changes (default 500ms)")
+	fs.BoolVar(&opts.Open, "open", false, "open the test page in the browser, with its window shown")
+	fs.BoolVar(&opts.Headless, "headless", false, "open the test page in a headless browser kept running across the rebuilds, following the runs in the terminal")
+	browser := fs.String("browser", "", "browser for -open and -headless: chrome, chromium, edge, firefox or an executable (default: first one found)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: wasmtest serve [flags] [package dir]")
+		fs.PrintDefaults()
+	}
+	dirs, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(dirs) > 1 {
+		fs.Usage()
+		return 2
+	}
+	opts.Open = opts.Open || opts.Headless
+	dir := "."
+	if len(dirs) == 1 {
+		dir = dirs[0]
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	wopts := []wasmtest.Option{wasmtest.WithInstallDisabled()}
+	if *browser != "" {
+		wopts = append(wopts, wasmtest.WithBrowser(*browser))
+	}
+	err = wasmtest.New(wopts...).Serve(ctx, dir, opts, printProgress)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	return wasmtest.ExitCode(err)
+}
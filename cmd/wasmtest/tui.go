@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/cdvelop/wasmtest"
+)
+
+func init() {
+	commands["tui"] = command{
+		summary: "browse and rerun the js/wasm tests in an interactive terminal view",
+		run:     runTUI,
+	}
+}
+
+func runTUI(args []string) int {
+	fs := newFlagSet("tui")
+	tags := fs.String("tags", "", "comma separated build tags")
+	timeout := fs.Duration("timeout", 0, "bound each run (default 3m, or the configuration file value)")
+	browser := fs.String("browser", "", "browser: chrome, chromium, edge, firefox or an executable (default: first one found)")
+	backend := fs.String("backend", "", "backend: browser, builtin, node, deno, wasmtime, wasmer, docker or auto (default browser)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: wasmtest tui [flags] [dirs or ./... patterns]")
+		fs.PrintDefaults()
+	}
+	patterns, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+
+	var uiArgs []any
+	if *tags != "" {
+		uiArgs = append(uiArgs, wasmtest.ExecOptions{Tags: strings.Split(*tags, ",")})
+	}
+	if *timeout > 0 {
+		uiArgs = append(uiArgs, *timeout)
+	}
+	if *browser != "" {
+		uiArgs = append(uiArgs, wasmtest.WithBrowser(*browser))
+	}
+	if *backend != "" {
+		uiArgs = append(uiArgs, wasmtest.WithBackend(*backend))
+	}
+	ui, err := wasmtest.NewTestUI(patterns, uiArgs...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); !ok {
+		ui.Theme = wasmtest.DefaultTheme
+	}
+
+	restore, err := cbreak()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wasmtest: the tui needs an interactive terminal:", err)
+		return wasmtest.ExitEnvironment
+	}
+	// The alternate screen keeps the shell scrollback intact.
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Print("\x1b[?25h\x1b[?1049l")
+		restore()
+	}()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	keys := make(chan string)
+	go readKeys(os.Stdin, keys)
+
+	ui.Key("a")
+	draw(ui)
+	redraw := time.NewTicker(100 * time.Millisecond)
+	defer redraw.Stop()
+	dirty := false
+	for {
+		select {
+		case key, ok := <-keys:
+			if !ok || !ui.Key(key) {
+				return 0
+			}
+			draw(ui)
+		case <-interrupt:
+			ui.Key("q")
+			return 0
+		case <-ui.Updates():
+			dirty = true
+		case <-redraw.C:
+			// Runs update the view at every output line; drawing is
+			// throttled.
+			if dirty {
+				draw(ui)
+				dirty = false
+			}
+		}
+	}
+}
+
+// draw renders ui over the whole terminal.
+func draw(ui *wasmtest.TestUI) {
+	rows, cols := terminalSize()
+	view := strings.ReplaceAll(ui.Render(cols, rows), "\n", "\x1b[K\n")
+	fmt.Print("\x1b[H" + view + "\x1b[K\x1b[J")
+}
+
+// cbreak makes the terminal deliver key presses as they are typed, without
+// echoing them, and returns the function restoring its previous mode.
+func cbreak() (func(), error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return nil, err
+	}
+	return func() { stty(strings.TrimSpace(saved)) }, nil
+}
+
+// terminalSize returns the rows and columns of the terminal, or zeros when
+// unknown.
+func terminalSize() (rows, cols int) {
+	out, err := stty("size")
+	if err != nil {
+		return 0, 0
+	}
+	fmt.Sscan(out, &rows, &cols)
+	return rows, cols
+}
+
+// stty runs stty on the terminal of the standard input.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		err = fmt.Errorf("stty %s: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return string(out), err
+}
+
+// keyNames are the names of the special keys given to TestUI.Key, by the
+// bytes terminals send for them.
+var keyNames = map[string]string{
+	"\x1b[A": "up",
+	"\x1bOA": "up",
+	"\x1b[B": "down",
+	"\x1bOB": "down",
+	"\x1b":   "esc",
+	"\r":     "enter",
+	"\n":     "enter",
+	"\x7f":   "backspace",
+	"\b":     "backspace",
+	"\x03":   "ctrl+c",
+}
+
+// readKeys sends the names of the keys read from in to keys, closing it at
+// the end of the input.
+func readKeys(in io.Reader, keys chan<- string) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return
+		}
+		for s := string(buf[:n]); s != ""; {
+			key, size := nextKey(s)
+			s = s[size:]
+			if key != "" {
+				keys <- key
+			}
+		}
+	}
+}
+
+// nextKey returns the name of the key at the start of s and its length in
+// bytes; unknown escape sequences get an empty name.
+func nextKey(s string) (string, int) {
+	if s[0] == '\x1b' && len(s) >= 3 && (s[1] == '[' || s[1] == 'O') {
+		end := 2
+		for end < len(s) && (s[end] < 0x40 || s[end] > 0x7e) {
+			end++
+		}
+		end = min(end+1, len(s))
+		return keyNames[s[:end]], end
+	}
+	for seq, name := range keyNames {
+		if len(seq) == 1 && s[0] == seq[0] {
+			return name, 1
+		}
+	}
+	r := []rune(s)[0]
+	return string(r), len(string(r))
+}
@@ -0,0 +1,156 @@
+package wasmtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CompileStats describes the js/wasm compilation of a test package. It is
+// reported through the progress callback as ("compile", CompileStats) before
+// the tests run.
+type CompileStats struct {
+	// Package is the import path of the compiled test package.
+	Package string `json:"package"`
+	// Duration is the wall time spent building the test binary.
+	Duration time.Duration `json:"duration"`
+	// Cached is true when nothing had to be compiled because every package
+	// was served from the Go build cache.
+	Cached bool `json:"cached"`
+	// Compiled lists the import paths that had to be recompiled.
+	Compiled []string `json:"compiled"`
+	// GoWasm is the GOWASM feature set of the js/wasm build; empty means
+	// the default set. Always empty for native builds.
+	GoWasm string `json:"goWasm,omitempty"`
+	// Binary is the path of the test binary kept by WithKeepBinary.
+	Binary string `json:"binary,omitempty"`
+	// Reused is true when go test -c did not run at all: the build inputs
+	// were unchanged and the binary came from WithBinaryCache. Duration is
+	// then the time spent checking them.
+	Reused bool `json:"reused,omitempty"`
+}
+
+// errKeepBinary wraps the errors keeping a test binary (see WithKeepBinary).
+var errKeepBinary = errors.New("wasmtest: keeping the test binary failed")
+
+// errBinaryCache wraps the errors storing a test binary in the binary cache
+// (see WithBinaryCache).
+var errBinaryCache = errors.New("wasmtest: caching the test binary failed")
+
+// WithKeepBinary keeps the test binaries compiled by go test runs in dir,
+// one subdirectory per package named after its import path, instead of
+// discarding them. Each is written as a bundle, with the harness files and
+// the manifest, so it can be run again with RunBundle or RunBinary without
+// the sources, archived, or inspected with WASM tooling. The
+// WASMTEST_KEEP_BINARY environment variable and the keep_binary setting of
+// the configuration file set it too. Native and TinyGo builds are not kept.
+func WithKeepBinary(dir string) Option {
+	return func(w *Wasmtest) { w.keepBinary = dir }
+}
+
+// String renders the stats for log output.
+func (s CompileStats) String() string {
+	if s.Reused {
+		return fmt.Sprintf("reused the %s test binary, checked in %v (sources unchanged)", s.Package, s.Duration.Round(time.Millisecond))
+	}
+	if s.Cached {
+		return fmt.Sprintf("compiled %s in %v (build cache hit)", s.Package, s.Duration.Round(time.Millisecond))
+	}
+	return fmt.Sprintf("compiled %s in %v (%d packages rebuilt)", s.Package, s.Duration.Round(time.Millisecond), len(s.Compiled))
+}
+
+// compile builds the test binary described by spec with `go test -c -x`,
+// measuring how long it takes and which packages were actually compiled
+// according to the -x command trace. The binary itself is discarded, unless
+// kept by WithKeepBinary; the following go test run picks it up from the
+// build cache. A non empty cache is the WithBinaryCache entry the binary is
+// stored in.
+func (w *Wasmtest) compile(ctx context.Context, spec execSpec, cache string) (CompileStats, error) {
+	var stats CompileStats
+	if !spec.native {
+		stats.GoWasm = lookupEnv(spec.environ(), "GOWASM")
+	}
+
+	list := exec.CommandContext(ctx, spec.toolchain.command(), "list", "-f", "{{.ImportPath}}")
+	list.Dir = spec.dir
+	list.Env = spec.environ()
+	out, err := list.Output()
+	if err != nil {
+		return stats, err
+	}
+	stats.Package = strings.TrimSpace(string(out))
+
+	tmp, err := makeTempDir("wasmtest-compile-")
+	if err != nil {
+		return stats, err
+	}
+	defer os.RemoveAll(tmp)
+
+	bin := filepath.Join(tmp, "test.wasm")
+	var keep string
+	if w.keepBinary != "" && !spec.native {
+		name := artifactName(stats.Package)
+		if spec.target == TargetWASIP1 {
+			name += "_wasip1"
+		}
+		if keep, err = filepath.Abs(filepath.Join(w.keepBinary, name)); err == nil {
+			err = os.MkdirAll(keep, 0o755)
+		}
+		if err != nil {
+			return stats, fmt.Errorf("%w: %v", errKeepBinary, err)
+		}
+		bin = filepath.Join(keep, "test.wasm")
+	}
+
+	args := append([]string{"test", "-c", "-x", "-o", bin}, spec.args...)
+	cmd := exec.CommandContext(ctx, spec.toolchain.command(), args...)
+	cmd.Dir = spec.dir
+	cmd.Env = spec.environ()
+	var trace bytes.Buffer
+	cmd.Stderr = &trace
+
+	start := time.Now()
+	err = cmd.Run()
+	stats.Duration = time.Since(start)
+	if err != nil {
+		return stats, err
+	}
+
+	stats.Compiled = compiledPackages(trace.String())
+	stats.Cached = len(stats.Compiled) == 0
+	if cache != "" {
+		if err := storeBinary(cache, bin, stats.Package); err != nil {
+			return stats, fmt.Errorf("%w: %v", errBinaryCache, err)
+		}
+	}
+	if keep != "" {
+		if _, err := writeBundleFiles(ctx, spec, keep, stats.Package, nil); err != nil {
+			return stats, fmt.Errorf("%w: %v", errKeepBinary, err)
+		}
+		stats.Binary = bin
+	}
+	return stats, nil
+}
+
+// compiledPackages returns the import paths passed with -p to the compile
+// tool in a `go build -x` trace.
+func compiledPackages(trace string) []string {
+	var pkgs []string
+	for _, line := range strings.Split(trace, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.TrimSuffix(filepath.Base(fields[0]), ".exe") != "compile" {
+			continue
+		}
+		if i := slices.Index(fields, "-p"); i >= 0 && i+1 < len(fields) && !slices.Contains(pkgs, fields[i+1]) {
+			pkgs = append(pkgs, fields[i+1])
+		}
+	}
+	return pkgs
+}
@@ -0,0 +1,94 @@
+package wasmtest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCompiledPackages(t *testing.T) {
+	trace := `mkdir -p $WORK/b001/
+cd /src/example
+/usr/local/go/pkg/tool/linux_amd64/compile -o $WORK/b128/_pkg_.a -trimpath "$WORK/b128=>" -p github.com/cdvelop/wasmtest/example -lang=go1.21
+/usr/local/go/pkg/tool/linux_amd64/compile -o $WORK/b001/_pkg_.a -p main -lang=go1.21
+/usr/local/go/pkg/tool/linux_amd64/link -o $WORK/b001/example.test.exe -importcfg $WORK/b001/importcfg.link
+`
+	got := compiledPackages(trace)
+	want := []string{"github.com/cdvelop/wasmtest/example", "main"}
+	if !slices.Equal(got, want) {
+		t.Errorf("compiledPackages() = %v, want %v", got, want)
+	}
+	if got := compiledPackages("mkdir -p $WORK/b001/\n"); len(got) != 0 {
+		t.Errorf("compiledPackages() on a cached build = %v, want none", got)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	w := &Wasmtest{log: func(...any) {}, safeLog: func(...any) {}}
+	ctx := context.Background()
+
+	// The first build may or may not be cached; the second one must be.
+	if _, err := w.compile(ctx, execSpec{dir: "./example"}, ""); err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	stats, err := w.compile(ctx, execSpec{dir: "./example"}, "")
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	if stats.Package != "github.com/cdvelop/wasmtest/example" {
+		t.Errorf("Package = %q", stats.Package)
+	}
+	if !stats.Cached || stats.Duration <= 0 {
+		t.Errorf("second compile = %+v, want a timed cache hit", stats)
+	}
+}
+
+func TestCompileGoWasm(t *testing.T) {
+	w := &Wasmtest{log: func(...any) {}, safeLog: func(...any) {}}
+	ctx := context.Background()
+
+	stats, err := w.compile(ctx, execSpec{dir: "./example", env: []string{"GOWASM=satconv,signext"}}, "")
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	if stats.GoWasm != "satconv,signext" {
+		t.Errorf("GoWasm = %q, want satconv,signext", stats.GoWasm)
+	}
+	if _, err := w.compile(ctx, execSpec{dir: "./example", env: []string{"GOWASM=bogus"}}, ""); err == nil {
+		t.Error("compile accepted an unknown GOWASM feature")
+	}
+}
+
+func TestExecuteKeepBinary(t *testing.T) {
+	keep := t.TempDir()
+	w := New(WithInstallDisabled(), WithBackend(BackendNode), WithKeepBinary(keep))
+	progress, msgs := collectProgress()
+	if err := w.execute(t.Context(), execSpec{dir: writeModule(t, map[string]string{"p_test.go": wasmPassTest})}, progress); err != nil {
+		t.Fatalf("execute failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	bundle := filepath.Join(keep, "example.com_tmp")
+	for _, name := range []string{"test.wasm", "wasm_exec.js", "index.html", "manifest.json"} {
+		if _, err := os.Stat(filepath.Join(bundle, name)); err != nil {
+			t.Errorf("kept bundle: %v", err)
+		}
+	}
+	if out := strings.Join(msgs(), "\n"); !strings.Contains(out, "test binary kept in "+filepath.Join(bundle, "test.wasm")) {
+		t.Errorf("unexpected progress:\n%s", out)
+	}
+
+	if pkg := binaryPackage(filepath.Join(bundle, "test.wasm")); pkg != "example.com/tmp" {
+		t.Errorf("kept package = %q", pkg)
+	}
+
+	// The kept binary runs again without the sources.
+	progress, msgs = collectProgress()
+	if err := w.RunBinary(t.Context(), filepath.Join(bundle, "test.wasm"), ExecOptions{}, progress); err != nil {
+		t.Fatalf("RunBinary failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	if out := strings.Join(msgs(), "\n"); !strings.Contains(out, "test pass TestPass") {
+		t.Errorf("unexpected progress:\n%s", out)
+	}
+}
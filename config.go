@@ -0,0 +1,784 @@
+package wasmtest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigFiles are the names of the configuration files looked up by
+// FindConfig, in order of preference.
+var ConfigFiles = []string{"wasmtest.yaml", "wasmtest.yml", ".wasmtest.toml"}
+
+// Config holds shared defaults loaded from a wasmtest.yaml or .wasmtest.toml
+// file committed at the module root, e.g.:
+//
+//	dir: wasm_tests
+//	timeout: 5m
+//	browser: chromium
+//	tags: [integration]
+//	args: [-count=1, -short]
+//	env:
+//	  WASM_HEADLESS: "off"
+//
+// or, in TOML:
+//
+//	dir = "wasm_tests"
+//	timeout = "5m"
+//	args = ["-count=1", "-short"]
+//
+//	[env]
+//	WASM_HEADLESS = "off"
+//
+// Only this flat subset of YAML and TOML is supported, so that no parser
+// dependency is needed.
+//
+// The environment variables WASMTEST_DIR, WASMTEST_TIMEOUT,
+// WASMTEST_PACKAGE_TIMEOUT, WASMTEST_BACKEND, WASMTEST_WASI_RUNTIME,
+// WASMTEST_DOCKER_IMAGE, WASMTEST_TARGET, WASMTEST_TINYGO, WASMTEST_BROWSER,
+// WASMTEST_BROWSER_FLAGS (space separated), WASMTEST_RUN, WASMTEST_SKIP,
+// WASMTEST_TAGS (comma separated), WASMTEST_ARGS (space separated go test
+// flags), WASMTEST_CHANGED_SINCE, WASMTEST_ARTIFACTS_DIR, WASMTEST_HISTORY,
+// WASMTEST_KEEP_BINARY, WASMTEST_BINARY_CACHE, WASMTEST_SLOWEST,
+// WASMTEST_PARALLEL, WASMTEST_SHARD_INDEX, WASMTEST_SHARD_TOTAL,
+// WASMTEST_VERBOSITY, WASMTEST_LOG_FILE, WASMTEST_LOG_MAX_SIZE,
+// WASMTEST_HEADFUL, WASMTEST_SKIP_INSTALL, WASMTEST_INSTALL_DIR,
+// WASMTEST_OFFLINE, WASMTEST_WASMBROWSERTEST_VERSION,
+// WASMTEST_RUNNER_UPGRADE, WASMTEST_PATH_FIX and WASMTEST_GO_TOOLCHAIN
+// override the file values, so CI pipelines can tweak them without code
+// changes. Arguments given to RunTests override both.
+type Config struct {
+	// Path is the file the configuration was loaded from.
+	Path string
+	// Dir is the test directory, relative to the file.
+	Dir     string
+	Timeout time.Duration
+	// PackageTimeout bounds each package of a multi-package run; it is
+	// passed as go test -timeout.
+	PackageTimeout time.Duration
+	// Backend runs the test binaries (see WithBackend).
+	Backend string
+	// WASIRuntime is the runtime executable of the WASI backends (see
+	// WithWASIRuntime).
+	WASIRuntime string
+	// DockerImage is the container image of BackendDocker (see
+	// WithDockerImage), run with its entrypoint.
+	DockerImage string
+	// Target is the target of the go test builds (see WithTarget).
+	Target string
+	// TinyGo is the target of the TinyGo builds (see WithTinyGo); empty
+	// uses the standard toolchain.
+	TinyGo string
+	// Browser is the browser used to run the tests, a Browser name or an
+	// executable (see WithBrowser).
+	Browser string
+	// BrowserFlags are extra browser command line flags (see
+	// WithBrowserFlags).
+	BrowserFlags []string
+	// Run is the -run regexp selecting the tests to run.
+	Run string
+	// Skip is the -skip regexp excluding tests.
+	Skip string
+	// Tags are extra build tags passed as -tags.
+	Tags []string
+	// Args holds extra go test flags.
+	Args []string
+	// Env holds extra environment variables for go test.
+	Env map[string]string
+	// ChangedSince restricts RunTests to the packages affected by the git
+	// changes since this revision (see ChangedSince).
+	ChangedSince string
+	// ArtifactsDir is where RunTests writes the report files of each run
+	// (see ArtifactsDir).
+	ArtifactsDir string
+	// History is where RunTests records each run for the dashboard (see
+	// HistoryDir).
+	History string
+	// KeepBinary is where the compiled test binaries are kept (see
+	// WithKeepBinary).
+	KeepBinary string
+	// BinaryCache is where the compiled test binaries are cached (see
+	// WithBinaryCache).
+	BinaryCache string
+	// Slowest is the number of slowest tests printed at the end of a run
+	// (see SlowestTests).
+	Slowest int
+	// Parallel is the number of packages run at once (see Parallel).
+	Parallel int
+	// ShardIndex and ShardTotal select the part of the tests run (see
+	// Shard). They are only read from the environment, as each CI machine
+	// runs another shard.
+	ShardIndex, ShardTotal int
+	// Verbosity selects what RunTests logs.
+	Verbosity Verbosity
+	// LogFile, LogMaxSize and LogBackups tee the progress messages to a
+	// rotated log file (see WithLogFile). The size accepts KB, MB and GB
+	// suffixes in the file and the environment.
+	LogFile    string
+	LogMaxSize int64
+	LogBackups int
+	// Headful shows the browser window (see WithHeadful).
+	Headful bool
+	// SkipInstall stops New from installing wasmbrowsertest (see
+	// WithInstallDisabled).
+	SkipInstall bool
+	// InstallEnv holds extra environment variables for the go install of
+	// wasmbrowsertest (see WithInstallEnv).
+	InstallEnv map[string]string
+	// InstallDir is where wasmbrowsertest is installed (see
+	// WithInstallDir).
+	InstallDir string
+	// Offline never installs wasmbrowsertest and fails fast without it (see
+	// WithNoInstall).
+	Offline bool
+	// WasmBrowserTestVersion pins the wasmbrowsertest version New installs
+	// (see WithWasmBrowserTestVersion).
+	WasmBrowserTestVersion string
+	// RunnerUpgrade is the policy for an outdated wasmbrowsertest (see
+	// WithRunnerUpgrade).
+	RunnerUpgrade string
+	// PathFix adds the install directory to the PATH of go test when it is
+	// missing (see WithPathFix).
+	PathFix bool
+	// GoToolchain is the go executable or GOROOT running the go commands
+	// (see WithGoToolchain).
+	GoToolchain string
+}
+
+// FindConfig looks for one of ConfigFiles from dir up to the module root
+// (the first directory containing go.mod) and returns its path, or "" when
+// there is none.
+func FindConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		for _, name := range ConfigFiles {
+			path := filepath.Join(dir, name)
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			}
+		}
+		parent := filepath.Dir(dir)
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil || parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// LoadConfig loads the configuration found by FindConfig from dir, or a zero
+// Config when there is no file, and applies the WASMTEST_* environment
+// variables on top of it.
+func LoadConfig(dir string) (*Config, error) {
+	path, err := FindConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{}
+	if path != "" {
+		if cfg, err = ReadConfig(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.applyEnv(os.Getenv); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// applyEnv overrides the settings of c with the WASMTEST_* variables
+// returned by getenv.
+func (c *Config) applyEnv(getenv func(string) string) error {
+	if v := getenv("WASMTEST_DIR"); v != "" {
+		c.Dir = v
+	}
+	if v := getenv("WASMTEST_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("wasmtest: WASMTEST_TIMEOUT: %w", err)
+		}
+		c.Timeout = d
+	}
+	if v := getenv("WASMTEST_PACKAGE_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("wasmtest: WASMTEST_PACKAGE_TIMEOUT: %w", err)
+		}
+		c.PackageTimeout = d
+	}
+	if v := getenv("WASMTEST_BACKEND"); v != "" {
+		c.Backend = v
+	}
+	if v := getenv("WASMTEST_WASI_RUNTIME"); v != "" {
+		c.WASIRuntime = v
+	}
+	if v := getenv("WASMTEST_DOCKER_IMAGE"); v != "" {
+		c.DockerImage = v
+	}
+	if v := getenv("WASMTEST_TARGET"); v != "" {
+		c.Target = v
+	}
+	if v := getenv("WASMTEST_TINYGO"); v != "" {
+		c.TinyGo = v
+	}
+	if v := getenv("WASMTEST_BROWSER"); v != "" {
+		c.Browser = v
+	}
+	if v := getenv("WASMTEST_BROWSER_FLAGS"); v != "" {
+		c.BrowserFlags = strings.Fields(v)
+	}
+	if v := getenv("WASMTEST_RUN"); v != "" {
+		c.Run = v
+	}
+	if v := getenv("WASMTEST_SKIP"); v != "" {
+		c.Skip = v
+	}
+	if v := getenv("WASMTEST_TAGS"); v != "" {
+		c.Tags = strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' })
+	}
+	if v := getenv("WASMTEST_ARGS"); v != "" {
+		c.Args = strings.Fields(v)
+	}
+	if v := getenv("WASMTEST_CHANGED_SINCE"); v != "" {
+		c.ChangedSince = v
+	}
+	if v := getenv("WASMTEST_ARTIFACTS_DIR"); v != "" {
+		c.ArtifactsDir = v
+	}
+	if v := getenv("WASMTEST_HISTORY"); v != "" {
+		c.History = v
+	}
+	if v := getenv("WASMTEST_KEEP_BINARY"); v != "" {
+		c.KeepBinary = v
+	}
+	if v := getenv("WASMTEST_BINARY_CACHE"); v != "" {
+		c.BinaryCache = v
+	}
+	if v := getenv("WASMTEST_SLOWEST"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("wasmtest: WASMTEST_SLOWEST: %w", err)
+		}
+		c.Slowest = n
+	}
+	if v := getenv("WASMTEST_PARALLEL"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("wasmtest: WASMTEST_PARALLEL: %w", err)
+		}
+		c.Parallel = n
+	}
+	for name, dst := range map[string]*int{"WASMTEST_SHARD_INDEX": &c.ShardIndex, "WASMTEST_SHARD_TOTAL": &c.ShardTotal} {
+		if v := getenv(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("wasmtest: %s: %w", name, err)
+			}
+			*dst = n
+		}
+	}
+	if v := getenv("WASMTEST_VERBOSITY"); v != "" {
+		verbosity, err := ParseVerbosity(v)
+		if err != nil {
+			return fmt.Errorf("wasmtest: WASMTEST_VERBOSITY: %w", err)
+		}
+		c.Verbosity = verbosity
+	}
+	if v := getenv("WASMTEST_LOG_FILE"); v != "" {
+		c.LogFile = v
+	}
+	if v := getenv("WASMTEST_LOG_MAX_SIZE"); v != "" {
+		size, err := parseSize(v)
+		if err != nil {
+			return fmt.Errorf("wasmtest: WASMTEST_LOG_MAX_SIZE: %w", err)
+		}
+		c.LogMaxSize = size
+	}
+	if v := getenv("WASMTEST_HEADFUL"); v != "" {
+		headful, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("wasmtest: WASMTEST_HEADFUL: %w", err)
+		}
+		c.Headful = headful
+	}
+	if v := getenv("WASMTEST_SKIP_INSTALL"); v != "" {
+		skip, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("wasmtest: WASMTEST_SKIP_INSTALL: %w", err)
+		}
+		c.SkipInstall = skip
+	}
+	if v := getenv("WASMTEST_INSTALL_DIR"); v != "" {
+		c.InstallDir = v
+	}
+	if v := getenv("WASMTEST_OFFLINE"); v != "" {
+		offline, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("wasmtest: WASMTEST_OFFLINE: %w", err)
+		}
+		c.Offline = offline
+	}
+	if v := getenv("WASMTEST_WASMBROWSERTEST_VERSION"); v != "" {
+		c.WasmBrowserTestVersion = v
+	}
+	if v := getenv("WASMTEST_RUNNER_UPGRADE"); v != "" {
+		if err := checkRunnerUpgrade(v); err != nil {
+			return fmt.Errorf("wasmtest: WASMTEST_RUNNER_UPGRADE: %w", err)
+		}
+		c.RunnerUpgrade = v
+	}
+	if v := getenv("WASMTEST_PATH_FIX"); v != "" {
+		fix, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("wasmtest: WASMTEST_PATH_FIX: %w", err)
+		}
+		c.PathFix = fix
+	}
+	if v := getenv("WASMTEST_GO_TOOLCHAIN"); v != "" {
+		c.GoToolchain = v
+	}
+	return nil
+}
+
+// ReadConfig parses the configuration file at path. Files ending in .toml
+// are read as TOML, anything else as YAML.
+func ReadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	parse := parseYAMLConfig
+	if strings.HasSuffix(path, ".toml") {
+		parse = parseTOMLConfig
+	}
+	values, err := parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("wasmtest: %s: %w", path, err)
+	}
+
+	cfg := &Config{Path: path}
+	for key, v := range values {
+		if v == nil {
+			continue
+		}
+		var err error
+		switch key {
+		case "dir":
+			cfg.Dir, err = configString(v)
+			if err == nil && cfg.Dir != "" && !filepath.IsAbs(cfg.Dir) {
+				cfg.Dir = filepath.Join(filepath.Dir(path), cfg.Dir)
+			}
+		case "timeout":
+			var s string
+			if s, err = configString(v); err == nil {
+				cfg.Timeout, err = time.ParseDuration(s)
+			}
+		case "package_timeout":
+			var s string
+			if s, err = configString(v); err == nil {
+				cfg.PackageTimeout, err = time.ParseDuration(s)
+			}
+		case "backend":
+			cfg.Backend, err = configString(v)
+		case "wasi_runtime":
+			cfg.WASIRuntime, err = configString(v)
+		case "docker_image":
+			cfg.DockerImage, err = configString(v)
+		case "target":
+			cfg.Target, err = configString(v)
+		case "tinygo":
+			cfg.TinyGo, err = configString(v)
+		case "browser":
+			cfg.Browser, err = configString(v)
+		case "browser_flags":
+			cfg.BrowserFlags, err = configList(v)
+		case "run":
+			cfg.Run, err = configString(v)
+		case "skip":
+			cfg.Skip, err = configString(v)
+		case "tags":
+			cfg.Tags, err = configList(v)
+		case "args":
+			cfg.Args, err = configList(v)
+		case "changed_since":
+			cfg.ChangedSince, err = configString(v)
+		case "artifacts_dir":
+			cfg.ArtifactsDir, err = configString(v)
+			if err == nil && cfg.ArtifactsDir != "" && !filepath.IsAbs(cfg.ArtifactsDir) {
+				cfg.ArtifactsDir = filepath.Join(filepath.Dir(path), cfg.ArtifactsDir)
+			}
+		case "history":
+			cfg.History, err = configString(v)
+			if err == nil && cfg.History != "" && !filepath.IsAbs(cfg.History) {
+				cfg.History = filepath.Join(filepath.Dir(path), cfg.History)
+			}
+		case "keep_binary":
+			cfg.KeepBinary, err = configString(v)
+			if err == nil && cfg.KeepBinary != "" && !filepath.IsAbs(cfg.KeepBinary) {
+				cfg.KeepBinary = filepath.Join(filepath.Dir(path), cfg.KeepBinary)
+			}
+		case "binary_cache":
+			cfg.BinaryCache, err = configString(v)
+			if err == nil && cfg.BinaryCache != "" && !filepath.IsAbs(cfg.BinaryCache) {
+				cfg.BinaryCache = filepath.Join(filepath.Dir(path), cfg.BinaryCache)
+			}
+		case "slowest":
+			var s string
+			if s, err = configString(v); err == nil {
+				cfg.Slowest, err = strconv.Atoi(s)
+			}
+		case "parallel":
+			var s string
+			if s, err = configString(v); err == nil {
+				cfg.Parallel, err = strconv.Atoi(s)
+			}
+		case "verbosity":
+			var s string
+			if s, err = configString(v); err == nil {
+				cfg.Verbosity, err = ParseVerbosity(s)
+			}
+		case "log_file":
+			cfg.LogFile, err = configString(v)
+			if err == nil && cfg.LogFile != "" && !filepath.IsAbs(cfg.LogFile) {
+				cfg.LogFile = filepath.Join(filepath.Dir(path), cfg.LogFile)
+			}
+		case "log_max_size":
+			var s string
+			if s, err = configString(v); err == nil {
+				cfg.LogMaxSize, err = parseSize(s)
+			}
+		case "log_backups":
+			var s string
+			if s, err = configString(v); err == nil {
+				cfg.LogBackups, err = strconv.Atoi(s)
+			}
+		case "headful":
+			var s string
+			if s, err = configString(v); err == nil {
+				cfg.Headful, err = strconv.ParseBool(s)
+			}
+		case "skip_install":
+			var s string
+			if s, err = configString(v); err == nil {
+				cfg.SkipInstall, err = strconv.ParseBool(s)
+			}
+		case "install_dir":
+			cfg.InstallDir, err = configString(v)
+			if err == nil && cfg.InstallDir != "" && !filepath.IsAbs(cfg.InstallDir) {
+				cfg.InstallDir = filepath.Join(filepath.Dir(path), cfg.InstallDir)
+			}
+		case "offline":
+			var s string
+			if s, err = configString(v); err == nil {
+				cfg.Offline, err = strconv.ParseBool(s)
+			}
+		case "wasmbrowsertest_version":
+			cfg.WasmBrowserTestVersion, err = configString(v)
+		case "runner_upgrade":
+			if cfg.RunnerUpgrade, err = configString(v); err == nil {
+				err = checkRunnerUpgrade(cfg.RunnerUpgrade)
+			}
+		case "path_fix":
+			var s string
+			if s, err = configString(v); err == nil {
+				cfg.PathFix, err = strconv.ParseBool(s)
+			}
+		case "go_toolchain":
+			cfg.GoToolchain, err = configString(v)
+		case "env":
+			env, ok := v.(map[string]string)
+			if !ok {
+				err = errors.New("must be a mapping of variable names to values")
+			}
+			cfg.Env = env
+		case "install_env":
+			env, ok := v.(map[string]string)
+			if !ok {
+				err = errors.New("must be a mapping of variable names to values")
+			}
+			cfg.InstallEnv = env
+		default:
+			err = errors.New("unknown setting")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("wasmtest: %s: %s: %w", path, key, err)
+		}
+	}
+	return cfg, nil
+}
+
+// Options returns the options applying the settings of c to New.
+func (c *Config) Options() []Option {
+	var opts []Option
+	if c.Dir != "" {
+		opts = append(opts, WithTestDir(c.Dir))
+	}
+	if c.Timeout > 0 {
+		opts = append(opts, WithTimeout(c.Timeout))
+	}
+	if c.Backend != "" {
+		opts = append(opts, WithBackend(c.Backend))
+	}
+	if c.WASIRuntime != "" {
+		opts = append(opts, WithWASIRuntime(c.WASIRuntime))
+	}
+	if c.DockerImage != "" {
+		opts = append(opts, WithDockerImage(c.DockerImage, ""))
+	}
+	if c.Target != "" {
+		opts = append(opts, WithTarget(c.Target))
+	}
+	if c.TinyGo != "" {
+		opts = append(opts, WithTinyGo(c.TinyGo))
+	}
+	if c.KeepBinary != "" {
+		opts = append(opts, WithKeepBinary(c.KeepBinary))
+	}
+	if c.BinaryCache != "" {
+		opts = append(opts, WithBinaryCache(c.BinaryCache))
+	}
+	if c.Browser != "" {
+		opts = append(opts, WithBrowser(c.Browser))
+	}
+	if len(c.BrowserFlags) > 0 {
+		opts = append(opts, WithBrowserFlags(c.BrowserFlags...))
+	}
+	if c.Run != "" {
+		opts = append(opts, WithRun(c.Run))
+	}
+	if c.Skip != "" {
+		opts = append(opts, WithSkip(c.Skip))
+	}
+	if len(c.Tags) > 0 {
+		opts = append(opts, WithTags(c.Tags...))
+	}
+	if c.Verbosity != Normal {
+		opts = append(opts, WithVerbosity(c.Verbosity))
+	}
+	if c.LogFile != "" {
+		opts = append(opts, WithLogFile(c.LogFile, c.LogMaxSize, c.LogBackups))
+	}
+	if c.Headful {
+		opts = append(opts, WithHeadful())
+	}
+	if c.SkipInstall {
+		opts = append(opts, WithInstallDisabled())
+	}
+	if len(c.InstallEnv) > 0 {
+		env := make([]string, 0, len(c.InstallEnv))
+		for k, v := range c.InstallEnv {
+			env = append(env, k+"="+v)
+		}
+		opts = append(opts, WithInstallEnv(env...))
+	}
+	if c.InstallDir != "" {
+		opts = append(opts, WithInstallDir(c.InstallDir))
+	}
+	if c.Offline {
+		opts = append(opts, WithNoInstall())
+	}
+	if c.WasmBrowserTestVersion != "" {
+		opts = append(opts, WithWasmBrowserTestVersion(c.WasmBrowserTestVersion))
+	}
+	if c.RunnerUpgrade != "" {
+		opts = append(opts, WithRunnerUpgrade(c.RunnerUpgrade))
+	}
+	if c.PathFix {
+		opts = append(opts, WithPathFix())
+	}
+	if c.GoToolchain != "" {
+		opts = append(opts, WithGoToolchain(c.GoToolchain))
+	}
+	return opts
+}
+
+// environ returns c.Env as KEY=VALUE entries.
+func (c *Config) environ() []string {
+	env := make([]string, 0, len(c.Env))
+	for k, v := range c.Env {
+		env = append(env, k+"="+v)
+	}
+	return env
+}
+
+// parseSize parses a byte size such as "512", "64KB", "10MB" or "1GB".
+func parseSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		scale  int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}}
+	s = strings.ToUpper(strings.TrimSpace(s))
+	scale := int64(1)
+	for _, u := range units {
+		if rest, ok := strings.CutSuffix(s, u.suffix); ok {
+			s, scale = strings.TrimSpace(rest), u.scale
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * scale, nil
+}
+
+func configString(v any) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", errors.New("must be a single value")
+	}
+	return s, nil
+}
+
+func configList(v any) ([]string, error) {
+	switch v := v.(type) {
+	case []string:
+		return v, nil
+	case string:
+		return strings.Fields(v), nil
+	}
+	return nil, errors.New("must be a list")
+}
+
+// parseYAMLConfig parses top level "key: value" pairs, where a value may
+// be a scalar, an inline [a, b] list, a block of "- item" lines or a block
+// of indented "key: value" pairs.
+func parseYAMLConfig(data string) (map[string]any, error) {
+	values := map[string]any{}
+	var block string
+	for i, line := range strings.Split(data, "\n") {
+		line = stripComment(strings.TrimRight(line, " \t\r"))
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		indented := line[0] == ' ' || line[0] == '\t'
+		switch {
+		case indented && block != "" && strings.HasPrefix(trimmed, "- "):
+			list, _ := values[block].([]string)
+			values[block] = append(list, unquote(strings.TrimSpace(trimmed[2:])))
+		case indented && block != "":
+			key, value, ok := strings.Cut(trimmed, ":")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected key: value", i+1)
+			}
+			m, ok := values[block].(map[string]string)
+			if !ok {
+				m = map[string]string{}
+			}
+			m[strings.TrimSpace(key)] = unquote(strings.TrimSpace(value))
+			values[block] = m
+		case indented:
+			return nil, fmt.Errorf("line %d: unexpected indentation", i+1)
+		default:
+			key, value, ok := strings.Cut(trimmed, ":")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected key: value", i+1)
+			}
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			block = ""
+			switch {
+			case value == "":
+				block = key
+				values[key] = nil
+			case strings.HasPrefix(value, "["):
+				list, err := parseInlineList(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", i+1, err)
+				}
+				values[key] = list
+			default:
+				values[key] = unquote(value)
+			}
+		}
+	}
+	return values, nil
+}
+
+// parseTOMLConfig parses top level "key = value" pairs, where a value is a
+// string or an array of strings, and [table] sections of string values.
+func parseTOMLConfig(data string) (map[string]any, error) {
+	values := map[string]any{}
+	var table string
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(stripComment(line))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") && !strings.Contains(line, "=") {
+			table = strings.TrimSpace(line[1 : len(line)-1])
+			values[table] = map[string]string{}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", i+1)
+		}
+		key, value = unquote(strings.TrimSpace(key)), strings.TrimSpace(value)
+		if table != "" {
+			values[table].(map[string]string)[key] = unquote(value)
+			continue
+		}
+		if strings.HasPrefix(value, "[") {
+			list, err := parseInlineList(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			values[key] = list
+			continue
+		}
+		values[key] = unquote(value)
+	}
+	return values, nil
+}
+
+// parseInlineList parses a one line [a, "b"] list.
+func parseInlineList(value string) ([]string, error) {
+	if !strings.HasSuffix(value, "]") {
+		return nil, errors.New("lists must be written on a single line")
+	}
+	list := []string{}
+	for _, item := range strings.Split(value[1:len(value)-1], ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, unquote(item))
+		}
+	}
+	return list, nil
+}
+
+// stripComment removes a trailing # comment outside quotes.
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unquote removes the quotes around a double or single quoted value.
+func unquote(value string) string {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		if s, err := strconv.Unquote(value); err == nil {
+			return s
+		}
+	}
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
@@ -0,0 +1,235 @@
+package wasmtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadConfig(t *testing.T) {
+	dir := t.TempDir()
+	yaml := `# shared settings
+dir: wasm_tests
+timeout: 5m
+browser: "/usr/bin/chromium" # pinned
+args:
+  - -count=1
+  - -short
+env:
+  WASM_HEADLESS: "off"
+  GOFLAGS: -mod=mod
+install_env:
+  GOPROXY: https://proxy.corp
+`
+	toml := `dir = "wasm_tests"
+timeout = "5m"
+browser = "/usr/bin/chromium"
+args = ["-count=1", "-short"]
+
+[env]
+WASM_HEADLESS = "off"
+GOFLAGS = "-mod=mod"
+
+[install_env]
+GOPROXY = "https://proxy.corp"
+`
+	for name, content := range map[string]string{"wasmtest.yaml": yaml, ".wasmtest.toml": toml} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := ReadConfig(path)
+		if err != nil {
+			t.Fatalf("ReadConfig(%s): %v", name, err)
+		}
+		if cfg.Dir != filepath.Join(dir, "wasm_tests") || cfg.Timeout != 5*time.Minute || cfg.Browser != "/usr/bin/chromium" {
+			t.Errorf("%s: unexpected config %+v", name, cfg)
+		}
+		if !slices.Equal(cfg.Args, []string{"-count=1", "-short"}) {
+			t.Errorf("%s: Args = %q", name, cfg.Args)
+		}
+		if cfg.Env["WASM_HEADLESS"] != "off" || cfg.Env["GOFLAGS"] != "-mod=mod" {
+			t.Errorf("%s: Env = %v", name, cfg.Env)
+		}
+		if cfg.InstallEnv["GOPROXY"] != "https://proxy.corp" || len(cfg.InstallEnv) != 1 {
+			t.Errorf("%s: InstallEnv = %v", name, cfg.InstallEnv)
+		}
+	}
+
+	bad := filepath.Join(dir, "bad.yaml")
+	if err := os.WriteFile(bad, []byte("timeout: soon\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadConfig(bad); err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("ReadConfig accepted an invalid timeout: %v", err)
+	}
+	if err := os.WriteFile(bad, []byte("retries: 3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadConfig(bad); err == nil {
+		t.Error("ReadConfig accepted an unknown setting")
+	}
+	if err := os.WriteFile(bad, []byte("runner_upgrade: sometimes\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadConfig(bad); err == nil || !strings.Contains(err.Error(), "runner upgrade policy") {
+		t.Errorf("ReadConfig accepted an unknown runner upgrade policy: %v", err)
+	}
+}
+
+func TestFindConfig(t *testing.T) {
+	root := writeModule(t, map[string]string{"wasmtest.yaml": "timeout: 1m\n"})
+	sub := filepath.Join(root, "pkg", "sub")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if path, err := FindConfig(sub); err != nil || path != filepath.Join(root, "wasmtest.yaml") {
+		t.Errorf("FindConfig() = %q, %v", path, err)
+	}
+
+	// The search stops at the module root.
+	nested := filepath.Join(sub, "go.mod")
+	if err := os.WriteFile(nested, []byte("module example.com/nested\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if path, err := FindConfig(sub); err != nil || path != "" {
+		t.Errorf("FindConfig() crossed the module root: %q, %v", path, err)
+	}
+	if cfg, err := LoadConfig(sub); err != nil || cfg.Path != "" {
+		t.Errorf("LoadConfig() without a file = %+v, %v", cfg, err)
+	}
+}
+
+func TestRunTestsUsesConfig(t *testing.T) {
+	node := nodeExec(t)
+	root := writeModule(t, map[string]string{
+		"wasmtest.yaml": "dir: tests\nargs: [-run, TestSelected]\nenv:\n  WANT_VALUE: from-config\n",
+	})
+	tests := filepath.Join(root, "tests")
+	if err := os.Mkdir(tests, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	src := "//go:build js && wasm\n\npackage tests\n\nimport (\n\t\"os\"\n\t\"testing\"\n)\n\n" +
+		"func TestSelected(t *testing.T) {\n\tif v := os.Getenv(\"WANT_VALUE\"); v != \"from-config\" {\n\t\tt.Fatalf(\"WANT_VALUE = %q\", v)\n\t}\n}\n\n" +
+		"func TestOther(t *testing.T) {}\n"
+	if err := os.WriteFile(filepath.Join(tests, "cfg_test.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(root)
+
+	res, err := RunTestsResult(func(...any) {}, ExecOptions{Args: []string{"-exec", node}}, WithInstallDisabled())
+	if err != nil {
+		t.Fatalf("RunTestsResult failed: %v", err)
+	}
+	if !slices.Equal(res.PassedTests, []string{"TestSelected"}) || res.Dir != tests {
+		t.Errorf("config not applied: dir %s, passed %q", res.Dir, res.PassedTests)
+	}
+}
+
+func TestRunTestsUsesConfigOptions(t *testing.T) {
+	node := nodeExec(t)
+	src := "//go:build js && wasm\n\npackage p\n\nimport (\n\t\"os\"\n\t\"testing\"\n)\n\n" +
+		"func TestHeadful(t *testing.T) {\n\tif v := os.Getenv(\"WASM_HEADLESS\"); v != \"off\" {\n\t\tt.Fatalf(\"WASM_HEADLESS = %q\", v)\n\t}\n}\n"
+	root := writeModule(t, map[string]string{
+		"wasmtest.yaml": "dir: .\nkeep_binary: bin\nheadful: true\nskip_install: true\n",
+		"h_test.go":     src,
+	})
+	t.Chdir(root)
+
+	var logs []string
+	res, err := RunTestsResult(func(a ...any) { logs = append(logs, fmt.Sprint(a...)) }, ExecOptions{Args: []string{"-exec", node}})
+	if err != nil {
+		t.Fatalf("RunTestsResult failed: %v\n%s", err, strings.Join(logs, "\n"))
+	}
+	if !slices.Equal(res.PassedTests, []string{"TestHeadful"}) {
+		t.Errorf("headful not applied: passed %q\n%s", res.PassedTests, strings.Join(logs, "\n"))
+	}
+	if _, err := os.Stat(filepath.Join(root, "bin", "example.com_tmp", "test.wasm")); err != nil {
+		t.Errorf("keep_binary not applied: %v", err)
+	}
+}
+
+func TestConfigEnvOverrides(t *testing.T) {
+	cfg := &Config{Dir: "from-file", Timeout: time.Minute, Args: []string{"-short"}}
+	env := map[string]string{
+		"WASMTEST_DIR":                     "from-env",
+		"WASMTEST_TIMEOUT":                 "90s",
+		"WASMTEST_BROWSER":                 "firefox",
+		"WASMTEST_RUN":                     "TestDOM$",
+		"WASMTEST_ARGS":                    "-count=1 -v",
+		"WASMTEST_SKIP_INSTALL":            "true",
+		"WASMTEST_TAGS":                    "integration,dev",
+		"WASMTEST_SKIP":                    "TestFlaky",
+		"WASMTEST_ARTIFACTS_DIR":           "out",
+		"WASMTEST_HISTORY":                 "runs",
+		"WASMTEST_KEEP_BINARY":             "bin",
+		"WASMTEST_BINARY_CACHE":            "cache",
+		"WASMTEST_SLOWEST":                 "3",
+		"WASMTEST_PARALLEL":                "4",
+		"WASMTEST_SHARD_INDEX":             "1",
+		"WASMTEST_SHARD_TOTAL":             "3",
+		"WASMTEST_VERBOSITY":               "quiet",
+		"WASMTEST_LOG_FILE":                "run.log",
+		"WASMTEST_LOG_MAX_SIZE":            "64KB",
+		"WASMTEST_HEADFUL":                 "1",
+		"WASMTEST_BROWSER_FLAGS":           "--lang=es --enable-unsafe-webgpu",
+		"WASMTEST_BACKEND":                 "node",
+		"WASMTEST_WASI_RUNTIME":            "/opt/wasmtime",
+		"WASMTEST_TINYGO":                  "wasip1",
+		"WASMTEST_TARGET":                  "wasip1/wasm",
+		"WASMTEST_DOCKER_IMAGE":            "example/chrome",
+		"WASMTEST_WASMBROWSERTEST_VERSION": "v0.8.0",
+		"WASMTEST_OFFLINE":                 "true",
+		"WASMTEST_INSTALL_DIR":             "/opt/wasm/bin",
+		"WASMTEST_RUNNER_UPGRADE":          "auto",
+		"WASMTEST_PATH_FIX":                "1",
+		"WASMTEST_GO_TOOLCHAIN":            "/opt/go1.25rc1",
+	}
+	if err := cfg.applyEnv(func(k string) string { return env[k] }); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Dir != "from-env" || cfg.Timeout != 90*time.Second || cfg.Browser != "firefox" || cfg.Run != "TestDOM$" || !cfg.SkipInstall || !cfg.Headful || cfg.Backend != "node" || cfg.WASIRuntime != "/opt/wasmtime" || cfg.TinyGo != "wasip1" || cfg.Target != TargetWASIP1 || cfg.DockerImage != "example/chrome" || cfg.WasmBrowserTestVersion != "v0.8.0" || !cfg.Offline || cfg.InstallDir != "/opt/wasm/bin" || cfg.RunnerUpgrade != RunnerUpgradeAuto || !cfg.PathFix || cfg.GoToolchain != "/opt/go1.25rc1" {
+		t.Errorf("env not applied: %+v", cfg)
+	}
+	if !slices.Equal(cfg.Args, []string{"-count=1", "-v"}) {
+		t.Errorf("Args = %q", cfg.Args)
+	}
+	if !slices.Equal(cfg.BrowserFlags, []string{"--lang=es", "--enable-unsafe-webgpu"}) {
+		t.Errorf("BrowserFlags = %q", cfg.BrowserFlags)
+	}
+	if !slices.Equal(cfg.Tags, []string{"integration", "dev"}) || cfg.Skip != "TestFlaky" || cfg.ArtifactsDir != "out" || cfg.History != "runs" || cfg.KeepBinary != "bin" || cfg.BinaryCache != "cache" || cfg.Slowest != 3 || cfg.Parallel != 4 || cfg.ShardIndex != 1 || cfg.ShardTotal != 3 || cfg.Verbosity != Quiet || cfg.LogFile != "run.log" || cfg.LogMaxSize != 64<<10 {
+		t.Errorf("Tags = %q, Skip = %q, ArtifactsDir = %q, Slowest = %d, Parallel = %d", cfg.Tags, cfg.Skip, cfg.ArtifactsDir, cfg.Slowest, cfg.Parallel)
+	}
+
+	for in, want := range map[string]int64{"512": 512, "10MB": 10 << 20, "1 gb": 1 << 30, "3B": 3} {
+		if got, err := parseSize(in); err != nil || got != want {
+			t.Errorf("parseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	if _, err := parseSize("lots"); err == nil {
+		t.Error("parseSize accepted an invalid size")
+	}
+
+	env = map[string]string{"WASMTEST_TIMEOUT": "later"}
+	if err := cfg.applyEnv(func(k string) string { return env[k] }); err == nil {
+		t.Error("invalid WASMTEST_TIMEOUT accepted")
+	}
+	env = map[string]string{"WASMTEST_RUNNER_UPGRADE": "always"}
+	if err := cfg.applyEnv(func(k string) string { return env[k] }); err == nil {
+		t.Error("invalid WASMTEST_RUNNER_UPGRADE accepted")
+	}
+
+	// The variables are defaults for New too; options still win.
+	t.Setenv("WASMTEST_TIMEOUT", "2m")
+	t.Setenv("WASMTEST_SKIP_INSTALL", "1")
+	if w := New(); w.timeout != 2*time.Minute || !w.installDisabled {
+		t.Errorf("New ignored the environment: timeout %v, install disabled %v", w.timeout, w.installDisabled)
+	}
+	if w := New(WithTimeout(time.Minute)); w.timeout != time.Minute {
+		t.Errorf("WithTimeout didn't override WASMTEST_TIMEOUT: %v", w.timeout)
+	}
+}
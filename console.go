@@ -0,0 +1,41 @@
+package wasmtest
+
+import "strings"
+
+// ConsoleMessage is a call to the browser console (console.log,
+// console.error, ...) made by the tests through syscall/js or by the page,
+// reported apart from the output of the test binary as ("console",
+// ConsoleMessage) by RunBundle and its WebDriver, Playwright and remote
+// browser backends. wasmbrowsertest mixes the console with the test output,
+// so go test runs don't report them.
+type ConsoleMessage struct {
+	// Level is the console method called: "log", "info", "warn", "error"
+	// or "debug".
+	Level string `json:"level"`
+	// Text is the message, with the arguments joined by spaces.
+	Text string `json:"text"`
+	// Test is the test running when the message was logged, if known.
+	Test string `json:"test,omitempty"`
+}
+
+// String renders the message for log output, e.g. "[error] boom".
+func (m ConsoleMessage) String() string {
+	return "[" + m.Level + "] " + m.Text
+}
+
+// runningTest follows the tests of the go test -v output line to tell the
+// test running after it, given the one running before.
+func runningTest(running, line string) string {
+	fields := strings.Fields(line)
+	switch {
+	case len(fields) >= 3 && fields[0] == "===" && (fields[1] == "RUN" || fields[1] == "CONT" || fields[1] == "NAME"):
+		return fields[2]
+	case len(fields) >= 3 && fields[0] == "---" && strings.HasSuffix(fields[1], ":") && fields[2] == running:
+		// The test finished: its parent, if any, runs again.
+		if i := strings.LastIndex(running, "/"); i >= 0 {
+			return running[:i]
+		}
+		return ""
+	}
+	return running
+}
@@ -0,0 +1,60 @@
+package wasmtest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunningTest(t *testing.T) {
+	running := ""
+	for _, step := range []struct{ line, want string }{
+		{"=== RUN   TestA", "TestA"},
+		{"=== RUN   TestA/sub", "TestA/sub"},
+		{"    a_test.go:5: log", "TestA/sub"},
+		{"    --- PASS: TestA/sub (0.00s)", "TestA"},
+		{"--- PASS: TestA (0.00s)", ""},
+		{"=== CONT  TestB", "TestB"},
+		{"--- FAIL: TestC (0.00s)", "TestB"},
+	} {
+		if running = runningTest(running, step.line); running != step.want {
+			t.Errorf("after %q running = %q, want %q", step.line, running, step.want)
+		}
+	}
+}
+
+func TestRunBundleConsole(t *testing.T) {
+	browser := nodeBrowser(t)
+	dir := writeModule(t, map[string]string{"c_test.go": "//go:build js && wasm\n\npackage p\n\n" +
+		"import (\n\t\"syscall/js\"\n\t\"testing\"\n)\n\n" +
+		"func TestConsole(t *testing.T) {\n\tjs.Global().Get(\"console\").Call(\"error\", \"boom\", 42)\n\tt.Log(\"go output\")\n}\n"})
+	w := New(WithInstallDisabled())
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	out := t.TempDir()
+	if _, err := w.Bundle(ctx, dir, out); err != nil {
+		t.Fatalf("Bundle failed: %v", err)
+	}
+
+	var consoles []ProgressEvent
+	var stdout []string
+	progress := ProgressFunc(func(ev ProgressEvent) {
+		switch ev.Kind {
+		case EventConsole:
+			consoles = append(consoles, ev)
+		case EventStdout:
+			stdout = append(stdout, ev.Message)
+		}
+	})
+	if err := w.RunBundle(ctx, out, RunBundleOptions{Browser: browser}, progress); err != nil {
+		t.Fatalf("RunBundle failed: %v", err)
+	}
+	want := ConsoleMessage{Level: "error", Text: "boom 42", Test: "TestConsole"}
+	if len(consoles) != 1 || consoles[0].Data != want || consoles[0].TestName != "TestConsole" {
+		t.Errorf("console events = %+v, want %+v", consoles, want)
+	}
+	if joined := strings.Join(stdout, "\n"); strings.Contains(joined, "boom") || !strings.Contains(joined, "go output") {
+		t.Errorf("stdout:\n%s", joined)
+	}
+}
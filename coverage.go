@@ -0,0 +1,154 @@
+package wasmtest
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CoverProfile is a RunTests argument naming the file where the Go coverage
+// profile of the run is written. Each package runs with -coverprofile and
+// the profiles of all of them are merged into one, which go tool cover
+// reads. The share of statements covered is logged at the end of the run
+// and stored in RunResult.Coverage. Pass -coverpkg in ExecOptions.Args to
+// measure the packages the tests exercise rather than the test packages.
+type CoverProfile string
+
+// coverageMissing is reported for a package whose run wrote no coverage
+// profile.
+var coverageMissing = Warning{
+	Code: "coverage-missing",
+	Hint: "the builtin backend and TinyGo runs have no file system to write the profile to; use the browser or node backend",
+}
+
+// coverProfile returns the path of a new file in dir for the coverage
+// profile of the package in pkgDir.
+func coverProfile(dir, pkgDir string) (string, error) {
+	f, err := os.CreateTemp(dir, artifactName(pkgDir)+"-*.out")
+	if err != nil {
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+// coverBlock is the number of statements of a block of a coverage profile
+// and the number of times it ran.
+type coverBlock struct {
+	stmts, count int
+}
+
+// mergeCoverProfiles merges the coverage profiles srcs, skipping the empty
+// ones, into dst and returns the percentage of statements covered. Blocks
+// measured by several packages, as with -coverpkg, are covered when any of
+// them ran them; their counts add up in the count and atomic modes.
+func mergeCoverProfiles(dst string, srcs []string) (float64, error) {
+	mode := ""
+	blocks := map[string]coverBlock{}
+	for _, src := range srcs {
+		f, err := os.Open(src)
+		if err != nil {
+			return 0, err
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if m, ok := strings.CutPrefix(line, "mode: "); ok {
+				if mode != "" && m != mode {
+					f.Close()
+					return 0, fmt.Errorf("wasmtest: coverage profile %s has mode %s, not %s", src, m, mode)
+				}
+				mode = m
+				continue
+			}
+			fields := strings.Fields(line)
+			if len(fields) != 3 {
+				continue
+			}
+			stmts, err1 := strconv.Atoi(fields[1])
+			count, err2 := strconv.Atoi(fields[2])
+			if err1 != nil || err2 != nil {
+				f.Close()
+				return 0, fmt.Errorf("wasmtest: malformed coverage profile line %q in %s", line, src)
+			}
+			b := blocks[fields[0]]
+			b.stmts = stmts
+			if mode == "set" {
+				b.count = max(b.count, count)
+			} else {
+				b.count += count
+			}
+			blocks[fields[0]] = b
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return 0, err
+		}
+	}
+	if mode == "" {
+		return 0, fmt.Errorf("wasmtest: no coverage profile was written")
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "mode: %s\n", mode)
+	var total, covered int
+	keys := make([]string, 0, len(blocks))
+	for key := range blocks {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		b := blocks[key]
+		fmt.Fprintf(&out, "%s %d %d\n", key, b.stmts, b.count)
+		total += b.stmts
+		if b.count > 0 {
+			covered += b.stmts
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(dst, []byte(out.String()), 0o644); err != nil {
+		return 0, err
+	}
+	if total == 0 {
+		return 0, nil
+	}
+	return 100 * float64(covered) / float64(total), nil
+}
+
+// coverageLine returns the percentage of a "coverage: 75.0% of statements"
+// line printed by go test.
+func coverageLine(line string) (float64, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), "coverage: ")
+	if !ok {
+		return 0, false
+	}
+	pct, _, ok := strings.Cut(rest, "% of statements")
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(pct, 64)
+	return v, err == nil
+}
+
+// mergeCoverage merges the coverage profiles of the packages of result
+// into profile, storing the total in result.Coverage and logging it.
+func (s runSettings) mergeCoverage(result *RunResult, profile string) {
+	srcs, err := filepath.Glob(filepath.Join(s.coverDir, "*.out"))
+	if err != nil {
+		s.logger("[WASMTEST]", "error", "merging the coverage profiles:", err)
+		return
+	}
+	pct, err := mergeCoverProfiles(profile, srcs)
+	if err != nil {
+		s.logger("[WASMTEST]", "error", "merging the coverage profiles:", err)
+		return
+	}
+	result.Coverage = pct
+	s.logger("[WASMTEST]", "info", fmt.Sprintf("📊 coverage: %.1f%% of statements, profile written to %s", pct, profile))
+}
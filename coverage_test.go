@@ -0,0 +1,97 @@
+package wasmtest
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMergeCoverProfiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	a := write("a.out", "mode: set\nx/a.go:1.1,2.2 2 1\nx/a.go:3.1,4.2 1 0\nx/b.go:1.1,2.2 3 0\n")
+	b := write("b.out", "mode: set\nx/b.go:1.1,2.2 3 1\n")
+	empty := write("empty.out", "")
+
+	dst := filepath.Join(dir, "merged", "cover.out")
+	pct, err := mergeCoverProfiles(dst, []string{a, empty, b})
+	if err != nil {
+		t.Fatalf("mergeCoverProfiles failed: %v", err)
+	}
+	if math.Abs(pct-100*5.0/6) > 1e-9 {
+		t.Errorf("coverage = %v, want %v", pct, 100*5.0/6)
+	}
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "mode: set\nx/a.go:1.1,2.2 2 1\nx/a.go:3.1,4.2 1 0\nx/b.go:1.1,2.2 3 1\n"
+	if string(data) != want {
+		t.Errorf("merged profile:\n%s\nwant:\n%s", data, want)
+	}
+
+	if _, err := mergeCoverProfiles(dst, []string{empty}); err == nil {
+		t.Error("mergeCoverProfiles accepted no profile")
+	}
+	count := write("count.out", "mode: count\nx/a.go:1.1,2.2 2 1\n")
+	if _, err := mergeCoverProfiles(dst, []string{a, count}); err == nil {
+		t.Error("mergeCoverProfiles accepted mixed modes")
+	}
+}
+
+func TestCoverageLine(t *testing.T) {
+	for line, want := range map[string]float64{
+		"coverage: 75.0% of statements":                         75,
+		"\tcoverage: 12.5% of statements in ./...":              12.5,
+		"ok  	example.com/p	0.1s	coverage: 75.0% of statements": -1,
+		"coverage: [no statements]":                             -1,
+	} {
+		got, ok := coverageLine(line)
+		if (want < 0) == ok || (ok && got != want) {
+			t.Errorf("coverageLine(%q) = %v, %v", line, got, ok)
+		}
+	}
+}
+
+func TestRunTestsCoverProfile(t *testing.T) {
+	node := nodeExec(t)
+	root := writeModule(t, map[string]string{
+		"a/a.go":      "package a\n\nfunc Abs(x int) int {\n\tif x < 0 {\n\t\treturn -x\n\t}\n\treturn x\n}\n",
+		"a/a_test.go": "//go:build js && wasm\n\npackage a\n\nimport \"testing\"\n\nfunc TestAbs(t *testing.T) {\n\tif Abs(2) != 2 {\n\t\tt.Fatal(\"Abs(2) != 2\")\n\t}\n}\n",
+		"b/b.go":      "package b\n\nfunc Double(x int) int { return 2 * x }\n",
+		"b/b_test.go": "//go:build js && wasm\n\npackage b\n\nimport \"testing\"\n\nfunc TestDouble(t *testing.T) {\n\tif Double(2) != 4 {\n\t\tt.Fatal(\"Double(2) != 4\")\n\t}\n}\n",
+	})
+	profile := filepath.Join(t.TempDir(), "cover.out")
+	var log []string
+	logger := func(args ...any) { log = append(log, fmt.Sprintln(args...)) }
+	res, err := RunTestsResult(root+"/...", logger, CoverProfile(profile), ExecOptions{Args: []string{"-exec", node}}, WithInstallDisabled())
+	if err != nil {
+		t.Fatalf("RunTestsResult failed: %v\n%s", err, strings.Join(log, "\n"))
+	}
+	if len(res.Packages) != 2 {
+		t.Fatalf("%d packages ran, want 2", len(res.Packages))
+	}
+	if a, b := res.Packages[0].Coverage, res.Packages[1].Coverage; a != 66.7 || b != 100 {
+		t.Errorf("package coverage = %v, %v, want 66.7, 100", a, b)
+	}
+	// 2 of the 3 statements of Abs, and Double.
+	if res.Coverage != 75 {
+		t.Errorf("Coverage = %v, want 75", res.Coverage)
+	}
+	data, err := os.ReadFile(profile)
+	if err != nil || !strings.Contains(string(data), "example.com/tmp/a/a.go") || !strings.Contains(string(data), "example.com/tmp/b/b.go") {
+		t.Errorf("merged profile %s (%v):\n%s", profile, err, data)
+	}
+	if !strings.Contains(strings.Join(log, "\n"), "coverage: 75.0% of statements") {
+		t.Errorf("total coverage not logged:\n%s", strings.Join(log, "\n"))
+	}
+}
@@ -0,0 +1,261 @@
+package wasmtest
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WithCPUProfile collects a CPU profile of the browser while the tests run
+// and writes it in pprof format to dir as <package>.cpu.pprof, to read with
+// go tool pprof. An empty dir is the ArtifactsDir of RunTests, or the
+// current directory. go test runs rely on wasmbrowsertest, which profiles
+// the page through the DevTools protocol when given -test.cpuprofile;
+// RunBundle needs the Playwright Chromium engine (see WithPlaywright).
+// Other runs report a "cpuprofile-unsupported" warning.
+func WithCPUProfile(dir string) Option {
+	return func(w *Wasmtest) {
+		w.cpuProfile = true
+		w.cpuProfileDir = dir
+	}
+}
+
+// cpuProfileUnsupported is reported when WithCPUProfile is set for a run
+// that can't be profiled.
+var cpuProfileUnsupported = Warning{
+	Code:    "cpuprofile-unsupported",
+	Message: "CPU profiles are collected by wasmbrowsertest and the Playwright Chromium engine only; this run is not profiled",
+	Hint:    "run go test with wasmbrowsertest, or the bundle with WithPlaywright(PlaywrightOptions{Engine: PlaywrightChromium})",
+}
+
+// cpuProfilePath returns the absolute path of the CPU profile of the tests
+// called name, a package or directory.
+func (w *Wasmtest) cpuProfilePath(name string) (string, error) {
+	return filepath.Abs(filepath.Join(w.cpuProfileDir, artifactName(name)+".cpu.pprof"))
+}
+
+// dirProfileName names the CPU profile of the tests of dir after its path
+// relative to the working directory, or its base name when outside.
+func dirProfileName(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return dir
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, abs); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return filepath.Base(abs)
+}
+
+// reportCPUProfile reports the CPU profile written at path, or its absence.
+func reportCPUProfile(path string, progress func(msgs ...any)) {
+	if _, err := os.Stat(path); err != nil {
+		progress("warning", Warning{Code: "cpuprofile-missing", Message: "no CPU profile was written by the browser"})
+		return
+	}
+	progress("info", "CPU profile saved to "+path+"; inspect it with go tool pprof")
+}
+
+// saveCPUProfile converts the DevTools profile of the tests of pkg, written
+// at src, into their pprof profile.
+func (w *Wasmtest) saveCPUProfile(src, pkg string, progress func(msgs ...any)) {
+	dst, err := w.cpuProfilePath(pkg)
+	if err == nil {
+		err = convertCPUProfile(src, dst)
+	}
+	if err != nil {
+		progress("warning", Warning{Code: "cpuprofile-missing", Message: fmt.Sprintf("failed to save the CPU profile: %v", err)})
+		return
+	}
+	reportCPUProfile(dst, progress)
+}
+
+// cdpProfile is a profile of the DevTools protocol Profiler domain.
+type cdpProfile struct {
+	Nodes []struct {
+		ID        uint64 `json:"id"`
+		CallFrame struct {
+			FunctionName string `json:"functionName"`
+			URL          string `json:"url"`
+			LineNumber   int64  `json:"lineNumber"`
+		} `json:"callFrame"`
+		Children []uint64 `json:"children"`
+	} `json:"nodes"`
+	// StartTime and EndTime are in microseconds, as the TimeDeltas before
+	// each sample.
+	StartTime  int64    `json:"startTime"`
+	EndTime    int64    `json:"endTime"`
+	Samples    []uint64 `json:"samples"`
+	TimeDeltas []int64  `json:"timeDeltas"`
+}
+
+// convertCPUProfile converts the JSON DevTools profile at src into a pprof
+// profile at dst.
+func convertCPUProfile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	var p cdpProfile
+	if err := json.Unmarshal(data, &p); err != nil {
+		return fmt.Errorf("wasmtest: invalid DevTools profile: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	err = writePprof(f, &p)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writePprof writes p as a gzipped pprof protocol buffer: one location per
+// profile node, with the samples counted and timed in nanoseconds.
+func writePprof(out io.Writer, p *cdpProfile) error {
+	strs := map[string]int64{"": 0}
+	table := []string{""}
+	str := func(s string) uint64 {
+		i, ok := strs[s]
+		if !ok {
+			i = int64(len(table))
+			strs[s] = i
+			table = append(table, s)
+		}
+		return uint64(i)
+	}
+	valueType := func(typ, unit string) []byte {
+		var b protoBuffer
+		b.uint64(1, str(typ))
+		b.uint64(2, str(unit))
+		return b.data
+	}
+
+	var prof protoBuffer
+	prof.bytes(1, valueType("samples", "count"))
+	prof.bytes(1, valueType("cpu", "nanoseconds"))
+
+	// The node tree only lists children: the stacks need the parents.
+	parents := map[uint64]uint64{}
+	roots := map[uint64]bool{}
+	for _, n := range p.Nodes {
+		for _, c := range n.Children {
+			parents[c] = n.ID
+		}
+		if n.CallFrame.FunctionName == "(root)" {
+			roots[n.ID] = true
+		}
+	}
+	for i, id := range p.Samples {
+		var stack []uint64
+		for n, ok := id, true; ok && !roots[n]; n, ok = parents[n] {
+			stack = append(stack, n)
+		}
+		var nanos int64
+		if i < len(p.TimeDeltas) {
+			nanos = p.TimeDeltas[i] * 1000
+		}
+		var sample protoBuffer
+		sample.packed(1, stack)
+		sample.packed(2, []uint64{1, uint64(nanos)})
+		prof.bytes(2, sample.data)
+	}
+
+	functions := map[string]uint64{}
+	var funcs []byte
+	for _, n := range p.Nodes {
+		if roots[n.ID] {
+			continue
+		}
+		name := n.CallFrame.FunctionName
+		if name == "" {
+			name = "(anonymous)"
+		}
+		key := name + "\x00" + n.CallFrame.URL
+		fn, ok := functions[key]
+		if !ok {
+			fn = uint64(len(functions) + 1)
+			functions[key] = fn
+			var f protoBuffer
+			f.uint64(1, fn)
+			f.uint64(2, str(name))
+			f.uint64(3, str(name))
+			f.uint64(4, str(n.CallFrame.URL))
+			var entry protoBuffer
+			entry.bytes(5, f.data)
+			funcs = append(funcs, entry.data...)
+		}
+		var line protoBuffer
+		line.uint64(1, fn)
+		line.uint64(2, uint64(n.CallFrame.LineNumber+1))
+		var loc protoBuffer
+		loc.uint64(1, n.ID)
+		loc.bytes(4, line.data)
+		prof.bytes(4, loc.data)
+	}
+	prof.data = append(prof.data, funcs...)
+
+	for _, s := range table {
+		prof.bytes(6, []byte(s))
+	}
+	prof.uint64(9, uint64(p.StartTime*1000))
+	prof.uint64(10, uint64((p.EndTime-p.StartTime)*1000))
+	prof.bytes(11, valueType("cpu", "nanoseconds"))
+	if len(p.Samples) > 0 {
+		prof.uint64(12, uint64((p.EndTime-p.StartTime)*1000/int64(len(p.Samples))))
+	}
+
+	zw := gzip.NewWriter(out)
+	if _, err := zw.Write(prof.data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// protoBuffer encodes protocol buffer messages, enough for pprof profiles.
+type protoBuffer struct {
+	data []byte
+}
+
+func (b *protoBuffer) varint(x uint64) {
+	for x >= 0x80 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+// uint64 encodes a varint field, omitted when zero.
+func (b *protoBuffer) uint64(field int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.varint(uint64(field)<<3 | 0)
+	b.varint(x)
+}
+
+// bytes encodes a length-delimited field: a string or a message.
+func (b *protoBuffer) bytes(field int, data []byte) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(len(data)))
+	b.data = append(b.data, data...)
+}
+
+// packed encodes a packed repeated varint field.
+func (b *protoBuffer) packed(field int, xs []uint64) {
+	var inner protoBuffer
+	for _, x := range xs {
+		inner.varint(x)
+	}
+	b.bytes(field, inner.data)
+}
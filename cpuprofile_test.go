@@ -0,0 +1,122 @@
+package wasmtest
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pprofTop returns the go tool pprof -top listing of the profile at path.
+func pprofTop(t *testing.T, path string) string {
+	t.Helper()
+	out, err := exec.Command("go", "tool", "pprof", "-top", path).CombinedOutput()
+	if err != nil {
+		t.Fatalf("go tool pprof failed: %v\n%s", err, out)
+	}
+	return string(out)
+}
+
+func TestConvertCPUProfile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "cpu.json")
+	profile := `{
+		"nodes": [
+			{"id": 1, "callFrame": {"functionName": "(root)", "url": "", "lineNumber": -1}, "children": [2, 4]},
+			{"id": 2, "callFrame": {"functionName": "main.outer", "url": "wasm://wasm/1", "lineNumber": 0}, "children": [3]},
+			{"id": 3, "callFrame": {"functionName": "main.inner", "url": "wasm://wasm/1", "lineNumber": 0}},
+			{"id": 4, "callFrame": {"functionName": "", "url": "http://127.0.0.1/wasm_exec.js", "lineNumber": 9}}
+		],
+		"startTime": 1000, "endTime": 5000,
+		"samples": [3, 3, 2, 4], "timeDeltas": [0, 1000, 1000, 1000]
+	}`
+	if err := os.WriteFile(src, []byte(profile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(dir, "out", "cpu.pprof")
+	if err := convertCPUProfile(src, dst); err != nil {
+		t.Fatalf("convertCPUProfile: %v", err)
+	}
+	top := pprofTop(t, dst)
+	for _, want := range []string{"main.inner", "main.outer", "(anonymous)", "Type: cpu"} {
+		if !strings.Contains(top, want) {
+			t.Errorf("pprof -top misses %q:\n%s", want, top)
+		}
+	}
+
+	if err := os.WriteFile(src, []byte("not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := convertCPUProfile(src, dst); err == nil {
+		t.Error("convertCPUProfile accepted an invalid profile")
+	}
+}
+
+func TestRunBundleCPUProfile(t *testing.T) {
+	dir := fakePlaywrightDir(t)
+	profiles := t.TempDir()
+	w := New(WithInstallDisabled(), WithPlaywright(PlaywrightOptions{Dir: dir, NoInstall: true}), WithCPUProfile(profiles))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	out := t.TempDir()
+	m, err := w.Bundle(ctx, "./example", out, "-test.run=TestMathHelper")
+	if err != nil {
+		t.Fatalf("Bundle failed: %v", err)
+	}
+	progress, msgs := collectProgress()
+	if err := w.RunBundle(ctx, out, RunBundleOptions{}, progress); err != nil {
+		t.Fatalf("RunBundle failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	path := filepath.Join(profiles, artifactName(m.Package)+".cpu.pprof")
+	if !strings.Contains(strings.Join(msgs(), "\n"), "CPU profile saved to "+path) {
+		t.Errorf("profile not reported:\n%s", strings.Join(msgs(), "\n"))
+	}
+	if top := pprofTop(t, path); !strings.Contains(top, "main.spin") {
+		t.Errorf("pprof -top misses main.spin:\n%s", top)
+	}
+
+	// Other engines have no DevTools profiler.
+	w = New(WithInstallDisabled(), WithPlaywright(PlaywrightOptions{Engine: PlaywrightFirefox, Dir: dir, NoInstall: true}), WithCPUProfile(profiles))
+	progress, msgs = collectProgress()
+	if err := w.RunBundle(ctx, out, RunBundleOptions{}, progress); err != nil {
+		t.Fatalf("RunBundle failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	if !strings.Contains(strings.Join(msgs(), "\n"), "cpuprofile-unsupported") {
+		t.Errorf("no cpuprofile-unsupported warning:\n%s", strings.Join(msgs(), "\n"))
+	}
+}
+
+func TestExecuteCPUProfile(t *testing.T) {
+	// The fake wasmbrowsertest writes the profile it is asked for.
+	bin := t.TempDir()
+	fake := filepath.Join(bin, "wasmbrowsertest")
+	script := "#!/bin/sh\nfor arg; do case \"$arg\" in -test.cpuprofile=*) echo profile > \"${arg#-test.cpuprofile=}\";; esac; done\necho PASS\n"
+	if err := os.WriteFile(fake, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	dir := writeModule(t, map[string]string{"p_test.go": wasmPassTest})
+	profiles := t.TempDir()
+	w := New(WithInstallDisabled(), WithCPUProfile(profiles))
+	progress, msgs := collectProgress()
+	if err := w.execute(t.Context(), execSpec{dir: dir, exec: fake}, progress); err != nil {
+		t.Fatalf("execute failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	path := filepath.Join(profiles, artifactName(dirProfileName(dir))+".cpu.pprof")
+	if data, err := os.ReadFile(path); err != nil || string(data) != "profile\n" {
+		t.Errorf("profile = %q, %v\n%s", data, err, strings.Join(msgs(), "\n"))
+	}
+	// go test keeps no test binary in the package directory.
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.test")); len(matches) > 0 {
+		t.Errorf("test binary left in the package directory: %q", matches)
+	}
+
+	// Other runners can't profile the browser.
+	progress, msgs = collectProgress()
+	w.execute(t.Context(), execSpec{dir: dir, exec: "/bin/true"}, progress)
+	if !strings.Contains(strings.Join(msgs(), "\n"), "cpuprofile-unsupported") {
+		t.Errorf("no cpuprofile-unsupported warning:\n%s", strings.Join(msgs(), "\n"))
+	}
+}
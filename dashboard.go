@@ -0,0 +1,134 @@
+package wasmtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+)
+
+// DashboardHandler returns a self-contained web UI over the runs saved in h:
+// the run list with flakiness trends on "/", each run with its per-test
+// status and output on "/run/<id>", and the same data as JSON on
+// "/api/runs" and "/api/runs/<id>". Pages of live runs refresh themselves.
+func DashboardHandler(h *HistoryStore) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /{$}", func(rw http.ResponseWriter, r *http.Request) {
+		runs, err := h.List()
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		flaky, err := h.Flakiness(50)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		live := false
+		for _, run := range runs {
+			live = live || run.Live
+		}
+		renderDashboard(rw, "index", map[string]any{"Runs": runs, "Flaky": flaky, "Live": live})
+	})
+
+	mux.HandleFunc("GET /run/{id}", func(rw http.ResponseWriter, r *http.Request) {
+		run, err := h.Load(r.PathValue("id"))
+		if err != nil {
+			http.NotFound(rw, r)
+			return
+		}
+		renderDashboard(rw, "run", map[string]any{"Run": run, "Live": run.Live})
+	})
+
+	mux.HandleFunc("GET /api/runs", func(rw http.ResponseWriter, r *http.Request) {
+		runs, err := h.List()
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(rw, map[string]any{"schemaVersion": SchemaVersion, "runs": runs})
+	})
+
+	mux.HandleFunc("GET /api/runs/{id}", func(rw http.ResponseWriter, r *http.Request) {
+		run, err := h.Load(r.PathValue("id"))
+		if err != nil {
+			http.NotFound(rw, r)
+			return
+		}
+		writeJSON(rw, run)
+	})
+
+	mux.HandleFunc("GET /api/flaky", func(rw http.ResponseWriter, r *http.Request) {
+		flaky, err := h.Flakiness(50)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(rw, map[string]any{"schemaVersion": SchemaVersion, "flaky": flaky})
+	})
+
+	return mux
+}
+
+func writeJSON(rw http.ResponseWriter, v any) {
+	rw.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(rw)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func renderDashboard(rw http.ResponseWriter, name string, data map[string]any) {
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplates.ExecuteTemplate(rw, name, data); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+var dashboardTemplates = template.Must(template.New("").Funcs(template.FuncMap{
+	"ms":   func(d time.Duration) string { return d.Round(time.Millisecond).String() },
+	"pct":  func(f float64) string { return fmt.Sprintf("%.0f%%", f*100) },
+	"when": func(t time.Time) string { return t.Local().Format("2006-01-02 15:04:05") },
+}).Parse(`
+{{define "head"}}<!doctype html>
+<html><head><meta charset="utf-8"><title>WasmTest dashboard</title>
+{{if .Live}}<meta http-equiv="refresh" content="2">{{end}}
+<style>
+body{font-family:system-ui,sans-serif;margin:2rem;color:#222}
+table{border-collapse:collapse;margin-bottom:2rem}
+td,th{padding:.3rem .8rem;border-bottom:1px solid #ddd;text-align:left}
+.pass{color:#1a7f37}.fail{color:#cf222e}.skip,.run{color:#9a6700}
+pre{background:#f6f8fa;padding:1rem;overflow:auto;max-height:30rem}
+a{color:#0969da}
+</style></head><body>
+<h1><a href="/">WasmTest</a></h1>{{end}}
+
+{{define "status"}}{{if .Live}}<span class="run">running</span>{{else if eq .ExitCode 0}}<span class="pass">passed</span>{{else}}<span class="fail">failed</span>{{end}}{{end}}
+
+{{define "index"}}{{template "head" .}}
+<h2>Runs</h2>
+{{if not .Runs}}<p>No runs recorded yet.</p>{{else}}
+<table><tr><th>Run</th><th>Started</th><th>Status</th><th>Plans</th><th>Duration</th></tr>
+{{range .Runs}}<tr><td><a href="/run/{{.ID}}">{{.ID}}</a></td><td>{{when .Started}}</td><td>{{template "status" .}}</td><td>{{len .Plans}}</td><td>{{ms .Duration}}</td></tr>
+{{end}}</table>{{end}}
+<h2>Flaky tests</h2>
+{{if not .Flaky}}<p>No flaky tests in the recent runs.</p>{{else}}
+<table><tr><th>Plan</th><th>Test</th><th>Failures</th><th>Runs</th><th>Rate</th></tr>
+{{range .Flaky}}<tr><td>{{.Plan}}</td><td>{{.Test}}</td><td>{{.Failures}}</td><td>{{.Runs}}</td><td>{{pct .Rate}}</td></tr>
+{{end}}</table>{{end}}
+</body></html>{{end}}
+
+{{define "run"}}{{template "head" .}}
+{{with .Run}}<h2>Run {{.ID}} — {{template "status" .}}</h2>
+<p>Started {{when .Started}}{{if not .Live}}, took {{ms .Duration}}{{end}}</p>
+{{range .Plans}}<h3 class="{{if .Passed}}pass{{else}}fail{{end}}">{{.Name}} <small>{{.Dir}}</small></h3>
+{{if .Error}}<p class="fail">{{.Error}}</p>{{end}}
+<table><tr><th>Test</th><th>Status</th><th>Elapsed</th></tr>
+{{range .Tests}}<tr><td>{{.Name}}</td><td class="{{.Status}}">{{.Status}}</td><td>{{ms .Elapsed}}</td></tr>
+{{end}}</table>
+<details><summary>Output ({{len .Output}} lines)</summary><pre>{{range .Output}}{{.}}
+{{end}}</pre></details>
+{{end}}{{end}}
+</body></html>{{end}}
+`))
@@ -0,0 +1,57 @@
+package wasmtest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDashboardHandler(t *testing.T) {
+	h, err := OpenHistory(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := &RunRecord{ID: "20260101T000000.000000000", Started: time.Now(), ExitCode: 1, Plans: []PlanRecord{{
+		Name:   "browser",
+		Dir:    "./wasm_tests",
+		Error:  "tests failed",
+		Tests:  []TestRecord{{Name: "TestDOM", Status: "fail", Elapsed: time.Second}},
+		Output: []string{"--- FAIL: TestDOM (1.00s)", "<script>"},
+	}}}
+	if err := h.save(rec); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(DashboardHandler(h))
+	defer srv.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if code, body := get("/"); code != 200 || !strings.Contains(body, rec.ID) || !strings.Contains(body, "failed") {
+		t.Errorf("GET / = %d\n%s", code, body)
+	}
+	code, body := get("/run/" + rec.ID)
+	if code != 200 || !strings.Contains(body, "TestDOM") || !strings.Contains(body, "&lt;script&gt;") {
+		t.Errorf("GET /run = %d\n%s", code, body)
+	}
+	if code, body := get("/api/runs"); code != 200 || !strings.Contains(body, `"schemaVersion": 1`) {
+		t.Errorf("GET /api/runs = %d\n%s", code, body)
+	}
+	if code, body := get("/api/runs/" + rec.ID); code != 200 || !strings.Contains(body, `"status": "fail"`) {
+		t.Errorf("GET /api/runs/id = %d\n%s", code, body)
+	}
+	if code, _ := get("/run/missing"); code != http.StatusNotFound {
+		t.Errorf("GET /run/missing = %d, want 404", code)
+	}
+}
@@ -0,0 +1,40 @@
+// deno_exec.mjs runs a js/wasm test binary under Deno, as go_js_wasm_exec
+// does under node (see BackendDeno). Its arguments are the wasm_exec.js of
+// the Go installation, the test binary and the test binary arguments. It only
+// uses the node: modules Deno provides, so node runs it too.
+import fs from "node:fs";
+import os from "node:os";
+import path from "node:path";
+import process from "node:process";
+import { pathToFileURL } from "node:url";
+
+if (process.argv.length < 4) {
+	console.error("usage: deno_exec.mjs [wasm_exec.js] [wasm binary] [arguments]");
+	process.exit(1);
+}
+const [wasmExec, binary, ...args] = process.argv.slice(2);
+
+globalThis.fs = fs;
+globalThis.path = path;
+globalThis.process = process;
+await import(pathToFileURL(wasmExec).href);
+
+const go = new Go();
+go.argv = [binary, ...args];
+go.env = Object.assign({ TMPDIR: os.tmpdir() }, process.env);
+go.exit = process.exit;
+try {
+	const { instance } = await WebAssembly.instantiate(fs.readFileSync(binary), go.importObject);
+	process.on("exit", (code) => {
+		// The event loop is empty while Go still runs: a deadlock, which
+		// Go reports with the stack traces once resumed.
+		if (code === 0 && !go.exited) {
+			go._pendingEvent = { id: 0 };
+			go._resume();
+		}
+	});
+	await go.run(instance);
+} catch (err) {
+	console.error(err);
+	process.exit(1);
+}
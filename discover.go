@@ -0,0 +1,118 @@
+package wasmtest
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TestFunc is a test, benchmark, example or fuzz target found by
+// DiscoverTests.
+type TestFunc struct {
+	Name string `json:"name"`
+	// Kind is "test", "benchmark", "example" or "fuzz".
+	Kind string `json:"kind"`
+	// File and Line locate the declaration of the function.
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// PackageTests are the js/wasm test functions of the package in Dir.
+type PackageTests struct {
+	Dir string `json:"dir"`
+	// Package is the name of the package, with the _test suffix of an
+	// external test package when all the functions belong to it.
+	Package string     `json:"package"`
+	Funcs   []TestFunc `json:"funcs"`
+}
+
+// testFuncKinds maps the name prefixes of test functions to their kind.
+var testFuncKinds = []struct{ prefix, kind string }{
+	{"Test", "test"},
+	{"Benchmark", "benchmark"},
+	{"Example", "example"},
+	{"Fuzz", "fuzz"},
+}
+
+// DiscoverTests returns the test, benchmark, example and fuzz functions of
+// the js/wasm test files of the packages matched by pattern (see
+// FindPackages), in file and declaration order, without building or
+// running anything. TestMain is left out, as are the functions of files
+// not restricted to js/wasm.
+func DiscoverTests(pattern string, tags ...string) ([]PackageTests, error) {
+	dirs, err := FindPackages(pattern, tags...)
+	if err != nil {
+		return nil, err
+	}
+	var pkgs []PackageTests
+	for _, dir := range dirs {
+		pkg, err := discoverPackage(dir, TargetJS, tags)
+		if err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, nil
+}
+
+// discoverPackage returns the test functions of the test files of dir
+// built for target.
+func discoverPackage(dir, target string, tags []string) (PackageTests, error) {
+	pkg := PackageTests{Dir: dir, Funcs: []TestFunc{}}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return pkg, err
+	}
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), "_test.go") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return pkg, err
+		}
+		if !wasmOnly(src, target, tags) {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, src, parser.SkipObjectResolution)
+		if err != nil {
+			return pkg, err
+		}
+		if pkg.Package == "" || strings.HasSuffix(pkg.Package, "_test") {
+			pkg.Package = file.Name.Name
+		}
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || fn.Name.Name == "TestMain" {
+				continue
+			}
+			if kind := testFuncKind(fn.Name.Name); kind != "" {
+				pkg.Funcs = append(pkg.Funcs, TestFunc{Name: fn.Name.Name, Kind: kind, File: path, Line: fset.Position(fn.Pos()).Line})
+			}
+		}
+	}
+	return pkg, nil
+}
+
+// testFuncKind returns the kind of the function called name, or "" when it
+// is not a test function: like go test, TestX and Test_x are tests but
+// Testing is not.
+func testFuncKind(name string) string {
+	for _, k := range testFuncKinds {
+		rest, ok := strings.CutPrefix(name, k.prefix)
+		if !ok {
+			continue
+		}
+		if r, _ := utf8.DecodeRuneInString(rest); rest == "" || !unicode.IsLower(r) {
+			return k.kind
+		}
+	}
+	return ""
+}
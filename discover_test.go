@@ -0,0 +1,39 @@
+package wasmtest
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiscoverTests(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"ui/dom_test.go": "//go:build js && wasm\n\npackage ui\n\nimport \"testing\"\n\n" +
+			"func TestMain(m *testing.M) {}\n\nfunc TestDOM(t *testing.T) {}\n\nfunc Testing(t *testing.T) {}\n\n" +
+			"func BenchmarkRender(b *testing.B) {}\n\nfunc helper() {}\n",
+		"ui/example_test.go": "//go:build js && wasm\n\npackage ui_test\n\nfunc Example_render() {}\n\nfunc FuzzParse() {}\n",
+		"ui/native_test.go":  "package ui\n\nimport \"testing\"\n\nfunc TestNative(t *testing.T) {}\n",
+		"ui/gated_test.go":   "//go:build js && wasm && integration\n\npackage ui\n\nimport \"testing\"\n\nfunc TestGated(t *testing.T) {}\n",
+		"native/n_test.go":   "package native\n\nimport \"testing\"\n\nfunc TestNative(t *testing.T) {}\n",
+	})
+
+	pkgs, err := DiscoverTests(filepath.Join(dir, "..."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ui := filepath.Join(dir, "ui")
+	want := []PackageTests{{Dir: ui, Package: "ui", Funcs: []TestFunc{
+		{Name: "TestDOM", Kind: "test", File: filepath.Join(ui, "dom_test.go"), Line: 9},
+		{Name: "BenchmarkRender", Kind: "benchmark", File: filepath.Join(ui, "dom_test.go"), Line: 13},
+		{Name: "Example_render", Kind: "example", File: filepath.Join(ui, "example_test.go"), Line: 5},
+		{Name: "FuzzParse", Kind: "fuzz", File: filepath.Join(ui, "example_test.go"), Line: 7},
+	}}}
+	if !reflect.DeepEqual(pkgs, want) {
+		t.Errorf("DiscoverTests = %+v\nwant %+v", pkgs, want)
+	}
+
+	pkgs, err = DiscoverTests(ui, "integration")
+	if err != nil || len(pkgs) != 1 || len(pkgs[0].Funcs) != 5 {
+		t.Errorf("DiscoverTests with tags = %+v, %v", pkgs, err)
+	}
+}
@@ -0,0 +1,103 @@
+package wasmtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Default image and browser of BackendDocker (see WithDockerImage).
+const (
+	DefaultDockerImage   = "chromedp/headless-shell:latest"
+	defaultDockerBrowser = "/headless-shell/headless-shell"
+)
+
+// WithDockerImage sets the container image of BackendDocker and the Chrome
+// executable inside it; an empty browser uses the image entrypoint. The
+// default is DefaultDockerImage with its headless-shell. The
+// WASMTEST_DOCKER_IMAGE environment variable and the docker_image setting
+// of the configuration file set the image too.
+func WithDockerImage(image, browser string) Option {
+	return func(w *Wasmtest) {
+		w.dockerImage = image
+		w.dockerBrowser = browser
+	}
+}
+
+// dockerImageBrowser returns the image and browser of BackendDocker.
+func (w *Wasmtest) dockerImageBrowser() (image, browser string) {
+	if w.dockerImage == "" {
+		return DefaultDockerImage, defaultDockerBrowser
+	}
+	return w.dockerImage, w.dockerBrowser
+}
+
+// dockerBrowserScript writes, in a new temporary directory, an executable named
+// name standing for a Chrome browser: it runs the browser of w's image in a
+// container, with the given arguments and flags. The container shares the
+// host network, so the browser loads the pages served on localhost and its
+// DevTools endpoint is reachable, and it mounts the --user-data-dir
+// profile directory; it runs as the current user, so the profile can be
+// removed. remove deletes the directory and any container left running,
+// e.g. when the browser was killed.
+func (w *Wasmtest) dockerBrowserScript(name string, flags []string) (path string, remove func(), err error) {
+	docker, err := exec.LookPath("docker")
+	if err != nil {
+		return "", nil, fmt.Errorf("wasmtest: the docker backend needs docker in PATH: %w", err)
+	}
+	id := make([]byte, 6)
+	rand.Read(id)
+	label := "wasmtest.run=" + hex.EncodeToString(id)
+
+	image, browser := w.dockerImageBrowser()
+	run := shellQuote(docker) + ` run --rm --init --network host --user "$(id -u):$(id -g)" -e HOME=/tmp --label ` + shellQuote(label)
+	if browser != "" {
+		run += " --entrypoint " + shellQuote(browser)
+	}
+	args := shellQuote(image) + ` --no-sandbox "$@"`
+	for _, flag := range flags {
+		args += " " + shellQuote(flag)
+	}
+	script := "#!/bin/sh\n" +
+		"profile=\n" +
+		"for arg in \"$@\"; do\n" +
+		"\tcase \"$arg\" in\n" +
+		"\t--user-data-dir=*) profile=\"${arg#--user-data-dir=}\" ;;\n" +
+		"\tesac\n" +
+		"done\n" +
+		"if [ -n \"$profile\" ]; then\n" +
+		"\tmkdir -p \"$profile\"\n" +
+		"\texec " + run + " -v \"$profile:$profile\" " + args + "\n" +
+		"fi\n" +
+		"exec " + run + " " + args + "\n"
+
+	dir, err := makeTempDir("wasmtest-docker-")
+	if err != nil {
+		return "", nil, err
+	}
+	path = filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+	return path, func() {
+		os.RemoveAll(dir)
+		removeContainers(docker, label)
+	}, nil
+}
+
+// removeContainers removes the containers labeled label.
+func removeContainers(docker, label string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, docker, "ps", "-aq", "--filter", "label="+label).Output()
+	if ids := strings.Fields(string(out)); err == nil && len(ids) > 0 {
+		exec.CommandContext(ctx, docker, append([]string{"rm", "-f"}, ids...)...).Run()
+	}
+}
@@ -0,0 +1,93 @@
+package wasmtest
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDocker puts first on PATH a docker recording its arguments in the
+// returned file, one invocation per line, and running body for `docker
+// run`; `docker ps` lists the container abc.
+func fakeDocker(t *testing.T, body string) string {
+	t.Helper()
+	dir := t.TempDir()
+	record := filepath.Join(dir, "calls")
+	script := "#!/bin/sh\necho \"$@\" >> " + record + "\n" +
+		"case \"$1\" in\nps) echo abc; exit 0 ;;\nrm) exit 0 ;;\nesac\n" + body + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "docker"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return record
+}
+
+func TestDockerBrowserScript(t *testing.T) {
+	record := fakeDocker(t, "exit 0")
+	w := New(WithInstallDisabled(), WithBackend(BackendDocker))
+	path, remove, err := w.dockerBrowserScript("headless_shell", []string{"--lang=es"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := filepath.Join(t.TempDir(), "profile")
+	if out, err := exec.Command(path, "--user-data-dir="+profile, "--remote-debugging-port=0", "about:blank").CombinedOutput(); err != nil {
+		t.Fatalf("browser script failed: %v\n%s", err, out)
+	}
+	if _, err := os.Stat(profile); err != nil {
+		t.Errorf("profile directory not created: %v", err)
+	}
+	remove()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("browser script not removed: %v", err)
+	}
+
+	data, _ := os.ReadFile(record)
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(calls) != 3 {
+		t.Fatalf("docker calls = %q", calls)
+	}
+	for _, want := range []string{"run --rm", "--network host", "--entrypoint " + defaultDockerBrowser, "-v " + profile + ":" + profile, DefaultDockerImage + " --no-sandbox --user-data-dir=", "about:blank --lang=es"} {
+		if !strings.Contains(calls[0], want) {
+			t.Errorf("docker run %q misses %q", calls[0], want)
+		}
+	}
+	if !strings.HasPrefix(calls[1], "ps -aq --filter label=wasmtest.run=") || calls[2] != "rm -f abc" {
+		t.Errorf("cleanup calls = %q", calls[1:])
+	}
+
+	w = New(WithInstallDisabled(), WithDockerImage("example/chrome", ""))
+	path, remove, err = w.dockerBrowserScript("chrome", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remove()
+	script, _ := os.ReadFile(path)
+	if strings.Contains(string(script), "--entrypoint") || !strings.Contains(string(script), "'example/chrome' --no-sandbox") {
+		t.Errorf("custom image script:\n%s", script)
+	}
+}
+
+func TestRunBundleDocker(t *testing.T) {
+	browser := nodeBrowser(t)
+	// The fake container browser is the node browser opening the page, the
+	// last argument.
+	fakeDocker(t, "for page; do :; done\nexec "+browser+" \"$page\"")
+	w := New(WithInstallDisabled(), WithBackend(BackendDocker))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	out := t.TempDir()
+	if _, err := w.Bundle(ctx, "./example", out, "-test.run=TestMathHelper"); err != nil {
+		t.Fatalf("Bundle failed: %v", err)
+	}
+	progress, msgs := collectProgress()
+	if err := w.RunBundle(ctx, out, RunBundleOptions{}, progress); err != nil {
+		t.Fatalf("RunBundle failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	if !strings.Contains(strings.Join(msgs(), "\n"), "launched "+DefaultDockerImage+" (docker)") {
+		t.Errorf("docker launch not reported:\n%s", strings.Join(msgs(), "\n"))
+	}
+}
@@ -0,0 +1,194 @@
+package wasmtest
+
+import (
+	"cmp"
+	"context"
+	"debug/buildinfo"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// DoctorReport describes the tools the runs of a Wasmtest depend on, as
+// found by Doctor. Empty paths were not found.
+type DoctorReport struct {
+	// GoVersion is the version of the go command in PATH, and JSWasm
+	// reports whether it lists the js/wasm port.
+	GoVersion string `json:"goVersion,omitempty"`
+	JSWasm    bool   `json:"jsWasm"`
+	// WasmExecJS is the wasm_exec.js of the toolchain.
+	WasmExecJS string `json:"wasmExecJs,omitempty"`
+	// WasmBrowserTest is the wasmbrowsertest binary in PATH and
+	// WasmBrowserTestVersion its module version.
+	WasmBrowserTest        string `json:"wasmbrowsertest,omitempty"`
+	WasmBrowserTestVersion string `json:"wasmbrowsertestVersion,omitempty"`
+	// Runner is the go_js_wasm_exec go test runs, and RunnerTarget the
+	// file it resolves to through its symlinks.
+	Runner       string `json:"runner,omitempty"`
+	RunnerTarget string `json:"runnerTarget,omitempty"`
+	// Browsers are the browser executables found, in order of preference.
+	Browsers []string `json:"browsers"`
+	// InstallDir is where wasmbrowsertest is installed (see WithInstallDir)
+	// and InstallDirInPath whether PATH holds it, as go test needs.
+	InstallDir       string `json:"installDir,omitempty"`
+	InstallDirInPath bool   `json:"installDirInPath"`
+	// Display is the X11 or Wayland display of a Linux host, which only
+	// WithHeadful runs need.
+	Display string `json:"display,omitempty"`
+	// Problems are what would keep the tests from running, or make them
+	// run with another runner than expected.
+	Problems []Warning `json:"problems"`
+}
+
+// Healthy reports whether no problem was found.
+func (r *DoctorReport) Healthy() bool {
+	return len(r.Problems) == 0
+}
+
+// String renders the report for log output, a line per check followed by
+// the problems.
+func (r *DoctorReport) String() string {
+	found := func(s string) string {
+		if s == "" {
+			return "not found"
+		}
+		return s
+	}
+	var b strings.Builder
+	b.WriteString("🩺 wasmtest doctor\n")
+	jsWasm := "js/wasm supported"
+	if !r.JSWasm {
+		jsWasm = "js/wasm NOT supported"
+	}
+	fmt.Fprintf(&b, "  go: %s (%s)\n", found(r.GoVersion), jsWasm)
+	fmt.Fprintf(&b, "  wasm_exec.js: %s\n", found(r.WasmExecJS))
+	wbt := found(r.WasmBrowserTest)
+	if r.WasmBrowserTestVersion != "" {
+		wbt += " " + r.WasmBrowserTestVersion
+	}
+	fmt.Fprintf(&b, "  wasmbrowsertest: %s\n", wbt)
+	runner := found(r.Runner)
+	if r.RunnerTarget != "" && r.RunnerTarget != r.Runner {
+		runner += " -> " + r.RunnerTarget
+	}
+	fmt.Fprintf(&b, "  go_js_wasm_exec: %s\n", runner)
+	fmt.Fprintf(&b, "  browsers: %s\n", found(strings.Join(r.Browsers, ", ")))
+	inPath := "in PATH"
+	if !r.InstallDirInPath {
+		inPath = "NOT in PATH"
+	}
+	fmt.Fprintf(&b, "  install dir: %s (%s)\n", found(r.InstallDir), inPath)
+	if runtime.GOOS == "linux" {
+		fmt.Fprintf(&b, "  display: %s\n", found(r.Display))
+	}
+	if r.Healthy() {
+		b.WriteString("✅ no problem found")
+	}
+	for i, p := range r.Problems {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(p.String())
+	}
+	return b.String()
+}
+
+// Doctor checks the environment the tests of w run in: the go command and
+// its js/wasm support, wasm_exec.js, wasmbrowsertest and the
+// go_js_wasm_exec go test resolves, the browsers, whether the install
+// directory is in PATH and, on Linux, the display. RunTests prints the
+// report when a run fails because of the runner, and it can be printed
+// before the first run to explain a broken setup. Doctor installs nothing.
+func (w *Wasmtest) Doctor(ctx context.Context) *DoctorReport {
+	r := &DoctorReport{Browsers: []string{}, Problems: []Warning{}}
+	problem := func(code, hint, format string, args ...any) {
+		r.Problems = append(r.Problems, Warning{Code: code, Message: fmt.Sprintf(format, args...), Hint: hint})
+	}
+	path := filepath.SplitList(os.Getenv("PATH"))
+
+	if vars, err := goEnvVars(ctx, execSpec{toolchain: w.toolchain}, "GOVERSION", "GOROOT"); err != nil {
+		problem("go-missing", "install Go from https://go.dev/dl and add its bin directory to PATH", "the go command is not usable: %v", err)
+	} else {
+		r.GoVersion = vars["GOVERSION"]
+		if out, err := exec.CommandContext(ctx, w.toolchain.command(), "tool", "dist", "list").Output(); err == nil {
+			r.JSWasm = slices.Contains(strings.Fields(string(out)), "js/wasm")
+		}
+		if !r.JSWasm {
+			problem("js-wasm-unsupported", "use a Go toolchain with the js/wasm port", "%s does not list the js/wasm port", r.GoVersion)
+		}
+		if js, err := findWasmExecJS(vars["GOROOT"]); err == nil {
+			r.WasmExecJS = js
+		} else {
+			problem("wasm-exec-js-missing", "reinstall Go; some distribution packages leave lib/wasm out", "%v", err)
+		}
+	}
+
+	for _, name := range wasmBrowserTestNames {
+		if p, err := exec.LookPath(name); err == nil {
+			r.WasmBrowserTest = p
+			if info, err := buildinfo.ReadFile(p); err == nil {
+				r.WasmBrowserTestVersion = info.Main.Version
+			}
+			break
+		}
+	}
+	if r.WasmBrowserTest == "" {
+		problem("wasmbrowsertest-missing", "install it with go install "+wasmBrowserTestModule+"@latest, or let wasmtest install it (see WithAutoInstall)", "wasmbrowsertest is not in PATH")
+	}
+	if warn := w.wasmBrowserTestMismatch(); warn != nil {
+		r.Problems = append(r.Problems, *warn)
+	}
+	if r.WasmBrowserTest != "" {
+		if warn := w.runnerOutdated(ctx, execSpec{toolchain: w.toolchain}, r.WasmBrowserTest); warn != nil {
+			r.Problems = append(r.Problems, *warn)
+		}
+	}
+
+	r.Runner, _ = lookupRunner(path)
+	if r.Runner != "" {
+		r.RunnerTarget, _ = filepath.EvalSymlinks(r.Runner)
+	} else {
+		problem("runner-missing", "run the tests once with installs enabled so wasmtest links it to wasmbrowsertest", "go_js_wasm_exec is not in PATH: go test cannot run js/wasm tests")
+	}
+	if warn := verifyRunner(path, wasmBrowserTestModule); warn != nil {
+		r.Problems = append(r.Problems, *warn)
+	}
+
+	for _, candidate := range browserCandidates {
+		if p, err := exec.LookPath(candidate); err == nil && !slices.Contains(r.Browsers, p) {
+			r.Browsers = append(r.Browsers, p)
+		}
+	}
+	for _, p := range browserAppPaths[runtime.GOOS] {
+		if _, err := os.Stat(p); err == nil {
+			r.Browsers = append(r.Browsers, p)
+		}
+	}
+	if w.browser != "" {
+		if _, err := lookupBrowser(w.browser); err != nil {
+			problem("browser-missing", "install it, or pick one of the browsers found with WithBrowser", "%v", err)
+		}
+	} else if len(r.Browsers) == 0 {
+		problem("browser-missing", "install Chrome, Chromium, Edge or Firefox, or run the tests in Docker (see WithDockerImage)", "%v", errNoBrowser)
+	}
+
+	if dir, err := w.installBinDir(ctx); err == nil {
+		r.InstallDir = dir
+		r.InstallDirInPath = inPath(path, dir)
+		if !r.InstallDirInPath {
+			r.Problems = append(r.Problems, installDirWarning(dir))
+		}
+	}
+
+	if runtime.GOOS == "linux" {
+		r.Display = cmp.Or(os.Getenv("WAYLAND_DISPLAY"), os.Getenv("DISPLAY"))
+		if r.Display == "" && w.headful {
+			problem("no-display", "unset WithHeadful, or run the tests under xvfb-run", "headful runs need a display but neither DISPLAY nor WAYLAND_DISPLAY is set")
+		}
+	}
+	return r
+}
@@ -0,0 +1,81 @@
+package wasmtest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDoctor(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go not in PATH")
+	}
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("GOPATH", t.TempDir())
+	t.Setenv("DISPLAY", "")
+	t.Setenv("WAYLAND_DISPLAY", "")
+	// PATH holds go and a dangling go_js_wasm_exec, no wasmbrowsertest and
+	// no browser.
+	bin := t.TempDir()
+	if err := os.Symlink(filepath.Join(bin, "gone"), filepath.Join(bin, "go_js_wasm_exec")); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", filepath.Dir(goBin)+string(os.PathListSeparator)+bin)
+	install := t.TempDir()
+
+	w := New(WithInstallDisabled(), WithInstallDir(install), WithHeadful(), WithLogger(func(...any) {}))
+	r := w.Doctor(t.Context())
+	if r.GoVersion == "" || !r.JSWasm || r.WasmExecJS == "" {
+		t.Errorf("toolchain not reported: %+v", r)
+	}
+	if r.Runner != filepath.Join(bin, "go_js_wasm_exec") || r.InstallDir != install || r.InstallDirInPath {
+		t.Errorf("runner or install dir not reported: %+v", r)
+	}
+	var codes []string
+	for _, p := range r.Problems {
+		codes = append(codes, p.Code)
+	}
+	want := []string{"wasmbrowsertest-missing", "runner-stale", "install-dir-not-in-path"}
+	if len(browserAppPaths[runtime.GOOS]) == 0 {
+		want = append(want, "browser-missing")
+	}
+	if runtime.GOOS == "linux" {
+		want = append(want, "no-display")
+	}
+	for _, code := range want {
+		if !slices.Contains(codes, code) {
+			t.Errorf("problem %s not reported in %q", code, codes)
+		}
+	}
+	if r.Healthy() || !strings.Contains(r.String(), "install dir: "+install+" (NOT in PATH)") {
+		t.Errorf("report rendered as:\n%s", r)
+	}
+}
+
+func TestRunTestsDoctor(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go not in PATH")
+	}
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("GOPATH", t.TempDir())
+	t.Setenv("PATH", filepath.Dir(goBin))
+	dir := writeModule(t, map[string]string{"p_test.go": wasmPassTest})
+
+	var logged []string
+	logger := func(a ...any) { logged = append(logged, fmt.Sprint(a...)) }
+	_, err = RunTestsResult(dir, logger, WithNoInstall())
+	if !errors.Is(err, ErrRunnerMissing) {
+		t.Fatalf("RunTests = %v, want ErrRunnerMissing", err)
+	}
+	if out := strings.Join(logged, "\n"); !strings.Contains(out, "wasmtest doctor") || !strings.Contains(out, "wasmbrowsertest-missing") {
+		t.Errorf("doctor report not printed:\n%s", out)
+	}
+}
@@ -0,0 +1,350 @@
+package wasmtest
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// DefaultGeckodriverVersion is the geckodriver downloaded for Firefox when
+// DriverOptions.Version is empty.
+const DefaultGeckodriverVersion = "0.34.0"
+
+// Download locations of the drivers, variables for the tests.
+var (
+	// chromeForTestingURL serves the LATEST_RELEASE_<build> files naming the
+	// chromedriver of a Chrome build.
+	chromeForTestingURL = "https://googlechromelabs.github.io/chrome-for-testing"
+	// chromeDriverURL serves <version>/<platform>/chromedriver-<platform>.zip.
+	chromeDriverURL = "https://storage.googleapis.com/chrome-for-testing-public"
+	// geckodriverURL serves v<version>/geckodriver-v<version>-<platform>.
+	geckodriverURL = "https://github.com/mozilla/geckodriver/releases/download"
+)
+
+// driverStartTimeout bounds how long a managed driver may take to answer.
+const driverStartTimeout = 30 * time.Second
+
+// DriverOptions configures WithManagedWebDriver.
+type DriverOptions struct {
+	// Version pins the driver version, e.g. "120.0.6099.109" for
+	// chromedriver or "0.34.0" for geckodriver. Empty picks the chromedriver
+	// of the installed Chrome build, and DefaultGeckodriverVersion.
+	Version string
+	// CacheDir is where the drivers are downloaded to and reused from;
+	// empty is wasmtest/drivers in the user cache directory. Cleanup only
+	// removes the default one.
+	CacheDir string
+}
+
+// WithManagedWebDriver makes RunBundle drive the browser through a local
+// chromedriver or geckodriver, as WithWebDriver does with a running server:
+// the driver matching the browser selected by RunBundleOptions.Browser or
+// WithBrowser, found with the browser --version, is downloaded to the cache
+// directory when missing, started for the run and stopped after it. Cached
+// drivers are reused without network access, so runs keep working offline
+// once a driver was downloaded; WithNoInstall never downloads. Chrome,
+// Chromium and Firefox are supported. WithWebDriver and WithCloudGrid take
+// precedence, and go test runs ignore it.
+func WithManagedWebDriver(opts DriverOptions) Option {
+	return func(w *Wasmtest) { w.managedDriver = &opts }
+}
+
+// webDriverName returns the driver of a browser family.
+func webDriverName(family string) (string, error) {
+	switch family {
+	case BrowserChrome, BrowserChromium:
+		return "chromedriver", nil
+	case BrowserFirefox:
+		return "geckodriver", nil
+	}
+	return "", fmt.Errorf("wasmtest: no managed WebDriver for the %q browser; start its driver and use WithWebDriver", family)
+}
+
+// browserVersion returns the version printed by `browser --version`, e.g.
+// 120.0.6099.109 for "Google Chrome 120.0.6099.109".
+func browserVersion(ctx context.Context, browser string) (string, error) {
+	out, err := exec.CommandContext(ctx, browser, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("wasmtest: %s --version: %w", browser, err)
+	}
+	version := regexp.MustCompile(`\d+(\.\d+)+`).FindString(string(out))
+	if version == "" {
+		return "", fmt.Errorf("wasmtest: no version in the %s --version output %q", browser, strings.TrimSpace(string(out)))
+	}
+	return version, nil
+}
+
+// driverPlatform returns the download platform of driver for the host.
+func driverPlatform(driver string) (string, error) {
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+	platforms := map[string]string{
+		"linux/amd64":   "linux64",
+		"darwin/amd64":  "mac-x64",
+		"darwin/arm64":  "mac-arm64",
+		"windows/386":   "win32",
+		"windows/amd64": "win64",
+	}
+	if driver == "geckodriver" {
+		platforms = map[string]string{
+			"linux/amd64":   "linux64",
+			"linux/arm64":   "linux-aarch64",
+			"darwin/amd64":  "macos",
+			"darwin/arm64":  "macos-aarch64",
+			"windows/386":   "win32",
+			"windows/amd64": "win64",
+		}
+	}
+	if p, ok := platforms[platform]; ok {
+		return p, nil
+	}
+	return "", fmt.Errorf("wasmtest: no %s download for %s", driver, platform)
+}
+
+// driverCacheDir returns the directory of the cached drivers.
+func (o DriverOptions) driverCacheDir() (string, error) {
+	if o.CacheDir != "" {
+		return o.CacheDir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "wasmtest", "drivers"), nil
+}
+
+// findDriver returns the driver for the browser at path, from the cache,
+// or downloaded to it unless offline.
+func (w *Wasmtest) findDriver(ctx context.Context, browser string, progress func(msgs ...any)) (string, error) {
+	opts := *w.managedDriver
+	driver, err := webDriverName(browserFamily(browser))
+	if err != nil {
+		return "", err
+	}
+	cache, err := opts.driverCacheDir()
+	if err != nil {
+		return "", err
+	}
+	exe := driver
+	if runtime.GOOS == "windows" {
+		exe += ".exe"
+	}
+	cached := func(version string) string {
+		return filepath.Join(cache, driver, version, exe)
+	}
+
+	version := strings.TrimPrefix(opts.Version, "v")
+	if version == "" && driver == "geckodriver" {
+		version = DefaultGeckodriverVersion
+	}
+	var build string
+	if version == "" {
+		// chromedriver must match the Chrome build: a cached driver of the
+		// build is reused, or else the latest one is looked up.
+		v, err := browserVersion(ctx, browser)
+		if err != nil {
+			return "", err
+		}
+		parts := strings.Split(v, ".")
+		build = strings.Join(parts[:min(3, len(parts))], ".")
+		if matches, _ := filepath.Glob(cached(build + ".*")); len(matches) > 0 {
+			return matches[0], nil
+		}
+	} else if _, err := os.Stat(cached(version)); err == nil {
+		return cached(version), nil
+	}
+	if w.offline {
+		return "", newRunError(ErrRunnerMissing, "wasmtest: no cached %s %s in %s and offline mode never downloads it", driver, cmp.Or(version, build), cache)
+	}
+
+	if version == "" {
+		data, err := driverDownload(ctx, chromeForTestingURL+"/LATEST_RELEASE_"+build)
+		if err != nil {
+			return "", err
+		}
+		version = strings.TrimSpace(string(data))
+	}
+	platform, err := driverPlatform(driver)
+	if err != nil {
+		return "", err
+	}
+	url := fmt.Sprintf("%s/%s/%s/chromedriver-%s.zip", chromeDriverURL, version, platform, platform)
+	if driver == "geckodriver" {
+		ext := ".tar.gz"
+		if strings.HasPrefix(platform, "win") {
+			ext = ".zip"
+		}
+		url = fmt.Sprintf("%s/v%s/geckodriver-v%s-%s%s", geckodriverURL, version, version, platform, ext)
+	}
+	progress("info", fmt.Sprintf("downloading %s %s from %s", driver, version, url))
+	archive, err := driverDownload(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	path := cached(version)
+	if err := extractDriver(archive, exe, path); err != nil {
+		return "", fmt.Errorf("wasmtest: %s: %w", url, err)
+	}
+	return path, nil
+}
+
+// driverDownload returns the body of url.
+func driverDownload(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("wasmtest: downloading the driver: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("wasmtest: downloading the driver: GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// extractDriver writes the file named exe of a zip or tar.gz archive to
+// path, executable.
+func extractDriver(archive []byte, exe, path string) error {
+	var r io.Reader
+	if bytes.HasPrefix(archive, []byte("PK")) {
+		zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			return err
+		}
+		for _, f := range zr.File {
+			if filepath.Base(f.Name) == exe && !f.FileInfo().IsDir() {
+				rc, err := f.Open()
+				if err != nil {
+					return err
+				}
+				defer rc.Close()
+				r = rc
+				break
+			}
+		}
+	} else {
+		gz, err := gzip.NewReader(bytes.NewReader(archive))
+		if err != nil {
+			return err
+		}
+		tr := tar.NewReader(gz)
+		for {
+			hdr, err := tr.Next()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				return err
+			}
+			if filepath.Base(hdr.Name) == exe && hdr.Typeflag == tar.TypeReg {
+				r = tr
+				break
+			}
+		}
+	}
+	if r == nil {
+		return fmt.Errorf("no %s in the archive", exe)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// startDriver starts the driver at path on a free local port and returns
+// its endpoint once it answers, with a function stopping it.
+func startDriver(ctx context.Context, path string) (endpoint string, stop func(), err error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, err
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	cmd := exec.Command(path, fmt.Sprintf("--port=%d", port))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return "", nil, err
+	}
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+	stop = func() {
+		cmd.Process.Kill()
+		<-exited
+	}
+
+	endpoint = fmt.Sprintf("http://127.0.0.1:%d", port)
+	deadline := time.After(driverStartTimeout)
+	for {
+		if err := webDriverRequest(ctx, http.MethodGet, endpoint+"/status", nil, nil); err == nil {
+			return endpoint, stop, nil
+		}
+		select {
+		case <-exited:
+			return "", nil, fmt.Errorf("wasmtest: %s exited: %s", filepath.Base(path), strings.TrimSpace(stderr.String()))
+		case <-deadline:
+			stop()
+			return "", nil, fmt.Errorf("wasmtest: %s did not answer on port %d within %v", filepath.Base(path), port, driverStartTimeout)
+		case <-ctx.Done():
+			stop()
+			return "", nil, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// managedDriverCapabilities returns the capabilities of the sessions of a
+// managed driver running the browser at path: those of WithBrowserFlags,
+// with the browser binary and headless mode.
+func (w *Wasmtest) managedDriverCapabilities(browser string) map[string]any {
+	if w.webDriverCaps != nil {
+		return w.webDriverCaps
+	}
+	args := append([]string{}, w.browserFlags...)
+	if browserFamily(browser) == BrowserFirefox {
+		if !w.headful {
+			args = append(args, "-headless")
+		}
+		return map[string]any{"browserName": "firefox", "moz:firefoxOptions": map[string]any{"binary": browser, "args": args}}
+	}
+	if !w.headful {
+		args = append(args, "--headless=new")
+	}
+	return map[string]any{"browserName": "chrome", "goog:chromeOptions": map[string]any{"binary": browser, "args": args}}
+}
@@ -0,0 +1,152 @@
+package wasmtest
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeChrome returns a chrome shim printing version on --version and
+// otherwise opening its URL with the node browser.
+func fakeChrome(t *testing.T, version string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "chrome")
+	script := fmt.Sprintf("#!/bin/sh\n[ \"$1\" = --version ] && { echo 'Google Chrome %s '; exit 0; }\nexec %q \"$@\"\n", version, nodeBrowser(t))
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBrowserVersion(t *testing.T) {
+	chrome := fakeChrome(t, "120.0.6099.109")
+	if got, err := browserVersion(t.Context(), chrome); err != nil || got != "120.0.6099.109" {
+		t.Errorf("browserVersion() = %q, %v", got, err)
+	}
+	if _, err := webDriverName(BrowserEdge); err == nil {
+		t.Error("webDriverName accepted Edge")
+	}
+}
+
+func TestFindDriverDownloads(t *testing.T) {
+	if _, err := driverPlatform("chromedriver"); err != nil {
+		t.Skip(err)
+	}
+	chromePlatform, _ := driverPlatform("chromedriver")
+	geckoPlatform, _ := driverPlatform("geckodriver")
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	f, _ := zw.Create("chromedriver-" + chromePlatform + "/chromedriver")
+	f.Write([]byte("chromedriver 120"))
+	zw.Close()
+	var tarred bytes.Buffer
+	gz := gzip.NewWriter(&tarred)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: "geckodriver", Mode: 0o755, Size: 11, Typeflag: tar.TypeReg})
+	tw.Write([]byte("geckodriver"))
+	tw.Close()
+	gz.Close()
+
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		switch r.URL.Path {
+		case "/LATEST_RELEASE_120.0.6099":
+			rw.Write([]byte("120.0.6099.109\n"))
+		case "/120.0.6099.109/" + chromePlatform + "/chromedriver-" + chromePlatform + ".zip":
+			rw.Write(zipped.Bytes())
+		case "/v0.33.0/geckodriver-v0.33.0-" + geckoPlatform + ".tar.gz":
+			rw.Write(tarred.Bytes())
+		default:
+			http.NotFound(rw, r)
+		}
+	}))
+	defer srv.Close()
+	for _, v := range []*string{&chromeForTestingURL, &chromeDriverURL, &geckodriverURL} {
+		old := *v
+		*v = srv.URL
+		t.Cleanup(func() { *v = old })
+	}
+
+	cache := t.TempDir()
+	w := New(WithInstallDisabled(), WithManagedWebDriver(DriverOptions{CacheDir: cache}))
+	chrome := fakeChrome(t, "120.0.6099.62")
+	path, err := w.findDriver(t.Context(), chrome, func(...any) {})
+	if err != nil {
+		t.Fatalf("findDriver failed: %v (requests %q)", err, requests)
+	}
+	if data, _ := os.ReadFile(path); path != filepath.Join(cache, "chromedriver", "120.0.6099.109", "chromedriver") || string(data) != "chromedriver 120" {
+		t.Errorf("chromedriver at %s = %q", path, data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode()&0o111 == 0 {
+		t.Errorf("chromedriver not executable: %v", err)
+	}
+
+	// The cached driver of the build is reused offline.
+	srv.Close()
+	w = New(WithNoInstall(), WithManagedWebDriver(DriverOptions{CacheDir: cache}))
+	if again, err := w.findDriver(t.Context(), chrome, func(...any) {}); err != nil || again != path {
+		t.Errorf("cached findDriver = %q, %v", again, err)
+	}
+	if _, err := w.findDriver(t.Context(), fakeChrome(t, "121.0.6167.85"), func(...any) {}); !errors.Is(err, ErrRunnerMissing) {
+		t.Errorf("offline findDriver of another build = %v, want ErrRunnerMissing", err)
+	}
+
+	// A pinned geckodriver comes from its release archive.
+	srv = httptest.NewServer(srv.Config.Handler)
+	defer srv.Close()
+	geckodriverURL = srv.URL
+	firefox := filepath.Join(t.TempDir(), "firefox")
+	w = New(WithInstallDisabled(), WithManagedWebDriver(DriverOptions{CacheDir: cache, Version: "v0.33.0"}))
+	path, err = w.findDriver(t.Context(), firefox, func(...any) {})
+	if data, _ := os.ReadFile(path); err != nil || string(data) != "geckodriver" {
+		t.Errorf("geckodriver at %s = %q, %v", path, data, err)
+	}
+}
+
+func TestRunBundleManagedWebDriver(t *testing.T) {
+	chrome := fakeChrome(t, "120.0.6099.109")
+	script, err := filepath.Abs("testdata/nodedriver.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The driver of the Chrome build is already cached.
+	cache := t.TempDir()
+	driver := filepath.Join(cache, "chromedriver", "120.0.6099.71", "chromedriver")
+	if err := os.MkdirAll(filepath.Dir(driver), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(driver, []byte(fmt.Sprintf("#!/bin/sh\nexec node %q \"$@\"\n", script)), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	w := New(WithInstallDisabled(), WithBrowser(chrome), WithManagedWebDriver(DriverOptions{CacheDir: cache}))
+	caps := w.managedDriverCapabilities(chrome)["goog:chromeOptions"].(map[string]any)
+	if caps["binary"] != chrome || !strings.Contains(fmt.Sprint(caps["args"]), "--headless=new") {
+		t.Errorf("capabilities = %v", caps)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	out := t.TempDir()
+	if _, err := w.Bundle(ctx, "./example", out, "-test.run=TestMathHelper"); err != nil {
+		t.Fatalf("Bundle failed: %v", err)
+	}
+	progress, msgs := collectProgress()
+	if err := w.RunBundle(ctx, out, RunBundleOptions{}, progress); err != nil {
+		t.Fatalf("RunBundle failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	if got := strings.Join(msgs(), "\n"); !strings.Contains(got, "started "+driver) || !strings.Contains(got, "--- PASS: TestMathHelper") {
+		t.Errorf("unexpected progress:\n%s", got)
+	}
+}
@@ -64,7 +64,7 @@ func TestEnsureWasmBrowserTestInstalled_integration(t *testing.T) {
 
 	// Create Wasmtest; New will perform the installation/verification in the
 	// background. We wait here for a bit for it to complete.
-	w := New(logger)
+	w := New(WithLogger(logger))
 
 	// Use w to ensure it's referenced (and potentially to retrieve state).
 	_ = w.GetLastOperationID()
@@ -0,0 +1,102 @@
+package wasmtest
+
+import (
+	"runtime"
+	"strings"
+)
+
+// unsupportedGoFlags are GOFLAGS entries that can't be used when building
+// for js/wasm; inherited from the parent environment they would make every
+// build fail.
+var unsupportedGoFlags = []string{"-race", "-msan", "-asan"}
+
+// childEnv builds the environment of the go commands started by this
+// package. The parent environment is copied without its GOOS and GOARCH,
+// goos/goarch are set explicitly, and extra KEY=VALUE entries replace any
+// entry with the same key instead of being appended after it. When
+// targeting wasm, flags it doesn't support are removed from GOFLAGS,
+// whether it comes from the parent environment or from extra.
+func childEnv(parent []string, goos, goarch string, extra []string) []string {
+	env := make([]string, 0, len(parent)+len(extra)+2)
+	for _, kv := range parent {
+		switch envKey(kv) {
+		case "GOOS", "GOARCH":
+			continue
+		}
+		env = append(env, kv)
+	}
+	env = append(env, "GOOS="+goos, "GOARCH="+goarch)
+	for _, kv := range extra {
+		env = setEnv(env, kv)
+	}
+	if goarch == "wasm" {
+		for i, kv := range env {
+			if envKey(kv) == "GOFLAGS" {
+				env[i] = "GOFLAGS=" + filterGoFlags(kv[len("GOFLAGS="):])
+			}
+		}
+	}
+	return env
+}
+
+// setEnv replaces the entry of env with the same key as kv, or appends kv.
+func setEnv(env []string, kv string) []string {
+	key := envKey(kv)
+	for i, cur := range env {
+		if envKey(cur) == key {
+			env[i] = kv
+			return env
+		}
+	}
+	return append(env, kv)
+}
+
+// lookupEnv returns the value of key in env, or "" if it is not set.
+func lookupEnv(env []string, key string) string {
+	value := ""
+	for _, kv := range env {
+		if envKey(kv) == key {
+			_, value, _ = strings.Cut(kv, "=")
+		}
+	}
+	return value
+}
+
+// envKey returns the key of a KEY=VALUE entry. Keys are case-insensitive on
+// Windows so they are upper-cased there.
+func envKey(kv string) string {
+	key, _, _ := strings.Cut(kv, "=")
+	if runtime.GOOS == "windows" {
+		key = strings.ToUpper(key)
+	}
+	return key
+}
+
+// filterGoFlags drops the flags listed in unsupportedGoFlags from a GOFLAGS
+// value.
+func filterGoFlags(goflags string) string {
+	var kept []string
+	for _, f := range strings.Fields(goflags) {
+		name, _, _ := strings.Cut(f, "=")
+		unsupported := false
+		for _, u := range unsupportedGoFlags {
+			if name == u || name == "-"+u {
+				unsupported = true
+			}
+		}
+		if !unsupported {
+			kept = append(kept, f)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// SetEnvHook registers a function called with the working directory and the
+// final environment of every go test process started by Execute or
+// Orchestrate, before it starts. It is meant for debugging env issues; the
+// hook must not modify env.
+func (w *Wasmtest) SetEnvHook(hook func(dir string, env []string)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.envHook = hook
+}
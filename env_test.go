@@ -0,0 +1,65 @@
+package wasmtest
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestChildEnv(t *testing.T) {
+	parent := []string{
+		"GOOS=linux",
+		"HOME=/home/me",
+		"GOFLAGS=-mod=mod -race -tags=dev",
+		"GOARCH=amd64",
+		"WASM_HEADLESS=on",
+	}
+	got := childEnv(parent, "js", "wasm", []string{"WASM_HEADLESS=off", "EXTRA=1"})
+	want := []string{
+		"HOME=/home/me",
+		"GOFLAGS=-mod=mod -tags=dev",
+		"WASM_HEADLESS=off",
+		"GOOS=js",
+		"GOARCH=wasm",
+		"EXTRA=1",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("childEnv() =\n%q\nwant\n%q", got, want)
+	}
+
+	// Explicit entries win over the js/wasm defaults.
+	got = childEnv(nil, "js", "wasm", []string{"GOOS=wasip1"})
+	if !slices.Equal(got, []string{"GOOS=wasip1", "GOARCH=wasm"}) {
+		t.Errorf("childEnv() with GOOS override = %q", got)
+	}
+
+	// GOFLAGS set explicitly, as by a spec env, is filtered too.
+	got = childEnv([]string{"GOFLAGS=-mod=mod"}, "js", "wasm", []string{"GOFLAGS=-race -tags=dev"})
+	if !slices.Equal(got, []string{"GOFLAGS=-tags=dev", "GOOS=js", "GOARCH=wasm"}) {
+		t.Errorf("childEnv() with GOFLAGS override = %q", got)
+	}
+	t.Setenv("GOFLAGS", "-mod=mod")
+	spec := execSpec{env: []string{"GOFLAGS=-race -tags=dev"}}
+	if flags := lookupEnv(spec.environ(), "GOFLAGS"); flags != "-tags=dev" {
+		t.Errorf("GOFLAGS of a spec env = %q", flags)
+	}
+
+	// Native targets keep GOFLAGS untouched.
+	got = childEnv([]string{"GOFLAGS=-race"}, "linux", "amd64", nil)
+	if !slices.Equal(got, []string{"GOFLAGS=-race", "GOOS=linux", "GOARCH=amd64"}) {
+		t.Errorf("native childEnv() = %q", got)
+	}
+}
+
+func TestFilterGoFlags(t *testing.T) {
+	cases := map[string]string{
+		"":                          "",
+		"-race":                     "",
+		"--race -v":                 "-v",
+		"-mod=mod -msan -asan=1 -x": "-mod=mod -x",
+	}
+	for in, want := range cases {
+		if got := filterGoFlags(in); got != want {
+			t.Errorf("filterGoFlags(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package wasmtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Sentinel errors returned (wrapped) by RunTests and the other runners, for
+// use with errors.Is. The returned errors keep their detailed messages.
+var (
+	// ErrDirNotFound means the test directory does not exist.
+	ErrDirNotFound = errors.New("wasmtest: test directory not found")
+	// ErrNoWasmTests means the directory has no test file with the js/wasm
+	// build constraint.
+	ErrNoWasmTests = errors.New("wasmtest: no js/wasm test files found")
+	// ErrTimeout means the run was stopped because it exceeded its timeout.
+	ErrTimeout = errors.New("wasmtest: test run timed out")
+	// ErrRunnerMissing means go test could not find the program running
+	// the js/wasm test binary (wasmbrowsertest or go_js_wasm_exec).
+	ErrRunnerMissing = errors.New("wasmtest: js/wasm test runner not found")
+	// ErrUsage means the run was given invalid arguments or settings, e.g.
+	// an unsupported RunTests argument, a configuration file that doesn't
+	// parse or a shard index out of range.
+	ErrUsage = errors.New("wasmtest: invalid arguments")
+)
+
+// TestFailureError is returned when the tests ran but did not pass. Use
+// errors.As to get the failing tests.
+type TestFailureError struct {
+	// Dir is the directory of the tests.
+	Dir string
+	// Failed lists the failing tests, subtests included. It is empty when
+	// the package failed without a failing test, e.g. on build errors.
+	Failed []string
+	// ExitCode is the exit code of the go test process, or -1.
+	ExitCode int
+	// BuildFailed reports that the test binary could not be built, e.g.
+	// on compile or vet errors, so no test ran.
+	BuildFailed bool
+
+	msg string
+}
+
+func (e *TestFailureError) Error() string {
+	if e.msg != "" {
+		return e.msg
+	}
+	if len(e.Failed) == 0 {
+		return fmt.Sprintf("wasmtest: tests failed in directory %s", e.Dir)
+	}
+	return fmt.Sprintf("wasmtest: tests failed in directory %s: %s", e.Dir, strings.Join(e.Failed, ", "))
+}
+
+// runError carries a human friendly message while matching a sentinel
+// error with errors.Is. It unwraps to the error its message wraps, if any.
+type runError struct {
+	sentinel error
+	msg      string
+	cause    error
+}
+
+// newRunError returns an error matching sentinel whose message is formatted
+// from format and args, as by fmt.Errorf.
+func newRunError(sentinel error, format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	return &runError{sentinel: sentinel, msg: err.Error(), cause: errors.Unwrap(err)}
+}
+
+func (e *runError) Error() string        { return e.msg }
+func (e *runError) Is(target error) bool { return target == e.sentinel }
+func (e *runError) Unwrap() error        { return e.cause }
+
+// runnerMissing reports whether an output line says the js/wasm exec
+// program could not be found. Without go_js_wasm_exec in PATH, go test
+// runs the js/wasm test binary itself, which fails with "exec format
+// error".
+func runnerMissing(line string) bool {
+	if strings.HasPrefix(line, "fork/exec ") && strings.HasSuffix(strings.TrimSpace(line), ".test: exec format error") {
+		return true
+	}
+	return (strings.Contains(line, "go_js_wasm_exec") || strings.Contains(line, "wasmbrowsertest")) &&
+		(strings.Contains(line, "executable file not found") || strings.Contains(line, "no such file or directory"))
+}
+
+// buildFailed reports whether an output line is the go test summary of a
+// package that could not be built.
+func buildFailed(line string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, "FAIL") && (strings.HasSuffix(line, "[build failed]") || strings.HasSuffix(line, "[setup failed]"))
+}
+
+// Exit codes of the wasmtest command, returned by ExitCode so that
+// wrappers exit the same way.
+const (
+	// ExitOK means the tests passed.
+	ExitOK = 0
+	// ExitTestsFailed means the tests ran and some of them failed.
+	ExitTestsFailed = 1
+	// ExitBuildFailed means the tests could not be built, or there were
+	// no js/wasm tests to build. The command also exits with it on
+	// invalid command lines, arguments and settings (ErrUsage).
+	ExitBuildFailed = 2
+	// ExitEnvironment means the tests could not run on this host, e.g.
+	// because the js/wasm runner or the browser is missing.
+	ExitEnvironment = 3
+	// ExitTimeout means the run exceeded its timeout.
+	ExitTimeout = 4
+	// ExitCanceled means the run was canceled, e.g. by an interrupt, the
+	// code shells report for SIGINT.
+	ExitCanceled = 130
+)
+
+// ExitCode returns the exit code for err, an error returned by RunTests or
+// the other runners: ExitOK for nil, ExitTimeout, ExitCanceled,
+// ExitBuildFailed (also for ErrUsage) or ExitTestsFailed as the error says,
+// and ExitEnvironment for the errors keeping the tests from running. Of
+// joined errors, e.g. those of several packages, the highest code wins.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		code := ExitOK
+		for _, err := range joined.Unwrap() {
+			code = max(code, ExitCode(err))
+		}
+		return code
+	}
+	var failure *TestFailureError
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return ExitTimeout
+	case errors.Is(err, context.Canceled):
+		return ExitCanceled
+	case errors.Is(err, ErrRunnerMissing):
+		return ExitEnvironment
+	case errors.Is(err, ErrUsage), errors.Is(err, ErrDirNotFound), errors.Is(err, ErrNoWasmTests):
+		return ExitBuildFailed
+	case errors.As(err, &failure):
+		if failure.BuildFailed {
+			return ExitBuildFailed
+		}
+		return ExitTestsFailed
+	case errors.As(err, &exitErr):
+		// The test process ran, as with RunBinary, and failed.
+		return ExitTestsFailed
+	}
+	return ExitEnvironment
+}
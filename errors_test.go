@@ -0,0 +1,139 @@
+package wasmtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	err := RunTests("./does-not-exist", func(...any) {})
+	if !errors.Is(err, ErrDirNotFound) || !strings.Contains(err.Error(), "DIRECTORY ERROR") {
+		t.Errorf("missing dir: %v", err)
+	}
+
+	dir := writeModule(t, map[string]string{"native_test.go": "package tmp\n"})
+	if err := RunTests(dir, func(...any) {}); !errors.Is(err, ErrNoWasmTests) {
+		t.Errorf("no wasm tests: %v", err)
+	}
+
+	for line, want := range map[string]bool{
+		`go: exec: "go_js_wasm_exec": executable file not found in $PATH`:      true,
+		"fork/exec /tmp/go-build4272469422/b001/tmp.test: exec format error\n": true,
+		"--- FAIL: TestX":                    false,
+		"    x_test.go:9: exec format error": false,
+	} {
+		if got := runnerMissing(line); got != want {
+			t.Errorf("runnerMissing(%q) = %v, want %v", line, got, want)
+		}
+	}
+}
+
+func TestRunTestsExecFormatError(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go not in PATH")
+	}
+	dir := writeModule(t, map[string]string{"p_test.go": wasmPassTest})
+
+	// Without go_js_wasm_exec in PATH, go test runs the js/wasm binary.
+	var logs []string
+	err = RunTests(dir, func(a ...any) { logs = append(logs, fmt.Sprint(a...)) },
+		ExecOptions{Env: []string{"PATH=" + filepath.Dir(goBin)}}, WithInstallDisabled())
+	if !errors.Is(err, ErrRunnerMissing) || ExitCode(err) != ExitEnvironment {
+		t.Fatalf("RunTests = %v (exit %d), want ErrRunnerMissing", err, ExitCode(err))
+	}
+	if out := strings.Join(logs, "\n"); !strings.Contains(out, "doctor") {
+		t.Errorf("no doctor report after the exec format error:\n%s", out)
+	}
+}
+
+func TestTestFailureError(t *testing.T) {
+	node := nodeExec(t)
+	dir := writeModule(t, map[string]string{
+		"fail_test.go": "//go:build js && wasm\n\npackage tmp\n\nimport \"testing\"\n\nfunc TestOK(t *testing.T) {}\n\nfunc TestBad(t *testing.T) { t.Fatal(\"bad\") }\n",
+	})
+
+	err := RunTests(dir, func(...any) {}, ExecOptions{Args: []string{"-exec", node}}, WithInstallDisabled())
+	var failure *TestFailureError
+	if !errors.As(err, &failure) {
+		t.Fatalf("RunTests() = %v, want a TestFailureError", err)
+	}
+	if !slices.Equal(failure.Failed, []string{"TestBad"}) || failure.ExitCode != 1 || failure.Error() != err.Error() {
+		t.Errorf("unexpected failure: %+v", failure)
+	}
+	if code := ExitCode(err); code != ExitTestsFailed {
+		t.Errorf("ExitCode(failure) = %d, want %d", code, ExitTestsFailed)
+	}
+
+	broken := writeModule(t, map[string]string{
+		"broken_test.go": "//go:build js && wasm\n\npackage tmp\n\nimport \"testing\"\n\nfunc TestBroken(t *testing.T) { undefined() }\n",
+	})
+	err = RunTests(broken, func(...any) {}, ExecOptions{Args: []string{"-exec", node}}, WithInstallDisabled())
+	if !errors.As(err, &failure) || !failure.BuildFailed || ExitCode(err) != ExitBuildFailed {
+		t.Errorf("RunTests() = %v, want a build failure", err)
+	}
+
+	hang := writeModule(t, map[string]string{
+		"hang_test.go": "//go:build js && wasm\n\npackage tmp\n\nimport (\n\t\"testing\"\n\t\"time\"\n)\n\nfunc TestHang(t *testing.T) { time.Sleep(time.Hour) }\n",
+	})
+	err = RunTests(hang, func(...any) {}, 3*time.Second, ExecOptions{Args: []string{"-exec", node}}, WithInstallDisabled())
+	if !errors.Is(err, ErrTimeout) || ExitCode(err) != ExitTimeout {
+		t.Errorf("RunTests() = %v, want ErrTimeout", err)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	badConfig := writeModule(t, map[string]string{"wasmtest.yaml": "timeout: soon\n"})
+	t.Chdir(badConfig)
+	_, configErr := RunTestsResult(func(...any) {})
+	t.Chdir(t.TempDir())
+	_, argErr := RunTestsResult(func(...any) {}, 42)
+	_, shardErr := RunTestsResult(func(...any) {}, Shard{Index: 3, Total: 2})
+	for _, err := range []error{configErr, argErr, shardErr} {
+		if !errors.Is(err, ErrUsage) {
+			t.Errorf("%v is not ErrUsage", err)
+		}
+	}
+
+	for _, tt := range []struct {
+		err  error
+		want int
+	}{
+		{nil, ExitOK},
+		{&TestFailureError{Failed: []string{"TestX"}}, ExitTestsFailed},
+		{fmt.Errorf("wrapped: %w", &TestFailureError{BuildFailed: true}), ExitBuildFailed},
+		{newRunError(ErrNoWasmTests, "no tests"), ExitBuildFailed},
+		{newRunError(ErrRunnerMissing, "no runner"), ExitEnvironment},
+		{errors.New("browser not found"), ExitEnvironment},
+		{newRunError(ErrTimeout, "timed out"), ExitTimeout},
+		{errors.Join(&TestFailureError{}, newRunError(ErrTimeout, "timed out"), &TestFailureError{BuildFailed: true}), ExitTimeout},
+		{errors.Join(&TestFailureError{}, &TestFailureError{BuildFailed: true}), ExitBuildFailed},
+		{newRunError(ErrUsage, "bad flag"), ExitBuildFailed},
+		{configErr, ExitBuildFailed},
+		{argErr, ExitBuildFailed},
+		{shardErr, ExitBuildFailed},
+		{fmt.Errorf("canceled: %w", context.Canceled), ExitCanceled},
+		{errors.Join(&TestFailureError{}, fmt.Errorf("canceled: %w", context.Canceled)), ExitCanceled},
+	} {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+
+	// The sentinel of a run error doesn't hide the error its message wraps.
+	if err := newRunError(ErrUsage, "config: %w", os.ErrNotExist); !errors.Is(err, ErrUsage) || !errors.Is(err, os.ErrNotExist) || err.Error() != "config: "+os.ErrNotExist.Error() {
+		t.Errorf("newRunError wrapping os.ErrNotExist = %v", err)
+	}
+
+	if !buildFailed("FAIL\texample.com/tmp [build failed]") || !buildFailed("FAIL\texample.com/tmp [setup failed]") || buildFailed("FAIL\texample.com/tmp\t0.1s") {
+		t.Error("buildFailed doesn't match the go test summary lines")
+	}
+}
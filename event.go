@@ -0,0 +1,170 @@
+package wasmtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventKind is the kind of a ProgressEvent.
+type EventKind int
+
+const (
+	// EventStdout is a line written to stdout by the tests.
+	EventStdout EventKind = iota
+	// EventStderr is a line written to stderr by the tests or go test.
+	EventStderr
+	// EventInfo is an informational message of wasmtest itself.
+	EventInfo
+	// EventWarning is a non fatal problem; Data holds a Warning when the
+	// warning is structured.
+	EventWarning
+	// EventError is a problem that stops the run.
+	EventError
+	// EventExit ends a run; Message is "ok" on success.
+	EventExit
+	// EventCompile reports the test binary build; Data holds CompileStats.
+	EventCompile
+	// EventTest reports a test starting or finishing, as recorded by go
+	// test -json; Data holds the TestEvent.
+	EventTest
+	// EventDebug is an internal trace of wasmtest, only reported with
+	// WithVerbosity(Debug).
+	EventDebug
+	// EventList holds the top level tests about to run, as reported by go
+	// test -list; Data holds the []string of names.
+	EventList
+	// EventConsole is a call to the browser console; Data holds the
+	// ConsoleMessage with its level.
+	EventConsole
+)
+
+// eventTags maps kinds to the tags of the untyped progress messages.
+var eventTags = map[EventKind]string{
+	EventStdout:  "out",
+	EventStderr:  "err",
+	EventInfo:    "info",
+	EventWarning: "warning",
+	EventError:   "error",
+	EventExit:    "exit",
+	EventCompile: "compile",
+	EventTest:    "test",
+	EventDebug:   "debug",
+	EventList:    "list",
+	EventConsole: "console",
+}
+
+// String returns the progress message tag of k, e.g. "out" or "exit".
+func (k EventKind) String() string {
+	if tag, ok := eventTags[k]; ok {
+		return tag
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+// MarshalText writes k as its tag.
+func (k EventKind) MarshalText() ([]byte, error) { return []byte(k.String()), nil }
+
+// UnmarshalText parses a tag written by MarshalText.
+func (k *EventKind) UnmarshalText(text []byte) error {
+	for kind, tag := range eventTags {
+		if tag == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("wasmtest: unknown event kind %q", text)
+}
+
+// ProgressEvent is the typed form of a progress message. In its JSON form,
+// which follows SchemaVersion, Kind is written as its tag (e.g. "out").
+type ProgressEvent struct {
+	Kind EventKind `json:"kind"`
+	// Message is the text of the event: the output line for stdout and
+	// stderr, "ok" or "error" followed by the details for exit events.
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+	// TestName is the test the event belongs to, when known: the test
+	// started, finished or running while an output line was written.
+	TestName string `json:"testName,omitempty"`
+	// Package is the directory of the package the event belongs to, in
+	// the runs of RunTests, whose packages may run at once (see Parallel).
+	Package string `json:"package,omitempty"`
+	// Data holds the structured value of the message, if any, e.g.
+	// CompileStats or Warning.
+	Data any `json:"data,omitempty"`
+}
+
+// MarshalJSON adds the schema version to each event, so that a stream of
+// events can be parsed without any other document.
+func (e ProgressEvent) MarshalJSON() ([]byte, error) {
+	type event ProgressEvent
+	return json.Marshal(struct {
+		SchemaVersion int `json:"schemaVersion"`
+		event
+	}{SchemaVersion, event(e)})
+}
+
+// Passed reports whether e is a successful exit event.
+func (e ProgressEvent) Passed() bool {
+	return e.Kind == EventExit && e.Message == "ok"
+}
+
+// String renders e like the corresponding untyped progress message.
+func (e ProgressEvent) String() string {
+	return e.Kind.String() + " " + e.Message
+}
+
+// ParseProgress converts an untyped progress message into a ProgressEvent.
+// Unknown tags are reported as EventInfo with the tag kept in Message.
+func ParseProgress(msgs ...any) ProgressEvent {
+	ev := ProgressEvent{Kind: EventInfo, Timestamp: time.Now()}
+	if len(msgs) == 0 {
+		return ev
+	}
+	tag := fmt.Sprint(msgs[0])
+	rest := msgs[1:]
+	found := false
+	for kind, t := range eventTags {
+		if t == tag {
+			ev.Kind, found = kind, true
+			break
+		}
+	}
+	if !found {
+		rest = msgs
+	}
+	if len(rest) == 1 {
+		switch v := rest[0].(type) {
+		case CompileStats, Warning, TestEvent, []string:
+			ev.Data = v
+		case ConsoleMessage:
+			ev.Data = v
+			ev.TestName = v.Test
+		case OutputLine:
+			ev.TestName = v.Test
+		}
+	}
+	ev.Message = strings.TrimSuffix(fmt.Sprintln(rest...), "\n")
+	return ev
+}
+
+// ProgressFunc adapts fn to the untyped progress callback accepted by
+// Execute and the other runners. It also keeps track of the running test,
+// from the EventTest events, to fill ProgressEvent.TestName. The returned
+// callback is safe for concurrent use.
+func ProgressFunc(fn func(ProgressEvent)) func(msgs ...any) {
+	var mu sync.Mutex
+	var tracker testTracker
+	return func(msgs ...any) {
+		ev := ParseProgress(msgs...)
+		mu.Lock()
+		defer mu.Unlock()
+		if t, ok := tracker.event(msgs...); ok && t.Test != "" {
+			ev.TestName = t.Test
+		}
+		fn(ev)
+	}
+}
@@ -0,0 +1,65 @@
+package wasmtest
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseProgress(t *testing.T) {
+	stats := CompileStats{Package: "example.com/pkg"}
+	cases := []struct {
+		msgs    []any
+		kind    EventKind
+		message string
+		data    bool
+	}{
+		{[]any{"out", "--- PASS: TestA (0.00s)"}, EventStdout, "--- PASS: TestA (0.00s)", false},
+		{[]any{"err", "exit status 1"}, EventStderr, "exit status 1", false},
+		{[]any{"error", "failed to start go test:", errors.New("boom")}, EventError, "failed to start go test: boom", false},
+		{[]any{"warning", raceWarning}, EventWarning, raceWarning.String(), true},
+		{[]any{"compile", stats}, EventCompile, stats.String(), true},
+		{[]any{"exit", "ok"}, EventExit, "ok", false},
+		{[]any{"something", "else"}, EventInfo, "something else", false},
+	}
+	for _, c := range cases {
+		ev := ParseProgress(c.msgs...)
+		if ev.Kind != c.kind || ev.Message != c.message || (ev.Data != nil) != c.data {
+			t.Errorf("ParseProgress(%v) = %+v, want kind %v and message %q", c.msgs, ev, c.kind, c.message)
+		}
+		if ev.Timestamp.IsZero() {
+			t.Errorf("ParseProgress(%v) has no timestamp", c.msgs)
+		}
+	}
+	if !ParseProgress("exit", "ok").Passed() || ParseProgress("exit", "error", "exit status 1").Passed() {
+		t.Error("Passed() doesn't match the exit status")
+	}
+}
+
+func TestProgressFuncTestName(t *testing.T) {
+	var got []ProgressEvent
+	progress := ProgressFunc(func(ev ProgressEvent) { got = append(got, ev) })
+	for _, msg := range [][]any{
+		{"test", TestEvent{Action: "run", Test: "TestA"}},
+		{"out", "=== RUN   TestA"},
+		{"out", "    a_test.go:10: hello"},
+		{"out", "--- PASS: TestA (0.00s)"},
+		{"test", TestEvent{Action: "pass", Test: "TestA"}},
+		{"out", "PASS"},
+		{"exit", "ok"},
+	} {
+		progress(msg...)
+	}
+
+	want := []string{"TestA", "TestA", "TestA", "TestA", "TestA", "", ""}
+	for i, ev := range got {
+		if ev.TestName != want[i] {
+			t.Errorf("event %d (%s) TestName = %q, want %q", i, ev, ev.TestName, want[i])
+		}
+	}
+	if got[0].Kind != EventTest || got[0].Kind.String() != "test" || got[0].Data == nil {
+		t.Errorf("first event = %+v, want a test event", got[0])
+	}
+	if got[6].Kind != EventExit || got[6].Kind.String() != "exit" {
+		t.Errorf("last event = %+v, want an exit event", got[6])
+	}
+}
@@ -0,0 +1,136 @@
+package wasmtest
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExecOptions controls the go test invocation of ExecuteWithOptions. The
+// zero value runs `go test -json` in the directory set with WithTestDir.
+type ExecOptions struct {
+	// Dir overrides the test directory set with WithTestDir.
+	Dir string
+	// Run is passed as -run, selecting the tests to run. Defaults to the
+	// pattern set with WithRun.
+	Run string
+	// Skip is passed as -skip, excluding the matching tests, e.g. known
+	// broken browser tests in one environment. Defaults to the pattern set
+	// with WithSkip.
+	Skip string
+	// Count is passed as -count, e.g. to repeat tests for stability
+	// checks; 0 leaves the go test default.
+	Count int
+	// Bench is passed as -bench, selecting the benchmarks to run (e.g. "."
+	// for all of them). Benchmarks don't run when it is empty.
+	Bench string
+	// BenchTime is passed as -benchtime, e.g. "2s" or "100x".
+	BenchTime string
+	// Benchmem passes -benchmem, reporting allocations of benchmarks.
+	Benchmem bool
+	// Shuffle is passed as -shuffle: "on" randomizes the order of tests and
+	// benchmarks to catch hidden interdependencies, e.g. in DOM state, and a
+	// number replays the order of that seed. The seed in use is reported as
+	// an info progress message and stored in RunResult.ShuffleSeed.
+	Shuffle string
+	// Timeout is passed as -timeout, bounding the test binary (not the
+	// whole run, see WithTimeout); 0 leaves the go test default.
+	Timeout time.Duration
+	// Tags are passed as -tags, e.g. to run tests gated behind
+	// //go:build js && wasm && integration. Defaults to the tags set with
+	// WithTags.
+	Tags []string
+	// Ldflags is passed as -ldflags.
+	Ldflags string
+	// Env holds extra KEY=VALUE entries for the go test process.
+	Env []string
+	// Args holds any other go test flags, appended after the ones above.
+	Args []string
+	// TestArgs are passed to the test binary untouched, after the -args
+	// of go test, for flags wasmtest doesn't model such as the custom
+	// flags of the tests. tinygo runs don't get them.
+	TestArgs []string
+	// Target is the build target, TargetJS or TargetWASIP1. Defaults to
+	// the one set with WithTarget.
+	Target string
+}
+
+// args returns the go test flags described by o.
+func (o ExecOptions) args() []string {
+	var args []string
+	if o.Run != "" {
+		args = append(args, "-run", o.Run)
+	}
+	if o.Skip != "" {
+		args = append(args, "-skip", o.Skip)
+	}
+	if o.Count > 0 {
+		args = append(args, "-count", strconv.Itoa(o.Count))
+	}
+	if o.Bench != "" {
+		args = append(args, "-bench", o.Bench)
+	}
+	if o.BenchTime != "" {
+		args = append(args, "-benchtime", o.BenchTime)
+	}
+	if o.Benchmem {
+		args = append(args, "-benchmem")
+	}
+	if o.Shuffle != "" {
+		args = append(args, "-shuffle", o.Shuffle)
+	}
+	if o.Timeout > 0 {
+		args = append(args, "-timeout", o.Timeout.String())
+	}
+	if len(o.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(o.Tags, ","))
+	}
+	if o.Ldflags != "" {
+		args = append(args, "-ldflags", o.Ldflags)
+	}
+	return append(args, o.Args...)
+}
+
+// spec returns the execSpec running o for w.
+func (o ExecOptions) spec(w *Wasmtest) execSpec {
+	dir := o.Dir
+	if dir == "" {
+		dir = w.testDir
+	}
+	o = o.withDefaults(w)
+	return execSpec{dir: dir, env: o.Env, args: o.args(), testArgs: o.TestArgs, tags: o.Tags, target: o.Target, toolchain: w.toolchain}
+}
+
+// withDefaults returns o with the filters and the build tags set with
+// WithRun, WithSkip and WithTags in place of the ones it leaves empty.
+func (o ExecOptions) withDefaults(w *Wasmtest) ExecOptions {
+	if o.Run == "" {
+		o.Run = w.runFilter
+	}
+	if o.Skip == "" {
+		o.Skip = w.skipFilter
+	}
+	if len(o.Tags) == 0 {
+		o.Tags = w.tags
+	}
+	return o
+}
+
+// ExecuteWithOptions is like Execute but passes the flags of opts to go
+// test, e.g. to select tests or set build tags, and returns the error of
+// the go test process (nil when the tests passed).
+func (w *Wasmtest) ExecuteWithOptions(opts ExecOptions, progress func(msgs ...any)) error {
+	if progress == nil {
+		progress = func(...any) {}
+	}
+	ctx, cancel := contextWithTimeout(w.timeout)
+	defer cancel()
+
+	if err := w.execute(ctx, opts.spec(w), progress); err != nil {
+		if ctx.Err() != nil {
+			return newRunError(ErrTimeout, "wasmtest: tests timed out after %v: %v", w.timeout, err)
+		}
+		return err
+	}
+	return nil
+}
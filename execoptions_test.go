@@ -0,0 +1,165 @@
+package wasmtest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecOptionsArgs(t *testing.T) {
+	opts := ExecOptions{
+		Run:     "TestDOM",
+		Skip:    "TestFlaky",
+		Count:   1,
+		Shuffle: "42",
+		Timeout: 30 * time.Second,
+		Tags:    []string{"a", "b"},
+		Ldflags: "-s -w",
+		Args:    []string{"-short"},
+	}
+	want := []string{"-run", "TestDOM", "-skip", "TestFlaky", "-count", "1", "-shuffle", "42", "-timeout", "30s", "-tags", "a,b", "-ldflags", "-s -w", "-short"}
+	if got := opts.args(); !slices.Equal(got, want) {
+		t.Errorf("args() = %q, want %q", got, want)
+	}
+	bench := ExecOptions{Run: "^$", Bench: ".", BenchTime: "100x", Benchmem: true}
+	if got := bench.args(); !slices.Equal(got, []string{"-run", "^$", "-bench", ".", "-benchtime", "100x", "-benchmem"}) {
+		t.Errorf("bench args() = %q", got)
+	}
+	if got := (ExecOptions{}).args(); len(got) != 0 {
+		t.Errorf("zero ExecOptions args() = %q", got)
+	}
+
+	w := New(WithInstallDisabled(), WithTestDir("./wasm_tests"))
+	if spec := (ExecOptions{}).spec(w); spec.dir != "./wasm_tests" {
+		t.Errorf("spec dir = %q, want the WithTestDir directory", spec.dir)
+	}
+	if spec := (ExecOptions{Dir: "./example"}).spec(w); spec.dir != "./example" {
+		t.Errorf("spec dir = %q, want ./example", spec.dir)
+	}
+
+	w = New(WithInstallDisabled(), WithRun("TestDOM"))
+	if spec := (ExecOptions{}).spec(w); !slices.Equal(spec.args, []string{"-run", "TestDOM"}) {
+		t.Errorf("WithRun not applied: %q", spec.args)
+	}
+	if spec := (ExecOptions{Run: "TestOther"}).spec(w); !slices.Equal(spec.args, []string{"-run", "TestOther"}) {
+		t.Errorf("ExecOptions.Run didn't override WithRun: %q", spec.args)
+	}
+
+	w = New(WithInstallDisabled(), WithSkip("TestBroken"))
+	if spec := (ExecOptions{}).spec(w); !slices.Equal(spec.args, []string{"-skip", "TestBroken"}) {
+		t.Errorf("WithSkip not applied: %q", spec.args)
+	}
+	if spec := (ExecOptions{Skip: "TestOther"}).spec(w); !slices.Equal(spec.args, []string{"-skip", "TestOther"}) {
+		t.Errorf("ExecOptions.Skip didn't override WithSkip: %q", spec.args)
+	}
+}
+
+func TestExecuteWithOptions(t *testing.T) {
+	node := nodeExec(t)
+	w := New(WithInstallDisabled(), WithLogger(func(...any) {}))
+	progress, msgs := collectProgress()
+	opts := ExecOptions{Dir: "./example", Run: "TestMathHelper", Args: []string{"-exec", node}}
+	if err := w.ExecuteWithOptions(opts, progress); err != nil {
+		t.Fatalf("ExecuteWithOptions failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	got := msgs()
+	if !slices.Contains(got, "out === RUN   TestMathHelper") || slices.ContainsFunc(got, func(m string) bool { return strings.Contains(m, "TestJSInterop") }) {
+		t.Errorf("-run was not applied:\n%s", strings.Join(got, "\n"))
+	}
+
+	opts.Run = "TestDoesNotExist"
+	opts.Args = append(opts.Args, "-count", "x")
+	if err := w.ExecuteWithOptions(opts, progress); err == nil {
+		t.Error("ExecuteWithOptions accepted an invalid -count")
+	}
+}
+
+func TestExecOptionsTestArgs(t *testing.T) {
+	node := nodeExec(t)
+	dir := writeModule(t, map[string]string{"p_test.go": `//go:build js && wasm
+
+package p
+
+import (
+	"flag"
+	"testing"
+)
+
+var myflag = flag.String("myflag", "", "")
+
+func TestFlag(t *testing.T) {
+	t.Log("myflag=" + *myflag)
+}
+`})
+	opts := ExecOptions{Args: []string{"-exec", node}, TestArgs: []string{"-test.v", "-myflag", "bar baz"}}
+	res, err := RunTestsResult(dir, func(...any) {}, opts, WithInstallDisabled())
+	if err != nil {
+		t.Fatalf("RunTestsResult failed: %v\n%s", err, strings.Join(res.RawOutput, "\n"))
+	}
+	if !slices.ContainsFunc(res.RawOutput, func(l string) bool { return strings.Contains(l, "myflag=bar baz") }) {
+		t.Errorf("TestArgs didn't reach the test binary:\n%s", strings.Join(res.RawOutput, "\n"))
+	}
+}
+
+func TestRunTestsBenchmarks(t *testing.T) {
+	node := nodeExec(t)
+	opts := ExecOptions{Run: "^$", Bench: "BenchmarkMathOperations", BenchTime: "10x", Benchmem: true, Args: []string{"-exec", node}}
+	res, err := RunTestsResult("./example", func(...any) {}, opts, WithInstallDisabled())
+	if err != nil {
+		t.Fatalf("RunTestsResult failed: %v", err)
+	}
+	if len(res.Benchmarks) != 1 {
+		t.Fatalf("Benchmarks = %+v\n%s", res.Benchmarks, strings.Join(res.RawOutput, "\n"))
+	}
+	b := res.Benchmarks[0]
+	if b.Name != "BenchmarkMathOperations" || b.N != 10 || b.NsPerOp <= 0 {
+		t.Errorf("unexpected benchmark %+v", b)
+	}
+	if _, ok := b.Metrics["allocs/op"]; !ok {
+		t.Errorf("-benchmem metrics missing: %v", b.Metrics)
+	}
+	if len(res.PassedTests) != 0 {
+		t.Errorf("tests ran despite -run ^$: %q", res.PassedTests)
+	}
+}
+
+func TestRunTestsShuffle(t *testing.T) {
+	node := nodeExec(t)
+	dir := writeModule(t, map[string]string{"fail_test.go": wasmFailTest + "\nfunc TestOther(t *testing.T) {}\n"})
+
+	var infos []string
+	logger := func(msgs ...any) {
+		if len(msgs) > 2 && msgs[1] == "info" {
+			infos = append(infos, fmt.Sprint(msgs[2:]...))
+		}
+	}
+	opts := ExecOptions{Shuffle: "on", Args: []string{"-exec", node}}
+	res, err := RunTestsResult(dir, logger, opts, WithInstallDisabled())
+	if res == nil || res.ShuffleSeed == "" {
+		t.Fatalf("no shuffle seed recorded: %+v, %v", res, err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "Shuffle seed: "+res.ShuffleSeed) {
+		t.Errorf("the failure doesn't mention the seed: %v", err)
+	}
+	if !slices.ContainsFunc(infos, func(s string) bool { return strings.Contains(s, "-shuffle="+res.ShuffleSeed) }) {
+		t.Errorf("the seed was not reported: %q", infos)
+	}
+
+	opts.Shuffle = res.ShuffleSeed
+	replay, _ := RunTestsResult(dir, func(...any) {}, opts, WithInstallDisabled())
+	if replay == nil || replay.ShuffleSeed != res.ShuffleSeed {
+		t.Errorf("replay ran with seed %+v, want %s", replay, res.ShuffleSeed)
+	}
+}
+
+func TestRunTestsSkip(t *testing.T) {
+	node := nodeExec(t)
+	dir := writeModule(t, map[string]string{"fail_test.go": wasmFailTest + "\nfunc TestOther(t *testing.T) {}\n"})
+
+	res, err := RunTestsResult(dir, func(...any) {}, ExecOptions{Skip: "TestFail", Args: []string{"-exec", node}}, WithInstallDisabled())
+	if err != nil || !slices.Equal(res.PassedTests, []string{"TestOther"}) {
+		t.Errorf("RunTestsResult() with Skip = %+v, %v", res, err)
+	}
+}
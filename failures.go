@@ -0,0 +1,53 @@
+package wasmtest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// failureSummaryLines is the number of output lines of each failed test
+// repeated by failureSummary.
+const failureSummaryLines = 20
+
+// failureSummary renders the failed tests of result and their output as the
+// section printed at the end of a run, so the details of the failures don't
+// have to be looked for in the whole output. It returns "" when no test
+// failed. A parent test without output of its own is left out when one of
+// its subtests is listed.
+func failureSummary(result *RunResult) string {
+	packages := result.Packages
+	if len(packages) == 0 {
+		packages = []*RunResult{result}
+	}
+	var b strings.Builder
+	count := 0
+	for _, pkg := range packages {
+		for _, test := range pkg.FailedTests {
+			output := pkg.FailureOutput[test]
+			if len(output) == 0 && slices.ContainsFunc(pkg.FailedTests, func(sub string) bool {
+				return strings.HasPrefix(sub, test+"/")
+			}) {
+				continue
+			}
+			count++
+			fmt.Fprintf(&b, "\n━━ %s", test)
+			if len(packages) > 1 {
+				fmt.Fprintf(&b, " (%s)", pkg.Dir)
+			}
+			if len(output) == 0 {
+				b.WriteString("\n    (no output)")
+			}
+			for _, line := range output[:min(len(output), failureSummaryLines)] {
+				b.WriteString("\n    " + strings.TrimPrefix(line, "    "))
+			}
+			if extra := len(output) - failureSummaryLines; extra > 0 {
+				fmt.Fprintf(&b, "\n    … %d more %s", extra, plural(extra, "line", "lines"))
+			}
+		}
+	}
+	if count == 0 {
+		return ""
+	}
+	return fmt.Sprintf("🔴 %d failed WASM %s:", count, plural(count, "test", "tests")) + b.String()
+}
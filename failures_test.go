@@ -0,0 +1,92 @@
+package wasmtest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRunResultFailureOutput(t *testing.T) {
+	r := newRunResult("wasm_tests")
+	for _, msg := range [][]any{
+		{"test", TestEvent{Action: "run", Test: "TestA"}},
+		{"out", "=== RUN   TestA"},
+		{"out", "    a_test.go:5: fine"},
+		{"test", TestEvent{Action: "pass", Test: "TestA"}},
+		{"test", TestEvent{Action: "run", Test: "TestB"}},
+		{"out", "=== RUN   TestB"},
+		{"test", TestEvent{Action: "run", Test: "TestB/sub"}},
+		{"out", "=== RUN   TestB/sub"},
+		{"out", "    b_test.go:9: want 1, got 2"},
+		{"out", "        extra detail"},
+		{"out", "    --- FAIL: TestB/sub (0.01s)"},
+		{"test", TestEvent{Action: "fail", Test: "TestB/sub"}},
+		{"out", "--- FAIL: TestB (0.02s)"},
+		{"test", TestEvent{Action: "fail", Test: "TestB"}},
+	} {
+		r.collect(msg...)
+	}
+
+	if len(r.FailureOutput) != 2 || len(r.FailureOutput["TestB"]) != 0 {
+		t.Errorf("FailureOutput = %q", r.FailureOutput)
+	}
+	if want := []string{"    b_test.go:9: want 1, got 2", "        extra detail"}; !slices.Equal(r.FailureOutput["TestB/sub"], want) {
+		t.Errorf("FailureOutput[TestB/sub] = %q, want %q", r.FailureOutput["TestB/sub"], want)
+	}
+
+	want := "🔴 1 failed WASM test:\n━━ TestB/sub\n    b_test.go:9: want 1, got 2\n        extra detail"
+	if got := failureSummary(r); got != want {
+		t.Errorf("failureSummary =\n%s\nwant\n%s", got, want)
+	}
+	if got := failureSummary(newRunResult("ok")); got != "" {
+		t.Errorf("failureSummary without failures = %q", got)
+	}
+
+	long := newRunResult("long")
+	long.FailedTests = []string{"TestLong"}
+	var lines []string
+	for i := range failureSummaryLines + 3 {
+		lines = append(lines, fmt.Sprint(i))
+	}
+	long.FailureOutput = map[string][]string{"TestLong": lines}
+	if got := failureSummary(long); !strings.HasSuffix(got, "\n    19\n    … 3 more lines") {
+		t.Errorf("failureSummary of a long output = %q", got)
+	}
+}
+
+func TestRunTestsFailureSummary(t *testing.T) {
+	node := nodeExec(t)
+	dir := writeModule(t, map[string]string{"f_test.go": wasmFailTest})
+	var log []string
+	logger := func(args ...any) { log = append(log, fmt.Sprintln(args...)) }
+	if err := RunTests(dir, logger, ExecOptions{Args: []string{"-exec", node}}, WithInstallDisabled()); err == nil {
+		t.Fatal("RunTests succeeded with a failing test")
+	}
+	last := log[len(log)-1]
+	if !strings.Contains(last, "🔴 1 failed WASM test:\n━━ TestFail\n    f_test.go:7: bad") {
+		t.Errorf("last log line = %q", last)
+	}
+}
+
+func TestRunTestsFailureSummaryParallel(t *testing.T) {
+	node := nodeExec(t)
+	// TestA logs its failure once TestB, started after it, is running too.
+	dir := writeModule(t, map[string]string{"f_test.go": "//go:build js && wasm\n\npackage p\n\nimport \"testing\"\n\n" +
+		"var started = make(chan bool)\n\n" +
+		"func TestA(t *testing.T) { t.Parallel(); <-started; t.Error(\"A failed\") }\n\n" +
+		"func TestB(t *testing.T) { t.Parallel(); t.Log(\"B log\"); close(started) }\n"})
+	var log []string
+	logger := func(args ...any) { log = append(log, fmt.Sprintln(args...)) }
+	result, err := RunTestsResult(dir, logger, ExecOptions{Args: []string{"-exec", node}}, WithInstallDisabled())
+	if err == nil {
+		t.Fatal("RunTests succeeded with a failing test")
+	}
+	if out := result.FailureOutput["TestA"]; len(out) != 1 || !strings.Contains(out[0], "A failed") {
+		t.Errorf("FailureOutput[TestA] = %q", out)
+	}
+	last := log[len(log)-1]
+	if !strings.Contains(last, "🔴 1 failed WASM test:\n━━ TestA\n    f_test.go:9: A failed") || strings.Contains(last, "B log") {
+		t.Errorf("last log line = %q", last)
+	}
+}
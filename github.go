@@ -0,0 +1,171 @@
+package wasmtest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// newGitHubReportWriter returns the "github" writer, configured from the
+// environment of the GitHub Actions runner.
+func newGitHubReportWriter(out io.Writer) ReportWriter {
+	return &githubReportWriter{
+		out:       out,
+		summary:   os.Getenv("GITHUB_STEP_SUMMARY"),
+		workspace: os.Getenv("GITHUB_WORKSPACE"),
+	}
+}
+
+// githubReportWriter is the built-in "github" writer for GitHub Actions: it
+// prints an ::error workflow command for each failed test, pointing at the
+// file and line of its first t.Error/t.Fatal message, and appends a
+// Markdown table of the run to the job summary ($GITHUB_STEP_SUMMARY).
+type githubReportWriter struct {
+	out io.Writer
+	// summary is the job summary file; empty skips the summary.
+	summary string
+	// workspace is the directory annotation paths are relative to;
+	// empty means the current directory.
+	workspace string
+	output    map[[2]string][]string
+	dirs      map[string]string
+}
+
+func (g *githubReportWriter) Begin(plans []RunPlan) error {
+	g.output = map[[2]string][]string{}
+	g.dirs = map[string]string{}
+	for _, plan := range plans {
+		g.dirs[plan.Name] = plan.Dir
+	}
+	return nil
+}
+
+func (g *githubReportWriter) TestEvent(ev TestEvent) error {
+	key := [2]string{ev.Plan, ev.Test}
+	switch ev.Action {
+	case "output":
+		if ev.Test != "" {
+			g.output[key] = append(g.output[key], ev.Output)
+		}
+	case "pass", "skip":
+		delete(g.output, key)
+	case "fail":
+		output := g.output[key]
+		delete(g.output, key)
+		return g.annotate(ev, output)
+	}
+	return nil
+}
+
+// testLogLine matches the "    file_test.go:12: message" lines of t.Log,
+// t.Error and t.Fatal.
+var testLogLine = regexp.MustCompile(`^\s+([^\s:]+\.go):(\d+): (.*)$`)
+
+// annotate prints the ::error command of a failed test.
+func (g *githubReportWriter) annotate(ev TestEvent, output []string) error {
+	var props []string
+	var message []string
+	for _, line := range output {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "--- ") {
+			continue
+		}
+		if m := testLogLine.FindStringSubmatch(line); m != nil && props == nil {
+			dir := g.dirs[ev.Plan]
+			if dir == "" {
+				dir = ev.Plan
+			}
+			props = append(props, "file="+escapeProperty(g.relative(filepath.Join(dir, m[1]))), "line="+m[2])
+		}
+		message = append(message, trimmed)
+	}
+	if len(message) == 0 {
+		// Subtest failures are annotated on their own.
+		return nil
+	}
+	props = append(props, "title="+escapeProperty(ev.Test+" failed"))
+	_, err := fmt.Fprintf(g.out, "::error %s::%s\n", strings.Join(props, ","), escapeData(strings.Join(message, "\n")))
+	return err
+}
+
+// relative returns path relative to the workspace, as annotations expect.
+func (g *githubReportWriter) relative(path string) string {
+	base := g.workspace
+	if base == "" {
+		base = "."
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	root, err := filepath.Abs(base)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	if rel, err := filepath.Rel(root, abs); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(abs)
+}
+
+func (g *githubReportWriter) End(report *Report) error {
+	if g.summary == "" {
+		return nil
+	}
+	f, err := os.OpenFile(g.summary, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, markdownSummary(report))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// markdownSummary renders report as a Markdown table with one row per plan,
+// followed by the list of failed tests.
+func markdownSummary(report *Report) string {
+	var b strings.Builder
+	status := "✅ WebAssembly tests passed"
+	if report.ExitCode != 0 {
+		status = "❌ WebAssembly tests failed"
+	}
+	fmt.Fprintf(&b, "### %s\n\n", status)
+	b.WriteString("| Plan | Status | Passed | Failed | Skipped | Duration |\n")
+	b.WriteString("| --- | --- | ---: | ---: | ---: | ---: |\n")
+	for _, res := range report.Results {
+		mark := "✅"
+		if !res.Passed() {
+			mark = "❌"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %d | %d | %d | %v |\n", escapeMarkdown(res.Plan.Name), mark,
+			len(res.PassedTests), len(res.FailedTests), len(res.SkippedTests), res.Duration.Round(time.Millisecond))
+	}
+	if failed := failureLines(report); len(failed) > 0 {
+		b.WriteString("\n**Failures**\n\n")
+		b.WriteString(strings.Join(failed, "\n"))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// escapeMarkdown escapes the characters breaking a Markdown table cell.
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+// escapeData escapes the message of a workflow command.
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a property value of a workflow command.
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
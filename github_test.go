@@ -0,0 +1,45 @@
+package wasmtest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitHubReportWriter(t *testing.T) {
+	node := nodeExec(t)
+	root := writeModule(t, map[string]string{
+		"pkg/gh_test.go": "//go:build js && wasm\n\npackage tmp\n\nimport \"testing\"\n\n" +
+			"func TestOK(t *testing.T) {}\n\nfunc TestBad(t *testing.T) {\n\tt.Error(\"first, 100%\")\n\tt.Fatal(\"second\")\n}\n",
+	})
+	summary := filepath.Join(t.TempDir(), "summary.md")
+	t.Setenv("GITHUB_STEP_SUMMARY", summary)
+	t.Setenv("GITHUB_WORKSPACE", root)
+
+	var out bytes.Buffer
+	gh, err := NewReportWriter("github", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, "pkg")
+	if err := RunTests(dir, func(...any) {}, gh, ExecOptions{Args: []string{"-exec", node}}, WithInstallDisabled()); err == nil {
+		t.Fatal("RunTests succeeded with a failing test")
+	}
+
+	want := "::error file=pkg/gh_test.go,line=10,title=TestBad failed::gh_test.go:10: first, 100%25%0Agh_test.go:11: second\n"
+	if out.String() != want {
+		t.Errorf("annotations:\n%q\nwant\n%q", out.String(), want)
+	}
+
+	data, err := os.ReadFile(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"### ❌ WebAssembly tests failed", "| " + dir + " | ❌ | 1 | 1 | 0 |", "- `TestBad` (" + dir + ")"} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("job summary lacks %q:\n%s", s, data)
+		}
+	}
+}
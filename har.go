@@ -0,0 +1,183 @@
+package wasmtest
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// WithHAR records the network requests made by the tests of RunBundle runs,
+// through fetch (including net/http) or XMLHttpRequest, into a HAR 1.2 file
+// at path, written at the end of the run whatever its outcome, so code
+// talking to a backend can be debugged and asserted on after the fact. Each
+// entry carries the running test as "_test", and failed requests their
+// error as "_error". The requests of the harness itself are left out. It
+// affects only RunBundle: go test runs fail with ErrUsage, as
+// wasmbrowsertest serves its own page.
+func WithHAR(path string) Option {
+	return func(w *Wasmtest) { w.harPath = path }
+}
+
+// networkRequest is a request of the tests as relayed by the harness page.
+type networkRequest struct {
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	RequestHeaders  map[string]string `json:"requestHeaders"`
+	PostData        string            `json:"postData"`
+	Status          int               `json:"status"`
+	StatusText      string            `json:"statusText"`
+	ResponseHeaders map[string]string `json:"responseHeaders"`
+	MimeType        string            `json:"mimeType"`
+	Size            int               `json:"size"`
+	Started         string            `json:"started"`
+	Time            float64           `json:"time"`
+	Error           string            `json:"error"`
+}
+
+// harEntry, harRequest and harResponse follow the HAR 1.2 format.
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Test            string      `json:"_test,omitempty"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+// harTimings only splits the total time as waiting: the page can't measure
+// the phases of a request.
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// harHeaders returns headers sorted by name.
+func harHeaders(headers map[string]string) []harNameValue {
+	list := []harNameValue{}
+	for name, value := range headers {
+		list = append(list, harNameValue{Name: name, Value: value})
+	}
+	slices.SortFunc(list, func(a, b harNameValue) int { return strings.Compare(a.Name, b.Name) })
+	return list
+}
+
+// harEntryOf converts a request relayed by the harness page, made while
+// test was running.
+func harEntryOf(req networkRequest, test string) harEntry {
+	query := []harNameValue{}
+	if u, err := url.Parse(req.URL); err == nil {
+		for name, values := range u.Query() {
+			for _, value := range values {
+				query = append(query, harNameValue{Name: name, Value: value})
+			}
+		}
+		slices.SortStableFunc(query, func(a, b harNameValue) int { return strings.Compare(a.Name, b.Name) })
+	}
+	entry := harEntry{
+		StartedDateTime: req.Started,
+		Time:            req.Time,
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         req.URL,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(req.RequestHeaders),
+			QueryString: query,
+			HeadersSize: -1,
+			BodySize:    len(req.PostData),
+		},
+		Response: harResponse{
+			Status:      req.Status,
+			StatusText:  req.StatusText,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(req.ResponseHeaders),
+			Content:     harContent{Size: req.Size, MimeType: req.MimeType},
+			HeadersSize: -1,
+			BodySize:    req.Size,
+		},
+		Timings: harTimings{Send: 0, Wait: req.Time, Receive: 0},
+		Test:    test,
+		Error:   req.Error,
+	}
+	if req.PostData != "" {
+		entry.Request.PostData = &harPostData{MimeType: req.RequestHeaders["content-type"], Text: req.PostData}
+	}
+	return entry
+}
+
+// writeHAR writes entries as a HAR file at path.
+func writeHAR(path string, entries []harEntry) error {
+	if entries == nil {
+		entries = []harEntry{}
+	}
+	var har struct {
+		Log struct {
+			Version string         `json:"version"`
+			Creator harNameVersion `json:"creator"`
+			Entries []harEntry     `json:"entries"`
+		} `json:"log"`
+	}
+	har.Log.Version = "1.2"
+	har.Log.Creator = harNameVersion{Name: "wasmtest", Version: cmp.Or(moduleVersion(), "(devel)")}
+	har.Log.Entries = entries
+	data, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+type harNameVersion struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
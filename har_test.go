@@ -0,0 +1,90 @@
+package wasmtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHAREntryOf(t *testing.T) {
+	e := harEntryOf(networkRequest{
+		Method:          "POST",
+		URL:             "http://api.test/items?b=2&a=1",
+		RequestHeaders:  map[string]string{"x-b": "2", "content-type": "text/plain"},
+		PostData:        "hi",
+		Status:          201,
+		ResponseHeaders: map[string]string{"content-type": "application/json"},
+		MimeType:        "application/json",
+		Size:            7,
+		Started:         "2024-01-02T03:04:05.000Z",
+		Time:            12,
+	}, "TestItems")
+	if e.Request.QueryString[0].Name != "a" || e.Request.Headers[0].Name != "content-type" {
+		t.Errorf("query string %v and headers %v are not sorted", e.Request.QueryString, e.Request.Headers)
+	}
+	if e.Request.PostData == nil || e.Request.PostData.Text != "hi" || e.Request.PostData.MimeType != "text/plain" {
+		t.Errorf("postData = %+v", e.Request.PostData)
+	}
+	if e.Response.Status != 201 || e.Response.Content.Size != 7 || e.Test != "TestItems" || e.Timings.Wait != 12 {
+		t.Errorf("entry = %+v", e)
+	}
+}
+
+func TestRunBundleHAR(t *testing.T) {
+	browser := nodeBrowser(t)
+	api := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Access-Control-Allow-Origin", "*")
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(`{"ok":true}`))
+	}))
+	defer api.Close()
+	dir := writeModule(t, map[string]string{"n_test.go": "//go:build js && wasm\n\npackage p\n\n" +
+		"import (\n\t\"syscall/js\"\n\t\"testing\"\n)\n\n" +
+		"func TestFetch(t *testing.T) {\n\tdone := make(chan struct{})\n" +
+		"\tthen := js.FuncOf(func(js.Value, []js.Value) any { close(done); return nil })\n\tdefer then.Release()\n" +
+		"\tjs.Global().Call(\"fetch\", \"" + api.URL + "/items?id=1\", map[string]any{\"method\": \"POST\", \"body\": \"hi\"}).Call(\"then\", then)\n" +
+		"\t<-done\n}\n"})
+	har := filepath.Join(t.TempDir(), "run.har")
+	w := New(WithInstallDisabled(), WithHAR(har))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	out := t.TempDir()
+	if _, err := w.Bundle(ctx, dir, out, "-test.v"); err != nil {
+		t.Fatalf("Bundle failed: %v", err)
+	}
+	progress, msgs := collectProgress()
+	if err := w.RunBundle(ctx, out, RunBundleOptions{Browser: browser}, progress); err != nil {
+		t.Fatalf("RunBundle failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+
+	data, err := os.ReadFile(har)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Log struct {
+			Version string     `json:"version"`
+			Entries []harEntry `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	// The harness requests (manifest, binary, output) are left out.
+	if doc.Log.Version != "1.2" || len(doc.Log.Entries) != 1 {
+		t.Fatalf("HAR = %s", data)
+	}
+	e := doc.Log.Entries[0]
+	if e.Request.Method != "POST" || e.Request.URL != api.URL+"/items?id=1" || e.Request.PostData == nil || e.Request.PostData.Text != "hi" {
+		t.Errorf("request = %+v", e.Request)
+	}
+	if e.Response.Status != 200 || e.Response.Content.MimeType != "application/json" || e.Test != "TestFetch" {
+		t.Errorf("entry = %+v", e)
+	}
+}
@@ -0,0 +1,253 @@
+package wasmtest
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// harnessPage is the HTML page running a Go test binary compiled for js/wasm
+// in the browser. It loads manifest.json (see BundleManifest), runs the
+// binary with wasm_exec.js and relays its output, the console calls, the
+// network requests of the tests and the exit code to the serving harness over HTTP, so no browser automation
+// protocol is needed.
+//
+//go:embed harness.html
+var harnessPage []byte
+
+// harnessExit is the exit status posted by the harness page.
+type harnessExit struct {
+	Code   int       `json:"code"`
+	Error  string    `json:"error"`
+	Memory memoryUse `json:"memory"`
+}
+
+// harness serves a test bundle to a browser and relays what the page
+// reports through progress using the same messages as Execute.
+type harness struct {
+	files    http.Handler
+	manifest func() ([]byte, error)
+	progress func(msgs ...any)
+
+	mu      sync.Mutex
+	partial map[int]string
+	// network holds the requests of the tests when recording (see
+	// WithHAR), nil otherwise.
+	network []harEntry
+	// memory samples the memory use of the page when profiling (see
+	// WithMemoryProfile), nil otherwise.
+	memory  *memorySampler
+	running string
+	loaded  bool
+	exited  bool
+	exit    chan harnessExit
+}
+
+// newHarness returns a harness serving files for the bundle assets and
+// manifest for manifest.json.
+func newHarness(files http.Handler, manifest func() ([]byte, error), progress func(msgs ...any)) *harness {
+	return &harness{
+		files:    files,
+		manifest: manifest,
+		progress: progress,
+		partial:  map[int]string{},
+		exit:     make(chan harnessExit, 1),
+	}
+}
+
+func (h *harness) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Cache-Control", "no-store")
+	switch {
+	case r.URL.Path == "/" || r.URL.Path == "/index.html":
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		rw.Write(harnessPage)
+	case r.URL.Path == "/manifest.json":
+		h.mu.Lock()
+		h.loaded = true
+		h.mu.Unlock()
+		data, err := h.manifest()
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write(data)
+	case r.URL.Path == "/output" && r.Method == http.MethodPost:
+		var chunks []struct {
+			FD      int             `json:"fd"`
+			Data    string          `json:"data"`
+			Console string          `json:"console"`
+			Network *networkRequest `json:"network"`
+			Memory  memoryUse       `json:"memory"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&chunks); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, c := range chunks {
+			if c.Network != nil {
+				h.request(*c.Network)
+				continue
+			}
+			if c.Console != "" {
+				h.console(c.Console, c.Data)
+				continue
+			}
+			h.write(c.FD, c.Data, c.Memory)
+		}
+	case r.URL.Path == "/exit" && r.Method == http.MethodPost:
+		var ex harnessExit
+		if err := json.NewDecoder(r.Body).Decode(&ex); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.finish(ex)
+	default:
+		h.files.ServeHTTP(rw, r)
+	}
+}
+
+// write splits output into lines, keeping a partial last line per fd until
+// the rest arrives. use is the memory use of the page when it was written.
+func (h *harness) write(fd int, data string, use memoryUse) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	buf := h.partial[fd] + data
+	lines := strings.Split(buf, "\n")
+	h.partial[fd] = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		if fd == 1 {
+			h.running = runningTest(h.running, line)
+			if h.memory != nil {
+				h.memory.line(line, use)
+			}
+		}
+		h.progress(fdTag(fd), line)
+	}
+}
+
+// console reports a console call of the page.
+func (h *harness) console(level, text string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.progress("console", ConsoleMessage{Level: level, Text: text, Test: h.running})
+}
+
+// request records a network request of the tests when recording.
+func (h *harness) request(req networkRequest) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.network != nil {
+		h.network = append(h.network, harEntryOf(req, h.running))
+	}
+}
+
+// recordNetwork starts recording the network requests of the tests.
+func (h *harness) recordNetwork() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.network = []harEntry{}
+}
+
+// requests returns the recorded network requests.
+func (h *harness) requests() []harEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.network)
+}
+
+// recordMemory starts sampling the memory use of the page at the points of
+// at.
+func (h *harness) recordMemory(at MemoryPoint) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.memory = &memorySampler{at: at}
+}
+
+// memorySamples returns the memory samples taken.
+func (h *harness) memorySamples() []MemorySample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.memory == nil {
+		return nil
+	}
+	return slices.Clone(h.memory.samples)
+}
+
+// pageLoaded reports whether a browser has loaded the harness page far
+// enough to request the manifest.
+func (h *harness) pageLoaded() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.loaded
+}
+
+// finish flushes pending output and records the exit status; only the first
+// reported exit counts.
+func (h *harness) finish(ex harnessExit) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.exited {
+		return
+	}
+	h.exited = true
+	for fd, rest := range h.partial {
+		if rest != "" {
+			h.progress(fdTag(fd), rest)
+		}
+	}
+	h.partial = map[int]string{}
+	if h.memory != nil && ex.Memory.Wasm > 0 {
+		h.memory.exit(ex.Code, ex.Memory)
+	}
+	h.exit <- ex
+}
+
+func fdTag(fd int) string {
+	if fd == 2 {
+		return "err"
+	}
+	return "out"
+}
+
+// serve starts serving the harness on addr (a random localhost port when
+// empty) and returns its URL. The server stops when ctx is done.
+func (h *harness) serve(ctx context.Context, addr string) (string, error) {
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	srv := &http.Server{Handler: h}
+	go srv.Serve(ln)
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	return "http://" + ln.Addr().String() + "/", nil
+}
+
+// wait blocks until the page reports the exit status of the test binary and
+// returns an error for non-zero exit codes.
+func (h *harness) wait(ctx context.Context) error {
+	select {
+	case ex := <-h.exit:
+		if ex.Error != "" {
+			return fmt.Errorf("wasmtest: test harness failed: %s", ex.Error)
+		}
+		if ex.Code != 0 {
+			return &TestFailureError{ExitCode: ex.Code, msg: fmt.Sprintf("exit status %d", ex.Code)}
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,300 @@
+package wasmtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHistoryDir is where run records are stored when no directory is
+// given to OpenHistory.
+const DefaultHistoryDir = ".wasmtest/history"
+
+// HistoryStore persists orchestrated runs as JSON files, one per run, so
+// they can be browsed later (see DashboardHandler) or analysed for flaky
+// tests.
+type HistoryStore struct {
+	dir string
+}
+
+// RunRecord is the persisted form of an orchestrated run.
+type RunRecord struct {
+	// SchemaVersion is the SchemaVersion the record was written with.
+	SchemaVersion int `json:"schemaVersion"`
+
+	ID       string        `json:"id"`
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+	ExitCode int           `json:"exitCode"`
+	// Live is true while the run is still in progress.
+	Live  bool         `json:"live"`
+	Plans []PlanRecord `json:"plans"`
+}
+
+// PlanRecord is the persisted form of a PlanResult.
+type PlanRecord struct {
+	Name     string        `json:"name"`
+	Dir      string        `json:"dir"`
+	Passed   bool          `json:"passed"`
+	ExitCode int           `json:"exitCode"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+	Tests    []TestRecord  `json:"tests"`
+	Output   []string      `json:"output"`
+}
+
+// TestRecord is the final status of a single test within a plan.
+type TestRecord struct {
+	Name string `json:"name"`
+	// Status is "run" while the test is in progress, then "pass", "fail"
+	// or "skip".
+	Status  string        `json:"status"`
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// FlakyTest summarizes a test that both passed and failed across the
+// analysed runs.
+type FlakyTest struct {
+	Plan     string  `json:"plan"`
+	Test     string  `json:"test"`
+	Runs     int     `json:"runs"`
+	Failures int     `json:"failures"`
+	Rate     float64 `json:"rate"`
+}
+
+// HistoryDir is a RunTests argument recording the run into the
+// HistoryStore of the directory, as a Recorder would, so the dashboard
+// lists it. It can also be set with the WASMTEST_HISTORY environment
+// variable or the history setting of the configuration file.
+type HistoryDir string
+
+// OpenHistory returns a HistoryStore saving its records in dir, creating it
+// if needed. An empty dir uses DefaultHistoryDir.
+func OpenHistory(dir string) (*HistoryStore, error) {
+	if dir == "" {
+		dir = DefaultHistoryDir
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("wasmtest: creating history dir: %w", err)
+	}
+	return &HistoryStore{dir: dir}, nil
+}
+
+// Dir returns the directory holding the records.
+func (h *HistoryStore) Dir() string { return h.dir }
+
+// Recorder returns a ReportWriter saving one run into the store. The record
+// is written when the run begins and refreshed as tests finish, so live
+// runs can be followed from another process. Use a new Recorder per run.
+func (h *HistoryStore) Recorder() ReportWriter {
+	return &historyRecorder{store: h}
+}
+
+// List returns all the records, newest first.
+func (h *HistoryStore) List() ([]RunRecord, error) {
+	entries, err := os.ReadDir(h.dir)
+	if err != nil {
+		return nil, err
+	}
+	var records []RunRecord
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		rec, err := h.Load(id)
+		if err != nil {
+			// A record being rewritten by a live run; skip it this time.
+			continue
+		}
+		records = append(records, *rec)
+	}
+	slices.SortFunc(records, func(a, b RunRecord) int { return strings.Compare(b.ID, a.ID) })
+	return records, nil
+}
+
+// Load returns the record with the given id.
+func (h *HistoryStore) Load(id string) (*RunRecord, error) {
+	if id != filepath.Base(id) {
+		return nil, fmt.Errorf("wasmtest: invalid run id %q", id)
+	}
+	data, err := os.ReadFile(filepath.Join(h.dir, id+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var rec RunRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+	if err := checkSchemaVersion("run record "+id, rec.SchemaVersion); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// Flakiness analyses the last n finished runs (all when n <= 0) and returns
+// the tests that both passed and failed, most flaky first.
+func (h *HistoryStore) Flakiness(n int) ([]FlakyTest, error) {
+	records, err := h.List()
+	if err != nil {
+		return nil, err
+	}
+	stats := map[[2]string]*FlakyTest{}
+	analysed := 0
+	for _, rec := range records {
+		if rec.Live {
+			continue
+		}
+		if n > 0 && analysed == n {
+			break
+		}
+		analysed++
+		for _, plan := range rec.Plans {
+			for _, test := range plan.Tests {
+				if test.Status != "pass" && test.Status != "fail" {
+					continue
+				}
+				key := [2]string{plan.Name, test.Name}
+				ft := stats[key]
+				if ft == nil {
+					ft = &FlakyTest{Plan: plan.Name, Test: test.Name}
+					stats[key] = ft
+				}
+				ft.Runs++
+				if test.Status == "fail" {
+					ft.Failures++
+				}
+			}
+		}
+	}
+
+	var flaky []FlakyTest
+	for _, ft := range stats {
+		if ft.Failures > 0 && ft.Failures < ft.Runs {
+			ft.Rate = float64(ft.Failures) / float64(ft.Runs)
+			flaky = append(flaky, *ft)
+		}
+	}
+	slices.SortFunc(flaky, func(a, b FlakyTest) int {
+		if a.Rate != b.Rate {
+			if a.Rate > b.Rate {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Plan+a.Test, b.Plan+b.Test)
+	})
+	return flaky, nil
+}
+
+// save atomically writes rec to the store.
+func (h *HistoryStore) save(rec *RunRecord) error {
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(h.dir, "."+rec.ID+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(h.dir, rec.ID+".json"))
+}
+
+// historyRecorder is the ReportWriter returned by HistoryStore.Recorder.
+type historyRecorder struct {
+	store     *HistoryStore
+	mu        sync.Mutex
+	rec       RunRecord
+	plans     map[string]int
+	lastWrite time.Time
+}
+
+func (r *historyRecorder) Begin(plans []RunPlan) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	r.rec = RunRecord{SchemaVersion: SchemaVersion, ID: now.UTC().Format("20060102T150405.000000000"), Started: now, Live: true}
+	r.plans = map[string]int{}
+	for _, p := range plans {
+		name := p.Name
+		if name == "" {
+			name = p.Dir
+		}
+		r.plans[name] = len(r.rec.Plans)
+		r.rec.Plans = append(r.rec.Plans, PlanRecord{Name: name, Dir: p.Dir})
+	}
+	r.lastWrite = now
+	return r.store.save(&r.rec)
+}
+
+func (r *historyRecorder) TestEvent(ev TestEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, ok := r.plans[ev.Plan]
+	if !ok {
+		// Complementary passes (e.g. native -race) get their own entry.
+		i = len(r.rec.Plans)
+		r.plans[ev.Plan] = i
+		r.rec.Plans = append(r.rec.Plans, PlanRecord{Name: ev.Plan})
+	}
+	plan := &r.rec.Plans[i]
+	if ev.Action == "output" {
+		plan.Output = append(plan.Output, ev.Output)
+		return nil
+	}
+	if ev.parallelStep() {
+		return nil
+	}
+	j := slices.IndexFunc(plan.Tests, func(t TestRecord) bool { return t.Name == ev.Test })
+	if j < 0 {
+		plan.Tests = append(plan.Tests, TestRecord{Name: ev.Test})
+		j = len(plan.Tests) - 1
+	}
+	plan.Tests[j].Status = ev.Action
+	plan.Tests[j].Elapsed = ev.Elapsed
+
+	// Refresh the live record at most twice per second.
+	if time.Since(r.lastWrite) < 500*time.Millisecond {
+		return nil
+	}
+	r.lastWrite = time.Now()
+	return r.store.save(&r.rec)
+}
+
+func (r *historyRecorder) End(report *Report) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rec.Live = false
+	r.rec.Duration = report.Duration
+	r.rec.ExitCode = report.ExitCode
+	for _, res := range report.Results {
+		i, ok := r.plans[res.Plan.Name]
+		if !ok {
+			continue
+		}
+		plan := &r.rec.Plans[i]
+		plan.Passed = res.Passed()
+		plan.ExitCode = res.ExitCode
+		plan.Duration = res.Duration
+		if res.Err != nil {
+			plan.Error = res.Err.Error()
+		}
+		if res.Race != nil {
+			if i, ok := r.plans[racePassName(res.Plan.Name)]; ok {
+				race := &r.rec.Plans[i]
+				race.Passed = res.Race.Passed()
+				race.ExitCode = res.Race.ExitCode
+				race.Duration = res.Race.Duration
+				if res.Race.Err != nil {
+					race.Error = res.Race.Err.Error()
+				}
+			}
+		}
+	}
+	return r.store.save(&r.rec)
+}
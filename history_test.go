@@ -0,0 +1,102 @@
+package wasmtest
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryRecorder(t *testing.T) {
+	node := nodeExec(t)
+	h, err := OpenHistory(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := &Orchestrator{Writers: []ReportWriter{h.Recorder()}}
+	if _, err := o.Run(context.Background(), []RunPlan{{Name: "node", Dir: "./example", Exec: node}}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	runs, err := h.List()
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("List() = %v, %v; want one run", runs, err)
+	}
+	run := runs[0]
+	if run.Live || run.ExitCode != 0 || len(run.Plans) != 1 || run.SchemaVersion != SchemaVersion {
+		t.Fatalf("unexpected record: %+v", run)
+	}
+	plan := run.Plans[0]
+	if !plan.Passed || len(plan.Output) == 0 {
+		t.Errorf("unexpected plan record: %+v", plan)
+	}
+	statuses := map[string]string{}
+	for _, test := range plan.Tests {
+		statuses[test.Name] = test.Status
+	}
+	if statuses["TestMathHelper"] != "pass" || statuses["TestDOMHelper"] != "skip" {
+		t.Errorf("test statuses = %v", statuses)
+	}
+}
+
+func TestRunTestsHistory(t *testing.T) {
+	node := nodeExec(t)
+	root := writeModule(t, map[string]string{
+		"wasmtest.yaml": "dir: .\nhistory: runs\n",
+		"p_test.go":     wasmPassTest,
+	})
+	t.Chdir(root)
+
+	// The history setting records every run, without a Recorder.
+	if err := RunTests(func(...any) {}, ExecOptions{Args: []string{"-exec", node}}, WithInstallDisabled()); err != nil {
+		t.Fatalf("RunTests failed: %v", err)
+	}
+	h, err := OpenHistory(filepath.Join(root, "runs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs, err := h.List()
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("List() = %v, %v; want one run", runs, err)
+	}
+	if run := runs[0]; run.Live || len(run.Plans) != 1 || len(run.Plans[0].Tests) != 1 || run.Plans[0].Tests[0].Status != "pass" {
+		t.Errorf("unexpected record: %+v", run)
+	}
+}
+
+func TestHistoryFlakiness(t *testing.T) {
+	h, err := OpenHistory(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, status := range []string{"pass", "fail", "pass", "pass"} {
+		rec := &RunRecord{
+			ID:      start.Add(time.Duration(i) * time.Minute).Format("20060102T150405.000000000"),
+			Started: start,
+			Plans: []PlanRecord{{Name: "p", Tests: []TestRecord{
+				{Name: "TestFlaky", Status: status},
+				{Name: "TestStable", Status: "pass"},
+			}}},
+		}
+		if err := h.save(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	flaky, err := h.Flakiness(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flaky) != 1 || flaky[0].Test != "TestFlaky" || flaky[0].Failures != 1 || flaky[0].Runs != 4 {
+		t.Errorf("Flakiness(0) = %+v", flaky)
+	}
+	// The failure is in the third newest run, outside of the last two.
+	if flaky, _ := h.Flakiness(2); len(flaky) != 0 {
+		t.Errorf("Flakiness(2) = %+v, want none", flaky)
+	}
+	if _, err := h.Load("../escape"); err == nil {
+		t.Error("Load accepted a path outside the store")
+	}
+}
@@ -0,0 +1,178 @@
+package wasmtest
+
+import (
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// htmlReportWriter is the built-in "html" writer producing a single
+// self-contained page, CSS and JavaScript included, with the test tree of
+// each plan, the output and duration of every test and the failure
+// details, to be attached to CI runs.
+type htmlReportWriter struct {
+	out   io.Writer
+	plans []RunPlan
+	// tests holds the tests of each plan in start order.
+	tests map[string][]*htmlTest
+	// byName indexes tests by plan and full name.
+	byName map[[2]string]*htmlTest
+	// output holds the package level output of each plan.
+	output map[string][]string
+}
+
+// htmlTest is a node of the test tree of a plan.
+type htmlTest struct {
+	// Name is the last element of the test name, Full the whole of it.
+	Name, Full string
+	// Status is "run" for tests that never finished, then "pass", "fail"
+	// or "skip".
+	Status   string
+	Elapsed  time.Duration
+	Output   []string
+	Subtests []*htmlTest
+}
+
+func (h *htmlReportWriter) Begin(plans []RunPlan) error {
+	h.plans = plans
+	h.tests = map[string][]*htmlTest{}
+	h.byName = map[[2]string]*htmlTest{}
+	h.output = map[string][]string{}
+	return nil
+}
+
+func (h *htmlReportWriter) TestEvent(ev TestEvent) error {
+	if ev.Test == "" {
+		if ev.Action == "output" {
+			h.output[ev.Plan] = append(h.output[ev.Plan], ev.Output)
+		}
+		return nil
+	}
+	key := [2]string{ev.Plan, ev.Test}
+	test := h.byName[key]
+	if test == nil {
+		test = &htmlTest{Full: ev.Test, Status: "run"}
+		h.byName[key] = test
+		h.tests[ev.Plan] = append(h.tests[ev.Plan], test)
+	}
+	switch ev.Action {
+	case "output":
+		test.Output = append(test.Output, ev.Output)
+	case "pass", "fail", "skip":
+		test.Status = ev.Action
+		test.Elapsed = ev.Elapsed
+	}
+	return nil
+}
+
+// htmlPlan is the template data of a plan.
+type htmlPlan struct {
+	Name, Dir string
+	Passed    bool
+	Duration  time.Duration
+	Error     string
+	Passes    int
+	Failures  int
+	Skips     int
+	Tests     []*htmlTest
+	Output    []string
+}
+
+func (h *htmlReportWriter) End(report *Report) error {
+	results := map[string]PlanResult{}
+	for _, res := range report.Results {
+		results[res.Plan.Name] = res
+	}
+	var plans []htmlPlan
+	for _, plan := range h.plans {
+		res, ran := results[plan.Name]
+		if !ran {
+			// Plans skipped by the run, e.g. not affected by ChangedSince.
+			continue
+		}
+		p := htmlPlan{
+			Name:     plan.Name,
+			Dir:      plan.Dir,
+			Passed:   res.Passed(),
+			Duration: res.Duration,
+			Passes:   len(res.PassedTests),
+			Failures: len(res.FailedTests),
+			Skips:    len(res.SkippedTests),
+			Tests:    testTree(h.tests[plan.Name]),
+			Output:   h.output[plan.Name],
+		}
+		if res.Err != nil {
+			p.Error = res.Err.Error()
+		}
+		plans = append(plans, p)
+	}
+	return htmlReportTemplate.Execute(h.out, map[string]any{
+		"Report":    report,
+		"Plans":     plans,
+		"Generated": time.Now(),
+	})
+}
+
+// testTree nests the subtests of tests, given in start order, under their
+// parents.
+func testTree(tests []*htmlTest) []*htmlTest {
+	byName := map[string]*htmlTest{}
+	var roots []*htmlTest
+	for _, test := range tests {
+		byName[test.Full] = test
+		test.Name, test.Subtests = test.Full, nil
+		if i := strings.LastIndex(test.Full, "/"); i >= 0 {
+			if parent := byName[test.Full[:i]]; parent != nil {
+				test.Name = test.Full[i+1:]
+				parent.Subtests = append(parent.Subtests, test)
+				continue
+			}
+		}
+		roots = append(roots, test)
+	}
+	return roots
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ms":   func(d time.Duration) string { return d.Round(time.Millisecond).String() },
+	"when": func(t time.Time) string { return t.Local().Format("2006-01-02 15:04:05") },
+}).Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>WasmTest report</title>
+<style>
+body{font-family:system-ui,sans-serif;margin:2rem;color:#222}
+.pass{color:#1a7f37}.fail{color:#cf222e}.skip,.run{color:#9a6700}
+summary{cursor:pointer;padding:.15rem 0}
+.test{margin-left:1.2rem}
+.elapsed{color:#777;font-size:.85em}
+.plan{border:1px solid #ddd;border-radius:6px;padding:.5rem 1rem;margin-bottom:1rem}
+pre{background:#f6f8fa;padding:.6rem;overflow:auto;max-height:30rem;margin:.3rem 0}
+.error{white-space:pre-wrap}
+body.failures-only .test.pass,body.failures-only .test.skip{display:none}
+</style></head><body>
+<h1>WasmTest report — {{if eq .Report.ExitCode 0}}<span class="pass">passed</span>{{else}}<span class="fail">failed</span>{{end}}</h1>
+<p>Generated {{when .Generated}}, took {{ms .Report.Duration}}.
+<label><input type="checkbox" id="failures-only"> Failures only</label>
+<button id="expand">Expand all</button> <button id="collapse">Collapse all</button></p>
+{{range .Plans}}<section class="plan">
+<h2 class="{{if .Passed}}pass{{else}}fail{{end}}">{{if .Passed}}✅{{else}}❌{{end}} {{.Name}}</h2>
+<p>{{.Dir}} — {{.Passes}} passed, {{.Failures}} failed, {{.Skips}} skipped in {{ms .Duration}}</p>
+{{if .Error}}<pre class="error fail">{{.Error}}</pre>{{end}}
+{{range .Tests}}{{template "test" .}}{{end}}
+{{if .Output}}<details><summary>Package output ({{len .Output}} lines)</summary><pre>{{range .Output}}{{.}}
+{{end}}</pre></details>{{end}}
+</section>
+{{end}}<script>
+document.getElementById("failures-only").addEventListener("change", e => document.body.classList.toggle("failures-only", e.target.checked));
+for (const [id, open] of [["expand", true], ["collapse", false]]) {
+  document.getElementById(id).addEventListener("click", () => document.querySelectorAll("details").forEach(d => d.open = open));
+}
+</script>
+</body></html>
+{{define "test"}}<details class="test {{.Status}}"{{if eq .Status "fail"}} open{{end}}>
+<summary><span class="{{.Status}}">{{.Status}}</span> {{.Name}} <span class="elapsed">{{ms .Elapsed}}</span></summary>
+{{if .Output}}<pre>{{range .Output}}{{.}}
+{{end}}</pre>{{end}}
+{{range .Subtests}}{{template "test" .}}{{end}}
+</details>
+{{end}}`))
@@ -0,0 +1,59 @@
+package wasmtest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHTMLReport(t *testing.T) {
+	node := nodeExec(t)
+	root := writeModule(t, map[string]string{
+		"pkg/html_test.go": "//go:build js && wasm\n\npackage tmp\n\nimport \"testing\"\n\n" +
+			"func TestTree(t *testing.T) {\n\tt.Run(\"leaf\", func(t *testing.T) { t.Log(\"<b>bold</b>\") })\n\tt.Run(\"broken\", func(t *testing.T) { t.Error(\"boom\") })\n}\n",
+	})
+	artifacts := filepath.Join(t.TempDir(), "artifacts")
+
+	dir := filepath.Join(root, "pkg")
+	if err := RunTests(dir, func(...any) {}, ArtifactsDir(artifacts), ExecOptions{Args: []string{"-exec", node}}, WithInstallDisabled()); err == nil {
+		t.Fatal("RunTests succeeded with a failing test")
+	}
+
+	data, err := os.ReadFile(filepath.Join(artifacts, "report.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(data)
+	for _, s := range []string{
+		"WasmTest report — <span class=\"fail\">failed</span>",
+		"1 passed, 2 failed, 0 skipped",
+		"<span class=\"fail\">fail</span> TestTree",
+		"<span class=\"pass\">pass</span> leaf",
+		"<span class=\"fail\">fail</span> broken",
+		"html_test.go:8: &lt;b&gt;bold&lt;/b&gt;",
+		"html_test.go:9: boom",
+		"<style>",
+		"<script>",
+	} {
+		if !strings.Contains(page, s) {
+			t.Errorf("report.html lacks %q:\n%s", s, page)
+		}
+	}
+	// Subtests are nested in their parent.
+	if strings.Index(page, "TestTree") > strings.Index(page, "leaf") {
+		t.Error("subtest rendered before its parent")
+	}
+}
+
+func TestTestTree(t *testing.T) {
+	tests := []*htmlTest{{Full: "TestA"}, {Full: "TestA/x"}, {Full: "TestA/x/y"}, {Full: "TestB"}, {Full: "TestC/orphan"}}
+	roots := testTree(tests)
+	if len(roots) != 3 || roots[0].Full != "TestA" || roots[1].Full != "TestB" || roots[2].Name != "TestC/orphan" {
+		t.Fatalf("testTree roots = %+v", roots)
+	}
+	x := roots[0].Subtests
+	if len(x) != 1 || x[0].Name != "x" || len(x[0].Subtests) != 1 || x[0].Subtests[0].Name != "y" {
+		t.Errorf("TestA subtests = %+v", x)
+	}
+}
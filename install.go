@@ -0,0 +1,146 @@
+package wasmtest
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// Stages of the wasmbrowsertest install (see InstallProgress).
+const (
+	InstallResolving   = "resolving module"
+	InstallDownloading = "downloading"
+	InstallBuilding    = "building"
+	InstallInstalled   = "installed at"
+)
+
+// InstallProgress is a stage of the wasmbrowsertest install, logged as
+// ("install", InstallProgress) through the WithLogger logger while go
+// install runs, so a TUI can show something during a multi-minute install.
+// The stages are InstallResolving, once, InstallDownloading, once per
+// downloaded module, InstallBuilding, once, and InstallInstalled.
+type InstallProgress struct {
+	Stage string `json:"stage"`
+	// Detail is the module, the downloaded module and version, the first
+	// compiled package or the installed binary path, by stage.
+	Detail string `json:"detail"`
+}
+
+// String renders the stage for log output.
+func (p InstallProgress) String() string {
+	return p.Stage + " " + p.Detail
+}
+
+// installProgress returns the stage announced by a line of `go install -x`
+// output, if any.
+func installProgress(line string) (InstallProgress, bool) {
+	if mod, ok := strings.CutPrefix(line, "go: downloading "); ok {
+		return InstallProgress{Stage: InstallDownloading, Detail: mod}, true
+	}
+	if pkgs := compiledPackages(line); len(pkgs) > 0 {
+		return InstallProgress{Stage: InstallBuilding, Detail: pkgs[0]}, true
+	}
+	return InstallProgress{}, false
+}
+
+// logInstallProgress logs the stages of the `go install -x` output read from
+// r, until its end. It returns the lines explaining a failure: the go
+// messages, and the module proxy requests that did not succeed.
+func (w *Wasmtest) logInstallProgress(r io.Reader) (failure []string) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	building := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if installFailure(line) {
+			failure = append(failure, line)
+		}
+		p, ok := installProgress(line)
+		if !ok || p.Stage == InstallBuilding && building {
+			continue
+		}
+		building = building || p.Stage == InstallBuilding
+		w.safeLog("install", p)
+	}
+	// Keep go install from blocking on an overlong line.
+	io.Copy(io.Discard, r)
+	return failure
+}
+
+// installFailure reports whether a line of `go install -x` output may
+// explain a failure: a go message other than a download, or the result of
+// a proxy request other than 200 OK, such as
+// "# get https://proxy.golang.org/...: 403 Forbidden (0.2s)".
+func installFailure(line string) bool {
+	if msg, ok := strings.CutPrefix(line, "go: "); ok {
+		return !strings.HasPrefix(msg, "downloading ")
+	}
+	get, ok := strings.CutPrefix(line, "# get ")
+	if !ok {
+		return false
+	}
+	// The request line is the URL alone, its result follows ": ".
+	i := strings.LastIndex(get, ": ")
+	return i >= 0 && !strings.HasPrefix(get[i+2:], "200 ")
+}
+
+// Prepare sets up ahead of time what the runs of w otherwise set up on
+// first use, for the build step of a CI or Docker image: wasmbrowsertest
+// (see Install), reinstalled when it is not the WithWasmBrowserTestVersion
+// one, the go_js_wasm_exec link to it that go test runs, and, when browser
+// names a Playwright engine (PlaywrightChromium, PlaywrightFirefox or
+// PlaywrightWebKit), that browser with `npx playwright install`. The test
+// runs of the image then need no network (see WithNoInstall).
+func (w *Wasmtest) Prepare(ctx context.Context, browser string, progress func(msgs ...any)) error {
+	if progress == nil {
+		progress = func(...any) {}
+	}
+	if browser != "" && !slices.Contains([]string{PlaywrightChromium, PlaywrightFirefox, PlaywrightWebKit}, browser) {
+		return fmt.Errorf("wasmtest: no managed %q browser; use %s, %s or %s", browser, PlaywrightChromium, PlaywrightFirefox, PlaywrightWebKit)
+	}
+
+	if warn := w.wasmBrowserTestMismatch(); warn != nil && !w.offline {
+		progress("info", warn.Message+"; installing "+w.wasmBrowserTestVersion)
+		w.installMu.Lock()
+		err := w.installWasmBrowserTest(ctx)
+		w.installMu.Unlock()
+		if err != nil {
+			return err
+		}
+	} else if err := w.Install(ctx); err != nil {
+		return err
+	}
+	if err := w.ensureWasmExecSymlink(progress); err != nil {
+		return err
+	}
+	if browser == "" {
+		return nil
+	}
+
+	// The playwright package is fetched by npx when no project has it.
+	progress("info", "installing the Playwright "+browser+" browser")
+	cmd := exec.CommandContext(ctx, "npx", "--yes", "playwright", "install", browser)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		return newRunError(ErrRunnerMissing, "wasmtest: installing the %s browser needs node and npx: %v", browser, err)
+	}
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			progress("out", line)
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("wasmtest: npx playwright install %s failed: %w", browser, err)
+	}
+	progress("info", "installed the Playwright "+browser+" browser")
+	return nil
+}
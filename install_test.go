@@ -0,0 +1,195 @@
+package wasmtest
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestInstallProgress(t *testing.T) {
+	for line, want := range map[string]InstallProgress{
+		"go: downloading github.com/chromedp/cdproto v0.0.0-2023":                                             {Stage: InstallDownloading, Detail: "github.com/chromedp/cdproto v0.0.0-2023"},
+		"/usr/local/go/pkg/tool/linux_amd64/compile -o $WORK/b042/_pkg_.a -p github.com/chromedp/cdproto/cdp": {Stage: InstallBuilding, Detail: "github.com/chromedp/cdproto/cdp"},
+	} {
+		if got, ok := installProgress(line); !ok || got != want {
+			t.Errorf("installProgress(%q) = %+v, %v; want %+v", line, got, ok, want)
+		}
+	}
+	for _, line := range []string{"mkdir -p $WORK/b001/", "# get https://proxy.golang.org/github.com/agnivade/wasmbrowsertest/@v/list", ""} {
+		if got, ok := installProgress(line); ok {
+			t.Errorf("installProgress(%q) = %+v", line, got)
+		}
+	}
+}
+
+func TestInstallStages(t *testing.T) {
+	// The fake go prints a go install -x trace and installs a fake
+	// wasmbrowsertest next to itself.
+	dir := t.TempDir()
+	script := `#!/bin/sh
+echo "go: downloading github.com/agnivade/wasmbrowsertest v0.8.0" >&2
+echo "go: downloading github.com/chromedp/chromedp v0.9.2" >&2
+echo "/go/pkg/tool/linux_amd64/compile -o b002/_pkg_.a -p github.com/chromedp/chromedp" >&2
+echo "/go/pkg/tool/linux_amd64/compile -o b001/_pkg_.a -p main" >&2
+printf '#!/bin/sh\n' > "$(dirname "$0")/wasmbrowsertest"
+chmod +x "$(dirname "$0")/wasmbrowsertest"
+`
+	if err := os.WriteFile(filepath.Join(dir, "go"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+"/bin"+string(os.PathListSeparator)+"/usr/bin")
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	var stages []string
+	w := New(WithAutoInstall(false), WithWasmBrowserTestVersion("v0.8.0"), WithLogger(func(args ...any) {
+		if len(args) == 2 && args[0] == "install" {
+			stages = append(stages, fmt.Sprint(args[1]))
+		}
+	}))
+	if err := w.Install(t.Context()); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	want := []string{
+		"resolving module github.com/agnivade/wasmbrowsertest@v0.8.0",
+		"downloading github.com/agnivade/wasmbrowsertest v0.8.0",
+		"downloading github.com/chromedp/chromedp v0.9.2",
+		"building github.com/chromedp/chromedp",
+		"installed at " + filepath.Join(dir, "wasmbrowsertest"),
+	}
+	if !slices.Equal(stages, want) {
+		t.Errorf("stages:\n%s\nwant:\n%s", strings.Join(stages, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestInstallFailureOutput(t *testing.T) {
+	for line, want := range map[string]bool{
+		"go: github.com/agnivade/wasmbrowsertest@latest: module lookup disabled by GOPROXY=off": true,
+		"go: downloading github.com/chromedp/chromedp v0.9.2":                                   false,
+		"# get https://proxy.corp/github.com/@v/list":                                           false,
+		"# get https://proxy.corp/github.com/@v/list: 200 OK (0.021s)":                          false,
+		"# get https://proxy.corp/github.com/@v/list: 403 Forbidden (0.021s)":                   true,
+		"mkdir -p $WORK/b001/": false,
+	} {
+		if got := installFailure(line); got != want {
+			t.Errorf("installFailure(%q) = %v", line, got)
+		}
+	}
+
+	// The fake go fails with the proxy set through WithInstallEnv.
+	dir := t.TempDir()
+	script := `#!/bin/sh
+echo "# get $GOPROXY/github.com/agnivade/wasmbrowsertest/@v/list" >&2
+echo "# get $GOPROXY/github.com/agnivade/wasmbrowsertest/@v/list: 403 Forbidden (0.012s)" >&2
+echo "go: github.com/agnivade/wasmbrowsertest@latest: reading $GOPROXY/github.com/agnivade/wasmbrowsertest/@v/list: 403 Forbidden" >&2
+exit 1
+`
+	if err := os.WriteFile(filepath.Join(dir, "go"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+"/bin"+string(os.PathListSeparator)+"/usr/bin")
+	w := New(WithAutoInstall(false), WithInstallEnv("GOPROXY=https://proxy.corp"), WithLogger(func(...any) {}))
+	err := w.Install(t.Context())
+	if err == nil {
+		t.Fatal("Install succeeded")
+	}
+	for _, want := range []string{"through https://proxy.corp failed", "list: 403 Forbidden (0.012s)", "go: github.com/agnivade/wasmbrowsertest@latest: reading"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+}
+
+func TestPathFix(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	// The fake go installs a fake wasmbrowsertest in GOBIN, which is not in
+	// PATH, and counts the installs.
+	fake := t.TempDir()
+	installs := filepath.Join(fake, "installs")
+	script := `#!/bin/sh
+echo install >> "` + installs + `"
+printf '#!/bin/sh\n' > "$GOBIN/wasmbrowsertest"
+chmod +x "$GOBIN/wasmbrowsertest"
+`
+	if err := os.WriteFile(filepath.Join(fake, "go"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", fake+string(os.PathListSeparator)+"/bin"+string(os.PathListSeparator)+"/usr/bin")
+	bin := t.TempDir()
+
+	w := New(WithAutoInstall(false), WithInstallDir(bin), WithLogger(func(...any) {}))
+	for range 2 {
+		if err := w.Install(t.Context()); err == nil || !strings.Contains(err.Error(), bin+", which is not in PATH") {
+			t.Errorf("Install = %v, want the install directory to add", err)
+		}
+	}
+	if data, _ := os.ReadFile(installs); string(data) != "install\n" {
+		t.Errorf("installed %d times, want once", strings.Count(string(data), "install"))
+	}
+	w = New(WithAutoInstall(false), WithInstallDir(bin), WithPathFix(), WithLogger(func(...any) {}))
+	if err := w.Install(t.Context()); err != nil {
+		t.Errorf("Install with WithPathFix = %v", err)
+	}
+}
+
+func TestPathFixExecute(t *testing.T) {
+	node := nodeExec(t)
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go not in PATH")
+	}
+	nodeBin, _ := exec.LookPath("node")
+	t.Setenv("PATH", strings.Join([]string{filepath.Dir(goBin), filepath.Dir(nodeBin), "/bin", "/usr/bin"}, string(os.PathListSeparator)))
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	// The go_js_wasm_exec of the install directory runs the tests with node.
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "go_js_wasm_exec"), []byte("#!/bin/sh\nexec "+node+` "$@"`+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	dir := writeModule(t, map[string]string{"p_test.go": wasmPassTest})
+
+	w := New(WithInstallDisabled(), WithInstallDir(bin), WithLogger(func(...any) {}))
+	progress, msgs := collectProgress()
+	if err := w.execute(t.Context(), execSpec{dir: dir}, progress); err == nil || !strings.Contains(strings.Join(msgs(), "\n"), "install-dir-not-in-path") {
+		t.Errorf("execute = %v, want the missing PATH entry reported\n%s", err, strings.Join(msgs(), "\n"))
+	}
+
+	w = New(WithInstallDisabled(), WithInstallDir(bin), WithPathFix(), WithLogger(func(...any) {}))
+	progress, msgs = collectProgress()
+	if err := w.execute(t.Context(), execSpec{dir: dir}, progress); err != nil || !strings.Contains(strings.Join(msgs(), "\n"), "added "+bin+" to the PATH of go test") {
+		t.Errorf("execute with WithPathFix = %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	// The fake go installs a fake wasmbrowsertest next to itself.
+	dir := t.TempDir()
+	script := `#!/bin/sh
+printf '#!/bin/sh\n' > "$(dirname "$0")/wasmbrowsertest"
+chmod +x "$(dirname "$0")/wasmbrowsertest"
+`
+	if err := os.WriteFile(filepath.Join(dir, "go"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+"/bin"+string(os.PathListSeparator)+"/usr/bin")
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	w := New(WithAutoInstall(false), WithInstallDir(dir), WithLogger(func(...any) {}))
+	if err := w.Prepare(t.Context(), "safari", nil); err == nil || !strings.Contains(err.Error(), `no managed "safari" browser`) {
+		t.Errorf("Prepare accepted an unknown browser: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "wasmbrowsertest")); err == nil {
+		t.Error("Prepare installed wasmbrowsertest for an unknown browser")
+	}
+
+	progress, msgs := collectProgress()
+	if err := w.Prepare(t.Context(), "", progress); err != nil {
+		t.Fatalf("Prepare failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	if dest, err := os.Readlink(filepath.Join(dir, "go_js_wasm_exec")); err != nil || dest != filepath.Join(dir, "wasmbrowsertest") {
+		t.Errorf("go_js_wasm_exec links to %q, %v", dest, err)
+	}
+}
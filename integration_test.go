@@ -28,14 +28,6 @@ func TestExecuteIntegration(t *testing.T) {
 		t.Skipf("Example directory not found: %s", exampleDir)
 	}
 
-	// Change to example directory
-	originalDir := cwd
-	defer os.Chdir(originalDir)
-
-	if err := os.Chdir(exampleDir); err != nil {
-		t.Fatalf("Failed to change to example directory: %v", err)
-	}
-
 	// Collect progress messages
 	var messages [][]any
 	var lastMessage []any
@@ -52,7 +44,7 @@ func TestExecuteIntegration(t *testing.T) {
 	}
 
 	// Initialize Wasmtest
-	w := New(logger)
+	w := New(WithLogger(logger), WithTestDir(exampleDir))
 
 	// Test the interfaces
 	if w.Name() == "" {
@@ -141,7 +133,7 @@ func TestExecuteWithNilProgress(t *testing.T) {
 		t.Logf("Log: %v", msgs)
 	}
 
-	w := New(logger)
+	w := New(WithLogger(logger))
 
 	// This should not panic and should return immediately
 	w.Execute(nil)
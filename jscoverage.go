@@ -0,0 +1,129 @@
+package wasmtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// WithJSCoverage collects the precise coverage of the JavaScript run by the
+// page (Profiler.takePreciseCoverage of the DevTools protocol), such as the
+// JS glue of a WASM app and wasm_exec.js, and writes it to
+// dir/<package>.js-coverage.json, next to the Go coverage profile when both
+// go to dir. The file keeps the V8 format ({"result": [ScriptCoverage...]}),
+// which c8 and v8-to-istanbul turn into lcov or HTML reports; the harness
+// page itself is left out. The covered share of each script is reported as
+// an info message. It affects only RunBundle with Playwright's Chromium
+// engine (see WithPlaywright): other bundle runs report a
+// "jscoverage-unsupported" warning, and go test runs fail with ErrUsage.
+func WithJSCoverage(dir string) Option {
+	return func(w *Wasmtest) { w.jsCoverageDir = dir }
+}
+
+// jsCoverageUnsupported is reported when WithJSCoverage is set for a run
+// that can't be measured.
+var jsCoverageUnsupported = Warning{
+	Code:    "jscoverage-unsupported",
+	Message: "JS coverage is only collected by RunBundle with the Playwright Chromium engine; this run is not measured",
+	Hint:    "run the bundle with WithPlaywright(PlaywrightOptions{Engine: PlaywrightChromium})",
+}
+
+// scriptCoverage is the coverage of one script, as returned by
+// Profiler.takePreciseCoverage. Unknown fields are dropped.
+type scriptCoverage struct {
+	ScriptID  string `json:"scriptId"`
+	URL       string `json:"url"`
+	Functions []struct {
+		FunctionName    string          `json:"functionName"`
+		Ranges          []coverageRange `json:"ranges"`
+		IsBlockCoverage bool            `json:"isBlockCoverage"`
+	} `json:"functions"`
+}
+
+// coverageRange is a range of a script run count times. Ranges nest: the
+// innermost one holds the count of its bytes.
+type coverageRange struct {
+	StartOffset int `json:"startOffset"`
+	EndOffset   int `json:"endOffset"`
+	Count       int `json:"count"`
+}
+
+// covered returns the number of bytes of the script run at least once, and
+// its size.
+func (s *scriptCoverage) covered() (covered, size int) {
+	var ranges []coverageRange
+	for _, f := range s.Functions {
+		ranges = append(ranges, f.Ranges...)
+	}
+	for _, r := range ranges {
+		size = max(size, r.EndOffset)
+	}
+	// Outer ranges are applied first, so the inner ones win.
+	slices.SortStableFunc(ranges, func(a, b coverageRange) int {
+		return (b.EndOffset - b.StartOffset) - (a.EndOffset - a.StartOffset)
+	})
+	counts := make([]bool, size)
+	for _, r := range ranges {
+		for i := max(r.StartOffset, 0); i < r.EndOffset; i++ {
+			counts[i] = r.Count > 0
+		}
+	}
+	for _, run := range counts {
+		if run {
+			covered++
+		}
+	}
+	return covered, size
+}
+
+// saveJSCoverage writes the coverage of pkg, taken at src by the page
+// served at page, to the WithJSCoverage directory and reports the covered
+// share of each script.
+func (w *Wasmtest) saveJSCoverage(src, page, pkg string, progress func(msgs ...any)) {
+	fail := func(err error) {
+		progress("warning", Warning{Code: "jscoverage-failed", Message: fmt.Sprintf("failed to save the JS coverage: %v", err)})
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		fail(err)
+		return
+	}
+	var coverage struct {
+		Result []scriptCoverage `json:"result"`
+	}
+	if err := json.Unmarshal(data, &coverage); err != nil {
+		fail(err)
+		return
+	}
+	// Scripts without URL are evaluated code, such as the DevTools'.
+	coverage.Result = slices.DeleteFunc(coverage.Result, func(s scriptCoverage) bool {
+		return s.URL == "" || strings.TrimSuffix(s.URL, "index.html") == page
+	})
+	if data, err = json.MarshalIndent(coverage, "", "  "); err != nil {
+		fail(err)
+		return
+	}
+	dst := filepath.Join(w.jsCoverageDir, artifactName(pkg)+".js-coverage.json")
+	if err = os.MkdirAll(filepath.Dir(dst), 0o755); err == nil {
+		err = os.WriteFile(dst, data, 0o644)
+	}
+	if err != nil {
+		fail(err)
+		return
+	}
+	var shares []string
+	for _, s := range coverage.Result {
+		if covered, size := s.covered(); size > 0 {
+			shares = append(shares, fmt.Sprintf("%s %.1f%%", path.Base(s.URL), 100*float64(covered)/float64(size)))
+		}
+	}
+	msg := "JS coverage saved to " + dst
+	if len(shares) > 0 {
+		msg += ": " + strings.Join(shares, ", ")
+	}
+	progress("info", msg)
+}
@@ -0,0 +1,66 @@
+package wasmtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScriptCoverageCovered(t *testing.T) {
+	var s scriptCoverage
+	data := `{"functions": [
+		{"ranges": [{"startOffset": 0, "endOffset": 100, "count": 1}, {"startOffset": 10, "endOffset": 40, "count": 0}]},
+		{"ranges": [{"startOffset": 20, "endOffset": 30, "count": 2}]}
+	]}`
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		t.Fatal(err)
+	}
+	// 0-10 and 40-100 run, 10-40 doesn't but for the function at 20-30.
+	if covered, size := s.covered(); covered != 80 || size != 100 {
+		t.Errorf("covered() = %d, %d; want 80, 100", covered, size)
+	}
+}
+
+func TestRunBundleJSCoverage(t *testing.T) {
+	pw := fakePlaywrightDir(t)
+	dir := t.TempDir()
+	w := New(WithInstallDisabled(), WithPlaywright(PlaywrightOptions{Dir: pw, NoInstall: true}), WithJSCoverage(dir))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	out := t.TempDir()
+	m, err := w.Bundle(ctx, "./example", out, "-test.run=TestMathHelper")
+	if err != nil {
+		t.Fatalf("Bundle failed: %v", err)
+	}
+	progress, msgs := collectProgress()
+	if err := w.RunBundle(ctx, out, RunBundleOptions{}, progress); err != nil {
+		t.Fatalf("RunBundle failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	path := filepath.Join(dir, artifactName(m.Package)+".js-coverage.json")
+	if !strings.Contains(strings.Join(msgs(), "\n"), "JS coverage saved to "+path+": glue.js 50.0%") {
+		t.Errorf("coverage not reported:\n%s", strings.Join(msgs(), "\n"))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var coverage struct {
+		Result []scriptCoverage `json:"result"`
+	}
+	// The harness page is left out.
+	if err := json.Unmarshal(data, &coverage); err != nil || len(coverage.Result) != 1 || !strings.HasSuffix(coverage.Result[0].URL, "/glue.js") {
+		t.Errorf("coverage = %s, %v", data, err)
+	}
+
+	// go test runs can't be measured and are rejected.
+	progress, msgs = collectProgress()
+	err = w.execute(t.Context(), execSpec{dir: writeModule(t, map[string]string{"p_test.go": wasmPassTest}), exec: "/bin/true"}, progress)
+	if !errors.Is(err, ErrUsage) || !strings.Contains(err.Error(), "WithPlaywright, WithJSCoverage") {
+		t.Errorf("go test run with JS coverage = %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+}
@@ -0,0 +1,218 @@
+package wasmtest
+
+import (
+	"debug/buildinfo"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+)
+
+// JSONReport is the document written by the "json" report writer, and as
+// run-report.json in the ArtifactsDir: the environment of the run, the
+// results and timings of every test and the raw event stream, so tools
+// don't have to scrape the logs. Its schema follows SchemaVersion.
+type JSONReport struct {
+	// SchemaVersion is the SchemaVersion the report was written with.
+	SchemaVersion int           `json:"schemaVersion"`
+	Started       time.Time     `json:"started"`
+	Duration      time.Duration `json:"duration"`
+	ExitCode      int           `json:"exitCode"`
+	Environment   Environment   `json:"environment"`
+	// Packages holds a record per plan; Output is left empty as the
+	// output is part of Events.
+	Packages []PlanRecord `json:"packages"`
+	// Events holds every test event in the order received.
+	Events []TestEvent `json:"events"`
+}
+
+// Environment describes the tools a run used.
+type Environment struct {
+	// GoVersion is the version of the go command running the tests.
+	GoVersion string `json:"goVersion"`
+	// GOOS and GOARCH are the host platform.
+	GOOS   string `json:"goos"`
+	GOARCH string `json:"goarch"`
+	// Browser is the browser executable, empty when none was found.
+	Browser string `json:"browser,omitempty"`
+	// Runner is the path of the wasmbrowsertest binary and RunnerVersion
+	// its module version; both are empty when it isn't installed.
+	Runner        string `json:"runner,omitempty"`
+	RunnerVersion string `json:"runnerVersion,omitempty"`
+	// Wasmtest is the version of this package, when known.
+	Wasmtest string `json:"wasmtest,omitempty"`
+}
+
+// detectEnvironment returns the Environment of a run using browser, a
+// Browser name or executable, or the first installed browser when empty,
+// and the go of tc.
+func detectEnvironment(browser string, tc goToolchain) Environment {
+	env := Environment{GoVersion: runtime.Version(), GOOS: runtime.GOOS, GOARCH: runtime.GOARCH, Browser: browser}
+	cmd := exec.Command(tc.command(), "env", "GOVERSION")
+	cmd.Env = execSpec{toolchain: tc}.environ()
+	if out, err := cmd.Output(); err == nil {
+		env.GoVersion = strings.TrimSpace(string(out))
+	}
+	if path, err := lookupBrowser(browser); err == nil {
+		env.Browser = path
+	}
+	for _, name := range []string{"wasmbrowsertest", "go_js_wasm_exec"} {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			continue
+		}
+		env.Runner = path
+		if info, err := buildinfo.ReadFile(path); err == nil {
+			env.RunnerVersion = info.Main.Version
+		}
+		break
+	}
+	env.Wasmtest = moduleVersion()
+	return env
+}
+
+// moduleVersion returns the version of this module in the running binary,
+// empty when unknown.
+func moduleVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range append([]*debug.Module{&info.Main}, info.Deps...) {
+			if dep.Path == "github.com/cdvelop/wasmtest" {
+				return dep.Version
+			}
+		}
+	}
+	return ""
+}
+
+// jsonReportWriter is the built-in "json" writer producing a JSONReport.
+type jsonReportWriter struct {
+	out io.Writer
+	// browser is the configured browser and toolchain the configured go,
+	// see detectEnvironment.
+	browser   string
+	toolchain goToolchain
+	report    JSONReport
+}
+
+func (j *jsonReportWriter) Begin(plans []RunPlan) error {
+	j.report = JSONReport{SchemaVersion: SchemaVersion, Started: time.Now(), Events: []TestEvent{}}
+	for _, plan := range plans {
+		name := plan.Name
+		if name == "" {
+			name = plan.Dir
+		}
+		j.report.Packages = append(j.report.Packages, PlanRecord{Name: name, Dir: plan.Dir, Tests: []TestRecord{}})
+	}
+	return nil
+}
+
+func (j *jsonReportWriter) TestEvent(ev TestEvent) error {
+	j.report.Events = append(j.report.Events, ev)
+	return nil
+}
+
+func (j *jsonReportWriter) End(report *Report) error {
+	j.report.Duration = report.Duration
+	j.report.ExitCode = report.ExitCode
+	j.report.Environment = detectEnvironment(j.browser, j.toolchain)
+	for i := range j.report.Packages {
+		plan := &j.report.Packages[i]
+		k := slices.IndexFunc(report.Results, func(res PlanResult) bool { return res.Plan.Name == plan.Name })
+		if k < 0 {
+			continue
+		}
+		res := report.Results[k]
+		plan.Passed = res.Passed()
+		plan.ExitCode = res.ExitCode
+		plan.Duration = res.Duration
+		if res.Err != nil {
+			plan.Error = res.Err.Error()
+		}
+	}
+	for _, ev := range j.report.Events {
+		if ev.Test == "" || ev.Action == "output" || ev.parallelStep() {
+			continue
+		}
+		i := slices.IndexFunc(j.report.Packages, func(p PlanRecord) bool { return p.Name == ev.Plan })
+		if i < 0 {
+			continue
+		}
+		plan := &j.report.Packages[i]
+		k := slices.IndexFunc(plan.Tests, func(t TestRecord) bool { return t.Name == ev.Test })
+		if k < 0 {
+			plan.Tests = append(plan.Tests, TestRecord{Name: ev.Test})
+			k = len(plan.Tests) - 1
+		}
+		plan.Tests[k].Status = ev.Action
+		plan.Tests[k].Elapsed = ev.Elapsed
+	}
+
+	enc := json.NewEncoder(j.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&j.report)
+}
+
+// ReadJSONReport reads a JSONReport written by the "json" report writer,
+// e.g. the run-report.json of an ArtifactsDir.
+func ReadJSONReport(r io.Reader) (*JSONReport, error) {
+	var report JSONReport
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return nil, fmt.Errorf("wasmtest: reading the run report: %w", err)
+	}
+	if err := checkSchemaVersion("run report", report.SchemaVersion); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Render replays the saved run of r through rw, as if rw had been attached
+// to it, so a run made in CI can be rendered in another format later, e.g.
+// as HTML or JUnit XML. The results of each package are rebuilt from the
+// events: the output of the failed tests is there, but the package level
+// output and the compile statistics are not. The "json" writer records the
+// environment of the host rendering the report, not of the run.
+func (r *JSONReport) Render(rw ReportWriter) error {
+	plans := make([]RunPlan, 0, len(r.Packages))
+	results := make([]PlanResult, 0, len(r.Packages))
+	for _, pkg := range r.Packages {
+		plan := RunPlan{Name: pkg.Name, Dir: pkg.Dir}
+		res := PlanResult{Plan: plan, RunResult: *newRunResult(pkg.Dir)}
+		for _, ev := range r.Events {
+			if ev.Plan != pkg.Name {
+				continue
+			}
+			if ev.Action == "output" {
+				res.RawOutput = append(res.RawOutput, ev.Output)
+			}
+			if ev.Test != "" {
+				res.collectOutput(ev)
+				res.collectTest(ev)
+			}
+		}
+		res.ExitCode = pkg.ExitCode
+		res.Duration = pkg.Duration
+		if pkg.Error != "" {
+			res.Err = errors.New(pkg.Error)
+		} else if !pkg.Passed {
+			res.Err = fmt.Errorf("%s failed", pkg.Name)
+		}
+		plans = append(plans, plan)
+		results = append(results, res)
+	}
+
+	if err := rw.Begin(plans); err != nil {
+		return err
+	}
+	for _, ev := range r.Events {
+		if err := rw.TestEvent(ev); err != nil {
+			return err
+		}
+	}
+	return rw.End(&Report{Results: results, Duration: r.Duration, ExitCode: r.ExitCode})
+}
@@ -0,0 +1,81 @@
+package wasmtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestJSONReport(t *testing.T) {
+	node := nodeExec(t)
+	root := writeModule(t, map[string]string{
+		"a/a_test.go": wasmPassTest,
+		"b/b_test.go": wasmFailTest,
+	})
+	artifacts := ArtifactsDir(filepath.Join(t.TempDir(), "artifacts"))
+	a, b := filepath.Join(root, "a"), filepath.Join(root, "b")
+	if err := RunTests([]string{a, b}, func(...any) {}, artifacts, ExecOptions{Args: []string{"-exec", node}}, WithInstallDisabled()); err == nil {
+		t.Fatal("RunTests succeeded with a failing package")
+	}
+
+	data, err := os.ReadFile(filepath.Join(string(artifacts), "run-report.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report JSONReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.SchemaVersion != SchemaVersion || report.ExitCode != 1 || report.Started.IsZero() {
+		t.Errorf("report header = %d, %d, %v", report.SchemaVersion, report.ExitCode, report.Started)
+	}
+	if env := report.Environment; !strings.HasPrefix(env.GoVersion, "go") || env.GOOS != runtime.GOOS || env.GOARCH != runtime.GOARCH {
+		t.Errorf("Environment = %+v", env)
+	}
+	if len(report.Packages) != 2 {
+		t.Fatalf("Packages = %+v", report.Packages)
+	}
+	pass, fail := report.Packages[0], report.Packages[1]
+	if pass.Dir != a || !pass.Passed || len(pass.Tests) != 1 || pass.Tests[0].Status != "pass" {
+		t.Errorf("passing package = %+v", pass)
+	}
+	if fail.Dir != b || fail.Passed || fail.Error == "" || len(fail.Tests) != 1 || fail.Tests[0].Status != "fail" {
+		t.Errorf("failing package = %+v", fail)
+	}
+
+	var runs, outputs int
+	for _, ev := range report.Events {
+		switch ev.Action {
+		case "run":
+			runs++
+		case "output":
+			outputs++
+		}
+	}
+	if runs != 2 || outputs == 0 {
+		t.Errorf("events: %d runs and %d outputs in %+v", runs, outputs, report.Events)
+	}
+
+	// The saved report renders in another format.
+	saved, err := ReadJSONReport(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	junit, _ := NewReportWriter("junit", &out)
+	if err := saved.Render(junit); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`<testsuites tests="2" failures="1"`, `<testcase name="TestPass" classname="` + a, `<failure message="Failed">`, "bad</failure>"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("rendered report lacks %q:\n%s", want, out.String())
+		}
+	}
+	if _, err := ReadJSONReport(strings.NewReader(`{"schemaVersion": 99}`)); err == nil {
+		t.Error("ReadJSONReport accepted a newer schema")
+	}
+}
@@ -0,0 +1,110 @@
+package wasmtest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// junitReportWriter is the built-in "junit" writer producing the JUnit XML
+// report read by most CI servers: a testsuite per plan with a testcase per
+// test, subtests included, the output of the failed tests as their failure
+// text, and an error testcase for a plan failing without a failed test,
+// e.g. on a build error.
+type junitReportWriter struct {
+	out io.Writer
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func (j *junitReportWriter) Begin(plans []RunPlan) error { return nil }
+
+func (j *junitReportWriter) TestEvent(ev TestEvent) error { return nil }
+
+func (j *junitReportWriter) End(report *Report) error {
+	suites := junitTestSuites{Time: junitSeconds(report.Duration)}
+	for _, res := range report.Results {
+		suite := junitTestSuite{Name: res.Plan.Name, Time: junitSeconds(res.Duration)}
+		tests := slices.Concat(res.PassedTests, res.FailedTests, res.SkippedTests)
+		slices.Sort(tests)
+		for _, test := range slices.Compact(tests) {
+			tc := junitTestCase{Name: test, Classname: res.Plan.Name, Time: junitSeconds(res.Durations[test])}
+			switch {
+			case slices.Contains(res.FailedTests, test):
+				tc.Failure = &junitMessage{Message: "Failed", Text: strings.Join(res.FailureOutput[test], "\n")}
+				suite.Failures++
+			case slices.Contains(res.SkippedTests, test):
+				tc.Skipped = &junitMessage{Message: "Skipped"}
+				suite.Skipped++
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+		if res.Err != nil && len(res.FailedTests) == 0 {
+			first, _, _ := strings.Cut(res.Err.Error(), "\n")
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      res.Plan.Name,
+				Classname: res.Plan.Name,
+				Time:      junitSeconds(res.Duration),
+				Error:     &junitMessage{Message: first, Text: res.Err.Error()},
+			})
+			suite.Errors++
+		}
+		suite.Tests = len(suite.Cases)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	if _, err := io.WriteString(j.out, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(j.out)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(j.out, "\n")
+	return err
+}
+
+// junitSeconds formats d as the seconds of a JUnit time attribute.
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
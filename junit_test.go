@@ -0,0 +1,59 @@
+package wasmtest
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJUnitReportWriter(t *testing.T) {
+	report := &Report{
+		ExitCode: 1,
+		Duration: 2500 * time.Millisecond,
+		Results: []PlanResult{
+			{
+				Plan: RunPlan{Name: "ui"},
+				RunResult: RunResult{
+					PassedTests:   []string{"TestA"},
+					FailedTests:   []string{"TestC"},
+					SkippedTests:  []string{"TestB"},
+					Durations:     map[string]time.Duration{"TestA": 100 * time.Millisecond, "TestC": 300 * time.Millisecond},
+					FailureOutput: map[string][]string{"TestC": {"c_test.go:9: got 1 & 2"}},
+					Duration:      time.Second,
+				},
+				Err: errors.New("tests failed"),
+			},
+			{Plan: RunPlan{Name: "broken"}, Err: errors.New("build failed\nmore details")},
+		},
+	}
+	var out bytes.Buffer
+	w, err := NewReportWriter("junit", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.End(report); err != nil {
+		t.Fatal(err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="4" failures="1" errors="1" skipped="1" time="2.500">
+  <testsuite name="ui" tests="3" failures="1" errors="0" skipped="1" time="1.000">
+    <testcase name="TestA" classname="ui" time="0.100"></testcase>
+    <testcase name="TestB" classname="ui" time="0.000">
+      <skipped message="Skipped"></skipped>
+    </testcase>
+    <testcase name="TestC" classname="ui" time="0.300">
+      <failure message="Failed">c_test.go:9: got 1 &amp; 2</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="broken" tests="1" failures="0" errors="1" skipped="0" time="0.000">
+    <testcase name="broken" classname="broken" time="0.000">
+      <error message="build failed">build failed&#xA;more details</error>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	if out.String() != want {
+		t.Errorf("junit report:\n%s\nwant\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,147 @@
+package wasmtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLogMaxSize is the size at which WithLogFile rotates the log file
+// when no size is given.
+const DefaultLogMaxSize = 10 << 20
+
+// WithLogFile tees every progress message of Execute and the other runners
+// to the file at path, one timestamped line per message tagged with the
+// test directory, so long CI runs and watch sessions leave a transcript for
+// post-mortem debugging. When a write would make the file exceed maxSize
+// bytes (DefaultLogMaxSize when <= 0), it is renamed to path.1, path.1 to
+// path.2 and so on, keeping at most backups old files, and a new file is
+// started. Several Wasmtest values logging to the same path share it.
+func WithLogFile(path string, maxSize int64, backups int) Option {
+	return func(w *Wasmtest) {
+		if maxSize <= 0 {
+			maxSize = DefaultLogMaxSize
+		}
+		w.logFile = sharedLogFile(path, maxSize, max(backups, 0))
+	}
+}
+
+var (
+	logFilesMu sync.Mutex
+	logFiles   = map[string]*rotatingFile{}
+)
+
+// sharedLogFile returns the rotatingFile of path, creating it on first use.
+// The latest size and backups settings win.
+func sharedLogFile(path string, maxSize int64, backups int) *rotatingFile {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	logFilesMu.Lock()
+	defer logFilesMu.Unlock()
+	f := logFiles[path]
+	if f == nil {
+		f = &rotatingFile{path: path}
+		logFiles[path] = f
+	}
+	f.mu.Lock()
+	f.maxSize, f.backups = maxSize, backups
+	f.mu.Unlock()
+	return f
+}
+
+// rotatingFile is an append-only log file rotated by size.
+type rotatingFile struct {
+	path    string
+	mu      sync.Mutex
+	maxSize int64
+	backups int
+	f       *os.File
+	size    int64
+}
+
+// Write appends p, rotating the file first when p would not fit.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// open opens the log file for appending, creating its directory if needed.
+func (r *rotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f, r.size = f, info.Size()
+	return nil
+}
+
+// rotate shifts the backups, moves the current file to path.1 and starts a
+// new one.
+func (r *rotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	r.f = nil
+	if r.backups == 0 {
+		if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return r.open()
+	}
+	os.Remove(r.backup(r.backups))
+	for i := r.backups - 1; i >= 1; i-- {
+		if err := os.Rename(r.backup(i), r.backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(r.path, r.backup(1)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return r.open()
+}
+
+// backup returns the path of the i-th old file.
+func (r *rotatingFile) backup(i int) string {
+	return r.path + "." + strconv.Itoa(i)
+}
+
+// logProgress writes the progress message msgs of the run in dir as a line
+// of the log file. Write errors are dropped: the transcript must never
+// break a run.
+func (r *rotatingFile) logProgress(dir string, msgs ...any) {
+	if len(msgs) == 0 {
+		return
+	}
+	if dir == "" {
+		dir = "."
+	}
+	text := strings.TrimSuffix(fmt.Sprintln(msgs[1:]...), "\n")
+	text = strings.ReplaceAll(text, "\n", "\n\t")
+	fmt.Fprintf(r, "%s [%s] %v %s\n", time.Now().Format("2006-01-02T15:04:05.000Z07:00"), dir, msgs[0], text)
+}
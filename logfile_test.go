@@ -0,0 +1,57 @@
+package wasmtest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "wasmtest.log")
+	r := sharedLogFile(path, 10, 2)
+	if sharedLogFile(path, 10, 2) != r {
+		t.Error("the same path got two log files")
+	}
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for file, want := range map[string]string{path: "fourth\n", path + ".1": "third\n", path + ".2": "second\n"} {
+		if data, err := os.ReadFile(file); err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v; want %q", filepath.Base(file), data, err, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("more backups than configured: %v", err)
+	}
+
+	// Without backups the file starts over.
+	r = sharedLogFile(filepath.Join(t.TempDir(), "solo.log"), 10, 0)
+	r.Write([]byte("first\n"))
+	r.Write([]byte("second\n"))
+	if data, _ := os.ReadFile(r.path); string(data) != "second\n" {
+		t.Errorf("solo.log = %q", data)
+	}
+}
+
+func TestRunTestsLogFile(t *testing.T) {
+	node := nodeExec(t)
+	dir := writeModule(t, map[string]string{"l_test.go": wasmFailTest})
+	path := filepath.Join(t.TempDir(), "run.log")
+
+	if err := RunTests(dir, func(...any) {}, Quiet, WithLogFile(path, 0, 1), ExecOptions{Args: []string{"-exec", node}}, WithInstallDisabled()); err == nil {
+		t.Fatal("RunTests succeeded with a failing test")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The transcript has every message, whatever the verbosity.
+	for _, s := range []string{"[" + dir + "] out === RUN   TestFail", "[" + dir + "] test fail TestFail", "[" + dir + "] exit error"} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("log file lacks %q:\n%s", s, data)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package wasmtest
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// markdownSlowest is the number of tests listed by the "markdown" writer.
+const markdownSlowest = 5
+
+// markdownReportWriter is the built-in "markdown" writer printing a compact
+// summary of the run, suitable for a pull request comment or a chat
+// message: the totals, the failed tests and the slowest tests.
+type markdownReportWriter struct {
+	out io.Writer
+}
+
+func (m *markdownReportWriter) Begin(plans []RunPlan) error { return nil }
+
+func (m *markdownReportWriter) TestEvent(ev TestEvent) error { return nil }
+
+func (m *markdownReportWriter) End(report *Report) error {
+	var b strings.Builder
+	var passed, failed, skipped int
+	for _, res := range report.Results {
+		passed += len(res.PassedTests)
+		failed += len(res.FailedTests)
+		skipped += len(res.SkippedTests)
+	}
+	status := "✅ WebAssembly tests passed"
+	if report.ExitCode != 0 {
+		status = "❌ WebAssembly tests failed"
+	}
+	fmt.Fprintf(&b, "### %s\n\n", status)
+	fmt.Fprintf(&b, "**%d passed, %d failed, %d skipped** in %d %s, %v\n",
+		passed, failed, skipped, len(report.Results), plural(len(report.Results), "package", "packages"), report.Duration.Round(time.Millisecond))
+
+	if failures := failureLines(report); len(failures) > 0 {
+		b.WriteString("\n**Failed**\n\n")
+		b.WriteString(strings.Join(failures, "\n"))
+		b.WriteString("\n")
+	}
+	if slowest := slowestTests(report, markdownSlowest); len(slowest) > 0 {
+		b.WriteString("\n**Slowest**\n\n")
+		for i, test := range slowest {
+			fmt.Fprintf(&b, "%d. `%s` %v", i+1, test.Test, test.Elapsed.Round(time.Millisecond))
+			if len(report.Results) > 1 {
+				fmt.Fprintf(&b, " (%s)", escapeMarkdown(test.Plan))
+			}
+			b.WriteString("\n")
+		}
+	}
+	_, err := io.WriteString(m.out, b.String())
+	return err
+}
+
+// failureLines returns a Markdown list item for each failed test of report,
+// and for each plan that failed without a failed test, e.g. on a build
+// error.
+func failureLines(report *Report) []string {
+	var lines []string
+	for _, res := range report.Results {
+		for _, test := range res.FailedTests {
+			lines = append(lines, fmt.Sprintf("- `%s` (%s)", test, escapeMarkdown(res.Plan.Name)))
+		}
+		if res.Err != nil && len(res.FailedTests) == 0 {
+			first, _, _ := strings.Cut(res.Err.Error(), "\n")
+			lines = append(lines, fmt.Sprintf("- %s: %s", escapeMarkdown(res.Plan.Name), escapeMarkdown(first)))
+		}
+	}
+	return lines
+}
+
+// plural returns one when n is 1 and many otherwise.
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
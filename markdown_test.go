@@ -0,0 +1,50 @@
+package wasmtest
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMarkdownReportWriter(t *testing.T) {
+	report := &Report{
+		ExitCode: 1,
+		Duration: 2500 * time.Millisecond,
+		Results: []PlanResult{
+			{
+				Plan: RunPlan{Name: "ui"},
+				RunResult: RunResult{
+					PassedTests: []string{"TestA", "TestB", "TestB/sub"},
+					FailedTests: []string{"TestC"},
+					Durations: map[string]time.Duration{
+						"TestA": 100 * time.Millisecond, "TestB": 900 * time.Millisecond,
+						"TestB/sub": 800 * time.Millisecond, "TestC": 300 * time.Millisecond,
+					},
+				},
+				Err: errors.New("tests failed"),
+			},
+			{Plan: RunPlan{Name: "broken|pkg"}, Err: errors.New("build failed\nmore details")},
+		},
+	}
+	var out bytes.Buffer
+	w, err := NewReportWriter("markdown", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.End(report); err != nil {
+		t.Fatal(err)
+	}
+	want := "### ❌ WebAssembly tests failed\n\n" +
+		"**3 passed, 1 failed, 0 skipped** in 2 packages, 2.5s\n\n" +
+		"**Failed**\n\n" +
+		"- `TestC` (ui)\n" +
+		"- broken\\|pkg: build failed\n\n" +
+		"**Slowest**\n\n" +
+		"1. `TestB` 900ms (ui)\n" +
+		"2. `TestC` 300ms (ui)\n" +
+		"3. `TestA` 100ms (ui)\n"
+	if out.String() != want {
+		t.Errorf("markdown summary:\n%s\nwant\n%s", out.String(), want)
+	}
+}
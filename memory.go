@@ -0,0 +1,153 @@
+package wasmtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MemoryPoint selects when WithMemoryProfile samples the memory of the
+// page. Points combine with |.
+type MemoryPoint int
+
+const (
+	// MemoryAtEnd samples once the test binary exited.
+	MemoryAtEnd MemoryPoint = 1 << iota
+	// MemoryOnFailure samples when a test fails and at the end of a failed
+	// run, and keeps the heap snapshot of failed runs.
+	MemoryOnFailure
+	// MemoryPerTest samples after every test.
+	MemoryPerTest
+)
+
+// MemorySample is the memory use of the page at one MemoryPoint.
+type MemorySample struct {
+	// Point is "test" (after a test, see MemoryPerTest), "failure" (after
+	// a failed test) or "end" (after the run).
+	Point string `json:"point"`
+	// Test is the test that just finished, empty at the end of the run.
+	Test string `json:"test,omitempty"`
+	// WasmMemory is the size in bytes of the linear memory of the Go
+	// program, its heap included. It never shrinks, so steady growth
+	// across tests points at a leak.
+	WasmMemory int64 `json:"wasmMemory"`
+	// JSHeap is the used JavaScript heap in bytes, where the browser tells
+	// it (Chromium), or 0.
+	JSHeap int64 `json:"jsHeap,omitempty"`
+}
+
+// MemoryReport is the <package>.memory.json file written by
+// WithMemoryProfile.
+type MemoryReport struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	Package       string         `json:"package"`
+	Samples       []MemorySample `json:"samples"`
+}
+
+// WithMemoryProfile samples the memory of RunBundle runs at the given
+// points into dir/<package>.memory.json (see MemoryReport), to track down
+// memory growth in long running WASM apps. Bundles run with Playwright's
+// Chromium engine also get a V8 heap snapshot of the page at the end of the
+// run, kept as dir/<package>.heapsnapshot with MemoryAtEnd, or for failed
+// runs with MemoryOnFailure; it opens in the Memory panel of the Chrome
+// devtools. An empty dir is the current directory. It affects only
+// RunBundle: go test runs fail with ErrUsage, as wasmbrowsertest serves its
+// own page.
+func WithMemoryProfile(dir string, at MemoryPoint) Option {
+	return func(w *Wasmtest) {
+		w.memoryDir = dir
+		w.memoryAt = at
+	}
+}
+
+// memoryUse is the memory use of the page posted along with its output.
+type memoryUse struct {
+	Wasm int64 `json:"wasm"`
+	JS   int64 `json:"js"`
+}
+
+// memorySampler collects the MemorySamples of a harness.
+type memorySampler struct {
+	at      MemoryPoint
+	samples []MemorySample
+}
+
+// line samples use if line ends a test of interest.
+func (s *memorySampler) line(line string, use memoryUse) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[0] != "---" || !strings.HasSuffix(fields[1], ":") {
+		return
+	}
+	switch {
+	case fields[1] == "FAIL:" && s.at&MemoryOnFailure != 0:
+		s.add("failure", fields[2], use)
+	case s.at&MemoryPerTest != 0:
+		s.add("test", fields[2], use)
+	}
+}
+
+// exit samples use at the end of a run that exited with code.
+func (s *memorySampler) exit(code int, use memoryUse) {
+	if s.at&MemoryAtEnd != 0 || (code != 0 && s.at&MemoryOnFailure != 0) {
+		s.add("end", "", use)
+	}
+}
+
+func (s *memorySampler) add(point, test string, use memoryUse) {
+	s.samples = append(s.samples, MemorySample{Point: point, Test: test, WasmMemory: use.Wasm, JSHeap: use.JS})
+}
+
+// memoryPath returns the path of the memory artifact of pkg with ext.
+func (w *Wasmtest) memoryPath(pkg, ext string) string {
+	return filepath.Join(w.memoryDir, artifactName(pkg)+ext)
+}
+
+// saveMemoryReport writes the samples of pkg and reports the growth of the
+// linear memory.
+func (w *Wasmtest) saveMemoryReport(pkg string, samples []MemorySample, progress func(msgs ...any)) {
+	if samples == nil {
+		samples = []MemorySample{}
+	}
+	path := w.memoryPath(pkg, ".memory.json")
+	data, err := json.MarshalIndent(MemoryReport{SchemaVersion: SchemaVersion, Package: pkg, Samples: samples}, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(path, data, 0o644)
+	}
+	if err != nil {
+		progress("warning", Warning{Code: "memory-failed", Message: fmt.Sprintf("failed to write the memory samples: %v", err)})
+		return
+	}
+	msg := "memory samples saved to " + path
+	if len(samples) > 0 {
+		msg += fmt.Sprintf(" (WASM memory %s at the last sample)", formatBytes(samples[len(samples)-1].WasmMemory))
+	}
+	progress("info", msg)
+}
+
+// saveHeapSnapshot moves the heap snapshot of pkg taken at src to the
+// WithMemoryProfile directory.
+func (w *Wasmtest) saveHeapSnapshot(src, pkg string, progress func(msgs ...any)) {
+	dst := w.memoryPath(pkg, ".heapsnapshot")
+	err := os.MkdirAll(filepath.Dir(dst), 0o755)
+	if err == nil {
+		// The temporary directory may be on another device.
+		if err = os.Rename(src, dst); err != nil {
+			err = copyFile(src, dst)
+		}
+	}
+	if err != nil {
+		progress("warning", Warning{Code: "memory-failed", Message: fmt.Sprintf("failed to save the heap snapshot: %v", err)})
+		return
+	}
+	progress("info", "heap snapshot saved to "+dst+"; open it in the Memory panel of the Chrome devtools")
+}
+
+// formatBytes renders n bytes in MiB.
+func formatBytes(n int64) string {
+	return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+}
@@ -0,0 +1,105 @@
+package wasmtest
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemorySampler(t *testing.T) {
+	lines := []string{"=== RUN   TestA", "--- PASS: TestA (0.00s)", "=== RUN   TestB", "    --- FAIL: TestB/sub (0.00s)", "--- FAIL: TestB (0.00s)", "FAIL"}
+	for _, tc := range []struct {
+		at   MemoryPoint
+		code int
+		want string
+	}{
+		{MemoryAtEnd, 1, "end:"},
+		{MemoryOnFailure, 1, "failure:TestB/sub failure:TestB end:"},
+		{MemoryOnFailure, 0, "failure:TestB/sub failure:TestB"},
+		{MemoryPerTest | MemoryAtEnd, 0, "test:TestA test:TestB/sub test:TestB end:"},
+	} {
+		s := &memorySampler{at: tc.at}
+		for i, line := range lines {
+			s.line(line, memoryUse{Wasm: int64(i)})
+		}
+		s.exit(tc.code, memoryUse{Wasm: 100})
+		var got []string
+		for _, sample := range s.samples {
+			got = append(got, sample.Point+":"+sample.Test)
+		}
+		if strings.Join(got, " ") != tc.want {
+			t.Errorf("samples at %d, exit %d = %q, want %q", tc.at, tc.code, got, tc.want)
+		}
+	}
+}
+
+func TestRunBundleMemoryProfile(t *testing.T) {
+	browser := nodeBrowser(t)
+	dir := t.TempDir()
+	w := New(WithInstallDisabled(), WithMemoryProfile(dir, MemoryPerTest|MemoryAtEnd))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	out := t.TempDir()
+	m, err := w.Bundle(ctx, "./example", out, "-test.v", "-test.run=TestMathHelper")
+	if err != nil {
+		t.Fatalf("Bundle failed: %v", err)
+	}
+	progress, msgs := collectProgress()
+	if err := w.RunBundle(ctx, out, RunBundleOptions{Browser: browser}, progress); err != nil {
+		t.Fatalf("RunBundle failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	data, err := os.ReadFile(filepath.Join(dir, artifactName(m.Package)+".memory.json"))
+	if err != nil {
+		t.Fatalf("no memory report: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	var report MemoryReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.SchemaVersion != SchemaVersion || report.Package != m.Package || len(report.Samples) < 2 {
+		t.Fatalf("memory report = %s", data)
+	}
+	first, last := report.Samples[0], report.Samples[len(report.Samples)-1]
+	if first.Point != "test" || first.Test != "TestMathHelper" || first.WasmMemory == 0 || last.Point != "end" || last.WasmMemory == 0 {
+		t.Errorf("memory samples = %+v", report.Samples)
+	}
+}
+
+func TestRunBundleHeapSnapshot(t *testing.T) {
+	pw := fakePlaywrightDir(t)
+	dir := t.TempDir()
+	w := New(WithInstallDisabled(), WithPlaywright(PlaywrightOptions{Dir: pw, NoInstall: true}), WithMemoryProfile(dir, MemoryOnFailure), WithLaunchRetries(0))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	// Passing runs keep no snapshot with MemoryOnFailure.
+	out := t.TempDir()
+	m, err := w.Bundle(ctx, "./example", out, "-test.run=TestMathHelper")
+	if err != nil {
+		t.Fatalf("Bundle failed: %v", err)
+	}
+	progress, msgs := collectProgress()
+	if err := w.RunBundle(ctx, out, RunBundleOptions{}, progress); err != nil {
+		t.Fatalf("RunBundle failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	if _, err := os.Stat(filepath.Join(dir, artifactName(m.Package)+".heapsnapshot")); err == nil {
+		t.Error("heap snapshot kept for a passing run")
+	}
+
+	out = t.TempDir()
+	if m, err = w.Bundle(ctx, writeModule(t, map[string]string{"h_test.go": wasmFailTest}), out); err != nil {
+		t.Fatalf("Bundle failed: %v", err)
+	}
+	progress, msgs = collectProgress()
+	if err := w.RunBundle(ctx, out, RunBundleOptions{}, progress); err == nil {
+		t.Fatal("RunBundle of failing tests succeeded")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, artifactName(m.Package)+".heapsnapshot"))
+	if err != nil || string(data) != `{"snapshot":{}}` {
+		t.Errorf("heap snapshot = %q, %v\n%s", data, err, strings.Join(msgs(), "\n"))
+	}
+}
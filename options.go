@@ -0,0 +1,241 @@
+package wasmtest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Option configures a Wasmtest created with New.
+type Option func(*Wasmtest)
+
+// WithLogger sets the logger receiving the messages of background
+// operations. It should accept variadic values similar to fmt.Println.
+func WithLogger(logger func(...any)) Option {
+	return func(w *Wasmtest) { w.log = logger }
+}
+
+// WithTimeout bounds each Execute call. Defaults to 10 minutes.
+func WithTimeout(d time.Duration) Option {
+	return func(w *Wasmtest) { w.timeout = d }
+}
+
+// WithInstallDisabled stops New from verifying and installing
+// wasmbrowsertest in the background. Use it when the runner is provisioned
+// separately or when tests use another -exec program.
+func WithInstallDisabled() Option {
+	return func(w *Wasmtest) { w.installDisabled = true }
+}
+
+// WithAutoInstall sets whether New verifies and installs wasmbrowsertest in
+// the background, the default. WithAutoInstall(false) is WithInstallDisabled:
+// call Install to prepare the environment explicitly instead.
+func WithAutoInstall(enabled bool) Option {
+	return func(w *Wasmtest) { w.installDisabled = !enabled }
+}
+
+// WithNoInstall is an offline mode for air-gapped hosts: wasmbrowsertest is
+// never installed with go install, neither by New nor later, and go test
+// runs in a browser fail fast, with an error matching ErrRunnerMissing,
+// when no go_js_wasm_exec is found in PATH instead of waiting on a doomed
+// download. The WASMTEST_OFFLINE environment variable and the offline
+// setting of the configuration file set it too.
+func WithNoInstall() Option {
+	return func(w *Wasmtest) {
+		w.installDisabled = true
+		w.offline = true
+	}
+}
+
+// WithInstallDir sets the directory wasmbrowsertest is installed in, and
+// linked as go_js_wasm_exec in, instead of the go install one (GOBIN, or the
+// bin directory of GOPATH). It should be in PATH, where go test looks for
+// go_js_wasm_exec. The WASMTEST_INSTALL_DIR environment variable and the
+// install_dir setting of the configuration file set it too.
+func WithInstallDir(dir string) Option {
+	return func(w *Wasmtest) { w.installDir = dir }
+}
+
+// WithInstallEnv adds KEY=VALUE entries to the environment of the go install
+// of wasmbrowsertest, overriding the inherited ones, e.g.
+// "GOPROXY=https://proxy.corp.example", "GOFLAGS=-mod=mod", "GONOSUMDB=*" or
+// "HTTPS_PROXY=http://proxy:3128" on corporate networks. The install_env
+// mapping of the configuration file sets them too.
+func WithInstallEnv(env ...string) Option {
+	return func(w *Wasmtest) { w.installEnv = append(w.installEnv, env...) }
+}
+
+// WithPathFix lets go test runs find go_js_wasm_exec when the install
+// directory (see WithInstallDir) holds it but is not in PATH, a common
+// setup mistake: the directory is added at the end of the PATH of the go
+// test process, instead of being reported as an "install-dir-not-in-path"
+// warning. The WASMTEST_PATH_FIX environment variable and the path_fix
+// setting of the configuration file set it too.
+func WithPathFix() Option {
+	return func(w *Wasmtest) { w.pathFix = true }
+}
+
+// WithGoToolchain runs the go commands of wasmtest with another Go
+// installation than the go in PATH, e.g. a release candidate or each
+// toolchain of a CI matrix. path is a go executable or a GOROOT directory
+// such as "$HOME/sdk/go1.25rc1". Builds, go test runs, the wasmbrowsertest
+// install and the wasm_exec.js lookup all use it, with GOTOOLCHAIN=local
+// so go doesn't switch releases. The WASMTEST_GO_TOOLCHAIN environment
+// variable and the go_toolchain setting of the configuration file set it
+// too.
+func WithGoToolchain(path string) Option {
+	return func(w *Wasmtest) { w.toolchain = newGoToolchain(path) }
+}
+
+// WithWasmBrowserTestVersion pins the wasmbrowsertest version New installs,
+// e.g. "v0.8.0", instead of the latest one, so runs are reproducible and
+// upstream changes don't break CI unannounced. An installed wasmbrowsertest
+// at another version is not replaced: New logs the mismatch and go test runs
+// report it as a "wasmbrowsertest-version" warning. The
+// WASMTEST_WASMBROWSERTEST_VERSION environment variable and the
+// wasmbrowsertest_version setting of the configuration file set it too.
+func WithWasmBrowserTestVersion(version string) Option {
+	return func(w *Wasmtest) {
+		if version == "latest" {
+			version = ""
+		}
+		if version != "" && !strings.HasPrefix(version, "v") {
+			version = "v" + version
+		}
+		w.wasmBrowserTestVersion = version
+	}
+}
+
+// Policies of WithRunnerUpgrade.
+const (
+	// RunnerUpgradeWarn reports an outdated wasmbrowsertest, the default.
+	RunnerUpgradeWarn = "warn"
+	// RunnerUpgradeAuto reinstalls it with the current toolchain.
+	RunnerUpgradeAuto = "auto"
+	// RunnerUpgradeOff doesn't check it.
+	RunnerUpgradeOff = "off"
+)
+
+// WithRunnerUpgrade selects what happens to a wasmbrowsertest built with an
+// older Go release than the toolchain running the tests, whose wasm_exec.js
+// support may not match: the usual cause of "invalid import" browser
+// failures after a Go upgrade. RunnerUpgradeWarn reports it as a
+// "runner-outdated" warning, RunnerUpgradeAuto makes New reinstall it (at
+// the pinned version, if any) unless installs are disabled, and
+// RunnerUpgradeOff skips the check; other values make New fail (see
+// Ready). The WASMTEST_RUNNER_UPGRADE environment variable and the
+// runner_upgrade setting of the configuration file set it too.
+func WithRunnerUpgrade(policy string) Option {
+	return func(w *Wasmtest) { w.runnerUpgrade = policy }
+}
+
+// checkRunnerUpgrade returns an error for a policy other than the
+// WithRunnerUpgrade ones.
+func checkRunnerUpgrade(policy string) error {
+	switch policy {
+	case "", RunnerUpgradeWarn, RunnerUpgradeAuto, RunnerUpgradeOff:
+		return nil
+	}
+	return fmt.Errorf("wasmtest: unknown runner upgrade policy %q (want %s, %s or %s)", policy, RunnerUpgradeWarn, RunnerUpgradeAuto, RunnerUpgradeOff)
+}
+
+// checkOptions returns an error describing the first invalid setting of
+// the options given to New.
+func (w *Wasmtest) checkOptions() error {
+	if err := checkRunnerUpgrade(w.runnerUpgrade); err != nil {
+		return newRunError(ErrUsage, "%w", err)
+	}
+	return nil
+}
+
+// WithGoWasmFeatures sets GOWASM for the js/wasm builds, selecting the
+// optional WebAssembly features the compiler may use, e.g. "satconv,signext".
+// Use it to check that the tests pass at the feature level supported by the
+// target browsers. The feature set is recorded in CompileStats and RunResult.
+func WithGoWasmFeatures(features string) Option {
+	return func(w *Wasmtest) { w.goWasm = features }
+}
+
+// WithLaunchRetries sets how many times a run is retried when the browser
+// fails to start with a known transient error, such as Chrome's
+// "DevToolsActivePort file doesn't exist" under CI load. Only launches are
+// retried: a run in which any test started is never repeated. Defaults to 2;
+// 0 disables retries.
+func WithLaunchRetries(n int) Option {
+	return func(w *Wasmtest) { w.launchRetries = max(n, 0) }
+}
+
+// WithBrowser selects the browser the tests run in: one of the Browser
+// names (chrome, chromium, edge or firefox), which picks the first
+// installed browser of that kind, an executable name looked up in PATH or
+// the path of a custom build. Go test runs through wasmbrowsertest find it
+// first on their PATH, as wasmbrowsertest has no browser flag, and fail
+// when it is Firefox, which only RunBundle can drive; RunBundle uses it when
+// RunBundleOptions.Browser is empty. The browser in use is reported as an
+// "info" message. Without it, wasmbrowsertest picks the first Chromium based
+// browser it finds.
+func WithBrowser(path string) Option {
+	return func(w *Wasmtest) { w.browser = path }
+}
+
+// WithBrowserFlags adds command line flags, such as
+// --enable-unsafe-webgpu or --lang=es, to the browser running the tests,
+// for tests exercising gated features. RunBundle passes them to the browser
+// it launches; go test runs through wasmbrowsertest start the browser
+// through a script adding them, which needs a POSIX shell. They come after
+// the flags set by wasmtest and wasmbrowsertest, so they can override them.
+func WithBrowserFlags(flags ...string) Option {
+	return func(w *Wasmtest) { w.browserFlags = append(w.browserFlags, flags...) }
+}
+
+// WithHeadful shows the browser window instead of running it headless, so
+// the DOM and the devtools can be inspected when a test fails locally. Go
+// test runs set WASM_HEADLESS=off for wasmbrowsertest, which still closes
+// the browser when the tests end. RunBundle opens the devtools as well and,
+// when the tests fail, leaves the browser open until it is closed, the
+// context is done or the WithFailurePause delay elapsed.
+func WithHeadful() Option {
+	return func(w *Wasmtest) { w.headful = true }
+}
+
+// WithFailurePause bounds how long WithHeadful keeps the browser open after
+// a failure; 0, the default, waits for the browser to be closed.
+func WithFailurePause(d time.Duration) Option {
+	return func(w *Wasmtest) { w.failurePause = max(d, 0) }
+}
+
+// WithRun sets the default -run regexp of Execute and ExecuteWithOptions,
+// so that only the matching tests run, e.g. the single failing one. A Run
+// given in ExecOptions takes precedence.
+func WithRun(pattern string) Option {
+	return func(w *Wasmtest) { w.runFilter = pattern }
+}
+
+// WithSkip sets the default -skip regexp passed to go test by Execute, so
+// that the matching tests are excluded without editing the test files. A
+// Skip given in ExecOptions takes precedence.
+func WithSkip(pattern string) Option {
+	return func(w *Wasmtest) { w.skipFilter = pattern }
+}
+
+// WithTags sets the extra build tags passed as -tags to go test by Execute,
+// e.g. "integration" for tests gated behind
+// //go:build js && wasm && integration. Tags given in ExecOptions take
+// precedence.
+func WithTags(tags ...string) Option {
+	return func(w *Wasmtest) { w.tags = tags }
+}
+
+// WithTestDir sets the directory where Execute runs the tests. Defaults to
+// the current directory.
+func WithTestDir(dir string) Option {
+	return func(w *Wasmtest) { w.testDir = dir }
+}
+
+// WithVerbosity sets the verbosity of Execute: at Debug the commands run,
+// the environment composed for them and the go_js_wasm_exec setup decisions
+// are reported as "debug" progress messages. Lower levels only matter to
+// RunTests, which filters what it logs (see Verbosity).
+func WithVerbosity(v Verbosity) Option {
+	return func(w *Wasmtest) { w.verbosity = v }
+}
@@ -0,0 +1,64 @@
+package wasmtest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewOptions(t *testing.T) {
+	var logged []any
+	w := New(
+		WithLogger(func(a ...any) { logged = append(logged, a...) }),
+		WithTimeout(time.Minute),
+		WithInstallDisabled(),
+		WithTestDir("./example"),
+		WithGoWasmFeatures("signext"),
+	)
+	if w.timeout != time.Minute || w.testDir != "./example" || !w.installDisabled || w.goWasm != "signext" {
+		t.Errorf("options not applied: %+v", w)
+	}
+	w.safeLog("hello")
+	if len(logged) != 1 || logged[0] != "hello" {
+		t.Errorf("logger not used: %v", logged)
+	}
+
+	if d := New(WithInstallDisabled()); d.timeout != 10*time.Minute || d.log == nil {
+		t.Errorf("defaults not applied: %+v", d)
+	}
+}
+
+func TestRunTestsRejectsUnknownArguments(t *testing.T) {
+	err := RunTests("./example", func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "func(string)") {
+		t.Errorf("RunTests() error = %v, want an unsupported argument error", err)
+	}
+}
+
+func TestNoInstall(t *testing.T) {
+	w := New(WithNoInstall(), WithLogger(func(...any) {}))
+	if !w.offline || !w.installDisabled {
+		t.Fatalf("offline mode not set: %+v", w)
+	}
+	t.Setenv("GOPATH", t.TempDir())
+	empty := t.TempDir()
+
+	progress, msgs := collectProgress()
+	start := time.Now()
+	err := w.execute(t.Context(), execSpec{dir: "./example", env: []string{"PATH=" + empty}}, progress)
+	if !errors.Is(err, ErrRunnerMissing) || !strings.Contains(err.Error(), "offline mode") {
+		t.Errorf("execute = %v, want ErrRunnerMissing\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	if d := time.Since(start); d > 30*time.Second {
+		t.Errorf("offline execute took %v", d)
+	}
+	if out := strings.Join(msgs(), "\n"); strings.Contains(out, "installation may be in progress") || strings.Contains(out, "test run") {
+		t.Errorf("unexpected progress:\n%s", out)
+	}
+
+	t.Setenv("PATH", empty)
+	if err := w.ensureWasmBrowserTestInstalled(t.Context()); !errors.Is(err, ErrRunnerMissing) {
+		t.Errorf("ensureWasmBrowserTestInstalled = %v, want ErrRunnerMissing", err)
+	}
+}
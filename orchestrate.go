@@ -0,0 +1,284 @@
+package wasmtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RunPlan describes one WebAssembly test run executed by Orchestrate.
+// Plans are independent of each other: each one may target a different
+// directory, runtime (via Exec) or browser configuration (via Env).
+type RunPlan struct {
+	// Name identifies the plan in the merged report. Defaults to Dir,
+	// followed by the Target when set.
+	Name string
+	// Dir is the directory containing the js/wasm tests.
+	Dir string
+	// Exec is the program passed to `go test -exec`, e.g. the node based
+	// $(go env GOROOT)/lib/wasm/go_js_wasm_exec. Empty uses the
+	// wasmbrowsertest runner installed by New.
+	Exec string
+	// Env holds extra KEY=VALUE entries for the test process, e.g.
+	// WASM_HEADLESS=off.
+	Env []string
+	// Args holds extra go test flags.
+	Args []string
+	// Timeout bounds the plan. Defaults to 3 minutes like RunTests.
+	Timeout time.Duration
+	// NativeRace adds a complementary pass running the package natively
+	// with -race, since the race detector is unavailable under js/wasm.
+	// Only the tests that build for the host platform run in that pass.
+	NativeRace bool
+	// Target is the build target of the directory, TargetJS or
+	// TargetWASIP1. Defaults to the one set with WithTarget; two plans of
+	// the same Dir can verify both.
+	Target string
+}
+
+// PlanResult is the outcome of a single RunPlan.
+type PlanResult struct {
+	Plan RunPlan
+	RunResult
+	// Err is nil when the plan passed.
+	Err error
+	// Race is the result of the native -race pass when RunPlan.NativeRace
+	// is set.
+	Race *PlanResult
+}
+
+// Passed reports whether the plan ran successfully, including its native
+// -race pass if any.
+func (r PlanResult) Passed() bool {
+	return r.Err == nil && (r.Race == nil || r.Race.Passed())
+}
+
+// Report is the merged outcome of all the plans given to Orchestrate.
+type Report struct {
+	// Results are in the same order as the plans.
+	Results  []PlanResult
+	Duration time.Duration
+	// ExitCode is the ExitCode of the errors of the failed plans: ExitOK
+	// when every plan passed.
+	ExitCode int
+}
+
+// Failed returns the results of the plans that did not pass.
+func (r *Report) Failed() []PlanResult {
+	var failed []PlanResult
+	for _, res := range r.Results {
+		if !res.Passed() {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// String renders a short human readable summary of the report.
+func (r *Report) String() string {
+	var b strings.Builder
+	for _, res := range r.Results {
+		status := "✅ PASS"
+		if !res.Passed() {
+			status = "❌ FAIL"
+		}
+		fmt.Fprintf(&b, "%s %s (%v)\n", status, res.Plan.Name, res.Duration.Round(time.Millisecond))
+		if len(res.FailedTests) > 0 {
+			fmt.Fprintf(&b, "    🧪 Failing Tests: %s\n", strings.Join(res.FailedTests, ", "))
+		}
+		if res.Err != nil && len(res.FailedTests) == 0 {
+			fmt.Fprintf(&b, "    🔴 %v\n", res.Err)
+		}
+		if res.Race != nil {
+			switch {
+			case res.Race.Passed():
+				fmt.Fprintf(&b, "    🏁 native -race pass: ok\n")
+			case len(res.Race.FailedTests) > 0:
+				fmt.Fprintf(&b, "    🏁 native -race pass failing tests: %s\n", strings.Join(res.Race.FailedTests, ", "))
+			default:
+				fmt.Fprintf(&b, "    🏁 native -race pass: %v\n", res.Race.Err)
+			}
+		}
+	}
+	fmt.Fprintf(&b, "%d/%d plans passed in %v", len(r.Results)-len(r.Failed()), len(r.Results), r.Duration.Round(time.Millisecond))
+	return b.String()
+}
+
+// Orchestrator executes several RunPlans sharing a single concurrency limit.
+type Orchestrator struct {
+	// Concurrency is the maximum number of plans running at once.
+	// Defaults to runtime.NumCPU().
+	Concurrency int
+	// Logger receives every progress message prefixed with the plan name.
+	// Defaults to a no-op logger.
+	Logger func(...any)
+	// Writers receive the test events of every plan and the merged report.
+	// See RegisterReportWriter for custom formats.
+	Writers []ReportWriter
+	// EnvHook, when set, receives the directory and final environment of
+	// every go test process. See Wasmtest.SetEnvHook. It is called
+	// concurrently when plans run in parallel.
+	EnvHook func(dir string, env []string)
+}
+
+// Orchestrate runs all plans with the default Orchestrator and returns one
+// merged report. The returned error is non-nil when at least one plan failed,
+// so CI pipelines can rely on a single exit status (Report.ExitCode).
+func Orchestrate(ctx context.Context, plans []RunPlan) (*Report, error) {
+	return (&Orchestrator{}).Run(ctx, plans)
+}
+
+// Run executes the plans and merges their results. See Orchestrate.
+func (o *Orchestrator) Run(ctx context.Context, plans []RunPlan) (*Report, error) {
+	limit := o.Concurrency
+	if limit <= 0 {
+		limit = runtime.NumCPU()
+	}
+	logger := o.Logger
+	if logger == nil {
+		logger = func(...any) {}
+	}
+
+	// A single instance is shared so the runner is only verified once.
+	w := New(WithLogger(logger))
+	w.SetEnvHook(o.EnvHook)
+
+	// Writers are never called concurrently.
+	var writersMu sync.Mutex
+	emit := func(ev TestEvent) {
+		writersMu.Lock()
+		defer writersMu.Unlock()
+		for _, rw := range o.Writers {
+			if err := rw.TestEvent(ev); err != nil {
+				logger("report writer error:", err)
+			}
+		}
+	}
+	for _, rw := range o.Writers {
+		if err := rw.Begin(plans); err != nil {
+			logger("report writer error:", err)
+		}
+	}
+
+	start := time.Now()
+	report := &Report{Results: make([]PlanResult, len(plans))}
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, plan := range plans {
+		if plan.Name == "" {
+			plan.Name = plan.Dir
+			if plan.Target != "" {
+				plan.Name += " (" + plan.Target + ")"
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+				report.Results[i] = w.runPlan(ctx, plan, logger, emit)
+			case <-ctx.Done():
+				report.Results[i] = PlanResult{Plan: plan, RunResult: *newRunResult(plan.Dir), Err: ctx.Err()}
+			}
+		}()
+	}
+	wg.Wait()
+	report.Duration = time.Since(start)
+
+	failed := report.Failed()
+	var errs []error
+	for _, res := range failed {
+		errs = append(errs, res.Err)
+		if res.Race != nil {
+			errs = append(errs, res.Race.Err)
+		}
+	}
+	report.ExitCode = ExitCode(errors.Join(errs...))
+	for _, rw := range o.Writers {
+		if err := rw.End(report); err != nil {
+			logger("report writer error:", err)
+		}
+	}
+	if len(failed) == 0 {
+		return report, nil
+	}
+	names := make([]string, 0, len(failed))
+	for _, res := range failed {
+		names = append(names, res.Plan.Name)
+	}
+	return report, fmt.Errorf("❌💥 %d of %d WebAssembly test plans FAILED: %s\n%s🔴 Error: %w", len(failed), len(plans), strings.Join(names, ", "), report.String(), errors.Join(errs...))
+}
+
+// runPlan executes a single plan and collects its result, passing every
+// output line to emit as a TestEvent.
+func (w *Wasmtest) runPlan(ctx context.Context, plan RunPlan, logger func(...any), emit func(TestEvent)) PlanResult {
+	if _, err := os.Stat(plan.Dir); err != nil {
+		return PlanResult{Plan: plan, RunResult: *newRunResult(plan.Dir), Err: newRunError(ErrDirNotFound, "❌💥 DIRECTORY ERROR: Test directory %s does not exist", plan.Dir)}
+	}
+
+	timeout := plan.Timeout
+	if timeout <= 0 {
+		timeout = 3 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	spec := execSpec{dir: plan.Dir, exec: plan.Exec, env: plan.Env, args: plan.Args, target: plan.Target, toolchain: w.toolchain}
+	res := w.runPass(ctx, plan, plan.Name, spec, timeout, logger, emit)
+	if plan.NativeRace {
+		spec = execSpec{dir: plan.Dir, env: plan.Env, args: append(withoutRace(plan.Args), "-race"), native: true, toolchain: w.toolchain}
+		race := w.runPass(ctx, plan, racePassName(plan.Name), spec, timeout, logger, emit)
+		res.Race = &race
+	}
+	return res
+}
+
+// runPass runs one go test invocation of plan, reporting it under name.
+func (w *Wasmtest) runPass(ctx context.Context, plan RunPlan, name string, spec execSpec, timeout time.Duration, logger func(...any), emit func(TestEvent)) PlanResult {
+	res := PlanResult{Plan: plan, RunResult: *newRunResult(plan.Dir)}
+
+	var tracker testTracker
+	progress := func(msgs ...any) {
+		logger(append([]any{"[" + name + "]"}, msgs...)...)
+		res.collect(msgs...)
+		if ev, ok := tracker.event(msgs...); ok {
+			ev.Plan = name
+			emit(ev)
+		}
+	}
+
+	start := time.Now()
+	err := w.execute(ctx, spec, progress)
+	res.Duration = time.Since(start)
+	res.setExit(err)
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case ctx.Err() == context.DeadlineExceeded:
+		res.ExitCode = -1
+		res.Err = newRunError(ErrTimeout, "⏰💥 TIMEOUT ERROR: plan %s timed out after %v", name, timeout)
+	case ctx.Err() != nil:
+		// go test was killed by the cancellation, not failed by the tests.
+		res.Err = ctx.Err()
+	case errors.As(err, &exitErr):
+		res.Err = &TestFailureError{
+			Dir:         plan.Dir,
+			Failed:      res.FailedTests,
+			ExitCode:    res.ExitCode,
+			BuildFailed: slices.ContainsFunc(res.RawOutput, buildFailed),
+			msg:         fmt.Sprintf("❌💥 tests FAILED in directory %s (%s): %v", plan.Dir, name, err),
+		}
+	default:
+		res.Err = err
+	}
+	return res
+}
@@ -0,0 +1,89 @@
+package wasmtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// nodeExec returns the node based go_js_wasm_exec shipped with the Go
+// toolchain, skipping the test when node is not available.
+func nodeExec(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node not found in PATH; skipping")
+	}
+	for _, dir := range []string{"lib/wasm", "misc/wasm"} {
+		p := filepath.Join(runtime.GOROOT(), dir, "go_js_wasm_exec")
+		if _, err := exec.LookPath(p); err == nil {
+			return p
+		}
+	}
+	t.Skip("go_js_wasm_exec not found in GOROOT; skipping")
+	return ""
+}
+
+func TestOrchestrate(t *testing.T) {
+	node := nodeExec(t)
+
+	plans := []RunPlan{
+		{Name: "node", Dir: "./example", Exec: node},
+		{Dir: "./does-not-exist", Exec: node},
+	}
+	o := &Orchestrator{Concurrency: 1, Logger: func(a ...any) { t.Log(a...) }}
+	report, err := o.Run(context.Background(), plans)
+	if err == nil {
+		t.Fatal("expected an error because one plan has a missing directory")
+	}
+	if !errors.Is(err, ErrDirNotFound) {
+		t.Errorf("Run() = %v, want the ErrDirNotFound of the missing directory", err)
+	}
+	if report.ExitCode != ExitBuildFailed {
+		t.Errorf("ExitCode = %d, want %d", report.ExitCode, ExitBuildFailed)
+	}
+	if len(report.Results) != 2 {
+		t.Fatalf("got %d results, want 2", len(report.Results))
+	}
+
+	first := report.Results[0]
+	if !first.Passed() || first.ExitCode != 0 {
+		t.Errorf("node plan failed: %v\n%s", first.Err, strings.Join(first.RawOutput, "\n"))
+	}
+	if first.Compile.Package == "" {
+		t.Errorf("node plan has no compile stats")
+	}
+	second := report.Results[1]
+	if second.Passed() || second.Plan.Name != "./does-not-exist" {
+		t.Errorf("missing dir plan = %+v, want failure named after its Dir", second)
+	}
+	if !strings.Contains(report.String(), "1/2 plans passed") {
+		t.Errorf("unexpected summary:\n%s", report.String())
+	}
+}
+
+func TestOrchestrateCanceled(t *testing.T) {
+	node := nodeExec(t)
+	dir := writeModule(t, map[string]string{
+		"p_test.go": "//go:build js && wasm\n\npackage p\n\nimport (\n\t\"testing\"\n\t\"time\"\n)\n\nfunc TestSlow(t *testing.T) { time.Sleep(time.Minute) }\n",
+	})
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	o := &Orchestrator{Concurrency: 1, Logger: func(a ...any) {
+		if strings.Contains(fmt.Sprint(a...), "=== RUN") {
+			cancel()
+		}
+	}}
+	report, err := o.Run(ctx, []RunPlan{{Dir: dir, Exec: node, Args: []string{"-v"}}})
+	var failure *TestFailureError
+	if !errors.Is(report.Results[0].Err, context.Canceled) || errors.As(err, &failure) {
+		t.Errorf("Run() = %v, want context.Canceled", err)
+	}
+	if report.ExitCode != ExitCanceled {
+		t.Errorf("ExitCode = %d, want %d", report.ExitCode, ExitCanceled)
+	}
+}
@@ -0,0 +1,297 @@
+package wasmtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// isPackagePattern reports whether dir is a Go package pattern such as
+// ./... or ./pkg/... rather than a single directory.
+func isPackagePattern(dir string) bool {
+	return dir == "..." || strings.HasSuffix(filepath.ToSlash(dir), "/...")
+}
+
+// FindPackages returns the directories matched by pattern that contain
+// js/wasm tests. A pattern ending in /... (e.g. ./...) matches the
+// directory before it and all of its subdirectories, in lexical order, like
+// the go command does: testdata and vendor directories, directories starting
+// with "." or "_" and nested modules are skipped. Any other pattern is a
+// single directory. Test files gated by extra build tags are only found
+// when tags includes them.
+func FindPackages(pattern string, tags ...string) ([]string, error) {
+	return findPackages(pattern, TargetJS, tags)
+}
+
+// findPackages is FindPackages for the tests of target.
+func findPackages(pattern, target string, tags []string) ([]string, error) {
+	if !isPackagePattern(pattern) {
+		ok, err := hasWasmTests(pattern, target, tags...)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return []string{pattern}, nil
+	}
+
+	root := filepath.Clean(strings.TrimSuffix(strings.TrimSuffix(filepath.ToSlash(pattern), "..."), "/"))
+	if root == "" {
+		root = "."
+	}
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root {
+			name := d.Name()
+			if name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+		ok, err := hasWasmTests(path, target, tags...)
+		if err != nil {
+			return err
+		}
+		if ok {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+// Parallel is a RunTests argument running up to N of the packages of a
+// multi package run at once, each with its own go test process and
+// browser, so large monorepos don't wait for one package after the other.
+// Their log lines are prefixed with the package directory, and the
+// ProgressEvents tell their Package. Zero or one, the default, runs them in
+// order. It can also be set with the WASMTEST_PARALLEL environment variable
+// or the parallel setting of the configuration file.
+type Parallel int
+
+// runPackages runs the packages matched by patterns, directories or ./...
+// patterns, one after the other or s.parallel at once, and aggregates them
+// into a single RunResult, whose Packages field keeps the result of each
+// package in the order of the patterns. A package that fails doesn't stop
+// the others; the errors of all of them are joined.
+//
+// The timeout of s is the overall deadline of all packages; ExecOptions.Timeout
+// bounds each of them (go test -timeout).
+func (s runSettings) runPackages(parent context.Context, patterns []string) (*RunResult, error) {
+	probe := &Wasmtest{}
+	for _, opt := range s.options() {
+		opt(probe)
+	}
+	// The tests are listed with the filter and the tags the runs use.
+	exec := s.exec.withDefaults(probe)
+	dirs, err := expandPatterns(patterns, s.target(), exec.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if s.shard.Total > 1 {
+		count := len(dirs)
+		if dirs, s.shardRuns, err = s.shard.assign(dirs, exec.Run, s.target(), exec.Tags); err != nil {
+			return nil, err
+		}
+		s.logger("[WASMTEST]", "info", fmt.Sprintf("🧩 shard %v: %d of %d packages", s.shard, len(dirs), count))
+	}
+	parent, cancel := context.WithTimeoutCause(parent, s.timeout, newRunError(ErrTimeout, "overall deadline of %v", s.timeout))
+	defer cancel()
+	if warm := s.warmBrowser(len(dirs)); warm != nil {
+		defer warm.Close()
+		s.opts = append(slices.Clone(s.opts), WithWarmBrowser(warm))
+	}
+	// wasmbrowsertest is verified once, before the packages run, rather
+	// than in the background by the Wasmtest of each of them.
+	<-New(s.options()...).Ready()
+	s.opts = append(slices.Clone(s.opts), WithInstallDisabled())
+
+	results := make([]*RunResult, len(dirs))
+	errs := make([]error, len(dirs))
+	ran := make([]bool, len(dirs))
+	start := time.Now()
+	if s.parallel > 1 && len(dirs) > 1 {
+		s.runParallel(parent, dirs, results, errs, ran)
+	} else {
+		for i, dir := range dirs {
+			if parent.Err() != nil {
+				break
+			}
+			ran[i] = true
+			s.logger("[WASMTEST]", "info", "📦 package", dir)
+			results[i], errs[i] = s.run(parent, dir)
+		}
+	}
+
+	total := newRunResult(strings.Join(patterns, " "))
+	total.ExitCode = 0
+	total.Duration = time.Since(start)
+	var failed []string
+	run := 0
+	for i, dir := range dirs {
+		if !ran[i] {
+			continue
+		}
+		run++
+		if results[i] != nil {
+			total.add(results[i])
+		}
+		if errs[i] != nil {
+			failed = append(failed, dir)
+		}
+	}
+
+	summary := fmt.Sprintf("📦 %d packages: %d passed, %d failed", len(dirs), run-len(failed), len(failed))
+	if len(failed) > 0 {
+		summary += " (" + strings.Join(failed, ", ") + ")"
+	}
+	if run < len(dirs) {
+		summary += fmt.Sprintf(", %d not run", len(dirs)-run)
+	}
+	s.logger("[WASMTEST]", "info", summary)
+	return total, errors.Join(errs...)
+}
+
+// runParallel runs dirs, s.parallel at once, storing the outcome of each
+// in results and errs and whether it started in ran. The logger, event and
+// progress callbacks of s are never called concurrently; log lines are
+// prefixed with the package directory.
+func (s runSettings) runParallel(parent context.Context, dirs []string, results []*RunResult, errs []error, ran []bool) {
+	var mu sync.Mutex
+	logger, events, progress := s.logger, s.events, s.progress
+	if events != nil {
+		s.events = func(ev ProgressEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events(ev)
+		}
+	}
+	if progress != nil {
+		s.progress = func(p TestProgress) {
+			mu.Lock()
+			defer mu.Unlock()
+			progress(p)
+		}
+	}
+
+	sem := make(chan struct{}, s.parallel)
+	var wg sync.WaitGroup
+	for i, dir := range dirs {
+		select {
+		case sem <- struct{}{}:
+		case <-parent.Done():
+		}
+		if parent.Err() != nil {
+			break
+		}
+		ran[i] = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			s := s
+			s.logger = func(a ...any) {
+				mu.Lock()
+				defer mu.Unlock()
+				logger(append([]any{"[" + dir + "]"}, a...)...)
+			}
+			s.logger("[WASMTEST]", "info", "📦 package", dir)
+			results[i], errs[i] = s.run(parent, dir)
+		}()
+	}
+	wg.Wait()
+}
+
+// expandPatterns replaces the ./... patterns of patterns with the packages
+// of target they match with the build tags. Plain directories are kept as
+// they are.
+func expandPatterns(patterns []string, target string, tags []string) ([]string, error) {
+	var dirs []string
+	for _, pattern := range patterns {
+		if !isPackagePattern(pattern) {
+			// Missing directories and tests are reported by run.
+			dirs = append(dirs, pattern)
+			continue
+		}
+		found, err := findPackages(pattern, target, tags)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, newRunError(ErrDirNotFound, "❌💥 DIRECTORY ERROR: Test directory %s does not exist\n🔴 Please ensure the pattern points to a directory of the module", pattern)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("❌💥 DIRECTORY ERROR: Failed to walk %s\n🔴 Details: %w", pattern, err)
+		}
+		if len(found) == 0 {
+			return nil, newRunError(ErrNoWasmTests, "❌💥 NO TEST FILES: No package with %s test files matches %s\n🔴 Required: Files must have a build constraint selecting the target, e.g. '//go:build js && wasm' or '//go:build wasip1'\n💡 Check that your test files have the correct build tags, and pass the extra tags gating them in ExecOptions.Tags", target, pattern)
+		}
+		dirs = append(dirs, found...)
+	}
+	return dirs, nil
+}
+
+// Failures returns the failed tests of each failed package of a multi
+// package run, by directory.
+func (r *RunResult) Failures() map[string][]string {
+	failures := map[string][]string{}
+	for _, pkg := range r.Packages {
+		if !pkg.Passed() {
+			failures[pkg.Dir] = pkg.FailedTests
+		}
+	}
+	return failures
+}
+
+// warmBrowser returns a WarmBrowser for the packages of a run of the
+// builtin backend to open their tests in, or nil when there is a single
+// package or the browser can't be kept warm: it is given already, shown
+// with WithHeadful, or Firefox.
+func (s runSettings) warmBrowser(packages int) *WarmBrowser {
+	probe := &Wasmtest{}
+	for _, opt := range s.options() {
+		opt(probe)
+	}
+	if packages < 2 || probe.backend != BackendBuiltin || probe.warmBrowser != nil || probe.headful {
+		return nil
+	}
+	if path, err := lookupBrowser(probe.browser); err != nil || isFirefox(path) {
+		return nil
+	}
+	return NewWarmBrowser(probe.browser, probe.browserFlags...)
+}
+
+// add merges the result of one package into r. The tests of r.Durations
+// and r.FailureOutput are keyed by package, as packages may have tests of
+// the same name; r.Duration is left to the caller, since the packages may
+// run in parallel.
+func (r *RunResult) add(pkg *RunResult) {
+	r.Packages = append(r.Packages, pkg)
+	r.PassedTests = append(r.PassedTests, pkg.PassedTests...)
+	r.FailedTests = append(r.FailedTests, pkg.FailedTests...)
+	r.SkippedTests = append(r.SkippedTests, pkg.SkippedTests...)
+	for name, d := range pkg.Durations {
+		r.Durations[pkg.Dir+"."+name] = d
+	}
+	r.Benchmarks = append(r.Benchmarks, pkg.Benchmarks...)
+	r.RawOutput = append(r.RawOutput, pkg.RawOutput...)
+	for name, lines := range pkg.FailureOutput {
+		if r.FailureOutput == nil {
+			r.FailureOutput = map[string][]string{}
+		}
+		r.FailureOutput[pkg.Dir+"."+name] = lines
+	}
+	if r.ExitCode == 0 {
+		r.ExitCode = pkg.ExitCode
+	}
+}
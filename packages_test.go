@@ -0,0 +1,234 @@
+package wasmtest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	wasmPassTest = "//go:build js && wasm\n\npackage p\n\nimport \"testing\"\n\nfunc TestPass(t *testing.T) {}\n"
+	wasmFailTest = "//go:build js && wasm\n\npackage p\n\nimport \"testing\"\n\nfunc TestFail(t *testing.T) { t.Fatal(\"bad\") }\n"
+)
+
+func TestFindPackages(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		"a/a_test.go":          wasmPassTest,
+		"a/b/b_test.go":        wasmPassTest,
+		"native/n_test.go":     "package p\n",
+		"a/testdata/t_test.go": wasmPassTest,
+		"_skip/s_test.go":      wasmPassTest,
+		".hidden/h_test.go":    wasmPassTest,
+		"nested/go.mod":        "module example.com/nested\n",
+		"nested/n_test.go":     wasmPassTest,
+		"vendor/x/v_test.go":   wasmPassTest,
+		"c/deep/er/c_test.go":  wasmPassTest,
+	})
+
+	got, err := FindPackages(filepath.Join(root, "..."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(root, "a"), filepath.Join(root, "a", "b"), filepath.Join(root, "c", "deep", "er")}
+	if !slices.Equal(got, want) {
+		t.Errorf("FindPackages() = %q, want %q", got, want)
+	}
+
+	if got, err := FindPackages(filepath.Join(root, "a")); err != nil || !slices.Equal(got, want[:1]) {
+		t.Errorf("FindPackages(dir) = %q, %v", got, err)
+	}
+	if got, _ := FindPackages(filepath.Join(root, "native")); len(got) != 0 {
+		t.Errorf("FindPackages(native) = %q", got)
+	}
+}
+
+func TestFindPackagesTarget(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		"js/js_test.go":     wasmPassTest,
+		"wasi/wasi_test.go": "//go:build wasip1\n\npackage p\n\nimport \"testing\"\n\nfunc TestPass(t *testing.T) {}\n",
+	})
+
+	for target, want := range map[string]string{TargetJS: "js", TargetWASIP1: "wasi"} {
+		got, err := findPackages(filepath.Join(root, "..."), target, nil)
+		if err != nil || !slices.Equal(got, []string{filepath.Join(root, want)}) {
+			t.Errorf("findPackages(%s) = %q, %v; want the %s package", target, got, err, want)
+		}
+	}
+
+	// The wasip1 package is no js/wasm one, but it is found for a wasip1 run.
+	_, err := RunTestsResult(filepath.Join(root, "wasi"), func(...any) {}, WithInstallDisabled())
+	if !errors.Is(err, ErrNoWasmTests) || !strings.Contains(err.Error(), TargetJS) {
+		t.Errorf("js run of the wasip1 package = %v, want ErrNoWasmTests", err)
+	}
+	_, err = RunTestsResult(filepath.Join(root, "wasi"), func(...any) {}, ExecOptions{Target: TargetWASIP1}, WithInstallDisabled(), WithWASIRuntime(filepath.Join(t.TempDir(), "wasmtime")))
+	if err == nil || errors.Is(err, ErrNoWasmTests) {
+		t.Errorf("wasip1 run of the wasip1 package = %v, want the missing wasmtime", err)
+	}
+}
+
+func TestRunTestsPackagePattern(t *testing.T) {
+	node := nodeExec(t)
+	root := writeModule(t, map[string]string{
+		"ok/ok_test.go":     wasmPassTest,
+		"ok/sub/ok_test.go": wasmPassTest,
+		"bad/bad_test.go":   wasmFailTest,
+	})
+
+	res, err := RunTestsResult(filepath.Join(root, "..."), func(...any) {}, ExecOptions{Args: []string{"-exec", node}}, WithInstallDisabled())
+	var failure *TestFailureError
+	if !errors.As(err, &failure) || failure.Dir != filepath.Join(root, "bad") {
+		t.Fatalf("RunTestsResult() = %v, want the failure of the bad package", err)
+	}
+	if len(res.Packages) != 3 || res.Packages[0].Dir != filepath.Join(root, "bad") {
+		t.Fatalf("unexpected packages: %+v", res.Packages)
+	}
+	if !slices.Equal(res.PassedTests, []string{"TestPass", "TestPass"}) || !slices.Equal(res.FailedTests, []string{"TestFail"}) || res.ExitCode != 1 {
+		t.Errorf("unexpected aggregate: passed %q, failed %q, exit %d", res.PassedTests, res.FailedTests, res.ExitCode)
+	}
+
+	if err := RunTests(filepath.Join(root, "missing", "..."), func(...any) {}); !errors.Is(err, ErrDirNotFound) {
+		t.Errorf("missing root: %v", err)
+	}
+}
+
+func TestRunTestsPackagesInstallOnce(t *testing.T) {
+	node := nodeExec(t)
+	// A runner in PATH is verified without installing it.
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "go_js_wasm_exec"), []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	root := writeModule(t, map[string]string{"a/a_test.go": wasmPassTest, "b/b_test.go": wasmPassTest})
+
+	logger, log := collectProgress()
+	if err := RunTests(filepath.Join(root, "..."), logger, ExecOptions{Args: []string{"-exec", node}}); err != nil {
+		t.Fatalf("RunTests failed: %v\n%s", err, strings.Join(log(), "\n"))
+	}
+	if n := strings.Count(strings.Join(log(), "\n"), "found go_js_wasm_exec"); n != 1 {
+		t.Errorf("wasmbrowsertest verified %d times for 2 packages:\n%s", n, strings.Join(log(), "\n"))
+	}
+}
+
+func TestRunTestsMultipleDirs(t *testing.T) {
+	node := nodeExec(t)
+	root := writeModule(t, map[string]string{
+		"ui/ui_test.go":     wasmFailTest,
+		"core/core_test.go": wasmPassTest,
+	})
+	ui, core, missing := filepath.Join(root, "ui"), filepath.Join(root, "core"), filepath.Join(root, "missing")
+
+	res, err := RunTestsResult([]string{core, ui, missing}, func(...any) {}, ExecOptions{Args: []string{"-exec", node}}, WithInstallDisabled())
+	var failure *TestFailureError
+	if !errors.As(err, &failure) || failure.Dir != ui || !errors.Is(err, ErrDirNotFound) {
+		t.Fatalf("RunTestsResult() = %v, want the ui failure and the missing directory", err)
+	}
+	if len(res.Packages) != 2 || res.Packages[0].Dir != core || !res.Packages[0].Passed() {
+		t.Fatalf("unexpected packages: %+v", res.Packages)
+	}
+	if failures := res.Failures(); len(failures) != 1 || !slices.Equal(failures[ui], []string{"TestFail"}) {
+		t.Errorf("Failures() = %v", failures)
+	}
+}
+
+func TestRunTestsPackageTimeouts(t *testing.T) {
+	node := nodeExec(t)
+	hang := "//go:build js && wasm\n\npackage p\n\nimport (\n\t\"testing\"\n\t\"time\"\n)\n\nfunc TestHang(t *testing.T) { time.Sleep(time.Hour) }\n"
+	root := writeModule(t, map[string]string{
+		"a/a_test.go": hang,
+		"b/b_test.go": wasmPassTest,
+	})
+	opts := ExecOptions{Timeout: 2 * time.Second, Args: []string{"-exec", node}}
+
+	// The slow package fails on its own timeout; the next one still runs.
+	res, err := RunTestsResult(filepath.Join(root, "..."), func(...any) {}, opts, WithInstallDisabled())
+	if !errors.Is(err, ErrTimeout) || !strings.Contains(err.Error(), "PACKAGE TIMEOUT") || !strings.Contains(err.Error(), filepath.Join(root, "a")) || !strings.Contains(err.Error(), "TestHang") {
+		t.Errorf("RunTestsResult() = %v, want the package timeout of a", err)
+	}
+	if len(res.Packages) != 2 || !res.Packages[1].Passed() {
+		t.Errorf("unexpected packages: %+v", res.Packages)
+	}
+
+	// The overall timeout still bounds the whole run.
+	opts.Timeout = 0
+	_, err = RunTestsResult(filepath.Join(root, "..."), func(...any) {}, opts, 3*time.Second, WithInstallDisabled())
+	if !errors.Is(err, ErrTimeout) || !strings.Contains(err.Error(), "overall deadline of 3s") {
+		t.Errorf("RunTestsResult() = %v, want the overall timeout", err)
+	}
+}
+
+func TestRunTestsParallel(t *testing.T) {
+	node := nodeExec(t)
+	// Each test waits for the other package to start: run one after the
+	// other, they would time out.
+	rendezvous := func(self, other string) string {
+		return `//go:build js && wasm
+
+package p
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMeet(t *testing.T) {
+	dir := os.Getenv("RENDEZVOUS")
+	if err := os.WriteFile(filepath.Join(dir, "` + self + `"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(20 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if _, err := os.Stat(filepath.Join(dir, "` + other + `")); err == nil {
+			return
+		}
+	}
+	t.Fatal("` + other + ` never started")
+}
+`
+	}
+	root := writeModule(t, map[string]string{
+		"a/a_test.go": rendezvous("a", "b"),
+		"b/b_test.go": rendezvous("b", "a"),
+	})
+
+	logger, log := collectProgress()
+	var events []ProgressEvent
+	opts := ExecOptions{Args: []string{"-exec", node}, Env: []string{"RENDEZVOUS=" + t.TempDir()}}
+	res, err := RunTestsResult(filepath.Join(root, "..."), logger, opts, Parallel(2), WithInstallDisabled(),
+		func(ev ProgressEvent) { events = append(events, ev) })
+	if err != nil {
+		t.Fatalf("RunTestsResult failed: %v\n%s", err, strings.Join(log(), "\n"))
+	}
+	a, b := filepath.Join(root, "a"), filepath.Join(root, "b")
+	if len(res.Packages) != 2 || res.Packages[0].Dir != a || res.Packages[1].Dir != b || len(res.PassedTests) != 2 {
+		t.Fatalf("unexpected result: %d packages, passed %q", len(res.Packages), res.PassedTests)
+	}
+	// The tests of the same name stay apart, and the packages overlap in
+	// the wall time of the run.
+	if _, ok := res.Durations[a+".TestMeet"]; !ok || len(res.Durations) != 2 {
+		t.Errorf("Durations = %v, want one TestMeet per package", res.Durations)
+	}
+	if da, db := res.Packages[0].Duration, res.Packages[1].Duration; res.Duration < max(da, db) || res.Duration >= da+db {
+		t.Errorf("Duration = %v for packages of %v and %v run in parallel", res.Duration, da, db)
+	}
+	for _, line := range log() {
+		if !strings.HasPrefix(line, "["+a+"]") && !strings.HasPrefix(line, "["+b+"]") && !strings.Contains(line, "📦 2 packages") {
+			t.Errorf("log line without its package: %q", line)
+		}
+	}
+	packages := map[string]bool{}
+	for _, ev := range events {
+		packages[ev.Package] = true
+		if ev.TestName == "TestMeet" && ev.Package == "" {
+			t.Errorf("event without its package: %+v", ev)
+		}
+	}
+	if !packages[a] || !packages[b] || len(packages) != 2 {
+		t.Errorf("event packages: %v", packages)
+	}
+}
@@ -0,0 +1,152 @@
+package wasmtest
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//go:embed playwright.js
+var playwrightScript []byte
+
+// Playwright browser engines (see PlaywrightOptions).
+const (
+	PlaywrightChromium = "chromium"
+	PlaywrightFirefox  = "firefox"
+	PlaywrightWebKit   = "webkit"
+)
+
+// playwrightStopTimeout bounds how long the Playwright script may take to
+// save the trace and close the browser at the end of a run.
+const playwrightStopTimeout = 30 * time.Second
+
+// PlaywrightOptions configures WithPlaywright.
+type PlaywrightOptions struct {
+	// Engine is the browser engine: PlaywrightChromium (the default),
+	// PlaywrightFirefox or PlaywrightWebKit.
+	Engine string
+	// Trace is the path of a Playwright trace of the run (screenshots and
+	// DOM snapshots), to open with `npx playwright show-trace`.
+	Trace string
+	// Dir is the directory the playwright npm package is looked up from,
+	// before the global packages; empty is the current directory.
+	Dir string
+	// NoInstall stops the download of a missing browser with
+	// `npx playwright install`.
+	NoInstall bool
+}
+
+// WithPlaywright makes RunBundle open the tests with Playwright, which
+// manages its own Chromium, Firefox and WebKit builds, downloading the
+// missing ones, and can record a trace of the run. Playwright is driven
+// through its npm package, so node and the playwright package (npm install
+// playwright) are needed on the executing host; no Go dependency is added.
+// WithHeadful and WithBrowserFlags apply to the Playwright browser too. It
+// affects only RunBundle: go test runs fail with ErrUsage.
+func WithPlaywright(opts PlaywrightOptions) Option {
+	return func(w *Wasmtest) {
+		if opts.Engine == "" {
+			opts.Engine = PlaywrightChromium
+		}
+		w.playwright = &opts
+	}
+}
+
+// playwrightRecording tells what the Playwright script records besides the
+// trace; empty paths are not recorded.
+type playwrightRecording struct {
+	// video is the directory of the video of the page.
+	video string
+	// profile is the path of the DevTools CPU profile.
+	profile string
+	// heapSnapshot is the path of the V8 heap snapshot taken at the end.
+	heapSnapshot string
+	// jsCoverage is the path of the precise JS coverage.
+	jsCoverage string
+}
+
+// launchPlaywright opens url with Playwright as configured by
+// WithPlaywright, with the recordings of rec. When ctx is done the script is
+// asked to save the trace and the recordings and close the browser, then killed after playwrightStopTimeout. The returned
+// channel receives the script exit error, with its last error message.
+func (w *Wasmtest) launchPlaywright(ctx context.Context, url string, rec playwrightRecording) (<-chan error, error) {
+	opts := *w.playwright
+	if opts.Trace != "" {
+		if abs, err := filepath.Abs(opts.Trace); err == nil {
+			opts.Trace = abs
+		}
+	}
+	config, err := json.Marshal(map[string]any{
+		"engine":       opts.Engine,
+		"url":          url,
+		"headless":     !w.headful,
+		"args":         append([]string{}, w.browserFlags...),
+		"trace":        opts.Trace,
+		"video":        rec.video,
+		"profile":      rec.profile,
+		"heapSnapshot": rec.heapSnapshot,
+		"jsCoverage":   rec.jsCoverage,
+		"install":      !opts.NoInstall,
+	})
+	if err != nil {
+		return nil, err
+	}
+	script, err := os.CreateTemp("", "wasmtest-playwright-*.js")
+	if err != nil {
+		return nil, err
+	}
+	_, err = script.Write(playwrightScript)
+	if cerr := script.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(script.Name())
+		return nil, err
+	}
+
+	cmd := exec.Command("node", script.Name(), string(config))
+	cmd.Dir = opts.Dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		os.Remove(script.Name())
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			stdin.Close()
+			select {
+			case <-finished:
+			case <-time.After(playwrightStopTimeout):
+				cmd.Process.Kill()
+			}
+		case <-finished:
+		}
+	}()
+	go func() {
+		err := cmd.Wait()
+		close(finished)
+		os.Remove(script.Name())
+		lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+		if last := lines[len(lines)-1]; err != nil && last != "" {
+			err = fmt.Errorf("%w: %s", err, last)
+		}
+		done <- err
+	}()
+	return done, nil
+}
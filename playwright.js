@@ -0,0 +1,98 @@
+// playwright.js opens a wasmtest bundle page with Playwright (see
+// WithPlaywright). Its argument is a JSON object with the engine, url,
+// headless, args, trace, video, profile, heapSnapshot, jsCoverage and install
+// settings.
+// The browser is closed, and the recordings saved, when stdin is closed.
+"use strict";
+
+const { execSync } = require("child_process");
+const config = JSON.parse(process.argv[2]);
+
+// loadPlaywright finds the playwright package from the working directory,
+// then from the global packages.
+function loadPlaywright() {
+	const paths = [process.cwd()];
+	try {
+		paths.push(execSync("npm root -g", { encoding: "utf8", stdio: ["ignore", "pipe", "ignore"] }).trim());
+	} catch (err) {}
+	for (const name of ["playwright", "playwright-core", "@playwright/test"]) {
+		try {
+			return require(require.resolve(name, { paths }));
+		} catch (err) {
+			if (err.code !== "MODULE_NOT_FOUND") throw err;
+		}
+	}
+	throw new Error("the playwright package was not found; install it with npm install playwright");
+}
+
+(async () => {
+	const engine = loadPlaywright()[config.engine];
+	if (!engine) {
+		throw new Error(`unknown Playwright engine ${config.engine}`);
+	}
+	const options = { headless: config.headless, args: config.args };
+	let browser;
+	try {
+		browser = await engine.launch(options);
+	} catch (err) {
+		if (!config.install || !/Executable doesn't exist|playwright install/.test(err.message)) {
+			throw err;
+		}
+		process.stderr.write(`installing the Playwright ${config.engine} browser\n`);
+		execSync(`npx playwright install ${config.engine}`, { stdio: ["ignore", process.stderr, process.stderr] });
+		browser = await engine.launch(options);
+	}
+	browser.on("disconnected", () => process.exit(1));
+
+	const context = await browser.newContext(config.video ? { recordVideo: { dir: config.video } } : {});
+	if (config.trace) {
+		await context.tracing.start({ screenshots: true, snapshots: true });
+	}
+	const closed = new Promise((resolve) => {
+		process.stdin.on("end", resolve);
+		process.stdin.on("close", resolve);
+		process.stdin.resume();
+	});
+	const page = await context.newPage();
+	// The CPU profile, the heap snapshot and the coverage are taken through
+	// the DevTools protocol, Chromium only.
+	let cdp;
+	if (config.profile || config.jsCoverage) {
+		cdp = await context.newCDPSession(page);
+		await cdp.send("Profiler.enable");
+	}
+	if (config.profile) {
+		await cdp.send("Profiler.start");
+	}
+	if (config.jsCoverage) {
+		await cdp.send("Profiler.startPreciseCoverage", { callCount: true, detailed: true });
+	}
+	await page.goto(config.url);
+	await closed;
+
+	if (config.profile) {
+		const { profile } = await cdp.send("Profiler.stop");
+		require("fs").writeFileSync(config.profile, JSON.stringify(profile));
+	}
+	if (config.jsCoverage) {
+		const { result } = await cdp.send("Profiler.takePreciseCoverage");
+		require("fs").writeFileSync(config.jsCoverage, JSON.stringify({ result }));
+	}
+	if (config.heapSnapshot) {
+		cdp = cdp || await context.newCDPSession(page);
+		const fd = require("fs").openSync(config.heapSnapshot, "w");
+		cdp.on("HeapProfiler.addHeapSnapshotChunk", ({ chunk }) => require("fs").writeSync(fd, chunk));
+		await cdp.send("HeapProfiler.takeHeapSnapshot", { reportProgress: false });
+		require("fs").closeSync(fd);
+	}
+	if (config.trace) {
+		await context.tracing.stop({ path: config.trace });
+	}
+	browser.removeAllListeners("disconnected");
+	// The video is written when its context closes.
+	await context.close();
+	await browser.close();
+})().catch((err) => {
+	process.stderr.write(`${err.message}\n`);
+	process.exit(1);
+});
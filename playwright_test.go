@@ -0,0 +1,127 @@
+package wasmtest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakePlaywright is a playwright npm package whose browsers load the page
+// with testdata/nodebrowser.js, whose traces and videos record the page URL,
+// whose CPU profiles always show main.spin, whose heap snapshots are
+// {"snapshot":{}} and whose JS coverage has a half covered glue.js.
+const fakePlaywright = `"use strict";
+const { spawn } = require("child_process");
+const engine = (name) => ({
+	launch: async (options) => {
+		let child;
+		const listeners = {};
+		return {
+			on: (event, fn) => { listeners[event] = fn; },
+			removeAllListeners: () => { delete listeners.disconnected; },
+			close: async () => { child.kill(); },
+			newContext: async (contextOptions) => {
+				let url;
+				return {
+					close: async () => {
+						if (contextOptions.recordVideo) {
+							require("fs").mkdirSync(contextOptions.recordVideo.dir, { recursive: true });
+							require("fs").writeFileSync(contextOptions.recordVideo.dir + "/page.webm", url);
+						}
+					},
+					newCDPSession: async () => {
+						const handlers = {};
+						return {
+							on: (event, fn) => { handlers[event] = fn; },
+							send: async (method) => {
+								if (method === "HeapProfiler.takeHeapSnapshot") {
+									handlers["HeapProfiler.addHeapSnapshotChunk"]({ chunk: '{"snapshot":' });
+									handlers["HeapProfiler.addHeapSnapshotChunk"]({ chunk: '{}}' });
+								}
+								if (method === "Profiler.takePreciseCoverage") {
+									return { result: [
+										{ scriptId: "1", url: url, functions: [] },
+										{ scriptId: "2", url: url + "glue.js", functions: [
+											{ functionName: "", isBlockCoverage: true, ranges: [{ startOffset: 0, endOffset: 100, count: 1 }, { startOffset: 50, endOffset: 100, count: 0 }] },
+										] },
+									] };
+								}
+								return method !== "Profiler.stop" ? {} : { profile: {
+									nodes: [
+										{ id: 1, callFrame: { functionName: "(root)", url: "", lineNumber: -1 }, children: [2] },
+										{ id: 2, callFrame: { functionName: "main.spin", url: "wasm://wasm/1", lineNumber: 0 } },
+									],
+									startTime: 0, endTime: 2000, samples: [2, 2], timeDeltas: [1000, 1000],
+								} };
+							},
+						};
+					},
+					tracing: {
+						start: async () => {},
+						stop: async ({ path }) => require("fs").writeFileSync(path, name + " " + url + " headless=" + options.headless),
+					},
+					newPage: async () => ({
+						goto: async (u) => {
+							url = u;
+							child = spawn(process.execPath, [process.env.NODE_BROWSER, u], { stdio: "ignore" });
+						},
+					}),
+				};
+			},
+		};
+	},
+});
+module.exports = { chromium: engine("chromium"), firefox: engine("firefox"), webkit: engine("webkit") };
+`
+
+// fakePlaywrightDir returns a directory with the fakePlaywright package,
+// skipping the test without node.
+func fakePlaywrightDir(t *testing.T) string {
+	t.Helper()
+	nodeBrowser(t)
+	script, err := filepath.Abs("testdata/nodebrowser.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NODE_BROWSER", script)
+	dir := t.TempDir()
+	pkg := filepath.Join(dir, "node_modules", "playwright")
+	if err := os.MkdirAll(pkg, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pkg, "index.js"), []byte(fakePlaywright), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestRunBundlePlaywright(t *testing.T) {
+	dir := fakePlaywrightDir(t)
+
+	trace := filepath.Join(t.TempDir(), "trace.zip")
+	w := New(WithInstallDisabled(), WithPlaywright(PlaywrightOptions{Engine: PlaywrightWebKit, Trace: trace, Dir: dir, NoInstall: true}))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	out := t.TempDir()
+	if _, err := w.Bundle(ctx, "./example", out, "-test.run=TestMathHelper"); err != nil {
+		t.Fatalf("Bundle failed: %v", err)
+	}
+	progress, msgs := collectProgress()
+	if err := w.RunBundle(ctx, out, RunBundleOptions{}, progress); err != nil {
+		t.Fatalf("RunBundle failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	// The trace is saved before RunBundle returns.
+	if data, err := os.ReadFile(trace); err != nil || !strings.HasPrefix(string(data), "webkit http://127.0.0.1:") || !strings.HasSuffix(string(data), "headless=true") {
+		t.Errorf("trace = %q, %v", data, err)
+	}
+
+	// Without the package, the script explains what is missing.
+	w = New(WithInstallDisabled(), WithPlaywright(PlaywrightOptions{Dir: t.TempDir()}), WithLaunchRetries(0))
+	progress, msgs = collectProgress()
+	if err := w.RunBundle(ctx, out, RunBundleOptions{}, progress); err == nil || !strings.Contains(err.Error(), "npm install playwright") {
+		t.Errorf("RunBundle without playwright = %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+}
@@ -0,0 +1,83 @@
+package wasmtest
+
+import (
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// osOnlyPackages are packages that build for js/wasm but can't work in a
+// browser: their functions fail at run time.
+var osOnlyPackages = []string{"os/exec", "os/signal", "os/user", "plugin"}
+
+// Precheck statically inspects the Go files of dir for syscall/js misuse
+// without compiling anything: files importing syscall/js that also build
+// for the host platform (missing the js/wasm build constraint), and
+// js/wasm-only files importing packages that can't work in a browser. Each
+// finding is returned as a Warning whose message starts with file:line.
+// Files are selected with the extra build tags set, as go test -tags does.
+func Precheck(dir string, tags ...string) ([]Warning, error) {
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	host := build.Default
+	wasm := build.Default
+	wasm.GOOS, wasm.GOARCH, wasm.CgoEnabled = "js", "wasm", false
+	host.BuildTags, wasm.BuildTags = tags, tags
+
+	var warnings []Warning
+	fset := token.NewFileSet()
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		native, err := host.MatchFile(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		js, err := wasm.MatchFile(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		if !native && !js {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ImportsOnly)
+		if err != nil {
+			// Syntax errors are left to the compiler.
+			continue
+		}
+		for _, imp := range f.Imports {
+			path, _ := strconv.Unquote(imp.Path.Value)
+			pos := fset.Position(imp.Pos())
+			switch {
+			case path == "syscall/js" && native:
+				warnings = append(warnings, Warning{
+					Code:    "syscall-js-native",
+					Message: fmt.Sprintf("%s:%d: imports syscall/js but also builds for %s/%s, where that package doesn't exist", pos.Filename, pos.Line, host.GOOS, host.GOARCH),
+					Hint:    "add //go:build js && wasm at the top of the file (or name it *_js.go)",
+				})
+			case js && !native && slices.Contains(osOnlyPackages, path):
+				warnings = append(warnings, Warning{
+					Code:    "os-api-in-wasm",
+					Message: fmt.Sprintf("%s:%d: js/wasm file imports %s, which fails at run time in a browser", pos.Filename, pos.Line, path),
+					Hint:    "move the code using it to a file built for the host platform only (//go:build !js)",
+				})
+			}
+		}
+	}
+	return warnings, nil
+}
@@ -0,0 +1,46 @@
+package wasmtest
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPrecheck(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"ok_test.go":     "//go:build js && wasm\n\npackage tmp\n\nimport \"syscall/js\"\n\nvar _ = js.Global\n",
+		"suffix_js.go":   "package tmp\n\nimport \"syscall/js\"\n\nvar _ = js.Global\n",
+		"untagged.go":    "package tmp\n\nimport (\n\t\"fmt\"\n\t\"syscall/js\"\n)\n\nvar _ = fmt.Sprint(js.Global)\n",
+		"exec_test.go":   "//go:build js && wasm\n\npackage tmp\n\nimport \"os/exec\"\n\nvar _ = exec.Command\n",
+		"native.go":      "//go:build !js\n\npackage tmp\n\nimport \"os/exec\"\n\nvar _ = exec.Command\n",
+		"broken_test.go": "//go:build js && wasm\n\npackage tmp\n\nimport (\n",
+	})
+
+	warnings, err := Precheck(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 2 {
+		t.Fatalf("Precheck() = %v, want 2 warnings", warnings)
+	}
+	for _, want := range []struct{ code, at string }{
+		{"syscall-js-native", "untagged.go:5:"},
+		{"os-api-in-wasm", "exec_test.go:5:"},
+	} {
+		found := false
+		for _, w := range warnings {
+			found = found || (w.Code == want.code && strings.Contains(w.Message, want.at))
+		}
+		if !found {
+			t.Errorf("missing %s warning at %s in %v", want.code, want.at, warnings)
+		}
+	}
+
+	// A native run fails before compiling anything.
+	w := New(WithInstallDisabled(), WithLogger(func(...any) {}))
+	var msgs [][]any
+	err = w.execute(context.Background(), execSpec{dir: dir, native: true}, func(m ...any) { msgs = append(msgs, m) })
+	if err == nil || len(msgs) != 1 || msgs[0][0] != "error" {
+		t.Errorf("native execute = %v, progress %v; want a single precheck error", err, msgs)
+	}
+}
@@ -0,0 +1,184 @@
+package wasmtest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Theme holds the ANSI escape sequences the "pretty" report writer colors
+// its output with. Empty fields leave the text as is.
+type Theme struct {
+	Pass, Fail, Skip string
+	// Dim is used for package output, e.g. compiler messages, and the
+	// less relevant lines of failure excerpts.
+	Dim string
+	// Accent highlights plan names and the t.Error/t.Fatal lines of
+	// failure excerpts.
+	Accent string
+}
+
+var (
+	// DefaultTheme uses the standard terminal colors.
+	DefaultTheme = Theme{Pass: "\x1b[32m", Fail: "\x1b[31m", Skip: "\x1b[33m", Dim: "\x1b[2m", Accent: "\x1b[1m"}
+	// HighContrastTheme uses bright colors, bold failures and underlined
+	// highlights for dark or low contrast terminals.
+	HighContrastTheme = Theme{Pass: "\x1b[92m", Fail: "\x1b[1;91m", Skip: "\x1b[93m", Dim: "\x1b[37m", Accent: "\x1b[1;4m"}
+	// NoColorTheme prints plain text.
+	NoColorTheme = Theme{}
+)
+
+// NewPrettyReportWriter returns a console ReportWriter printing a colored
+// line per test (subtests indented under their parent), the highlighted
+// output of failed tests, the package output dimmed and a colored summary.
+// The registered "pretty" writer uses DefaultTheme when out is a terminal
+// and the NO_COLOR environment variable is unset, and NoColorTheme
+// otherwise.
+func NewPrettyReportWriter(out io.Writer, theme Theme) ReportWriter {
+	return &prettyReportWriter{out: out, theme: theme}
+}
+
+// newAutoPrettyReportWriter is the factory of the "pretty" writer.
+func newAutoPrettyReportWriter(out io.Writer) ReportWriter {
+	theme := DefaultTheme
+	if !colorEnabled(out) {
+		theme = NoColorTheme
+	}
+	return NewPrettyReportWriter(out, theme)
+}
+
+// colorEnabled reports whether colors should be written to out: out must be
+// a terminal and NO_COLOR (https://no-color.org) unset.
+func colorEnabled(out io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	f, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// prettyReportWriter is the ReportWriter of NewPrettyReportWriter.
+type prettyReportWriter struct {
+	out   io.Writer
+	theme Theme
+	multi bool
+	// started holds the plans whose header was printed.
+	started []string
+	// output holds the lines written by each running test.
+	output map[[2]string][]string
+}
+
+func (p *prettyReportWriter) Begin(plans []RunPlan) error {
+	p.multi = len(plans) > 1
+	p.started = nil
+	p.output = map[[2]string][]string{}
+	return nil
+}
+
+func (p *prettyReportWriter) TestEvent(ev TestEvent) error {
+	if err := p.header(ev.Plan); err != nil {
+		return err
+	}
+	key := [2]string{ev.Plan, ev.Test}
+	switch ev.Action {
+	case "output":
+		if ev.Test != "" {
+			p.output[key] = append(p.output[key], ev.Output)
+			return nil
+		}
+		return p.printf("%s\n", p.paint(p.theme.Dim, ev.Output))
+	case "pass", "fail", "skip":
+	default:
+		return nil
+	}
+	output := p.output[key]
+	delete(p.output, key)
+
+	indent := strings.Repeat("  ", 1+strings.Count(ev.Test, "/"))
+	name := ev.Test[strings.LastIndex(ev.Test, "/")+1:]
+	elapsed := p.paint(p.theme.Dim, "("+ev.Elapsed.Round(time.Millisecond).String()+")")
+	switch ev.Action {
+	case "pass":
+		return p.printf("%s%s %s %s\n", indent, p.paint(p.theme.Pass, "✓"), name, elapsed)
+	case "skip":
+		return p.printf("%s%s %s %s\n", indent, p.paint(p.theme.Skip, "-"), name, p.paint(p.theme.Skip, "(skipped)"))
+	}
+	if err := p.printf("%s%s %s %s\n", indent, p.paint(p.theme.Fail, "✗"), p.paint(p.theme.Fail, name), elapsed); err != nil {
+		return err
+	}
+	for _, line := range output {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "--- ") {
+			continue
+		}
+		color := p.theme.Dim
+		if testLogLine.MatchString(line) {
+			color = p.theme.Accent
+		}
+		if err := p.printf("%s  %s %s\n", indent, p.paint(p.theme.Fail, "│"), p.paint(color, trimmed)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// header prints the name of plan before its first event when several
+// plans run.
+func (p *prettyReportWriter) header(plan string) error {
+	if !p.multi || slices.Contains(p.started, plan) {
+		return nil
+	}
+	p.started = append(p.started, plan)
+	return p.printf("%s\n", p.paint(p.theme.Accent, "▶ "+plan))
+}
+
+func (p *prettyReportWriter) End(report *Report) error {
+	var passed, failed, skipped int
+	for _, res := range report.Results {
+		passed += len(res.PassedTests)
+		failed += len(res.FailedTests)
+		skipped += len(res.SkippedTests)
+		if res.Err != nil && len(res.FailedTests) == 0 {
+			first, _, _ := strings.Cut(res.Err.Error(), "\n")
+			if err := p.printf("%s %s: %s\n", p.paint(p.theme.Fail, "✗"), p.paint(p.theme.Accent, res.Plan.Name), first); err != nil {
+				return err
+			}
+		}
+	}
+	status := p.paint(p.theme.Pass, "PASS")
+	if report.ExitCode != 0 {
+		status = p.paint(p.theme.Fail, "FAIL")
+	}
+	counts := []string{p.paint(p.theme.Pass, fmt.Sprintf("%d passed", passed))}
+	if failed > 0 {
+		counts = append(counts, p.paint(p.theme.Fail, fmt.Sprintf("%d failed", failed)))
+	} else {
+		counts = append(counts, "0 failed")
+	}
+	if skipped > 0 {
+		counts = append(counts, p.paint(p.theme.Skip, fmt.Sprintf("%d skipped", skipped)))
+	} else {
+		counts = append(counts, "0 skipped")
+	}
+	return p.printf("\n%s %s %s\n", status, strings.Join(counts, ", "), p.paint(p.theme.Dim, "in "+report.Duration.Round(time.Millisecond).String()))
+}
+
+// paint wraps s in color, unless color is empty.
+func (p *prettyReportWriter) paint(color, s string) string {
+	if color == "" {
+		return s
+	}
+	return color + s + "\x1b[0m"
+}
+
+func (p *prettyReportWriter) printf(format string, args ...any) error {
+	_, err := fmt.Fprintf(p.out, format, args...)
+	return err
+}
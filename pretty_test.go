@@ -0,0 +1,100 @@
+package wasmtest
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// prettyEvents feeds a run of two plans to rw.
+func prettyEvents(t *testing.T, rw ReportWriter) {
+	t.Helper()
+	if err := rw.Begin([]RunPlan{{Name: "ui"}, {Name: "broken"}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, ev := range []TestEvent{
+		{Plan: "ui", Action: "run", Test: "TestOK"},
+		{Plan: "ui", Action: "run", Test: "TestOK/sub"},
+		{Plan: "ui", Action: "pass", Test: "TestOK/sub", Elapsed: time.Millisecond},
+		{Plan: "ui", Action: "pass", Test: "TestOK", Elapsed: 12 * time.Millisecond},
+		{Plan: "ui", Action: "run", Test: "TestBad"},
+		{Plan: "ui", Action: "output", Test: "TestBad", Output: "=== RUN   TestBad"},
+		{Plan: "ui", Action: "output", Test: "TestBad", Output: "    bad_test.go:9: want 1"},
+		{Plan: "ui", Action: "output", Test: "TestBad", Output: "        got 2"},
+		{Plan: "ui", Action: "output", Test: "TestBad", Output: "--- FAIL: TestBad (0.00s)"},
+		{Plan: "ui", Action: "fail", Test: "TestBad", Elapsed: 3 * time.Millisecond},
+		{Plan: "ui", Action: "skip", Test: "TestLater"},
+		{Plan: "broken", Action: "output", Output: "./x_test.go:3:1: syntax error"},
+	} {
+		if err := rw.TestEvent(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	report := &Report{ExitCode: 1, Duration: 1500 * time.Millisecond, Results: []PlanResult{
+		{Plan: RunPlan{Name: "ui"}, RunResult: RunResult{PassedTests: []string{"TestOK/sub", "TestOK"}, FailedTests: []string{"TestBad"}, SkippedTests: []string{"TestLater"}}, Err: errors.New("tests failed")},
+		{Plan: RunPlan{Name: "broken"}, Err: errors.New("build failed\ndetails")},
+	}}
+	if err := rw.End(report); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrettyReportWriter(t *testing.T) {
+	var out bytes.Buffer
+	prettyEvents(t, NewPrettyReportWriter(&out, NoColorTheme))
+	want := `▶ ui
+    ✓ sub (1ms)
+  ✓ TestOK (12ms)
+  ✗ TestBad (3ms)
+    │ bad_test.go:9: want 1
+    │ got 2
+  - TestLater (skipped)
+▶ broken
+./x_test.go:3:1: syntax error
+✗ broken: build failed
+
+FAIL 2 passed, 1 failed, 1 skipped in 1.5s
+`
+	if out.String() != want {
+		t.Errorf("plain output:\n%s\nwant\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	prettyEvents(t, NewPrettyReportWriter(&out, DefaultTheme))
+	for _, s := range []string{
+		"\x1b[32m✓\x1b[0m TestOK",
+		"\x1b[31m✗\x1b[0m \x1b[31mTestBad\x1b[0m",
+		"\x1b[1mbad_test.go:9: want 1\x1b[0m",
+		"\x1b[2mgot 2\x1b[0m",
+		"\x1b[2m./x_test.go:3:1: syntax error\x1b[0m",
+		"\x1b[31mFAIL\x1b[0m",
+	} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("colored output lacks %q:\n%q", s, out.String())
+		}
+	}
+}
+
+func TestColorEnabled(t *testing.T) {
+	if colorEnabled(&bytes.Buffer{}) {
+		t.Error("colors enabled for a buffer")
+	}
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if colorEnabled(f) {
+		t.Error("colors enabled for a regular file")
+	}
+	if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+		defer tty.Close()
+		t.Setenv("NO_COLOR", "")
+		if colorEnabled(tty) {
+			t.Error("colors enabled with NO_COLOR set")
+		}
+	}
+}
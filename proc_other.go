@@ -0,0 +1,23 @@
+//go:build !unix
+
+package wasmtest
+
+import (
+	"os"
+	"os/exec"
+)
+
+// killProcessTree is a no-op outside unix: only the go process itself is
+// killed when the context is done.
+func killProcessTree(cmd *exec.Cmd) {}
+
+// processRunning reports whether a process with the id pid may exist.
+// Where processes can't be looked up, it is assumed to.
+func processRunning(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package wasmtest
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// killProcessTree makes cmd run in its own process group and kills the whole
+// group when its context is done, so the test binary and the browser started
+// by go test don't outlive a canceled run.
+func killProcessTree(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// processRunning reports whether a process with the id pid exists.
+func processRunning(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,167 @@
+package wasmtest
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// TestProgress is a snapshot of the progress of the tests of a package. A
+// func(TestProgress) RunTests argument receives one when the test list is
+// known, before the first test runs, and then each time a top level test
+// finishes, so TUIs can render a progress bar.
+type TestProgress struct {
+	// Dir is the package directory.
+	Dir string
+	// Total is the number of top level tests expected to run, from go test
+	// -list; 0 when the list couldn't be obtained.
+	Total int
+	// Done counts the finished top level tests, Passed, Failed and Skipped
+	// break it down.
+	Done, Passed, Failed, Skipped int
+	// Test is the last finished test, empty in the first snapshot.
+	Test string
+}
+
+// String renders p as "12/34 tests, 2 failed", or "12 tests, 2 failed"
+// when the total is unknown.
+func (p TestProgress) String() string {
+	s := fmt.Sprintf("%d/%d tests", p.Done, p.Total)
+	if p.Total == 0 {
+		s = fmt.Sprintf("%d %s", p.Done, plural(p.Done, "test", "tests"))
+	}
+	if p.Failed > 0 {
+		s += fmt.Sprintf(", %d failed", p.Failed)
+	}
+	return s
+}
+
+// Fraction returns the completed fraction of the run, between 0 and 1, or
+// 0 when the total is unknown.
+func (p TestProgress) Fraction() float64 {
+	if p.Total == 0 {
+		return 0
+	}
+	return min(float64(p.Done)/float64(p.Total), 1)
+}
+
+// progressTracker turns the "list" and "test" progress messages of a run
+// into TestProgress snapshots.
+type progressTracker struct {
+	progress TestProgress
+	notify   func(TestProgress)
+}
+
+func (t *progressTracker) update(msgs ...any) {
+	if len(msgs) < 2 {
+		return
+	}
+	switch v := msgs[1].(type) {
+	case []string:
+		if msgs[0] == "list" {
+			t.progress.Total = len(v)
+			t.notify(t.progress)
+		}
+	case TestEvent:
+		if strings.Contains(v.Test, "/") {
+			return
+		}
+		switch v.Action {
+		case "pass":
+			t.progress.Passed++
+		case "fail":
+			t.progress.Failed++
+		case "skip":
+			t.progress.Skipped++
+		default:
+			return
+		}
+		t.progress.Done++
+		t.progress.Test = v.Test
+		t.notify(t.progress)
+	}
+}
+
+// listedTest matches the test, example and fuzz target names printed by go
+// test -list; benchmarks only run with -bench and are not counted.
+var listedTest = regexp.MustCompile(`^(Test|Example|Fuzz)\w*$`)
+
+// listTests returns the top level tests the go test run of spec will run,
+// found in the test files, or with go test -list when that fails or finds
+// none, e.g. for test files without a js/wasm build constraint. The -run
+// and -skip patterns of spec.args are applied to the names, up to their
+// first slash, like go test does for top level tests.
+func (w *Wasmtest) listTests(ctx context.Context, spec execSpec) ([]string, error) {
+	var listed []string
+	if pkg, err := discoverPackage(spec.dir, spec.target, spec.tags); err == nil {
+		for _, fn := range pkg.Funcs {
+			listed = append(listed, fn.Name)
+		}
+	}
+	if len(listed) == 0 {
+		args := []string{"test", "-list", "."}
+		if spec.exec != "" {
+			args = append(args, "-exec", spec.exec)
+		}
+		args = append(args, spec.args...)
+		cmd := exec.CommandContext(ctx, spec.toolchain.command(), args...)
+		cmd.Dir = spec.dir
+		cmd.Env = spec.environ()
+		killProcessTree(cmd)
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, err
+		}
+		listed = strings.Split(string(out), "\n")
+	}
+
+	run, err := topLevelPattern(flagValue(spec.args, "run"))
+	if err != nil {
+		return nil, err
+	}
+	skip, err := topLevelPattern(flagValue(spec.args, "skip"))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, line := range listed {
+		name := strings.TrimSpace(line)
+		if !listedTest.MatchString(name) {
+			continue
+		}
+		if (run == nil || run.MatchString(name)) && (skip == nil || !skip.MatchString(name)) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// flagValue returns the value of the last -name flag of args, given as
+// "-name value" or "-name=value", with one or two dashes.
+func flagValue(args []string, name string) string {
+	value := ""
+	for i, arg := range args {
+		flag := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if flag == arg {
+			continue
+		}
+		if v, ok := strings.CutPrefix(flag, name+"="); ok {
+			value = v
+		} else if flag == name && i+1 < len(args) {
+			value = args[i+1]
+		}
+	}
+	return value
+}
+
+// topLevelPattern compiles the part of a -run or -skip pattern matching top
+// level test names, or returns nil for an empty pattern.
+func topLevelPattern(pattern string) (*regexp.Regexp, error) {
+	pattern, _, _ = strings.Cut(pattern, "/")
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
@@ -0,0 +1,91 @@
+package wasmtest
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestTestProgressString(t *testing.T) {
+	for _, tt := range []struct {
+		p    TestProgress
+		want string
+		frac float64
+	}{
+		{TestProgress{Total: 34, Done: 12, Failed: 2}, "12/34 tests, 2 failed", 12.0 / 34},
+		{TestProgress{Total: 4, Done: 4}, "4/4 tests", 1},
+		{TestProgress{Done: 1}, "1 test", 0},
+	} {
+		if got := tt.p.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.p, got, tt.want)
+		}
+		if got := tt.p.Fraction(); got != tt.frac {
+			t.Errorf("%+v.Fraction() = %v, want %v", tt.p, got, tt.frac)
+		}
+	}
+}
+
+func TestFlagValue(t *testing.T) {
+	args := []string{"-run", "TestA", "-count", "1", "--skip=TestB/slow", "-v"}
+	if got := flagValue(args, "run"); got != "TestA" {
+		t.Errorf("run = %q", got)
+	}
+	if got := flagValue(args, "skip"); got != "TestB/slow" {
+		t.Errorf("skip = %q", got)
+	}
+	if got := flagValue(args, "bench"); got != "" {
+		t.Errorf("bench = %q", got)
+	}
+	re, err := topLevelPattern("TestB/slow")
+	if err != nil || !re.MatchString("TestB") || re.MatchString("TestA") {
+		t.Errorf("topLevelPattern = %v, %v", re, err)
+	}
+}
+
+func TestRunTestsProgress(t *testing.T) {
+	node := nodeExec(t)
+	dir := writeModule(t, map[string]string{
+		"p_test.go": "//go:build js && wasm\n\npackage p\n\nimport \"testing\"\n\n" +
+			"func TestA(t *testing.T) { t.Run(\"sub\", func(t *testing.T) {}) }\n" +
+			"func TestB(t *testing.T) { t.Fatal(\"bad\") }\n" +
+			"func TestC(t *testing.T) {}\n",
+	})
+	var got []TestProgress
+	progress := func(p TestProgress) { got = append(got, p) }
+	opts := ExecOptions{Skip: "TestC", Args: []string{"-exec", node}}
+	if err := RunTests(dir, func(...any) {}, progress, opts, WithInstallDisabled()); err == nil {
+		t.Fatal("RunTests succeeded with a failing test")
+	}
+
+	want := []TestProgress{
+		{Dir: dir, Total: 2},
+		{Dir: dir, Total: 2, Done: 1, Passed: 1, Test: "TestA"},
+		{Dir: dir, Total: 2, Done: 2, Passed: 1, Failed: 1, Test: "TestB"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("progress snapshots:\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestListTests(t *testing.T) {
+	w := New(WithInstallDisabled())
+	dir := writeModule(t, map[string]string{
+		"p_test.go": "//go:build js && wasm\n\npackage p\n\nimport \"testing\"\n\n" +
+			"func TestA(t *testing.T) {}\nfunc TestB(t *testing.T) {}\nfunc BenchmarkC(b *testing.B) {}\n",
+	})
+
+	// The test files are enough: no go command runs.
+	spec := execSpec{dir: dir, args: []string{"-run", "TestA|TestB", "-skip", "TestB"}, toolchain: goToolchain{bin: filepath.Join(t.TempDir(), "no-go")}}
+	if names, err := w.listTests(t.Context(), spec); err != nil || !slices.Equal(names, []string{"TestA"}) {
+		t.Errorf("listTests from the sources = %q, %v", names, err)
+	}
+
+	// Test files without a js/wasm constraint are listed by go test -list.
+	node := nodeExec(t)
+	dir = writeModule(t, map[string]string{
+		"p_test.go": "package p\n\nimport \"testing\"\n\nfunc TestPlain(t *testing.T) {}\n",
+	})
+	if names, err := w.listTests(t.Context(), execSpec{dir: dir, exec: node}); err != nil || !slices.Equal(names, []string{"TestPlain"}) {
+		t.Errorf("listTests with go test -list = %q, %v", names, err)
+	}
+}
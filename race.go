@@ -0,0 +1,82 @@
+package wasmtest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Warning is a structured, non fatal diagnostic reported through the
+// progress callback as ("warning", Warning).
+type Warning struct {
+	// Code identifies the kind of warning, e.g. "race-unsupported".
+	Code string `json:"code"`
+	// Message explains what happened.
+	Message string `json:"message"`
+	// Hint suggests how to address it.
+	Hint string `json:"hint,omitempty"`
+}
+
+// String renders the warning for log output.
+func (w Warning) String() string {
+	if w.Hint == "" {
+		return fmt.Sprintf("⚠️ %s: %s", w.Code, w.Message)
+	}
+	return fmt.Sprintf("⚠️ %s: %s\n💡 %s", w.Code, w.Message, w.Hint)
+}
+
+// raceWarning is reported when -race is requested for a js/wasm run.
+var raceWarning = Warning{
+	Code:    "race-unsupported",
+	Message: "the race detector is not supported for GOOS=js GOARCH=wasm; -race was ignored",
+	Hint:    "set RunPlan.NativeRace to run the package natively with -race as a complementary pass",
+}
+
+// racePassName is the name under which the native -race pass of a plan
+// reports its events.
+func racePassName(plan string) string { return plan + " (native -race)" }
+
+// raceRequested reports whether -race appears in the go test args or in the
+// GOFLAGS entry of env.
+func raceRequested(args, env []string) bool {
+	if isRaceFlag(args) {
+		return true
+	}
+	for _, kv := range env {
+		if envKey(kv) == "GOFLAGS" {
+			_, goflags, _ := strings.Cut(kv, "=")
+			if isRaceFlag(strings.Fields(goflags)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isRaceFlag reports whether flags enable the race detector.
+func isRaceFlag(flags []string) bool {
+	enabled := false
+	for _, f := range flags {
+		if !strings.HasPrefix(f, "-") {
+			continue
+		}
+		switch strings.TrimLeft(f, "-") {
+		case "race", "race=true", "race=1":
+			enabled = true
+		case "race=false", "race=0":
+			enabled = false
+		}
+	}
+	return enabled
+}
+
+// withoutRace returns a copy of args without any -race flag.
+func withoutRace(args []string) []string {
+	kept := make([]string, 0, len(args))
+	for _, a := range args {
+		if name, _, _ := strings.Cut(strings.TrimLeft(a, "-"), "="); strings.HasPrefix(a, "-") && name == "race" {
+			continue
+		}
+		kept = append(kept, a)
+	}
+	return kept
+}
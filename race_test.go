@@ -0,0 +1,96 @@
+package wasmtest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRaceRequested(t *testing.T) {
+	cases := []struct {
+		args []string
+		env  []string
+		want bool
+	}{
+		{nil, nil, false},
+		{[]string{"-race"}, nil, true},
+		{[]string{"--race=true"}, nil, true},
+		{[]string{"-race", "-race=false"}, nil, false},
+		{[]string{"-run", "race"}, nil, false},
+		{nil, []string{"GOFLAGS=-mod=mod -race"}, true},
+		{nil, []string{"GOFLAGS=-mod=mod"}, false},
+	}
+	for _, c := range cases {
+		if got := raceRequested(c.args, c.env); got != c.want {
+			t.Errorf("raceRequested(%q, %q) = %v, want %v", c.args, c.env, got, c.want)
+		}
+	}
+
+	got := withoutRace([]string{"-race", "-run", "race", "--race=1", "-count=1"})
+	if !slices.Equal(got, []string{"-run", "race", "-count=1"}) {
+		t.Errorf("withoutRace() = %q", got)
+	}
+}
+
+// writeModule creates a throwaway module in a temp dir from name/content
+// pairs and returns its path.
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/tmp\n\ngo 1.21\n"
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestNativeRacePass(t *testing.T) {
+	node := nodeExec(t)
+	if testing.Short() {
+		t.Skip("building the race runtime is slow; skipping in short mode")
+	}
+
+	dir := writeModule(t, map[string]string{
+		"sum.go":       "package tmp\n\nfunc Sum(a, b int) int { return a + b }\n",
+		"sum_test.go":  "package tmp\n\nimport \"testing\"\n\nfunc TestNative(t *testing.T) {\n\tif Sum(1, 2) != 3 {\n\t\tt.Fatal(\"bad sum\")\n\t}\n}\n",
+		"wasm_test.go": "//go:build js && wasm\n\npackage tmp\n\nimport \"testing\"\n\nfunc TestWasm(t *testing.T) {}\n",
+	})
+
+	var mu sync.Mutex
+	var logs []string
+	o := &Orchestrator{Logger: func(a ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		logs = append(logs, fmt.Sprint(a...))
+	}}
+	report, err := o.Run(context.Background(), []RunPlan{{Name: "tmp", Dir: dir, Exec: node, Args: []string{"-race"}, NativeRace: true}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	res := report.Results[0]
+	if res.Race == nil || !res.Race.Passed() {
+		t.Fatalf("native race pass = %+v", res.Race)
+	}
+	if !slices.ContainsFunc(res.RawOutput, func(l string) bool { return strings.Contains(l, "TestWasm") }) {
+		t.Errorf("js/wasm pass didn't run TestWasm: %q", res.RawOutput)
+	}
+	if !slices.ContainsFunc(res.Race.RawOutput, func(l string) bool { return strings.Contains(l, "TestNative") }) {
+		t.Errorf("native pass didn't run TestNative: %q", res.Race.RawOutput)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.ContainsFunc(logs, func(l string) bool { return strings.Contains(l, raceWarning.Code) }) {
+		t.Errorf("race warning not reported; logs=%q", logs)
+	}
+}
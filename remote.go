@@ -0,0 +1,98 @@
+package wasmtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// WithRemoteBrowser makes RunBundle open the tests in an already running
+// browser instead of launching one, e.g. a browser in another container or
+// one shared for a debugging session. endpoint is its DevTools (CDP)
+// address, either the WebSocket URL printed by the browser, like
+// ws://host:9222/devtools/browser/<id>, or http://host:9222. The tests run
+// in a new tab, closed at the end of the run. The browser must reach the
+// served bundle: set RunBundleOptions.Addr, and RunBundleOptions.URL when
+// the runner is known under another name there. Go test runs ignore it, as
+// wasmbrowsertest always launches its own browser.
+func WithRemoteBrowser(endpoint string) Option {
+	return func(w *Wasmtest) { w.remoteBrowser = endpoint }
+}
+
+// devtoolsHTTPBase returns the base URL of the DevTools HTTP endpoint of
+// the browser listening at endpoint.
+func devtoolsHTTPBase(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("wasmtest: invalid remote browser endpoint %q: %w", endpoint, err)
+	}
+	switch u.Scheme {
+	case "ws", "http":
+		u.Scheme = "http"
+	case "wss", "https":
+		u.Scheme = "https"
+	default:
+		return "", fmt.Errorf("wasmtest: remote browser endpoint %q is not a ws, wss, http or https URL", endpoint)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("wasmtest: remote browser endpoint %q has no host", endpoint)
+	}
+	return u.Scheme + "://" + u.Host, nil
+}
+
+// openRemoteTab opens page in a new tab of the browser listening at
+// endpoint, through the /json/new DevTools endpoint. closeTab closes it.
+func openRemoteTab(ctx context.Context, endpoint, page string) (closeTab func(), err error) {
+	base, err := devtoolsHTTPBase(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	// Chrome only accepts PUT since version 111, older versions GET.
+	var target struct {
+		ID string `json:"id"`
+	}
+	for _, method := range []string{http.MethodPut, http.MethodGet} {
+		var status int
+		status, err = devtoolsRequest(ctx, method, base+"/json/new?"+url.QueryEscape(page), &target)
+		if err == nil || status != http.StatusMethodNotAllowed {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		// The run context may already be done: the tab is closed anyway.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		devtoolsRequest(ctx, http.MethodGet, base+"/json/close/"+url.PathEscape(target.ID), nil)
+	}, nil
+}
+
+// devtoolsRequest sends a request to the DevTools HTTP endpoint and decodes
+// its JSON response into v, when not nil.
+func devtoolsRequest(ctx context.Context, method, u string, v any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("wasmtest: remote browser: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("wasmtest: remote browser: %s %s: %s", method, strings.SplitN(u, "?", 2)[0], resp.Status)
+	}
+	if v == nil {
+		return resp.StatusCode, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return resp.StatusCode, fmt.Errorf("wasmtest: remote browser: invalid response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
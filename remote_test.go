@@ -0,0 +1,82 @@
+package wasmtest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDevtoolsHTTPBase(t *testing.T) {
+	for endpoint, want := range map[string]string{
+		"ws://127.0.0.1:9222/devtools/browser/8f3a": "http://127.0.0.1:9222",
+		"wss://grid.example.com/devtools/browser/1": "https://grid.example.com",
+		"http://chrome:9222":                        "http://chrome:9222",
+		"chrome:9222":                               "",
+		"ws:///devtools/browser/1":                  "",
+	} {
+		got, err := devtoolsHTTPBase(endpoint)
+		if got != want || (want == "") != (err != nil) {
+			t.Errorf("devtoolsHTTPBase(%q) = %q, %v; want %q", endpoint, got, err, want)
+		}
+	}
+}
+
+func TestRunBundleRemoteBrowser(t *testing.T) {
+	browser := nodeBrowser(t)
+	// The fake DevTools endpoint opens each new tab in its own node browser.
+	var mu sync.Mutex
+	tabs := map[string]*exec.Cmd{}
+	var closed []string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/json/new" && r.Method == http.MethodPut:
+			page, err := url.QueryUnescape(r.URL.RawQuery)
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			cmd := exec.Command(browser, page)
+			if err := cmd.Start(); err != nil {
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			tabs["tab1"] = cmd
+			rw.Write([]byte(`{"id": "tab1", "type": "page"}`))
+		case strings.HasPrefix(r.URL.Path, "/json/close/"):
+			id := strings.TrimPrefix(r.URL.Path, "/json/close/")
+			if cmd := tabs[id]; cmd != nil {
+				cmd.Process.Kill()
+				cmd.Wait()
+			}
+			closed = append(closed, id)
+		default:
+			http.NotFound(rw, r)
+		}
+	}))
+	defer srv.Close()
+
+	w := New(WithInstallDisabled(), WithRemoteBrowser("ws"+strings.TrimPrefix(srv.URL, "http")+"/devtools/browser/abc"))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	out := t.TempDir()
+	if _, err := w.Bundle(ctx, "./example", out, "-test.run=TestMathHelper"); err != nil {
+		t.Fatalf("Bundle failed: %v", err)
+	}
+	progress, msgs := collectProgress()
+	if err := w.RunBundle(ctx, out, RunBundleOptions{}, progress); err != nil {
+		t.Fatalf("RunBundle failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(closed) != 1 || closed[0] != "tab1" {
+		t.Errorf("closed tabs = %q", closed)
+	}
+}
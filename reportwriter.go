@@ -0,0 +1,255 @@
+package wasmtest
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TestEvent describes a single step of a test run as seen in the go test
+// output: a test starting, finishing with a status, or printing output.
+type TestEvent struct {
+	Time time.Time `json:"time"`
+	// Plan is the name of the RunPlan that produced the event.
+	Plan string `json:"plan,omitempty"`
+	// Action is one of "run", "pause", "cont", "pass", "fail", "skip" or
+	// "output". A parallel test pauses until the sequential ones are done
+	// and then continues.
+	Action string `json:"action"`
+	// Test is the test name, empty for package level output. Output
+	// events get the test that wrote the line.
+	Test string `json:"test,omitempty"`
+	// Elapsed is set for pass, fail and skip events.
+	Elapsed time.Duration `json:"elapsed,omitempty"`
+	// Output holds the raw line for output events.
+	Output string `json:"output,omitempty"`
+}
+
+// String renders ev as "pass TestName (1.5s)", or as the line of output
+// events.
+func (ev TestEvent) String() string {
+	switch ev.Action {
+	case "output":
+		return ev.Output
+	case "pass", "fail", "skip":
+		return fmt.Sprintf("%s %s (%v)", ev.Action, ev.Test, ev.Elapsed)
+	}
+	return ev.Action + " " + ev.Test
+}
+
+// parallelStep reports whether ev is a parallel test pausing or
+// continuing, which leaves its state unchanged.
+func (ev TestEvent) parallelStep() bool {
+	return ev.Action == "pause" || ev.Action == "cont"
+}
+
+// testTracker follows the running tests through the "test" progress
+// messages to tell which test an output line belongs to.
+type testTracker struct {
+	running []string
+}
+
+// event converts a "test", "out" or "err" progress message into a
+// TestEvent. Output events get the test of their OutputLine, or else the
+// innermost test running when they were written, if any; paused tests are
+// not running.
+func (t *testTracker) event(msgs ...any) (TestEvent, bool) {
+	if len(msgs) < 2 {
+		return TestEvent{}, false
+	}
+	if ev, ok := msgs[1].(TestEvent); ok {
+		if ev.Action == "run" || ev.Action == "cont" {
+			t.running = append(t.running, ev.Test)
+		} else if i := slices.Index(t.running, ev.Test); i >= 0 {
+			t.running = slices.Delete(t.running, i, i+1)
+		}
+		return ev, true
+	}
+	if tag := fmt.Sprint(msgs[0]); tag != "out" && tag != "err" {
+		return TestEvent{}, false
+	}
+	ev := TestEvent{Time: time.Now(), Action: "output", Output: fmt.Sprint(msgs[1])}
+	if line, ok := msgs[1].(OutputLine); ok {
+		ev.Test = line.Test
+	} else if n := len(t.running); n > 0 {
+		ev.Test = t.running[n-1]
+	}
+	return ev, true
+}
+
+// ReportWriter receives the events of an orchestrated run so custom output
+// formats can be produced without changes to this package. Begin is called
+// once before any plan starts, TestEvent for every event of every plan
+// (never concurrently), and End once with the merged report.
+type ReportWriter interface {
+	Begin(plans []RunPlan) error
+	TestEvent(ev TestEvent) error
+	End(report *Report) error
+}
+
+// ReportWriterFactory creates a ReportWriter writing to out.
+type ReportWriterFactory func(out io.Writer) ReportWriter
+
+var (
+	reportWritersMu sync.RWMutex
+	reportWriters   = map[string]ReportWriterFactory{
+		"text":     func(out io.Writer) ReportWriter { return &textReportWriter{out: out} },
+		"tap":      func(out io.Writer) ReportWriter { return &tapReportWriter{out: out} },
+		"github":   newGitHubReportWriter,
+		"json":     func(out io.Writer) ReportWriter { return &jsonReportWriter{out: out} },
+		"html":     func(out io.Writer) ReportWriter { return &htmlReportWriter{out: out} },
+		"markdown": func(out io.Writer) ReportWriter { return &markdownReportWriter{out: out} },
+		"pretty":   newAutoPrettyReportWriter,
+		"teamcity": func(out io.Writer) ReportWriter { return &teamcityReportWriter{out: out} },
+		"junit":    func(out io.Writer) ReportWriter { return &junitReportWriter{out: out} },
+	}
+)
+
+// RegisterReportWriter makes a ReportWriter available under name, replacing
+// any writer previously registered with the same name.
+func RegisterReportWriter(name string, factory ReportWriterFactory) {
+	reportWritersMu.Lock()
+	defer reportWritersMu.Unlock()
+	reportWriters[name] = factory
+}
+
+// NewReportWriter returns the registered ReportWriter called name.
+func NewReportWriter(name string, out io.Writer) (ReportWriter, error) {
+	reportWritersMu.RLock()
+	factory, ok := reportWriters[name]
+	reportWritersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("wasmtest: unknown report writer %q (available: %s)", name, strings.Join(ReportWriters(), ", "))
+	}
+	return factory(out), nil
+}
+
+// ReportWriters returns the sorted names of the registered report writers.
+func ReportWriters() []string {
+	reportWritersMu.RLock()
+	defer reportWritersMu.RUnlock()
+	names := make([]string, 0, len(reportWriters))
+	for name := range reportWriters {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// textReportWriter is the built-in "text" writer printing one line per
+// finished test and the report summary.
+type textReportWriter struct {
+	out io.Writer
+}
+
+func (t *textReportWriter) Begin(plans []RunPlan) error {
+	_, err := fmt.Fprintf(t.out, "running %d plans\n", len(plans))
+	return err
+}
+
+func (t *textReportWriter) TestEvent(ev TestEvent) error {
+	switch ev.Action {
+	case "pass", "fail", "skip":
+		_, err := fmt.Fprintf(t.out, "%-4s %s %s (%v)\n", strings.ToUpper(ev.Action), ev.Plan, ev.Test, ev.Elapsed)
+		return err
+	}
+	return nil
+}
+
+func (t *textReportWriter) End(report *Report) error {
+	_, err := fmt.Fprintln(t.out, report.String())
+	return err
+}
+
+// tapReportWriter is the built-in "tap" writer producing Test Anything
+// Protocol version 13: one ok/not ok line per finished test, with a YAML
+// diagnostic block holding the output of failed tests, and the plan line
+// at the end since the number of tests is only known then.
+type tapReportWriter struct {
+	out   io.Writer
+	multi bool
+	n     int
+	// output holds the lines written by each running test, by plan and
+	// test name.
+	output map[[2]string][]string
+}
+
+func (t *tapReportWriter) Begin(plans []RunPlan) error {
+	t.multi = len(plans) > 1
+	t.output = map[[2]string][]string{}
+	_, err := fmt.Fprintln(t.out, "TAP version 13")
+	return err
+}
+
+func (t *tapReportWriter) TestEvent(ev TestEvent) error {
+	key := [2]string{ev.Plan, ev.Test}
+	switch ev.Action {
+	case "output":
+		if ev.Test != "" {
+			t.output[key] = append(t.output[key], ev.Output)
+		}
+		return nil
+	case "pass", "fail", "skip":
+	default:
+		return nil
+	}
+	output := t.output[key]
+	delete(t.output, key)
+
+	name := ev.Test
+	if t.multi {
+		name = ev.Plan + ": " + name
+	}
+	t.n++
+	switch ev.Action {
+	case "pass":
+		_, err := fmt.Fprintf(t.out, "ok %d - %s\n", t.n, name)
+		return err
+	case "skip":
+		_, err := fmt.Fprintf(t.out, "ok %d - %s # SKIP\n", t.n, name)
+		return err
+	}
+	if _, err := fmt.Fprintf(t.out, "not ok %d - %s\n", t.n, name); err != nil {
+		return err
+	}
+	return t.diagnostic(ev.Plan, ev.Elapsed, output)
+}
+
+func (t *tapReportWriter) End(report *Report) error {
+	// A plan failing without any failed test, e.g. on a build error, still
+	// has to fail the TAP stream.
+	for _, res := range report.Results {
+		if res.Err == nil || len(res.FailedTests) > 0 {
+			continue
+		}
+		t.n++
+		if _, err := fmt.Fprintf(t.out, "not ok %d - %s\n", t.n, res.Plan.Name); err != nil {
+			return err
+		}
+		if err := t.diagnostic(res.Plan.Name, res.Duration, strings.Split(res.Err.Error(), "\n")); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(t.out, "1..%d\n", t.n)
+	return err
+}
+
+// diagnostic writes the YAML block following a "not ok" line.
+func (t *tapReportWriter) diagnostic(plan string, elapsed time.Duration, output []string) error {
+	var b strings.Builder
+	b.WriteString("  ---\n")
+	fmt.Fprintf(&b, "  duration_ms: %d\n", elapsed.Milliseconds())
+	fmt.Fprintf(&b, "  plan: %q\n", plan)
+	if len(output) > 0 {
+		b.WriteString("  output: |\n")
+		for _, line := range output {
+			fmt.Fprintf(&b, "    %s\n", line)
+		}
+	}
+	b.WriteString("  ...\n")
+	_, err := io.WriteString(t.out, b.String())
+	return err
+}
@@ -0,0 +1,115 @@
+package wasmtest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// recordingWriter is a ReportWriter keeping every call for inspection.
+type recordingWriter struct {
+	calls  []string
+	events []TestEvent
+}
+
+func (r *recordingWriter) Begin(plans []RunPlan) error {
+	r.calls = append(r.calls, "begin")
+	return nil
+}
+
+func (r *recordingWriter) TestEvent(ev TestEvent) error {
+	r.events = append(r.events, ev)
+	return nil
+}
+
+func (r *recordingWriter) End(report *Report) error {
+	r.calls = append(r.calls, "end")
+	return nil
+}
+
+func TestReportWriterRegistry(t *testing.T) {
+	rec := &recordingWriter{}
+	RegisterReportWriter("recording", func(io.Writer) ReportWriter { return rec })
+
+	if !slices.Contains(ReportWriters(), "recording") || !slices.Contains(ReportWriters(), "text") {
+		t.Fatalf("ReportWriters() = %v", ReportWriters())
+	}
+	if _, err := NewReportWriter("missing", io.Discard); err == nil {
+		t.Error("expected an error for an unknown writer")
+	}
+	rw, err := NewReportWriter("recording", io.Discard)
+	if err != nil || rw != rec {
+		t.Fatalf("NewReportWriter() = %v, %v", rw, err)
+	}
+
+	node := nodeExec(t)
+	var text bytes.Buffer
+	textWriter, _ := NewReportWriter("text", &text)
+	o := &Orchestrator{Writers: []ReportWriter{rec, textWriter}}
+	if _, err := o.Run(context.Background(), []RunPlan{{Name: "node", Dir: "./example", Exec: node}}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if !slices.Equal(rec.calls, []string{"begin", "end"}) {
+		t.Errorf("calls = %v", rec.calls)
+	}
+	passed := slices.ContainsFunc(rec.events, func(ev TestEvent) bool {
+		return ev.Plan == "node" && ev.Action == "pass" && ev.Test == "TestMathHelper"
+	})
+	if !passed {
+		t.Errorf("no pass event for TestMathHelper in %d events", len(rec.events))
+	}
+	if !strings.Contains(text.String(), "PASS node TestMathHelper") {
+		t.Errorf("unexpected text output:\n%s", text.String())
+	}
+}
+
+func TestTAPReportWriter(t *testing.T) {
+	node := nodeExec(t)
+	dir := writeModule(t, map[string]string{
+		"tap_test.go": "//go:build js && wasm\n\npackage tmp\n\nimport \"testing\"\n\n" +
+			"func TestOK(t *testing.T) {}\n\nfunc TestBad(t *testing.T) { t.Log(\"some context\"); t.Fatal(\"boom\") }\n\n" +
+			"func TestLater(t *testing.T) { t.Skip(\"not yet\") }\n",
+	})
+
+	var out bytes.Buffer
+	tap, err := NewReportWriter("tap", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := &recordingWriter{}
+	if err := RunTests(dir, func(...any) {}, tap, rec, ExecOptions{Args: []string{"-exec", node}}, WithInstallDisabled()); err == nil {
+		t.Fatal("RunTests succeeded with a failing test")
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"TAP version 13\n",
+		"ok 1 - TestOK\n",
+		"not ok 2 - TestBad\n  ---\n",
+		"    tap_test.go:9: some context\n",
+		"ok 3 - TestLater # SKIP\n",
+		"1..3\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("TAP output lacks %q:\n%s", want, got)
+		}
+	}
+	if !slices.Equal(rec.calls, []string{"begin", "end"}) || !slices.ContainsFunc(rec.events, func(ev TestEvent) bool {
+		return ev.Plan == dir && ev.Action == "output" && ev.Test == "TestBad" && strings.Contains(ev.Output, "boom")
+	}) {
+		t.Errorf("RunTests didn't drive the writer: %v, %d events", rec.calls, len(rec.events))
+	}
+
+	// A build failure without any test result still fails the stream.
+	out.Reset()
+	broken := writeModule(t, map[string]string{"broken_test.go": "//go:build js && wasm\n\npackage tmp\n\nfunc broken( {\n"})
+	tap, _ = NewReportWriter("tap", &out)
+	_ = RunTests(broken, func(...any) {}, tap, ExecOptions{Args: []string{"-exec", node}}, WithInstallDisabled())
+	if got := out.String(); !strings.Contains(got, "not ok 1 - "+broken) || !strings.HasSuffix(got, "1..1\n") {
+		t.Errorf("unexpected TAP output for a build failure:\n%s", got)
+	}
+}
@@ -0,0 +1,215 @@
+package wasmtest
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RunResult is the structured outcome of a test run, built from the
+// progress messages so callers don't have to parse the output themselves.
+// Its JSON form follows SchemaVersion.
+type RunResult struct {
+	// SchemaVersion is the SchemaVersion of the JSON form.
+	SchemaVersion int `json:"schemaVersion"`
+	// Dir is the directory the tests ran in.
+	Dir          string   `json:"dir"`
+	PassedTests  []string `json:"passedTests"`
+	FailedTests  []string `json:"failedTests"`
+	SkippedTests []string `json:"skippedTests"`
+	// Durations holds the elapsed time reported by go test for each
+	// finished test, subtests included. With several packages, the tests
+	// are prefixed with the package directory and a dot, as in
+	// "./ui.TestDOM".
+	Durations map[string]time.Duration `json:"durations"`
+	// Benchmarks holds the results of the benchmarks run with
+	// ExecOptions.Bench, in output order.
+	Benchmarks []BenchmarkResult `json:"benchmarks,omitempty"`
+	// RawOutput holds the stdout and stderr lines of the run in order.
+	RawOutput []string `json:"rawOutput"`
+	// FailureOutput holds the output lines of each failed test, subtests
+	// included, without the "=== RUN" and "--- FAIL" framing lines. Its
+	// tests are named as in Durations.
+	FailureOutput map[string][]string `json:"failureOutput,omitempty"`
+	// Compile holds the build time and cache usage of the test package.
+	Compile CompileStats `json:"compile"`
+	// ShuffleSeed is the -shuffle seed the tests ran with, empty when they
+	// ran in source order. Pass it as ExecOptions.Shuffle to replay a
+	// failing order.
+	ShuffleSeed string `json:"shuffleSeed,omitempty"`
+	// GoWasm is the GOWASM feature set the tests were built with (see
+	// WithGoWasmFeatures); empty means the default set.
+	GoWasm string `json:"goWasm,omitempty"`
+	// Coverage is the percentage of statements covered, reported by the
+	// runs with a CoverProfile; for a ./... run it is the one of the merged
+	// profile.
+	Coverage float64 `json:"coverage,omitempty"`
+	// ExitCode is the exit code of the go test process: 0 on success, or -1
+	// when the process could not start or was killed (e.g. on timeout).
+	ExitCode int `json:"exitCode"`
+	// Duration is the wall time of the whole run.
+	Duration time.Duration `json:"duration"`
+	// Packages holds the result of each package of a ./... run, whose
+	// top level lists and output are the union of the packages' ones. It
+	// is empty for a single directory.
+	Packages []*RunResult `json:"packages,omitempty"`
+
+	// tracker and running attribute the output lines to the tests that
+	// wrote them until they finish.
+	tracker testTracker
+	running map[string][]string
+}
+
+// BenchmarkResult is one benchmark result line of go test, such as
+// "BenchmarkSum 1000000 1052 ns/op 0 B/op".
+type BenchmarkResult struct {
+	Name string `json:"name"`
+	// N is the number of iterations.
+	N int `json:"n"`
+	// NsPerOp is the ns/op value.
+	NsPerOp float64 `json:"nsPerOp"`
+	// Metrics holds the other values by unit, e.g. "B/op" and "allocs/op".
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+}
+
+// parseBenchmarkLine parses a benchmark result line of go test.
+func parseBenchmarkLine(line string) (BenchmarkResult, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || len(fields)%2 != 0 || !strings.HasPrefix(fields[0], "Benchmark") {
+		return BenchmarkResult{}, false
+	}
+	n, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return BenchmarkResult{}, false
+	}
+	b := BenchmarkResult{Name: fields[0], N: n}
+	for i := 2; i < len(fields); i += 2 {
+		v, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return BenchmarkResult{}, false
+		}
+		if unit := fields[i+1]; unit == "ns/op" {
+			b.NsPerOp = v
+		} else {
+			if b.Metrics == nil {
+				b.Metrics = map[string]float64{}
+			}
+			b.Metrics[unit] = v
+		}
+	}
+	return b, true
+}
+
+// shuffleSeed returns the seed of the "-test.shuffle 1700000000" line
+// printed by test binaries run with -shuffle.
+func shuffleSeed(line string) (string, bool) {
+	seed, ok := strings.CutPrefix(strings.TrimSpace(line), "-test.shuffle ")
+	if !ok {
+		return "", false
+	}
+	if _, err := strconv.ParseInt(seed, 10, 64); err != nil {
+		return "", false
+	}
+	return seed, true
+}
+
+// newRunResult returns an empty RunResult for dir.
+func newRunResult(dir string) *RunResult {
+	return &RunResult{SchemaVersion: SchemaVersion, Dir: dir, Durations: map[string]time.Duration{}, ExitCode: -1}
+}
+
+// Passed reports whether the go test process exited successfully.
+func (r *RunResult) Passed() bool { return r.ExitCode == 0 }
+
+// collect updates r from a progress message.
+func (r *RunResult) collect(msgs ...any) {
+	if len(msgs) < 2 {
+		return
+	}
+	if ev, ok := r.tracker.event(msgs...); ok && ev.Test != "" {
+		r.collectOutput(ev)
+	}
+	if stats, ok := msgs[1].(CompileStats); ok {
+		r.Compile = stats
+		r.GoWasm = stats.GoWasm
+		return
+	}
+	if ev, ok := msgs[1].(TestEvent); ok {
+		r.collectTest(ev)
+		return
+	}
+	if tag := fmt.Sprint(msgs[0]); tag != "out" && tag != "err" {
+		return
+	}
+	line := fmt.Sprint(msgs[1])
+	r.RawOutput = append(r.RawOutput, line)
+	if seed, ok := shuffleSeed(line); ok {
+		r.ShuffleSeed = seed
+		return
+	}
+	if pct, ok := coverageLine(line); ok {
+		r.Coverage = pct
+		return
+	}
+	if b, ok := parseBenchmarkLine(line); ok {
+		r.Benchmarks = append(r.Benchmarks, b)
+	}
+}
+
+// collectTest records the final state of a test reported by go test -json.
+func (r *RunResult) collectTest(ev TestEvent) {
+	var list *[]string
+	switch ev.Action {
+	case "pass":
+		list = &r.PassedTests
+	case "fail":
+		list = &r.FailedTests
+	case "skip":
+		list = &r.SkippedTests
+	default:
+		return
+	}
+	if !slices.Contains(*list, ev.Test) {
+		*list = append(*list, ev.Test)
+	}
+	r.Durations[ev.Test] = ev.Elapsed
+}
+
+// collectOutput holds the output lines of each test, keeping them in
+// FailureOutput if the test fails.
+func (r *RunResult) collectOutput(ev TestEvent) {
+	switch ev.Action {
+	case "output":
+		line := strings.TrimRight(ev.Output, "\n")
+		if trimmed := strings.TrimSpace(line); trimmed == "" || strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "--- ") {
+			return
+		}
+		if r.running == nil {
+			r.running = map[string][]string{}
+		}
+		r.running[ev.Test] = append(r.running[ev.Test], line)
+	case "fail":
+		if r.FailureOutput == nil {
+			r.FailureOutput = map[string][]string{}
+		}
+		r.FailureOutput[ev.Test] = r.running[ev.Test]
+		delete(r.running, ev.Test)
+	case "pass", "skip":
+		delete(r.running, ev.Test)
+	}
+}
+
+// setExit records the error returned by the go test process.
+func (r *RunResult) setExit(err error) {
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		r.ExitCode = 0
+	case errors.As(err, &exitErr):
+		r.ExitCode = exitErr.ExitCode()
+	}
+}
@@ -0,0 +1,72 @@
+package wasmtest
+
+import (
+	"os/exec"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRunResultCollect(t *testing.T) {
+	r := newRunResult("wasm_tests")
+	stats := CompileStats{Package: "example.com/pkg", Cached: true}
+	for _, msg := range [][]any{
+		{"compile", stats},
+		{"test", TestEvent{Action: "run", Test: "TestA"}},
+		{"out", "=== RUN   TestA"},
+		{"out", "--- PASS: TestA (0.25s)"},
+		{"test", TestEvent{Action: "pass", Test: "TestA", Elapsed: 250 * time.Millisecond}},
+		{"out", "=== RUN   TestB"},
+		{"out", "    --- FAIL: TestB/sub (0.01s)"},
+		{"test", TestEvent{Action: "fail", Test: "TestB/sub", Elapsed: 10 * time.Millisecond}},
+		{"out", "--- FAIL: TestB (0.02s)"},
+		{"test", TestEvent{Action: "fail", Test: "TestB", Elapsed: 20 * time.Millisecond}},
+		{"out", "--- SKIP: TestC (0.00s)"},
+		{"test", TestEvent{Action: "skip", Test: "TestC"}},
+		{"err", "exit status 1"},
+		{"exit", "error", "exit status 1"},
+	} {
+		r.collect(msg...)
+	}
+
+	if !slices.Equal(r.PassedTests, []string{"TestA"}) {
+		t.Errorf("PassedTests = %q", r.PassedTests)
+	}
+	if !slices.Equal(r.FailedTests, []string{"TestB/sub", "TestB"}) {
+		t.Errorf("FailedTests = %q", r.FailedTests)
+	}
+	if !slices.Equal(r.SkippedTests, []string{"TestC"}) {
+		t.Errorf("SkippedTests = %q", r.SkippedTests)
+	}
+	if r.Durations["TestA"] != 250*time.Millisecond {
+		t.Errorf("Durations[TestA] = %v", r.Durations["TestA"])
+	}
+	if len(r.RawOutput) != 7 {
+		t.Errorf("RawOutput has %d lines, want 7", len(r.RawOutput))
+	}
+	if r.Compile.Package != stats.Package || !r.Compile.Cached {
+		t.Errorf("Compile = %+v", r.Compile)
+	}
+	if r.ExitCode != -1 || r.Passed() {
+		t.Errorf("ExitCode = %d before exit", r.ExitCode)
+	}
+}
+
+func TestRunResultSetExit(t *testing.T) {
+	r := newRunResult(".")
+	r.setExit(exec.Command("sh", "-c", "exit 3").Run())
+	if r.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", r.ExitCode)
+	}
+	r.setExit(nil)
+	if !r.Passed() {
+		t.Error("Passed() = false after a successful exit")
+	}
+}
+
+func TestRunTestsResultMissingDir(t *testing.T) {
+	res, err := RunTestsResult("./does-not-exist", func(...any) {})
+	if err == nil || res != nil {
+		t.Errorf("RunTestsResult = %v, %v; want nil result and an error", res, err)
+	}
+}
@@ -0,0 +1,124 @@
+package wasmtest
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// testBinary is a prebuilt test binary run by RunBinary.
+type testBinary struct {
+	// path is the absolute path of the binary.
+	path string
+	// pkg is the import path of the tested package, given to test2json.
+	pkg string
+}
+
+// RunBinary runs the prebuilt test binary at path, e.g. from `go test -c`
+// or Bundle, with the selected backend instead of compiling the tests, so
+// CI pipelines can build once and test on many configurations. The target
+// is read from the imports of the binary. opts select the tests as for
+// ExecuteWithOptions: Run, Skip, Count, Bench, BenchTime, Benchmem, Shuffle
+// and Timeout become the matching -test. flags, Args holds extra test
+// binary flags (e.g. -test.short), and Tags and Ldflags, build flags, are
+// ignored. The binary runs in opts.Dir, or the WithTestDir directory, so
+// its testdata files are found, and its output is converted with go tool
+// test2json into the usual progress messages. The package reported is the
+// one of the bundle manifest next to the binary, or else the binary name.
+func (w *Wasmtest) RunBinary(ctx context.Context, path string, opts ExecOptions, progress func(msgs ...any)) error {
+	if progress == nil {
+		progress = func(...any) {}
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	target, err := wasmTarget(abs)
+	if err != nil {
+		return err
+	}
+	if opts.Target != "" && opts.Target != target {
+		return fmt.Errorf("wasmtest: %s is a %s binary, not %s", path, target, opts.Target)
+	}
+	if w.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.timeout)
+		defer cancel()
+	}
+
+	// The defaults of spec apply to the test binary flags too.
+	opts.Run, opts.Skip = cmp.Or(opts.Run, w.runFilter), cmp.Or(opts.Skip, w.skipFilter)
+	spec := opts.spec(w)
+	spec.args, spec.testArgs = opts.testBinaryArgs(), nil
+	spec.target = target
+	spec.binary = &testBinary{path: abs, pkg: binaryPackage(abs)}
+	if err := w.execute(ctx, spec, progress); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return newRunError(ErrTimeout, "wasmtest: tests timed out after %v: %v", w.timeout, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// wasmTarget returns the target of the Go wasm binary at path, from the
+// module of its imports.
+func wasmTarget(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case !bytes.HasPrefix(data, []byte("\x00asm")):
+		return "", fmt.Errorf("wasmtest: %s is not a WebAssembly binary", path)
+	case bytes.Contains(data, []byte("wasi_snapshot_preview1")):
+		return TargetWASIP1, nil
+	case bytes.Contains(data, []byte("gojs")):
+		return TargetJS, nil
+	}
+	return "", fmt.Errorf("wasmtest: %s is not a Go js/wasm or wasip1/wasm binary", path)
+}
+
+// binaryPackage returns the package tested by the binary at path: the one
+// of the bundle manifest next to it, or else the binary name.
+func binaryPackage(path string) string {
+	if data, err := os.ReadFile(filepath.Join(filepath.Dir(path), "manifest.json")); err == nil {
+		var m BundleManifest
+		if json.Unmarshal(data, &m) == nil && m.Package != "" && m.Wasm == filepath.Base(path) {
+			return m.Package
+		}
+	}
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, ".wasm")
+	return strings.TrimSuffix(name, ".test")
+}
+
+// testBinaryFlags maps the go test flags of ExecOptions to the test binary
+// ones.
+var testBinaryFlags = map[string]string{
+	"-run":       "-test.run",
+	"-skip":      "-test.skip",
+	"-count":     "-test.count",
+	"-bench":     "-test.bench",
+	"-benchtime": "-test.benchtime",
+	"-benchmem":  "-test.benchmem",
+	"-shuffle":   "-test.shuffle",
+	"-timeout":   "-test.timeout",
+}
+
+// testBinaryArgs returns the test binary flags described by o.
+func (o ExecOptions) testBinaryArgs() []string {
+	args := ExecOptions{Run: o.Run, Skip: o.Skip, Count: o.Count, Bench: o.Bench, BenchTime: o.BenchTime, Benchmem: o.Benchmem, Shuffle: o.Shuffle, Timeout: o.Timeout}.args()
+	for i, arg := range args {
+		if flag, ok := testBinaryFlags[arg]; ok {
+			args[i] = flag
+		}
+	}
+	return slices.Concat(args, o.Args, o.TestArgs)
+}
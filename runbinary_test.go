@@ -0,0 +1,101 @@
+package wasmtest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildTestBinary compiles the tests of dir with go test -c for goos/wasm.
+func buildTestBinary(t *testing.T, dir, goos string) string {
+	t.Helper()
+	out := filepath.Join(t.TempDir(), "p.test.wasm")
+	cmd := exec.Command("go", "test", "-c", "-o", out)
+	cmd.Dir = dir
+	cmd.Env = childEnv(os.Environ(), goos, "wasm", nil)
+	if data, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go test -c failed: %v\n%s", err, data)
+	}
+	return out
+}
+
+func TestTestBinaryArgs(t *testing.T) {
+	opts := ExecOptions{Run: "TestA", Count: 2, Benchmem: true, Timeout: time.Minute, Tags: []string{"x"}, Args: []string{"-test.short"}, TestArgs: []string{"-myflag", "x"}}
+	want := []string{"-test.run", "TestA", "-test.count", "2", "-test.benchmem", "-test.timeout", "1m0s", "-test.short", "-myflag", "x"}
+	if got := opts.testBinaryArgs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("testBinaryArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestSplitExecArgs(t *testing.T) {
+	got := splitExecArgs(`/usr/bin/deno run '/tmp/my dir/x.mjs' "it's"  last`)
+	want := []string{"/usr/bin/deno", "run", "/tmp/my dir/x.mjs", "it's", "last"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitExecArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestRunBinary(t *testing.T) {
+	nodeExec(t)
+	dir := writeModule(t, map[string]string{
+		"p_test.go": wasmPassTest + "\nfunc TestOther(t *testing.T) {}\n",
+	})
+	binary := buildTestBinary(t, dir, "js")
+	if target, err := wasmTarget(binary); target != TargetJS {
+		t.Errorf("wasmTarget() = %q, %v", target, err)
+	}
+
+	w := New(WithInstallDisabled(), WithBackend(BackendNode), WithTestDir(dir))
+	progress, msgs := collectProgress()
+	if err := w.RunBinary(t.Context(), binary, ExecOptions{Run: "TestPass"}, progress); err != nil {
+		t.Fatalf("RunBinary failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	out := strings.Join(msgs(), "\n")
+	if !strings.Contains(out, "test pass TestPass") || strings.Contains(out, "TestOther") || strings.Contains(out, "compile") {
+		t.Errorf("unexpected progress:\n%s", out)
+	}
+
+	failing := buildTestBinary(t, writeModule(t, map[string]string{"f_test.go": wasmFailTest}), "js")
+	progress, msgs = collectProgress()
+	if err := w.RunBinary(t.Context(), failing, ExecOptions{}, progress); err == nil || !strings.Contains(strings.Join(msgs(), "\n"), "test fail TestFail") {
+		t.Errorf("failing binary: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+
+	if err := w.RunBinary(t.Context(), binary, ExecOptions{Target: TargetWASIP1}, nil); err == nil {
+		t.Errorf("a js binary ran as wasip1")
+	}
+	if err := w.RunBinary(t.Context(), filepath.Join(dir, "p_test.go"), ExecOptions{}, nil); err == nil || !strings.Contains(err.Error(), "not a WebAssembly binary") {
+		t.Errorf("non wasm file error = %v", err)
+	}
+}
+
+func TestRunBinaryWASI(t *testing.T) {
+	nodeExec(t)
+	bin := t.TempDir()
+	script, err := filepath.Abs("testdata/nodewasi.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bin, "wasmtime"), []byte("#!/bin/sh\nexec node --no-warnings "+script+" \"$@\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	dir := writeModule(t, map[string]string{
+		"p_test.go":       "package p\n\nimport (\n\t\"os\"\n\t\"testing\"\n)\n\nfunc TestData(t *testing.T) {\n\tif _, err := os.Stat(\"testdata/in.txt\"); err != nil {\n\t\tt.Fatal(err)\n\t}\n}\n",
+		"testdata/in.txt": "hi",
+	})
+	binary := buildTestBinary(t, dir, "wasip1")
+	w := New(WithInstallDisabled())
+	progress, msgs := collectProgress()
+	if err := w.RunBinary(t.Context(), binary, ExecOptions{Dir: dir}, progress); err != nil {
+		t.Fatalf("RunBinary failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	if out := strings.Join(msgs(), "\n"); !strings.Contains(out, "test pass TestData") || !strings.Contains(out, "wasip1/wasm tests with the wasmtime backend") {
+		t.Errorf("unexpected progress:\n%s", out)
+	}
+}
@@ -0,0 +1,200 @@
+package wasmtest
+
+import (
+	"cmp"
+	"context"
+	"debug/buildinfo"
+	"encoding/json"
+	"fmt"
+	"go/version"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// runnerRecord is what was seen of an installed wasmbrowsertest binary, to
+// notice when the runner go test picks up is later replaced.
+type runnerRecord struct {
+	SHA256  string `json:"sha256"`
+	Version string `json:"version"`
+}
+
+// runnerRecordsPath returns the file recording the runner binaries, keyed by
+// their path with the symlinks resolved.
+func runnerRecordsPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "wasmtest", "runners.json"), nil
+}
+
+// readRunnerRecords returns the recorded runners; a missing or unreadable
+// file is an empty record.
+func readRunnerRecords() map[string]runnerRecord {
+	records := map[string]runnerRecord{}
+	if path, err := runnerRecordsPath(); err == nil {
+		if data, err := os.ReadFile(path); err == nil {
+			json.Unmarshal(data, &records)
+		}
+	}
+	return records
+}
+
+// writeRunnerRecords atomically replaces the recorded runners.
+func writeRunnerRecords(records map[string]runnerRecord) error {
+	path, err := runnerRecordsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// recordRunner records the hash and version of the runner at path, e.g.
+// right after installing it.
+func recordRunner(path string) error {
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	sum, err := fileSHA256(target)
+	if err != nil {
+		return err
+	}
+	rec := runnerRecord{SHA256: sum}
+	if info, err := buildinfo.ReadFile(target); err == nil {
+		rec.Version = info.Main.Version
+	}
+	records := readRunnerRecords()
+	records[target] = rec
+	return writeRunnerRecords(records)
+}
+
+// lookupRunner returns the first go_js_wasm_exec of dirs, the PATH of go
+// test. Unlike exec.LookPath it also returns the dangling symlinks, with
+// stale set.
+func lookupRunner(dirs []string) (path string, stale bool) {
+	for _, dir := range dirs {
+		path := filepath.Join(dir, "go_js_wasm_exec")
+		if runtime.GOOS == "windows" {
+			path += ".exe"
+		}
+		if _, err := os.Lstat(path); err != nil {
+			continue
+		}
+		_, err := os.Stat(path)
+		return path, err != nil
+	}
+	return "", false
+}
+
+// runnerFound reports whether dirs hold a go_js_wasm_exec that is not a
+// dangling symlink.
+func runnerFound(dirs []string) bool {
+	path, stale := lookupRunner(dirs)
+	return path != "" && !stale
+}
+
+// verifyRunner checks the go_js_wasm_exec go test runs, found in the dirs
+// of its PATH, before a whole run is spent on it: it must exist, be a build
+// of module, and, at a recorded version, still have the recorded hash. A
+// runner seen for the first time, or at a new version, is recorded. It
+// returns nil when there is nothing to report, including when there is no
+// go_js_wasm_exec at all, which ensureWasmExecSymlink reports.
+func verifyRunner(dirs []string, module string) *Warning {
+	path, stale := lookupRunner(dirs)
+	if path == "" {
+		return nil
+	}
+	if stale {
+		dest, _ := os.Readlink(path)
+		return &Warning{
+			Code:    "runner-stale",
+			Message: fmt.Sprintf("%s is a symlink to %s, which does not exist", path, dest),
+			Hint:    "remove it so wasmtest links it to wasmbrowsertest again",
+		}
+	}
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil
+	}
+	info, err := buildinfo.ReadFile(target)
+	if err != nil || info.Main.Path != module {
+		what := "is not a Go program"
+		if err == nil {
+			what = "is a build of " + info.Main.Path
+		}
+		return &Warning{
+			Code:    "runner-mismatch",
+			Message: fmt.Sprintf("%s (%s) %s, not wasmbrowsertest: the tests may not run in a browser", path, target, what),
+			Hint:    "remove it, or install wasmbrowsertest with go install " + module + "@latest and link it there",
+		}
+	}
+	sum, err := fileSHA256(target)
+	if err != nil {
+		return nil
+	}
+
+	records := readRunnerRecords()
+	rec, ok := records[target]
+	if !ok || rec.Version != info.Main.Version {
+		records[target] = runnerRecord{SHA256: sum, Version: info.Main.Version}
+		writeRunnerRecords(records)
+		return nil
+	}
+	if rec.SHA256 != sum {
+		file, _ := runnerRecordsPath()
+		return &Warning{
+			Code:    "runner-modified",
+			Message: fmt.Sprintf("%s changed since it was recorded as wasmbrowsertest %s: its sha256 is %s, not %s", target, rec.Version, sum, rec.SHA256),
+			Hint:    fmt.Sprintf("reinstall it with go install %s@%s; if the change is expected, delete %s", module, rec.Version, file),
+		}
+	}
+	return nil
+}
+
+// runnerOutdated returns a warning when the wasmbrowsertest at path was
+// built with an older Go release than the toolchain of spec, nil when it
+// was not, the WithRunnerUpgrade policy is RunnerUpgradeOff or either
+// version is unknown.
+func (w *Wasmtest) runnerOutdated(ctx context.Context, spec execSpec, path string) *Warning {
+	if w.runnerUpgrade == RunnerUpgradeOff {
+		return nil
+	}
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil
+	}
+	info, err := buildinfo.ReadFile(target)
+	if err != nil || info.Main.Path != wasmBrowserTestModule {
+		return nil
+	}
+	vars, err := goEnvVars(ctx, spec, "GOVERSION")
+	if err != nil {
+		return nil
+	}
+	built, toolchain := version.Lang(info.GoVersion), version.Lang(vars["GOVERSION"])
+	if built == "" || toolchain == "" || version.Compare(built, toolchain) >= 0 {
+		return nil
+	}
+	hint := "reinstall it with go install " + wasmBrowserTestModule + "@" + cmp.Or(w.wasmBrowserTestVersion, "latest")
+	if w.runnerUpgrade != RunnerUpgradeAuto {
+		hint += ", or let wasmtest do it with WithRunnerUpgrade(RunnerUpgradeAuto)"
+	}
+	return &Warning{
+		Code:    "runner-outdated",
+		Message: fmt.Sprintf("%s was built with %s, older than the %s toolchain: its wasm_exec.js support may not match, failing the tests with \"invalid import\" errors", path, info.GoVersion, vars["GOVERSION"]),
+		Hint:    hint,
+	}
+}
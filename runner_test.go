@@ -0,0 +1,140 @@
+package wasmtest
+
+import (
+	"debug/buildinfo"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyRunner(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	if warn := verifyRunner([]string{dir}, wasmBrowserTestModule); warn != nil {
+		t.Errorf("missing runner reported: %+v", warn)
+	}
+
+	// The test binary stands in for wasmbrowsertest, with its own module.
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := buildinfo.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := filepath.Join(dir, "runner")
+	if err := copyFile(exe, runner); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "go_js_wasm_exec")
+	if err := os.Symlink(runner, link); err != nil {
+		t.Fatal(err)
+	}
+
+	if warn := verifyRunner([]string{dir}, wasmBrowserTestModule); warn == nil || warn.Code != "runner-mismatch" || !strings.Contains(warn.Message, info.Main.Path) {
+		t.Errorf("other program not reported: %+v", warn)
+	}
+	// First seen, then unchanged.
+	for range 2 {
+		if warn := verifyRunner([]string{dir}, info.Main.Path); warn != nil {
+			t.Errorf("recorded runner reported: %+v", warn)
+		}
+	}
+
+	f, err := os.OpenFile(runner, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("tampered")
+	f.Close()
+	if warn := verifyRunner([]string{dir}, info.Main.Path); warn == nil || warn.Code != "runner-modified" {
+		t.Errorf("modified runner not reported: %+v", warn)
+	}
+	// Installing records the new hash.
+	if err := recordRunner(link); err != nil {
+		t.Fatal(err)
+	}
+	if warn := verifyRunner([]string{dir}, info.Main.Path); warn != nil {
+		t.Errorf("reinstalled runner reported: %+v", warn)
+	}
+
+	os.Remove(runner)
+	if warn := verifyRunner([]string{dir}, info.Main.Path); warn == nil || warn.Code != "runner-stale" || !strings.Contains(warn.Message, runner) {
+		t.Errorf("dangling symlink not reported: %+v", warn)
+	}
+}
+
+func TestRunnerOutdated(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	// A wasmbrowsertest stand-in, built with the current toolchain.
+	src := t.TempDir()
+	os.WriteFile(filepath.Join(src, "go.mod"), []byte("module "+wasmBrowserTestModule+"\n\ngo 1.24\n"), 0o644)
+	os.WriteFile(filepath.Join(src, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644)
+	bin := t.TempDir()
+	runner := filepath.Join(bin, "wasmbrowsertest")
+	cmd := exec.Command("go", "build", "-o", runner, ".")
+	cmd.Dir = src
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build failed: %v\n%s", err, out)
+	}
+
+	// The fake go reports the toolchain version of FAKE_GOVERSION, and
+	// records the installs.
+	fake := t.TempDir()
+	installed := filepath.Join(fake, "installed")
+	script := `#!/bin/sh
+case "$1" in
+env) echo "{\"GOVERSION\": \"$FAKE_GOVERSION\"}" ;;
+install) touch "` + installed + `" ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(fake, "go"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", fake+string(os.PathListSeparator)+bin+string(os.PathListSeparator)+"/bin"+string(os.PathListSeparator)+"/usr/bin")
+
+	t.Setenv("FAKE_GOVERSION", "go1.1")
+	w := New(WithAutoInstall(false), WithLogger(func(...any) {}))
+	if warn := w.runnerOutdated(t.Context(), execSpec{}, runner); warn != nil {
+		t.Errorf("runner newer than the toolchain reported: %+v", warn)
+	}
+	t.Setenv("FAKE_GOVERSION", "go1.999.1")
+	if warn := w.runnerOutdated(t.Context(), execSpec{}, runner); warn == nil || warn.Code != "runner-outdated" || !strings.Contains(warn.Message, "go1.999.1") {
+		t.Errorf("outdated runner not reported: %+v", warn)
+	}
+	if warn := New(WithRunnerUpgrade(RunnerUpgradeOff), WithAutoInstall(false)).runnerOutdated(t.Context(), execSpec{}, runner); warn != nil {
+		t.Errorf("check not turned off: %+v", warn)
+	}
+
+	if err := w.Install(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(installed); err == nil {
+		t.Error("outdated runner reinstalled under RunnerUpgradeWarn")
+	}
+	w = New(WithAutoInstall(false), WithRunnerUpgrade(RunnerUpgradeAuto), WithLogger(func(...any) {}))
+	if err := w.Install(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(installed); err != nil {
+		t.Error("outdated runner not reinstalled under RunnerUpgradeAuto")
+	}
+}
+
+func TestRunnerUpgradeInvalid(t *testing.T) {
+	w := New(WithRunnerUpgrade("always"), WithLogger(func(...any) {}))
+	if err := <-w.Ready(); !errors.Is(err, ErrUsage) || !strings.Contains(err.Error(), "runner upgrade policy") {
+		t.Errorf("Ready() = %v", err)
+	}
+	if err := w.Install(t.Context()); !errors.Is(err, ErrUsage) {
+		t.Errorf("Install() = %v", err)
+	}
+	if err := w.execute(t.Context(), execSpec{dir: t.TempDir()}, func(...any) {}); !errors.Is(err, ErrUsage) {
+		t.Errorf("execute() = %v", err)
+	}
+}
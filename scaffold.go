@@ -0,0 +1,126 @@
+package wasmtest
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// ScaffoldOptions selects the files Scaffold creates besides the tests.
+type ScaffoldOptions struct {
+	// Config also creates a wasmtest.yaml in the parent directory of the
+	// test directory, pointing RunTests at it.
+	Config bool
+}
+
+// scaffoldSource is the non-test file of a scaffolded package: with only
+// _test.go files, `go build ./...` and `go vet ./...` report the directory.
+const scaffoldSource = `//go:build js && wasm
+
+package %s
+
+import "syscall/js"
+
+// setText sets the text content of the element with the given id.
+func setText(id, text string) {
+	js.Global().Get("document").Call("getElementById", id).Set("textContent", text)
+}
+`
+
+// scaffoldTest is the example test of a scaffolded package.
+const scaffoldTest = `//go:build js && wasm
+
+package %s
+
+import (
+	"syscall/js"
+	"testing"
+)
+
+func TestDOM(t *testing.T) {
+	document := js.Global().Get("document")
+	if document.IsUndefined() {
+		t.Skip("not running in a browser")
+	}
+	div := document.Call("createElement", "div")
+	div.Set("id", "greeting")
+	document.Get("body").Call("appendChild", div)
+	defer div.Call("remove")
+
+	setText("greeting", "Hello, WebAssembly!")
+	if got := div.Get("textContent").String(); got != "Hello, WebAssembly!" {
+		t.Errorf("textContent = %%q, want %%q", got, "Hello, WebAssembly!")
+	}
+}
+
+func TestJSInterop(t *testing.T) {
+	obj := js.Global().Get("Object").New()
+	obj.Set("answer", 42)
+	if got := obj.Get("answer").Int(); got != 42 {
+		t.Errorf("answer = %%d, want 42", got)
+	}
+}
+`
+
+// scaffoldConfig is the wasmtest.yaml created with ScaffoldOptions.Config.
+const scaffoldConfig = `# wasmtest configuration, see the Config type of github.com/cdvelop/wasmtest.
+dir: %s
+timeout: 5m
+`
+
+// Scaffold creates a js/wasm test package in dir, typically "wasm_tests":
+// an example test exercising the DOM and a non-test file, both with the
+// js && wasm build constraint, and with opts.Config a configuration file.
+// The package is named after dir. Existing files are never overwritten:
+// when one of them already exists, an error wrapping fs.ErrExist is
+// returned and nothing is written. It returns the created files.
+func Scaffold(dir string, opts ScaffoldOptions) ([]string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	pkg := packageName(filepath.Base(abs))
+	files := []struct{ path, content string }{
+		{filepath.Join(dir, "dom.go"), fmt.Sprintf(scaffoldSource, pkg)},
+		{filepath.Join(dir, "dom_test.go"), fmt.Sprintf(scaffoldTest, pkg)},
+	}
+	if opts.Config {
+		files = append(files, struct{ path, content string }{
+			filepath.Join(filepath.Dir(dir), ConfigFiles[0]), fmt.Sprintf(scaffoldConfig, filepath.ToSlash(filepath.Base(abs))),
+		})
+	}
+	for _, f := range files {
+		if _, err := os.Lstat(f.path); err == nil {
+			return nil, fmt.Errorf("wasmtest: %s already exists: %w", f.path, fs.ErrExist)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	var created []string
+	for _, f := range files {
+		if err := os.WriteFile(f.path, []byte(f.content), 0o644); err != nil {
+			return created, err
+		}
+		created = append(created, f.path)
+	}
+	return created, nil
+}
+
+// packageName returns a valid package name for the directory name base.
+func packageName(base string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return '_'
+	}, base)
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "p" + name
+	}
+	return name
+}
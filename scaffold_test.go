@@ -0,0 +1,52 @@
+package wasmtest
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestScaffold(t *testing.T) {
+	nodeExec(t)
+	root := writeModule(t, map[string]string{})
+	dir := filepath.Join(root, "wasm_tests")
+	created, err := Scaffold(dir, ScaffoldOptions{Config: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "dom.go"), filepath.Join(dir, "dom_test.go"), filepath.Join(root, "wasmtest.yaml")}
+	if !slices.Equal(created, want) {
+		t.Errorf("Scaffold created %q, want %q", created, want)
+	}
+	if cfg, err := LoadConfig(root); err != nil || cfg.Dir != dir {
+		t.Errorf("LoadConfig = %+v, %v", cfg, err)
+	}
+
+	// The scaffolded package builds and its tests pass, DOM test skipped,
+	// without warnings.
+	w := New(WithInstallDisabled(), WithBackend(BackendNode))
+	progress, msgs := collectProgress()
+	if err := w.ExecuteWithOptions(ExecOptions{Dir: dir}, progress); err != nil {
+		t.Fatalf("scaffolded tests failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	out := strings.Join(msgs(), "\n")
+	if !strings.Contains(out, "test pass TestJSInterop") || !strings.Contains(out, "test skip TestDOM") || strings.Contains(out, "warning") {
+		t.Errorf("unexpected progress:\n%s", out)
+	}
+
+	os.Remove(filepath.Join(dir, "dom.go"))
+	if _, err := Scaffold(dir, ScaffoldOptions{}); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Scaffold over existing files = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "dom.go")); err == nil {
+		t.Error("Scaffold wrote files despite the existing ones")
+	}
+
+	if got := packageName("2-ui.tests"); got != "p2_ui_tests" {
+		t.Errorf("packageName = %q", got)
+	}
+}
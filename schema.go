@@ -0,0 +1,19 @@
+package wasmtest
+
+import "fmt"
+
+// SchemaVersion is the major version of the JSON documents produced by this
+// package: RunResult, ProgressEvent, history records (RunRecord), run
+// reports (JSONReport), the dashboard API and bundle manifests. Each document carries it in its
+// "schemaVersion" field. Within a major version fields are only ever added;
+// renaming or removing a field, or changing its meaning, bumps the version.
+const SchemaVersion = 1
+
+// checkSchemaVersion rejects documents written with a newer, incompatible
+// schema. Documents without a version predate versioning and are version 1.
+func checkSchemaVersion(what string, v int) error {
+	if v > SchemaVersion {
+		return fmt.Errorf("wasmtest: %s has schema version %d, this version of wasmtest only understands up to %d; upgrade wasmtest", what, v, SchemaVersion)
+	}
+	return nil
+}
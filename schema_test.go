@@ -0,0 +1,45 @@
+package wasmtest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	if err := checkSchemaVersion("doc", 0); err != nil {
+		t.Errorf("unversioned document rejected: %v", err)
+	}
+	if err := checkSchemaVersion("doc", SchemaVersion+1); err == nil {
+		t.Error("newer schema version accepted")
+	}
+
+	data, err := json.Marshal(newRunResult("wasm_tests"))
+	if err != nil || !strings.Contains(string(data), `"schemaVersion":1`) {
+		t.Errorf("RunResult JSON = %s, %v", data, err)
+	}
+
+	ev := ParseProgress("out", "PASS")
+	data, err = json.Marshal(ev)
+	if err != nil || !strings.Contains(string(data), `"schemaVersion":1`) || !strings.Contains(string(data), `"kind":"out"`) {
+		t.Errorf("ProgressEvent JSON = %s, %v", data, err)
+	}
+	var back ProgressEvent
+	if err := json.Unmarshal(data, &back); err != nil || back.Kind != EventStdout || back.Message != "PASS" {
+		t.Errorf("ProgressEvent round trip = %+v, %v", back, err)
+	}
+
+	// History records written by a newer major version are not misread.
+	h, err := OpenHistory(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(h.Dir(), "future.json"), []byte(`{"schemaVersion": 2, "id": "future"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Load("future"); err == nil {
+		t.Error("Load accepted a record with a newer schema version")
+	}
+}
@@ -0,0 +1,345 @@
+package wasmtest
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultServeAddr is the address Serve listens on when none is given. It
+// stays the same across sessions, so a browser tab left open keeps working.
+const DefaultServeAddr = "localhost:8088"
+
+// ServeOptions configures Serve.
+type ServeOptions struct {
+	// Addr is the address the test page is served on; empty uses
+	// DefaultServeAddr.
+	Addr string
+	// Exec holds the test binary flags, e.g. Run to run only some tests.
+	// Its go test specific fields are ignored.
+	Exec ExecOptions
+	// Interval is how often the sources are checked for changes; zero
+	// checks every 500ms.
+	Interval time.Duration
+	// Open launches the browser (see WithBrowser) with its window shown on
+	// the test page. Otherwise the URL reported through progress is opened
+	// by hand.
+	Open bool
+	// Headless, with Open, launches the browser without a window, for a
+	// watch loop followed in the terminal. The browser and its page stay
+	// up across iterations, each rebuild only reloading the page, and a
+	// browser that exited is launched again on the next rebuild.
+	Headless bool
+}
+
+// liveReload is added to the harness page by Serve: the page reloads, and
+// so runs the tests again, when a new build is ready.
+const liveReload = `<script>if (globalThis.EventSource) new EventSource("events").onmessage = () => location.reload();</script>
+`
+
+// Serve is a development server for the js/wasm tests of dir. It serves the
+// test page on a stable address, rebuilds the tests when the Go files of
+// their module change and makes the open pages reload, and runs the tests
+// every time the page is loaded, so the browser and its devtools stay open
+// across iterations. Changes to files the tests are not built from, such as
+// the other packages of the module, don't rebuild them. The output of each
+// run is reported through progress with the same messages as RunBundle.
+// Serve returns when ctx is done, or with an error when the server can't
+// start.
+func (w *Wasmtest) Serve(ctx context.Context, dir string, opts ServeOptions, progress func(msgs ...any)) error {
+	if progress == nil {
+		progress = func(...any) {}
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return newRunError(ErrDirNotFound, "❌💥 DIRECTORY ERROR: Test directory %s does not exist", dir)
+	}
+	out, err := makeTempDir("wasmtest-serve-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(out)
+
+	opts.Exec.Run, opts.Exec.Skip = cmp.Or(opts.Exec.Run, w.runFilter), cmp.Or(opts.Exec.Skip, w.skipFilter)
+	s := &devServer{
+		dir:      out,
+		files:    http.FileServer(http.Dir(out)),
+		progress: progress,
+		reload:   map[chan struct{}]bool{},
+	}
+	// built is the build key of the bundle in out, to skip the rebuilds
+	// after changes to files the tests are not built from.
+	var built string
+	s.build = func() (bool, error) {
+		key, err := buildKey(ctx, w.bundleSpec(dir))
+		if err == nil && key != "" && key == built {
+			return false, nil
+		}
+		built = ""
+		if _, err := w.Bundle(ctx, dir, out, opts.Exec.testBinaryArgs()...); err != nil {
+			return true, err
+		}
+		built = key
+		return true, nil
+	}
+
+	ln, err := net.Listen("tcp", cmp.Or(opts.Addr, DefaultServeAddr))
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: s}
+	go srv.Serve(ln)
+	defer srv.Close()
+	url := "http://" + ln.Addr().String() + "/"
+
+	s.rebuild()
+	progress("info", fmt.Sprintf("serving the tests of %s on %s; reload the page to run them again", dir, url))
+	// exited receives the exit of the launched browser; relaunch launches
+	// it again if it exited.
+	var exited <-chan error
+	relaunch := func() {}
+	if opts.Open {
+		browser, err := lookupBrowser(w.browser)
+		if err != nil {
+			progress("error", err.Error())
+			return err
+		}
+		if exited, err = launchBrowser(ctx, browser, url, !opts.Headless, w.browserFlags); err != nil {
+			progress("error", "failed to launch browser:", err)
+			return err
+		}
+		progress("info", "launched "+browser)
+		// The page of a headless browser is not closed by hand: one that
+		// exited has crashed and is launched again with the next build.
+		relaunch = func() {
+			if !opts.Headless {
+				return
+			}
+			select {
+			case err := <-exited:
+				progress("warning", Warning{
+					Code:    "browser-exited",
+					Message: fmt.Sprintf("the browser exited (%v); launching it again", err),
+				})
+				if exited, err = launchBrowser(ctx, browser, url, false, w.browserFlags); err != nil {
+					progress("error", "failed to launch browser:", err)
+				}
+			default:
+			}
+		}
+	}
+
+	root := moduleRoot(dir)
+	interval := cmp.Or(opts.Interval, 500*time.Millisecond)
+	last := sourceStamp(root)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if stamp := sourceStamp(root); stamp != last {
+			last = stamp
+			progress("info", "sources changed, rebuilding")
+			if s.rebuild() {
+				s.notify()
+				relaunch()
+			}
+		}
+	}
+}
+
+// devServer serves the test page of Serve. Each load of the page starts a
+// new run with its own harness.
+type devServer struct {
+	// dir is the bundle directory, served by files.
+	dir      string
+	files    http.Handler
+	progress func(msgs ...any)
+	// build builds the bundle, unless its build inputs are unchanged, and
+	// reports whether it did.
+	build func() (bool, error)
+
+	// building is held for writing while the bundle is rebuilt, so pages
+	// never load a half written one.
+	building sync.RWMutex
+	buildErr error
+
+	mu     sync.Mutex
+	run    *harness
+	runs   int
+	reload map[chan struct{}]bool
+}
+
+// rebuild builds the bundle again and reports whether a new one is ready.
+func (s *devServer) rebuild() bool {
+	s.building.Lock()
+	defer s.building.Unlock()
+	start := time.Now()
+	built, err := s.build()
+	s.buildErr = err
+	if err != nil {
+		s.progress("error", err.Error())
+		return false
+	}
+	if !built {
+		s.progress("info", "the files the tests are built from are unchanged, skipping the rebuild")
+		return false
+	}
+	s.progress("info", fmt.Sprintf("built the tests in %v", time.Since(start).Round(time.Millisecond)))
+	return true
+}
+
+// notify makes the open pages reload.
+func (s *devServer) notify() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.reload {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *devServer) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Cache-Control", "no-store")
+	switch r.URL.Path {
+	case "/", "/index.html":
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		rw.Write(bytes.Replace(harnessPage, []byte("</head>"), []byte(liveReload+"</head>"), 1))
+	case "/events":
+		s.events(rw, r)
+	case "/manifest.json":
+		s.building.RLock()
+		defer s.building.RUnlock()
+		if s.buildErr != nil {
+			http.Error(rw, s.buildErr.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.start().ServeHTTP(rw, r)
+	default:
+		s.building.RLock()
+		defer s.building.RUnlock()
+		s.mu.Lock()
+		h := s.run
+		s.mu.Unlock()
+		if h == nil {
+			s.files.ServeHTTP(rw, r)
+			return
+		}
+		h.ServeHTTP(rw, r)
+	}
+}
+
+// start starts a new run, whose harness handles the requests of the page
+// from now on, and reports its result when the page posts it.
+func (s *devServer) start() *harness {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs++
+	n := s.runs
+	s.progress("info", fmt.Sprintf("run %d started", n))
+	h := newHarness(s.files, func() ([]byte, error) {
+		return os.ReadFile(filepath.Join(s.dir, "manifest.json"))
+	}, s.progress)
+	s.run = h
+	go func() {
+		if err := h.wait(context.Background()); err != nil {
+			s.progress("exit", "error", fmt.Sprintf("run %d: %v", n, err))
+			return
+		}
+		s.progress("exit", "ok")
+	}()
+	return h
+}
+
+// events streams a message to the page each time a new build is ready.
+func (s *devServer) events(rw http.ResponseWriter, r *http.Request) {
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		http.Error(rw, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	ch := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.reload[ch] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.reload, ch)
+		s.mu.Unlock()
+	}()
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ch:
+			fmt.Fprint(rw, "data: reload\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// moduleRoot returns the directory of the go.mod of dir, or dir itself
+// outside a module.
+func moduleRoot(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return dir
+	}
+	for d := abs; ; {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			return d
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return abs
+		}
+		d = parent
+	}
+}
+
+// sourceStamp returns a summary of the names, sizes and modification times
+// of the files the tests are built from under root: the Go files, go.mod and
+// go.sum, skipping the hidden directories and those of other modules.
+func sourceStamp(root string) string {
+	sum := sha256.New()
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil && path != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") && name != "go.mod" && name != "go.sum" {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			fmt.Fprintf(sum, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+		}
+		return nil
+	})
+	return string(sum.Sum(nil))
+}
@@ -0,0 +1,149 @@
+package wasmtest
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServe(t *testing.T) {
+	browser := nodeBrowser(t)
+	dir := writeModule(t, map[string]string{"p_test.go": wasmPassTest})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	progress, msgs := collectProgress()
+	served := make(chan error, 1)
+	go func() {
+		w := New(WithInstallDisabled(), WithBrowser(browser))
+		served <- w.Serve(ctx, dir, ServeOptions{Addr: "127.0.0.1:0", Interval: 50 * time.Millisecond, Open: true}, progress)
+	}()
+	waitFor := func(want string) {
+		t.Helper()
+		for !slices.ContainsFunc(msgs(), func(m string) bool { return strings.Contains(m, want) }) {
+			select {
+			case err := <-served:
+				t.Fatalf("Serve() = %v before %q; messages: %q", err, want, msgs())
+			case <-ctx.Done():
+				t.Fatalf("no %q message: %q", want, msgs())
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+	}
+
+	// The browser opened on the page runs the tests once.
+	waitFor("exit ok")
+	var url string
+	for _, m := range msgs() {
+		if match := regexp.MustCompile(`on (http://\S+);`).FindStringSubmatch(m); match != nil {
+			url = match[1]
+		}
+	}
+	if !slices.ContainsFunc(msgs(), func(m string) bool { return strings.HasPrefix(m, "out --- PASS: TestPass") }) {
+		t.Errorf("missing the test result: %q", msgs())
+	}
+
+	page, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page.Body.Close()
+	events, err := http.Get(url + "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer events.Body.Close()
+
+	// A change rebuilds the tests and reloads the page, which runs them
+	// again.
+	if err := os.WriteFile(filepath.Join(dir, "p_test.go"), []byte(wasmFailTest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(events.Body).ReadString('\n')
+	if err != nil || line != "data: reload\n" {
+		t.Fatalf("event = %q, %v", line, err)
+	}
+	waitFor("sources changed, rebuilding")
+	reload := exec.CommandContext(ctx, browser, url)
+	if err := reload.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer reload.Process.Kill()
+	waitFor("exit error run 2: exit status 1")
+	if !slices.ContainsFunc(msgs(), func(m string) bool { return strings.HasPrefix(m, "out --- FAIL: TestFail") }) {
+		t.Errorf("missing the failed test: %q", msgs())
+	}
+
+	// A new package the tests don't import isn't worth a rebuild.
+	if err := os.MkdirAll(filepath.Join(dir, "other"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "other", "o.go"), []byte("package other\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("skipping the rebuild")
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Errorf("Serve() = %v", err)
+	}
+}
+
+func TestServeHeadless(t *testing.T) {
+	browser := nodeBrowser(t)
+	dir := writeModule(t, map[string]string{"p_test.go": wasmPassTest})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	progress, msgs := collectProgress()
+	served := make(chan error, 1)
+	go func() {
+		w := New(WithInstallDisabled(), WithBrowser(browser))
+		served <- w.Serve(ctx, dir, ServeOptions{Addr: "127.0.0.1:0", Interval: 50 * time.Millisecond, Open: true, Headless: true}, progress)
+	}()
+	has := func(want string) bool {
+		return slices.ContainsFunc(msgs(), func(m string) bool { return strings.Contains(m, want) })
+	}
+	waitFor := func(want string, change func()) {
+		t.Helper()
+		for !has(want) {
+			if change != nil {
+				change()
+			}
+			select {
+			case err := <-served:
+				t.Fatalf("Serve() = %v before %q; messages: %q", err, want, msgs())
+			case <-ctx.Done():
+				t.Fatalf("no %q message: %q", want, msgs())
+			case <-time.After(200 * time.Millisecond):
+			}
+		}
+	}
+
+	waitFor("exit ok", nil)
+	// The stand-in browser exits after the run, as a crashed one would: a
+	// rebuild launches it again, which runs the new tests.
+	n := 0
+	waitFor("launching it again", func() {
+		n++
+		test := fmt.Sprintf("%s\n// change %d\n", wasmFailTest, n)
+		if err := os.WriteFile(filepath.Join(dir, "p_test.go"), []byte(test), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	})
+	waitFor("out --- FAIL: TestFail", nil)
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Errorf("Serve() = %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package wasmtest
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Shard is a RunTests argument running one part of the tests, so that a
+// long browser suite can be split over several CI machines, each running
+// the same command with its own Index. The top level tests, examples and
+// fuzz targets of the packages, as found by DiscoverTests and selected by
+// ExecOptions.Run, are taken package by package, sorted by name, and dealt
+// out to the shards in turn; every package runs with a -run pattern naming
+// its tests of the shard. The partition only depends on the test files, so the
+// shards of a commit run every test exactly once. Benchmarks are not
+// partitioned. It can also be set with the WASMTEST_SHARD_INDEX and
+// WASMTEST_SHARD_TOTAL environment variables.
+type Shard struct {
+	// Index is the shard to run, from 0 to Total-1.
+	Index int
+	// Total is the number of shards; 0 or 1 runs everything.
+	Total int
+	// Packages deals out whole packages, the ones with the most tests
+	// first, instead of tests, so that each package is built on a single
+	// machine. The shards are less even.
+	Packages bool
+}
+
+// String renders the shard as "2/4", counting from 1.
+func (sh Shard) String() string {
+	return fmt.Sprintf("%d/%d", sh.Index+1, sh.Total)
+}
+
+// validate reports an Index out of the range of Total.
+func (sh Shard) validate() error {
+	if sh.Total < 0 || sh.Total > 1 && (sh.Index < 0 || sh.Index >= sh.Total) {
+		return newRunError(ErrUsage, "❌💥 ARGUMENT ERROR: invalid shard index %d of %d shards\n💡 The index goes from 0 to the number of shards minus one", sh.Index, sh.Total)
+	}
+	return nil
+}
+
+// assign returns the directories of dirs the shard runs, in order, and the
+// -run pattern of each of them, given the -run pattern, the target and the
+// build tags of the run; a directory without a pattern keeps the one of the
+// run.
+func (sh Shard) assign(dirs []string, run, target string, tags []string) ([]string, map[string]string, error) {
+	// Like go test, the first element of the pattern selects the top
+	// level tests and the others the subtests.
+	top, sub, _ := strings.Cut(run, "/")
+	match := func(string) bool { return true }
+	if top != "" {
+		re, err := regexp.Compile(top)
+		if err != nil {
+			return nil, nil, newRunError(ErrUsage, "❌💥 ARGUMENT ERROR: invalid -run pattern %q: %w", run, err)
+		}
+		match = re.MatchString
+	}
+
+	type pkg struct {
+		dir   string
+		names []string
+	}
+	var pkgs []pkg
+	for _, dir := range dirs {
+		found, err := discoverPackage(dir, target, tags)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, newRunError(ErrDirNotFound, "❌💥 DIRECTORY ERROR: Test directory %s does not exist", dir)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("❌💥 DIRECTORY ERROR: Failed to read directory %s\n🔴 Details: %w", dir, err)
+		}
+		p := pkg{dir: dir}
+		for _, fn := range found.Funcs {
+			if fn.Kind != "benchmark" && match(fn.Name) {
+				p.names = append(p.names, fn.Name)
+			}
+		}
+		slices.Sort(p.names)
+		if len(p.names) > 0 {
+			pkgs = append(pkgs, p)
+		}
+	}
+
+	mine := map[string][]string{}
+	if sh.Packages {
+		// Each package goes to the shard with the fewest tests so far.
+		byTests := slices.Clone(pkgs)
+		slices.SortStableFunc(byTests, func(a, b pkg) int { return cmp.Compare(len(b.names), len(a.names)) })
+		load := make([]int, sh.Total)
+		for _, p := range byTests {
+			i := slices.Index(load, slices.Min(load))
+			load[i] += len(p.names)
+			if i == sh.Index {
+				mine[p.dir] = nil
+			}
+		}
+	} else {
+		n := 0
+		for _, p := range pkgs {
+			for _, name := range p.names {
+				if n%sh.Total == sh.Index {
+					mine[p.dir] = append(mine[p.dir], name)
+				}
+				n++
+			}
+		}
+	}
+
+	var assigned []string
+	runs := map[string]string{}
+	for _, p := range pkgs {
+		names, ok := mine[p.dir]
+		if !ok {
+			continue
+		}
+		assigned = append(assigned, p.dir)
+		if names != nil {
+			pattern := "^(" + strings.Join(names, "|") + ")$"
+			if sub != "" {
+				pattern += "/" + sub
+			}
+			runs[p.dir] = pattern
+		}
+	}
+	return assigned, runs, nil
+}
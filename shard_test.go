@@ -0,0 +1,120 @@
+package wasmtest
+
+import (
+	"errors"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const shardTests = `//go:build js && wasm
+
+package p
+
+import "testing"
+
+func TestA1(t *testing.T) {}
+func TestA2(t *testing.T) {}
+func TestA3(t *testing.T) {}
+`
+
+const shardOtherTests = `//go:build js && wasm
+
+package p
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestB1(t *testing.T)      {}
+func BenchmarkB(b *testing.B)  {}
+func ExampleB() {
+	fmt.Println("b")
+	// Output: b
+}
+`
+
+func TestShardAssign(t *testing.T) {
+	root := writeModule(t, map[string]string{"a/a_test.go": shardTests, "b/b_test.go": shardOtherTests})
+	a, b := filepath.Join(root, "a"), filepath.Join(root, "b")
+	for _, tc := range []struct {
+		shard Shard
+		run   string
+		want  map[string]string
+	}{
+		{Shard{Index: 0, Total: 2}, "", map[string]string{a: "^(TestA1|TestA3)$", b: "^(TestB1)$"}},
+		{Shard{Index: 1, Total: 2}, "", map[string]string{a: "^(TestA2)$", b: "^(ExampleB)$"}},
+		{Shard{Index: 0, Total: 2}, "TestA/sub", map[string]string{a: "^(TestA1|TestA3)$/sub"}},
+		{Shard{Index: 1, Total: 2}, "TestA/sub", map[string]string{a: "^(TestA2)$/sub"}},
+		{Shard{Index: 3, Total: 4}, "", map[string]string{b: "^(ExampleB)$"}},
+		// Whole packages keep the pattern of the run.
+		{Shard{Index: 0, Total: 2, Packages: true}, "", map[string]string{a: ""}},
+		{Shard{Index: 1, Total: 2, Packages: true}, "", map[string]string{b: ""}},
+	} {
+		dirs, runs, err := tc.shard.assign([]string{a, b}, tc.run, "", nil)
+		if err != nil {
+			t.Fatalf("%+v: %v", tc.shard, err)
+		}
+		got := map[string]string{}
+		for _, dir := range dirs {
+			got[dir] = runs[dir]
+		}
+		if !maps.Equal(got, tc.want) || !slices.IsSorted(dirs) {
+			t.Errorf("shard %+v of -run %q = %v (%q), want %v", tc.shard, tc.run, got, dirs, tc.want)
+		}
+	}
+
+	if _, _, err := (Shard{Total: 2}).assign([]string{filepath.Join(root, "missing")}, "", "", nil); !errors.Is(err, ErrDirNotFound) {
+		t.Errorf("missing directory: %v", err)
+	}
+	if err := (Shard{Index: 2, Total: 2}).validate(); err == nil {
+		t.Error("index 2 of 2 shards accepted")
+	}
+	if s := (Shard{Index: 1, Total: 4}).String(); s != "2/4" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestRunTestsShard(t *testing.T) {
+	node := nodeExec(t)
+	root := writeModule(t, map[string]string{"a/a_test.go": shardTests, "b/b_test.go": shardOtherTests})
+	opts := ExecOptions{Args: []string{"-exec", node}}
+
+	var all []string
+	for i := range 2 {
+		logger, log := collectProgress()
+		res, err := RunTestsResult(filepath.Join(root, "..."), logger, opts, Shard{Index: i, Total: 2}, WithInstallDisabled())
+		if err != nil {
+			t.Fatalf("shard %d failed: %v\n%s", i, err, strings.Join(log(), "\n"))
+		}
+		if !slices.Contains(log(), "[WASMTEST] info 🧩 shard "+Shard{Index: i, Total: 2}.String()+": 2 of 2 packages") {
+			t.Errorf("shard %d not logged:\n%s", i, strings.Join(log(), "\n"))
+		}
+		all = append(all, res.PassedTests...)
+	}
+	slices.Sort(all)
+	if want := []string{"ExampleB", "TestA1", "TestA2", "TestA3", "TestB1"}; !slices.Equal(all, want) {
+		t.Errorf("tests run by the shards = %q, want %q", all, want)
+	}
+
+	// The shards split the tests selected with WithRun.
+	all = nil
+	for i := range 2 {
+		res, err := RunTestsResult(filepath.Join(root, "..."), func(...any) {}, opts, Shard{Index: i, Total: 2}, WithRun("^TestA"), WithInstallDisabled())
+		if err != nil {
+			t.Fatalf("shard %d with WithRun failed: %v", i, err)
+		}
+		all = append(all, res.PassedTests...)
+	}
+	slices.Sort(all)
+	if want := []string{"TestA1", "TestA2", "TestA3"}; !slices.Equal(all, want) {
+		t.Errorf("tests run by the shards with WithRun = %q, want %q", all, want)
+	}
+
+	if err := RunTests(root, Shard{Index: -1, Total: 2}, func(...any) {}); err == nil {
+		t.Error("RunTests accepted a negative shard index")
+	}
+}
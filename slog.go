@@ -0,0 +1,92 @@
+package wasmtest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// WithSlog sends every progress message of Execute and the other runners to
+// logger as a structured record, in addition to the progress callback:
+// the level follows the message kind (errors at Error, warnings and stderr
+// lines at Warn, debug traces at Debug, the rest at Info) and the record
+// carries the "channel" (the message tag, e.g. "out" or "test"), the test
+// "dir" and, when known, the "test" running. Test results add "action" and
+// "elapsed", compile statistics "duration" and "cached", and warnings
+// "code". When logger records debug messages, the traces of
+// WithVerbosity(Debug) are produced too. Without WithLogger, the messages
+// of background operations, such as the wasmbrowsertest install, go to
+// logger as well.
+func WithSlog(logger *slog.Logger) Option {
+	return func(w *Wasmtest) { w.slog = logger }
+}
+
+// consoleLevels maps the console methods to slog levels; "log" and "info"
+// are Info.
+var consoleLevels = map[string]slog.Level{"error": slog.LevelError, "warn": slog.LevelWarn, "debug": slog.LevelDebug}
+
+// slogProgress writes the progress message msgs of the run in dir to the
+// slog logger. tracker follows the running tests of the run.
+func (w *Wasmtest) slogProgress(tracker *testTracker, dir string, msgs ...any) {
+	if len(msgs) == 0 {
+		return
+	}
+	ev := ParseProgress(msgs...)
+	level := slog.LevelInfo
+	switch ev.Kind {
+	case EventError:
+		level = slog.LevelError
+	case EventWarning, EventStderr:
+		level = slog.LevelWarn
+	case EventDebug:
+		level = slog.LevelDebug
+	case EventConsole:
+		if m, ok := ev.Data.(ConsoleMessage); ok {
+			level = consoleLevels[m.Level]
+		}
+	case EventExit:
+		if ev.Message != "ok" {
+			level = slog.LevelError
+		}
+	}
+	// The tracker sees every message, even those below the level.
+	t, _ := tracker.event(msgs...)
+	ctx := context.Background()
+	if !w.slog.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{slog.String("channel", ev.Kind.String()), slog.String("dir", dir)}
+	if t.Test != "" {
+		attrs = append(attrs, slog.String("test", t.Test))
+	}
+	message := ev.Message
+	switch data := ev.Data.(type) {
+	case TestEvent:
+		message = data.Action + " " + data.Test
+		attrs = append(attrs, slog.String("action", data.Action))
+		if data.Action != "run" && !data.parallelStep() {
+			attrs = append(attrs, slog.Duration("elapsed", data.Elapsed))
+		}
+	case CompileStats:
+		message = data.String()
+		attrs = append(attrs, slog.Duration("duration", data.Duration), slog.Bool("cached", data.Cached))
+	case Warning:
+		message = data.Message
+		attrs = append(attrs, slog.String("code", data.Code))
+		if data.Hint != "" {
+			attrs = append(attrs, slog.String("hint", data.Hint))
+		}
+	case ConsoleMessage:
+		message = data.Text
+		attrs = append(attrs, slog.String("console", data.Level))
+		if data.Test != "" && t.Test == "" {
+			attrs = append(attrs, slog.String("test", data.Test))
+		}
+	case []string:
+		message = fmt.Sprintf("%d tests to run", len(data))
+		attrs = append(attrs, slog.Any("tests", data))
+	}
+	w.slog.LogAttrs(ctx, level, strings.TrimSpace(message), attrs...)
+}
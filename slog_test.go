@@ -0,0 +1,72 @@
+package wasmtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestWithSlog(t *testing.T) {
+	node := nodeExec(t)
+	dir := writeModule(t, map[string]string{"s_test.go": wasmFailTest})
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	w := New(WithSlog(logger), WithInstallDisabled())
+	if err := w.execute(t.Context(), execSpec{dir: dir, exec: node}, func(...any) {}); err == nil {
+		t.Fatal("execute succeeded with a failing test")
+	}
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("invalid record %q: %v", line, err)
+		}
+		if rec["dir"] != dir {
+			t.Errorf("record without the dir: %v", rec)
+		}
+		records = append(records, rec)
+	}
+	find := func(match func(map[string]any) bool) map[string]any {
+		for _, rec := range records {
+			if match(rec) {
+				return rec
+			}
+		}
+		return nil
+	}
+
+	fail := find(func(r map[string]any) bool { return r["channel"] == "test" && r["action"] == "fail" })
+	if fail == nil || fail["test"] != "TestFail" || fail["level"] != "INFO" || fail["elapsed"] == nil {
+		t.Errorf("test result record = %v", fail)
+	}
+	out := find(func(r map[string]any) bool {
+		return r["channel"] == "out" && strings.Contains(r["msg"].(string), "bad")
+	})
+	if out == nil || out["test"] != "TestFail" {
+		t.Errorf("output record = %v", out)
+	}
+	exit := find(func(r map[string]any) bool { return r["channel"] == "exit" })
+	if exit == nil || exit["level"] != "ERROR" {
+		t.Errorf("exit record = %v", exit)
+	}
+	if find(func(r map[string]any) bool { return r["channel"] == "debug" && r["level"] == "DEBUG" }) == nil {
+		t.Error("no debug traces at the debug level")
+	}
+}
+
+func TestSlogConsole(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(WithSlog(slog.New(slog.NewJSONHandler(&buf, nil))), WithInstallDisabled())
+	w.slogProgress(&testTracker{}, "ui", "console", ConsoleMessage{Level: "warn", Text: "deprecated API", Test: "TestDOM"})
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec["level"] != "WARN" || rec["msg"] != "deprecated API" || rec["console"] != "warn" || rec["test"] != "TestDOM" || rec["channel"] != "console" {
+		t.Errorf("console record = %v", rec)
+	}
+}
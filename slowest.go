@@ -0,0 +1,88 @@
+package wasmtest
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SlowestTests is a RunTests argument printing the N slowest tests, as
+// timed by go test, at the end of the run, so the browser tests dragging
+// the suite down stand out. Zero, the default, prints nothing. It can also
+// be set with the WASMTEST_SLOWEST environment variable or the slowest
+// setting of the configuration file.
+type SlowestTests int
+
+// Slowest returns the n longest top level tests of the run, slowest first,
+// as "pass", "fail" or "skip" TestEvents whose Plan is the package
+// directory. Subtests are left out as their parent accounts for them.
+func (r *RunResult) Slowest(n int) []TestEvent {
+	packages := r.Packages
+	if len(packages) == 0 {
+		packages = []*RunResult{r}
+	}
+	var tests []TestEvent
+	for _, pkg := range packages {
+		tests = append(tests, pkg.timings(pkg.Dir)...)
+	}
+	return rankTests(tests, n)
+}
+
+// timings returns the finished top level tests of r as TestEvents of plan.
+func (r *RunResult) timings(plan string) []TestEvent {
+	var tests []TestEvent
+	for name, d := range r.Durations {
+		if strings.Contains(name, "/") {
+			continue
+		}
+		action := "pass"
+		if slices.Contains(r.FailedTests, name) {
+			action = "fail"
+		} else if slices.Contains(r.SkippedTests, name) {
+			action = "skip"
+		}
+		tests = append(tests, TestEvent{Plan: plan, Action: action, Test: name, Elapsed: d})
+	}
+	return tests
+}
+
+// slowestTests returns the n longest top level tests of report, slowest
+// first.
+func slowestTests(report *Report, n int) []TestEvent {
+	var tests []TestEvent
+	for _, res := range report.Results {
+		tests = append(tests, res.timings(res.Plan.Name)...)
+	}
+	return rankTests(tests, n)
+}
+
+// rankTests sorts tests slowest first and returns the first n of them.
+func rankTests(tests []TestEvent, n int) []TestEvent {
+	slices.SortFunc(tests, func(a, b TestEvent) int {
+		return cmp.Or(cmp.Compare(b.Elapsed, a.Elapsed), cmp.Compare(a.Plan, b.Plan), cmp.Compare(a.Test, b.Test))
+	})
+	return tests[:max(0, min(n, len(tests)))]
+}
+
+// slowestSummary renders the n slowest tests of result as the section
+// printed at the end of a run, or "" when no test finished.
+func slowestSummary(result *RunResult, n int) string {
+	slowest := result.Slowest(n)
+	if len(slowest) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "🐢 %d slowest WASM %s:", len(slowest), plural(len(slowest), "test", "tests"))
+	for i, test := range slowest {
+		fmt.Fprintf(&b, "\n  %2d. %-8v %s", i+1, test.Elapsed.Round(time.Millisecond), test.Test)
+		if len(result.Packages) > 1 {
+			fmt.Fprintf(&b, " (%s)", test.Plan)
+		}
+		if test.Action != "pass" {
+			fmt.Fprintf(&b, " [%s]", test.Action)
+		}
+	}
+	return b.String()
+}
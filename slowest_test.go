@@ -0,0 +1,54 @@
+package wasmtest
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunResultSlowest(t *testing.T) {
+	res := newRunResult("pkg")
+	res.PassedTests = []string{"TestA", "TestB", "TestB/sub"}
+	res.FailedTests = []string{"TestC"}
+	res.Durations = map[string]time.Duration{
+		"TestA": 100 * time.Millisecond, "TestB": 900 * time.Millisecond,
+		"TestB/sub": 850 * time.Millisecond, "TestC": 300 * time.Millisecond,
+	}
+
+	got := res.Slowest(2)
+	if len(got) != 2 || got[0].Test != "TestB" || got[1].Test != "TestC" || got[1].Action != "fail" || got[0].Plan != "pkg" {
+		t.Errorf("Slowest(2) = %+v", got)
+	}
+	if got := res.Slowest(10); len(got) != 3 {
+		t.Errorf("Slowest(10) = %+v", got)
+	}
+
+	want := "🐢 2 slowest WASM tests:\n   1. 900ms    TestB\n   2. 300ms    TestC [fail]"
+	if got := slowestSummary(res, 2); got != want {
+		t.Errorf("slowestSummary:\n%s\nwant\n%s", got, want)
+	}
+	if got := slowestSummary(newRunResult("empty"), 5); got != "" {
+		t.Errorf("slowestSummary without tests = %q", got)
+	}
+}
+
+func TestRunTestsSlowestTests(t *testing.T) {
+	node := nodeExec(t)
+	root := writeModule(t, map[string]string{
+		"a/a_test.go": wasmPassTest,
+		"b/b_test.go": "//go:build js && wasm\n\npackage p\n\nimport (\n\t\"testing\"\n\t\"time\"\n)\n\n" +
+			"func TestSlow(t *testing.T) { time.Sleep(200 * time.Millisecond) }\n",
+	})
+	var log []string
+	logger := func(args ...any) { log = append(log, fmt.Sprintln(args...)) }
+	err := RunTests(filepath.Join(root, "..."), logger, SlowestTests(1), ExecOptions{Args: []string{"-exec", node}}, WithInstallDisabled())
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := log[len(log)-1]
+	if !strings.Contains(last, "🐢 1 slowest WASM test:") || !strings.Contains(last, "TestSlow ("+filepath.Join(root, "b")+")") {
+		t.Errorf("last log line = %q", last)
+	}
+}
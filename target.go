@@ -0,0 +1,73 @@
+package wasmtest
+
+import "fmt"
+
+// Targets of the go test builds (see WithTarget), as GOOS/GOARCH.
+const (
+	// TargetJS builds for js/wasm, run in a browser, node or Deno. It is
+	// the default.
+	TargetJS = "js/wasm"
+	// TargetWASIP1 builds for wasip1/wasm, run by a WASI runtime:
+	// wasmtime, unless BackendWasmer is selected.
+	TargetWASIP1 = "wasip1/wasm"
+)
+
+// WithTarget sets the target of go test runs, TargetJS or TargetWASIP1, so
+// that libraries supporting both can verify them from the same harness.
+// ExecOptions.Target and RunPlan.Target override it for one run or one
+// directory. Without it the WASI backends build for wasip1 and the others
+// for js. The WASMTEST_TARGET environment variable and the target setting
+// of the configuration file set it too. TinyGo builds use their own
+// target.
+func WithTarget(target string) Option {
+	return func(w *Wasmtest) { w.target = target }
+}
+
+// target returns the target the tests of the run are built for: the one of
+// ExecOptions.Target, or else the one set with WithTarget, or else the one
+// of the backend.
+func (s runSettings) target() string {
+	probe := &Wasmtest{}
+	for _, opt := range s.options() {
+		opt(probe)
+	}
+	if s.exec.Target != "" {
+		return s.exec.Target
+	}
+	if probe.target != "" {
+		return probe.target
+	}
+	if wasiBackend(probe.backend) {
+		return TargetWASIP1
+	}
+	return TargetJS
+}
+
+// runTarget returns the target of spec: its own, or else the one set with
+// WithTarget, or else the one of its backend. The backend must be able to
+// run it.
+func (w *Wasmtest) runTarget(spec execSpec) (string, error) {
+	target := spec.target
+	if target == "" {
+		target = w.target
+	}
+	if target == "" {
+		if wasiBackend(spec.backend) {
+			return TargetWASIP1, nil
+		}
+		return TargetJS, nil
+	}
+	switch target {
+	case TargetJS:
+		if wasiBackend(spec.backend) && spec.exec == "" {
+			return "", fmt.Errorf("wasmtest: the %s backend can't run %s tests", spec.backend, target)
+		}
+	case TargetWASIP1:
+		if (spec.backend == BackendNode || spec.backend == BackendDeno) && spec.exec == "" {
+			return "", fmt.Errorf("wasmtest: the %s backend can't run %s tests", spec.backend, target)
+		}
+	default:
+		return "", fmt.Errorf("wasmtest: unknown target %q (want %s or %s)", target, TargetJS, TargetWASIP1)
+	}
+	return target, nil
+}
@@ -0,0 +1,67 @@
+package wasmtest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunTarget(t *testing.T) {
+	for _, tc := range []struct {
+		backend, option, spec, want string
+	}{
+		{"", "", "", TargetJS},
+		{BackendWasmer, "", "", TargetWASIP1},
+		{"", TargetWASIP1, "", TargetWASIP1},
+		{"", TargetWASIP1, TargetJS, TargetJS},
+		{BackendNode, "", TargetWASIP1, ""},
+		{BackendWasmtime, TargetJS, "", ""},
+		{"", "plan9/386", "", ""},
+	} {
+		w := New(WithInstallDisabled(), WithTarget(tc.option))
+		got, err := w.runTarget(execSpec{backend: tc.backend, target: tc.spec})
+		if got != tc.want || (tc.want == "") != (err != nil) {
+			t.Errorf("runTarget(backend %q, option %q, spec %q) = %q, %v; want %q", tc.backend, tc.option, tc.spec, got, err, tc.want)
+		}
+	}
+}
+
+func TestOrchestrateBothTargets(t *testing.T) {
+	node := nodeExec(t)
+	// The fake wasmtime runs the wasip1 binaries with the WASI support of
+	// node.
+	bin := t.TempDir()
+	script, err := filepath.Abs("testdata/nodewasi.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bin, "wasmtime"), []byte("#!/bin/sh\nexec node --no-warnings "+script+" \"$@\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	dir := writeModule(t, map[string]string{
+		"p_test.go": "//go:build wasm\n\npackage p\n\nimport (\n\t\"runtime\"\n\t\"testing\"\n)\n\nfunc TestGOOS(t *testing.T) { t.Log(\"built for\", runtime.GOOS) }\n",
+	})
+	o := &Orchestrator{Concurrency: 1}
+	report, err := o.Run(t.Context(), []RunPlan{
+		{Dir: dir, Exec: node, Target: TargetJS, Args: []string{"-v"}},
+		{Dir: dir, Target: TargetWASIP1, Args: []string{"-v"}},
+	})
+	if err != nil {
+		for _, r := range report.Results {
+			t.Logf("%s: %v\n%s", r.Plan.Name, r.Err, strings.Join(r.RawOutput, "\n"))
+		}
+		t.Fatalf("Run failed: %v", err)
+	}
+	for i, goos := range []string{"js", "wasip1"} {
+		r := report.Results[i]
+		if !strings.Contains(strings.Join(r.RawOutput, "\n"), "built for "+goos) {
+			t.Errorf("plan %s did not build for %s:\n%s", r.Plan.Name, goos, strings.Join(r.RawOutput, "\n"))
+		}
+	}
+	if name := report.Results[1].Plan.Name; name != dir+" (wasip1/wasm)" {
+		t.Errorf("wasip1 plan name = %q", name)
+	}
+}
@@ -0,0 +1,114 @@
+package wasmtest
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// teamcityReportWriter is the built-in "teamcity" writer printing TeamCity
+// service messages as the tests run: a test suite per plan, with
+// testStarted, testFailed, testIgnored and testFinished messages for each
+// test, so TeamCity and compatible systems show live per-test progress.
+// Messages carry the plan as flowId, keeping plans run in parallel apart.
+type teamcityReportWriter struct {
+	out io.Writer
+	// started holds the plans whose suite was opened, in order.
+	started []string
+	// output holds the lines written by each running test.
+	output map[[2]string][]string
+}
+
+func (tc *teamcityReportWriter) Begin(plans []RunPlan) error {
+	tc.started = nil
+	tc.output = map[[2]string][]string{}
+	return nil
+}
+
+func (tc *teamcityReportWriter) TestEvent(ev TestEvent) error {
+	if ev.Test == "" {
+		return nil
+	}
+	if err := tc.suite(ev.Plan); err != nil {
+		return err
+	}
+	key := [2]string{ev.Plan, ev.Test}
+	switch ev.Action {
+	case "run":
+		return tc.message("testStarted", ev.Plan, "name", ev.Test)
+	case "output":
+		tc.output[key] = append(tc.output[key], ev.Output)
+		return tc.message("testStdOut", ev.Plan, "name", ev.Test, "out", ev.Output)
+	case "skip":
+		if err := tc.message("testIgnored", ev.Plan, "name", ev.Test, "message", "skipped"); err != nil {
+			return err
+		}
+	case "fail":
+		details := strings.Join(tc.output[key], "\n")
+		if err := tc.message("testFailed", ev.Plan, "name", ev.Test, "message", ev.Test+" failed", "details", details); err != nil {
+			return err
+		}
+	case "pass":
+	default:
+		return nil
+	}
+	delete(tc.output, key)
+	return tc.message("testFinished", ev.Plan, "name", ev.Test, "duration", fmt.Sprint(ev.Elapsed.Milliseconds()))
+}
+
+// suite opens the test suite of plan on its first event.
+func (tc *teamcityReportWriter) suite(plan string) error {
+	if slices.Contains(tc.started, plan) {
+		return nil
+	}
+	tc.started = append(tc.started, plan)
+	return tc.message("testSuiteStarted", plan, "name", plan)
+}
+
+func (tc *teamcityReportWriter) End(report *Report) error {
+	// A plan failing without any failed test, e.g. on a build error, is
+	// reported as a failed test named after the plan.
+	for _, res := range report.Results {
+		if res.Err == nil || len(res.FailedTests) > 0 {
+			continue
+		}
+		name := res.Plan.Name
+		if err := tc.suite(name); err != nil {
+			return err
+		}
+		for _, msg := range [][]string{
+			{"testStarted", "name", name},
+			{"testFailed", "name", name, "message", "package failed", "details", res.Err.Error()},
+			{"testFinished", "name", name, "duration", fmt.Sprint(res.Duration.Milliseconds())},
+		} {
+			if err := tc.message(msg[0], name, msg[1:]...); err != nil {
+				return err
+			}
+		}
+	}
+	for _, plan := range tc.started {
+		if err := tc.message("testSuiteFinished", plan, "name", plan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// message prints the service message called name with the attribute
+// name/value pairs of attrs and the flowId of plan.
+func (tc *teamcityReportWriter) message(name, plan string, attrs ...string) error {
+	var b strings.Builder
+	b.WriteString("##teamcity[" + name)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		fmt.Fprintf(&b, " %s='%s'", attrs[i], escapeTeamCity(attrs[i+1]))
+	}
+	fmt.Fprintf(&b, " flowId='%s']\n", escapeTeamCity(plan))
+	_, err := io.WriteString(tc.out, b.String())
+	return err
+}
+
+// escapeTeamCity escapes a service message attribute value.
+func escapeTeamCity(s string) string {
+	return strings.NewReplacer("|", "||", "'", "|'", "\n", "|n", "\r", "|r", "[", "|[", "]", "|]").Replace(s)
+}
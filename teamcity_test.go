@@ -0,0 +1,61 @@
+package wasmtest
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTeamCityReportWriter(t *testing.T) {
+	var out bytes.Buffer
+	tc, err := NewReportWriter("teamcity", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tc.Begin([]RunPlan{{Name: "ui"}, {Name: "broken"}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, ev := range []TestEvent{
+		{Plan: "ui", Action: "run", Test: "TestOK"},
+		{Plan: "ui", Action: "pass", Test: "TestOK", Elapsed: 12 * time.Millisecond},
+		{Plan: "ui", Action: "run", Test: "TestBad"},
+		{Plan: "ui", Action: "output", Test: "TestBad", Output: "    bad_test.go:9: got 'x' [1]"},
+		{Plan: "ui", Action: "fail", Test: "TestBad", Elapsed: 3 * time.Millisecond},
+		{Plan: "ui", Action: "run", Test: "TestLater"},
+		{Plan: "ui", Action: "skip", Test: "TestLater"},
+		{Plan: "ui", Action: "output", Output: "FAIL"},
+	} {
+		if err := tc.TestEvent(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	report := &Report{ExitCode: 1, Results: []PlanResult{
+		{Plan: RunPlan{Name: "ui"}, RunResult: RunResult{FailedTests: []string{"TestBad"}}, Err: errors.New("tests failed")},
+		{Plan: RunPlan{Name: "broken"}, RunResult: RunResult{Duration: time.Second}, Err: errors.New("build failed\nsyntax error")},
+	}}
+	if err := tc.End(report); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `##teamcity[testSuiteStarted name='ui' flowId='ui']
+##teamcity[testStarted name='TestOK' flowId='ui']
+##teamcity[testFinished name='TestOK' duration='12' flowId='ui']
+##teamcity[testStarted name='TestBad' flowId='ui']
+##teamcity[testStdOut name='TestBad' out='    bad_test.go:9: got |'x|' |[1|]' flowId='ui']
+##teamcity[testFailed name='TestBad' message='TestBad failed' details='    bad_test.go:9: got |'x|' |[1|]' flowId='ui']
+##teamcity[testFinished name='TestBad' duration='3' flowId='ui']
+##teamcity[testStarted name='TestLater' flowId='ui']
+##teamcity[testIgnored name='TestLater' message='skipped' flowId='ui']
+##teamcity[testFinished name='TestLater' duration='0' flowId='ui']
+##teamcity[testSuiteStarted name='broken' flowId='broken']
+##teamcity[testStarted name='broken' flowId='broken']
+##teamcity[testFailed name='broken' message='package failed' details='build failed|nsyntax error' flowId='broken']
+##teamcity[testFinished name='broken' duration='1000' flowId='broken']
+##teamcity[testSuiteFinished name='ui' flowId='ui']
+##teamcity[testSuiteFinished name='broken' flowId='broken']
+`
+	if out.String() != want {
+		t.Errorf("service messages:\n%s\nwant\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,100 @@
+package wasmtest
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// test2jsonEvent is a record written by go test -json, see `go doc
+// test2json`.
+type test2jsonEvent struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// OutputLine is the line of an "out" or "err" progress message decoded
+// from go test -json, with the test that wrote it, empty for the package
+// level output. The parallel tests interleave their output, so it tells
+// which test a line belongs to better than the last test started. It
+// prints as the line.
+type OutputLine struct {
+	Line string
+	Test string
+}
+
+// String returns the line.
+func (l OutputLine) String() string { return l.Line }
+
+// lineDecoder converts the stdout lines of a test run into progress
+// messages.
+type lineDecoder interface {
+	decode(line string) [][]any
+	flush() [][]any
+}
+
+// test2jsonDecoder converts the stdout lines of go test -json into progress
+// messages: output records become "out" lines, build output "err" lines,
+// both as OutputLine, and test state changes "test" messages carrying a
+// TestEvent. Output split over several records, as for benchmarks, is
+// joined back into whole lines.
+type test2jsonDecoder struct {
+	partial map[string]string
+}
+
+// decode returns the progress messages of one stdout line. Lines that are
+// not test2json records, e.g. written by a custom -exec program, are kept as
+// "out" lines.
+func (d *test2jsonDecoder) decode(line string) [][]any {
+	var ev test2jsonEvent
+	if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &ev) != nil || ev.Action == "" {
+		return [][]any{{"out", line}}
+	}
+	switch ev.Action {
+	case "output", "build-output":
+		tag := "out"
+		if ev.Action == "build-output" {
+			tag = "err"
+		}
+		text := d.partial[ev.Test] + ev.Output
+		if !strings.HasSuffix(text, "\n") {
+			if d.partial == nil {
+				d.partial = map[string]string{}
+			}
+			d.partial[ev.Test] = text
+			return nil
+		}
+		delete(d.partial, ev.Test)
+		var msgs [][]any
+		for _, l := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+			msgs = append(msgs, []any{tag, OutputLine{Line: l, Test: ev.Test}})
+		}
+		return msgs
+	case "run", "pause", "cont", "pass", "fail", "skip":
+		if ev.Test == "" {
+			// Package level results are reported by the exit message.
+			return nil
+		}
+		return [][]any{{"test", TestEvent{
+			Time:    ev.Time,
+			Action:  ev.Action,
+			Test:    ev.Test,
+			Elapsed: time.Duration(ev.Elapsed * float64(time.Second)),
+		}}}
+	}
+	return nil
+}
+
+// flush returns the output left without a trailing newline.
+func (d *test2jsonDecoder) flush() [][]any {
+	var msgs [][]any
+	for test, text := range d.partial {
+		msgs = append(msgs, []any{"out", OutputLine{Line: text, Test: test}})
+		delete(d.partial, test)
+	}
+	return msgs
+}
@@ -0,0 +1,109 @@
+package wasmtest
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTest2jsonDecoder(t *testing.T) {
+	var dec test2jsonDecoder
+	var got []string
+	for _, line := range []string{
+		`{"Action":"start","Package":"p"}`,
+		`{"Action":"run","Package":"p","Test":"TestA"}`,
+		`{"Action":"output","Package":"p","Test":"TestA","Output":"=== RUN   TestA\n"}`,
+		`{"Action":"output","Package":"p","Test":"TestA","Output":"--- PASS: TestA (0.25s)\n"}`,
+		`{"Action":"pass","Package":"p","Test":"TestA","Elapsed":0.25}`,
+		`{"Action":"output","Package":"p","Test":"BenchmarkB","Output":"BenchmarkB   \t"}`,
+		`{"Action":"output","Package":"p","Test":"BenchmarkB","Output":"      10\t   123 ns/op\n"}`,
+		`{"Action":"build-output","Package":"p","Output":"./a_test.go:3:1: syntax error\n"}`,
+		`{"Action":"output","Package":"p","Output":"FAIL\n"}`,
+		`{"Action":"fail","Package":"p","Elapsed":1.5}`,
+		`not json`,
+		`{"Action":"output","Package":"p","Output":"no newline"}`,
+	} {
+		for _, msg := range dec.decode(line) {
+			got = append(got, fmt.Sprint(msg[0], " ", msg[1]))
+		}
+	}
+	for _, msg := range dec.flush() {
+		got = append(got, fmt.Sprint(msg[0], " ", msg[1]))
+	}
+
+	want := []string{
+		"test run TestA",
+		"out === RUN   TestA",
+		"out --- PASS: TestA (0.25s)",
+		"test pass TestA (250ms)",
+		"out BenchmarkB   \t      10\t   123 ns/op",
+		"err ./a_test.go:3:1: syntax error",
+		"out FAIL",
+		"out not json",
+		"out no newline",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("decoded messages:\n%q\nwant\n%q", got, want)
+	}
+
+	msgs := dec.decode(`{"Time":"2026-01-02T03:04:05Z","Action":"fail","Test":"TestC","Elapsed":2}`)
+	ev, ok := msgs[0][1].(TestEvent)
+	if !ok || ev.Action != "fail" || ev.Test != "TestC" || ev.Elapsed != 2*time.Second || ev.Time.Year() != 2026 {
+		t.Errorf("decoded event = %+v", msgs)
+	}
+}
+
+func TestParallelOutputAttribution(t *testing.T) {
+	// go test -json of two parallel tests, TestA failing after TestB
+	// started, so TestB is the last test started when TestA logs.
+	var dec test2jsonDecoder
+	var msgs [][]any
+	for _, line := range []string{
+		`{"Action":"run","Package":"p","Test":"TestA"}`,
+		`{"Action":"output","Package":"p","Test":"TestA","Output":"=== RUN   TestA\n"}`,
+		`{"Action":"output","Package":"p","Test":"TestA","Output":"=== PAUSE TestA\n"}`,
+		`{"Action":"pause","Package":"p","Test":"TestA"}`,
+		`{"Action":"run","Package":"p","Test":"TestB"}`,
+		`{"Action":"output","Package":"p","Test":"TestB","Output":"=== RUN   TestB\n"}`,
+		`{"Action":"output","Package":"p","Test":"TestB","Output":"=== PAUSE TestB\n"}`,
+		`{"Action":"pause","Package":"p","Test":"TestB"}`,
+		`{"Action":"output","Package":"p","Test":"TestA","Output":"=== CONT  TestA\n"}`,
+		`{"Action":"cont","Package":"p","Test":"TestA"}`,
+		`{"Action":"output","Package":"p","Test":"TestB","Output":"=== CONT  TestB\n"}`,
+		`{"Action":"cont","Package":"p","Test":"TestB"}`,
+		`{"Action":"output","Package":"p","Test":"TestA","Output":"    a_test.go:9: A failed\n"}`,
+		`{"Action":"output","Package":"p","Test":"TestB","Output":"    b_test.go:9: B log\n"}`,
+		`{"Action":"output","Package":"p","Test":"TestB","Output":"--- PASS: TestB (0.01s)\n"}`,
+		`{"Action":"pass","Package":"p","Test":"TestB","Elapsed":0.01}`,
+		`{"Action":"output","Package":"p","Test":"TestA","Output":"--- FAIL: TestA (0.02s)\n"}`,
+		`{"Action":"fail","Package":"p","Test":"TestA","Elapsed":0.02}`,
+		`{"Action":"output","Package":"p","Output":"FAIL\n"}`,
+	} {
+		msgs = append(msgs, dec.decode(line)...)
+	}
+
+	r := newRunResult("p")
+	var tracker testTracker
+	owners := map[string]string{}
+	for _, msg := range msgs {
+		r.collect(msg...)
+		if ev, ok := tracker.event(msg...); ok && ev.Action == "output" {
+			owners[ev.Output] = ev.Test
+		}
+	}
+	if got := r.FailureOutput["TestA"]; !slices.Equal(got, []string{"    a_test.go:9: A failed"}) {
+		t.Errorf("FailureOutput[TestA] = %q", got)
+	}
+	if _, ok := r.FailureOutput["TestB"]; ok {
+		t.Errorf("TestB got failure output: %q", r.FailureOutput)
+	}
+	for line, want := range map[string]string{"    a_test.go:9: A failed": "TestA", "    b_test.go:9: B log": "TestB", "FAIL": ""} {
+		if owners[line] != want {
+			t.Errorf("%q attributed to %q, want %q", line, owners[line], want)
+		}
+	}
+	if !slices.Equal(r.PassedTests, []string{"TestB"}) || !slices.Equal(r.FailedTests, []string{"TestA"}) {
+		t.Errorf("PassedTests = %q, FailedTests = %q", r.PassedTests, r.FailedTests)
+	}
+}
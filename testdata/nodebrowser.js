@@ -0,0 +1,17 @@
+// nodebrowser is a minimal stand-in for a headless browser used by the tests:
+// it loads the page given as last argument with node, runs its scripts in the
+// global scope and resolves relative URLs against the page URL.
+"use strict";
+
+const base = process.argv[process.argv.length - 1];
+const realFetch = globalThis.fetch;
+globalThis.fetch = (url, opts) => realFetch(new URL(url, base), opts);
+globalThis.document = { title: "" };
+
+(async () => {
+	const html = await (await fetch(base)).text();
+	for (const [, src, inline] of html.matchAll(/<script(?: src="([^"]+)")?>([\s\S]*?)<\/script>/g)) {
+		const code = src ? await (await fetch(src)).text() : inline;
+		(0, eval)(code);
+	}
+})();
@@ -0,0 +1,39 @@
+// nodedevtools is a stand-in for a Chrome started with
+// --remote-debugging-port=0 used by the tests: it writes DevToolsActivePort
+// in its --user-data-dir and opens each /json/new page with nodebrowser.js.
+// Each start is appended to the file named by NODE_DEVTOOLS_STARTS, if set.
+"use strict";
+
+const fs = require("fs");
+const http = require("http");
+const path = require("path");
+const { spawn } = require("child_process");
+
+const dataDir = process.argv.find((arg) => arg.startsWith("--user-data-dir=")).slice("--user-data-dir=".length);
+if (process.env.NODE_DEVTOOLS_STARTS) {
+	fs.appendFileSync(process.env.NODE_DEVTOOLS_STARTS, "start\n");
+}
+const tabs = {};
+let next = 1;
+const server = http.createServer((req, res) => {
+	const url = new URL(req.url, "http://localhost");
+	if (url.pathname === "/json/new") {
+		const id = "tab" + next++;
+		tabs[id] = spawn(process.execPath, [path.join(__dirname, "nodebrowser.js"), decodeURIComponent(url.search.slice(1))], { stdio: "ignore" });
+		res.end(JSON.stringify({ id, type: "page" }));
+	} else if (url.pathname.startsWith("/json/close/")) {
+		const id = url.pathname.slice("/json/close/".length);
+		if (tabs[id]) {
+			tabs[id].kill();
+			delete tabs[id];
+		}
+		res.end("Target is closing");
+	} else {
+		res.statusCode = 404;
+		res.end();
+	}
+});
+server.listen(0, "127.0.0.1", () => {
+	fs.writeFileSync(path.join(dataDir, "DevToolsActivePort"), server.address().port + "\n/devtools/browser/node\n");
+});
+process.on("SIGTERM", () => process.exit(0));
@@ -0,0 +1,33 @@
+// nodedriver is a minimal stand-in for chromedriver used by the tests: it
+// serves the WebDriver commands of RunBundle on its --port and opens the
+// session URL with the goog:chromeOptions binary.
+"use strict";
+
+const http = require("http");
+const { spawn } = require("child_process");
+
+const port = Number(process.argv.find((arg) => arg.startsWith("--port=")).slice("--port=".length));
+let binary;
+let browser;
+http.createServer((req, res) => {
+	let body = "";
+	req.on("data", (chunk) => (body += chunk));
+	req.on("end", () => {
+		const send = (value) => res.end(JSON.stringify({ value }));
+		if (req.method === "GET" && req.url === "/status") {
+			send({ ready: true });
+		} else if (req.method === "POST" && req.url === "/session") {
+			binary = JSON.parse(body).capabilities.alwaysMatch["goog:chromeOptions"].binary;
+			send({ sessionId: "s1", capabilities: {} });
+		} else if (req.method === "POST" && req.url === "/session/s1/url") {
+			browser = spawn(binary, [JSON.parse(body).url], { stdio: "ignore" });
+			send(null);
+		} else if (req.method === "DELETE" && req.url === "/session/s1") {
+			if (browser) browser.kill();
+			send(null);
+		} else {
+			res.statusCode = 404;
+			send({ error: "unknown command", message: req.url });
+		}
+	});
+}).listen(port, "127.0.0.1");
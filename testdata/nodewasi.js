@@ -0,0 +1,27 @@
+// nodewasi.js stands in for wasmtime and wasmer in the tests: it runs a
+// wasip1 binary with the WASI support of node, taking the --dir and --env
+// flags of `wasmtime run` and `wasmer run`.
+"use strict";
+const fs = require("fs");
+const { WASI } = require("wasi");
+
+const argv = process.argv.slice(2);
+const env = {};
+const preopens = {};
+let i = argv[0] === "run" ? 1 : 0;
+for (; i < argv.length && argv[i].startsWith("-"); i++) {
+	let [flag, value] = argv[i].split(/=(.*)/s);
+	if (value === undefined) value = argv[++i];
+	if (flag === "--dir") preopens[value] = value;
+	if (flag === "--env") {
+		const [k, v] = value.split(/=(.*)/s);
+		env[k] = v;
+	}
+}
+const args = argv.slice(i).filter((arg, j) => j !== 1 || arg !== "--");
+const wasi = new WASI({ version: "preview1", args, env, preopens, returnOnExit: true });
+(async () => {
+	const module = await WebAssembly.compile(fs.readFileSync(args[0]));
+	const instance = await WebAssembly.instantiate(module, wasi.getImportObject());
+	process.exitCode = wasi.start(instance);
+})();
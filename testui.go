@@ -0,0 +1,633 @@
+package wasmtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TestUI is an interactive view of the js/wasm tests of some packages: a
+// tree of their tests, subtests included once they ran, with the live state
+// of each one, and keys to rerun a single test or all of them, filter the
+// tree and show the output of a test. It renders to text and is driven by
+// key names, so it can run in a terminal, as `wasmtest tui` does, or be
+// embedded in another UI.
+//
+// It also works as a devtui handler: Name, Label and Execute rerun all the
+// tests, Value and Change get and set the filter and Content returns the
+// view.
+type TestUI struct {
+	patterns []string
+	args     []any
+	tags     []string
+	exec     ExecOptions
+
+	// Theme colors the view; the zero value, NoColorTheme, writes plain
+	// text.
+	Theme Theme
+
+	mu       sync.Mutex
+	pkgs     []*uiPackage
+	selected int
+	filter   string
+	editing  bool
+	output   bool
+	running  bool
+	cancel   context.CancelFunc
+	status   string
+	updates  chan struct{}
+}
+
+// uiPackage is a package of a TestUI with its tests in tree order.
+type uiPackage struct {
+	dir   string
+	label string
+	node  *uiNode
+	tests []*uiNode
+}
+
+// uiNode is a test, or a package for the first node of a uiPackage, and the
+// state of its last run: "" (not run), "queued", "run", "pass", "fail" or
+// "skip".
+type uiNode struct {
+	pkg     *uiPackage
+	name    string
+	state   string
+	elapsed time.Duration
+	output  []string
+}
+
+// depth returns the nesting level of n in the tree.
+func (n *uiNode) depth() int {
+	if n.name == "" {
+		return 0
+	}
+	return strings.Count(n.name, "/") + 1
+}
+
+// NewTestUI returns a TestUI for the packages matched by patterns,
+// directories or ./... patterns (see FindPackages), whose tests it lists
+// without running them. args are passed to RunTestsContext on every run,
+// e.g. Option, time.Duration and ExecOptions values; the UI sets the
+// directory, the test selection and a silent log.
+func NewTestUI(patterns []string, args ...any) (*TestUI, error) {
+	u := &TestUI{patterns: patterns, updates: make(chan struct{}, 1)}
+	for _, arg := range args {
+		if v, ok := arg.(ExecOptions); ok {
+			u.exec = v
+			u.tags = v.Tags
+			continue
+		}
+		u.args = append(u.args, arg)
+	}
+	if len(u.patterns) == 0 {
+		u.patterns = []string{"./..."}
+	}
+	if err := u.Reload(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Reload lists the tests of the packages again, keeping the state of those
+// still there.
+func (u *TestUI) Reload() error {
+	var pkgs []*uiPackage
+	for _, pattern := range u.patterns {
+		found, err := DiscoverTests(pattern, u.tags...)
+		if err != nil {
+			return err
+		}
+		for _, p := range found {
+			pkg := &uiPackage{dir: p.Dir, label: p.Dir}
+			if abs, err := filepath.Abs(p.Dir); err == nil {
+				pkg.dir = abs
+			}
+			pkg.node = &uiNode{pkg: pkg}
+			for _, fn := range p.Funcs {
+				if fn.Kind != "benchmark" {
+					pkg.tests = append(pkg.tests, &uiNode{pkg: pkg, name: fn.Name})
+				}
+			}
+			pkgs = append(pkgs, pkg)
+		}
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, pkg := range pkgs {
+		old := u.pkg(pkg.dir)
+		if old == nil {
+			continue
+		}
+		*pkg.node = *old.node
+		pkg.node.pkg = pkg
+		for _, n := range old.tests {
+			if strings.Contains(n.name, "/") {
+				continue
+			}
+			if i := pkg.index(n.name); i >= 0 {
+				*pkg.tests[i] = *n
+				pkg.tests[i].pkg = pkg
+			}
+		}
+	}
+	u.pkgs = pkgs
+	u.changed()
+	return nil
+}
+
+// pkg returns the package in dir, or nil.
+func (u *TestUI) pkg(dir string) *uiPackage {
+	for _, pkg := range u.pkgs {
+		if pkg.dir == dir {
+			return pkg
+		}
+	}
+	return nil
+}
+
+// index returns the position of the test called name in p.tests, or -1.
+func (p *uiPackage) index(name string) int {
+	for i, n := range p.tests {
+		if n.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// test returns the test called name, adding the subtests reported by a
+// run after their parent.
+func (p *uiPackage) test(name string) *uiNode {
+	if i := p.index(name); i >= 0 {
+		return p.tests[i]
+	}
+	n := &uiNode{pkg: p, name: name}
+	at := len(p.tests)
+	if parent, _, ok := cutLast(name, "/"); ok {
+		if i := p.index(parent); i >= 0 {
+			at = i + 1
+			for at < len(p.tests) && strings.HasPrefix(p.tests[at].name, parent+"/") {
+				at++
+			}
+		}
+	}
+	p.tests = append(p.tests[:at], append([]*uiNode{n}, p.tests[at:]...)...)
+	return n
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// Updates returns a channel receiving a value when the view changed and
+// should be rendered again.
+func (u *TestUI) Updates() <-chan struct{} { return u.updates }
+
+// changed signals an update; u.mu is held.
+func (u *TestUI) changed() {
+	select {
+	case u.updates <- struct{}{}:
+	default:
+	}
+}
+
+// visible returns the nodes shown with the current filter: the tests whose
+// name contains it, ignoring case, and their packages.
+func (u *TestUI) visible() []*uiNode {
+	filter := strings.ToLower(u.filter)
+	var rows []*uiNode
+	for _, pkg := range u.pkgs {
+		var tests []*uiNode
+		for _, n := range pkg.tests {
+			if strings.Contains(strings.ToLower(n.name), filter) {
+				tests = append(tests, n)
+			}
+		}
+		if len(tests) > 0 || filter == "" {
+			rows = append(append(rows, pkg.node), tests...)
+		}
+	}
+	return rows
+}
+
+// Key handles a key press: "up" or "k" and "down" or "j" move the
+// selection, "enter" or "r" rerun the selected test or package, "a" reruns
+// everything, "/" edits the filter (typed characters, "backspace", then
+// "enter" or "esc"), "o" shows or hides the output of the selected test,
+// "esc" stops the run and "q" quits. It returns false when the UI should
+// close.
+func (u *TestUI) Key(key string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	defer u.changed()
+	if u.editing {
+		switch key {
+		case "enter", "esc":
+			u.editing = false
+		case "backspace":
+			if r := []rune(u.filter); len(r) > 0 {
+				u.filter = string(r[:len(r)-1])
+			}
+		default:
+			if len([]rune(key)) == 1 {
+				u.filter += key
+			}
+		}
+		u.selected = 0
+		return true
+	}
+
+	rows := u.visible()
+	switch key {
+	case "up", "k":
+		u.selected = max(u.selected-1, 0)
+	case "down", "j":
+		u.selected = min(u.selected+1, len(rows)-1)
+	case "enter", "r":
+		if u.selected < len(rows) {
+			n := rows[u.selected]
+			u.start([]*uiPackage{n.pkg}, n.name)
+		}
+	case "a":
+		u.start(u.pkgs, "")
+	case "/":
+		u.editing = true
+	case "o":
+		u.output = !u.output
+	case "esc":
+		if u.cancel != nil {
+			u.cancel()
+		}
+	case "q", "ctrl+c":
+		if u.cancel != nil {
+			u.cancel()
+		}
+		return false
+	}
+	return true
+}
+
+// start runs test, or all the tests when empty, of pkgs in the background
+// unless a run is going on; u.mu is held.
+func (u *TestUI) start(pkgs []*uiPackage, test string) {
+	if u.running {
+		u.status = "a run is going on; esc stops it"
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	u.running, u.cancel = true, cancel
+	u.queue(pkgs, test)
+	go func() {
+		defer cancel()
+		u.run(ctx, pkgs, test)
+	}()
+}
+
+// Run runs test, or all the tests when empty, of every package and returns
+// the error of the first package failing.
+func (u *TestUI) Run(ctx context.Context, test string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	u.mu.Lock()
+	if u.running {
+		u.mu.Unlock()
+		return errors.New("wasmtest: a run is going on")
+	}
+	u.running, u.cancel = true, cancel
+	pkgs := u.pkgs
+	if test != "" {
+		pkgs = nil
+		for _, pkg := range u.pkgs {
+			if pkg.index(test) >= 0 {
+				pkgs = append(pkgs, pkg)
+			}
+		}
+	}
+	u.queue(pkgs, test)
+	u.mu.Unlock()
+	return u.run(ctx, pkgs, test)
+}
+
+// queue marks the tests about to run; u.mu is held.
+func (u *TestUI) queue(pkgs []*uiPackage, test string) {
+	for _, pkg := range pkgs {
+		pkg.node.state, pkg.node.output = "queued", nil
+		for _, n := range pkg.tests {
+			if test == "" || n.name == test || strings.HasPrefix(n.name, test+"/") {
+				n.state, n.output = "queued", nil
+			}
+		}
+	}
+	u.status = ""
+	u.changed()
+}
+
+// run runs test, or all the tests, of pkgs one package after the other.
+func (u *TestUI) run(ctx context.Context, pkgs []*uiPackage, test string) error {
+	var first error
+	for _, pkg := range pkgs {
+		if ctx.Err() != nil {
+			break
+		}
+		if err := u.runPackage(ctx, pkg, test); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.running, u.cancel = false, nil
+	for _, pkg := range pkgs {
+		for _, n := range append([]*uiNode{pkg.node}, pkg.tests...) {
+			if n.state == "queued" || n.state == "run" {
+				n.state = ""
+			}
+		}
+	}
+	switch {
+	case ctx.Err() != nil:
+		u.status = "stopped"
+	case first != nil:
+		u.status = "failed"
+	default:
+		u.status = "passed"
+	}
+	u.changed()
+	return first
+}
+
+// runPackage runs test, or all the tests, of pkg, updating the tree from
+// the progress events.
+func (u *TestUI) runPackage(ctx context.Context, pkg *uiPackage, test string) error {
+	u.mu.Lock()
+	pkg.node.state = "run"
+	u.changed()
+	u.mu.Unlock()
+
+	events := func(ev ProgressEvent) {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		switch ev.Kind {
+		case EventTest:
+			te, _ := ev.Data.(TestEvent)
+			if te.Test == "" || te.parallelStep() {
+				return
+			}
+			n := pkg.test(te.Test)
+			n.state = te.Action
+			if te.Action == "run" {
+				n.output = nil
+			} else {
+				n.elapsed = te.Elapsed
+			}
+		case EventStdout, EventStderr:
+			// The framing lines repeat what the tree shows.
+			if line := strings.TrimSpace(ev.Message); strings.HasPrefix(line, "=== ") || strings.HasPrefix(line, "--- ") {
+				return
+			}
+			if ev.TestName != "" {
+				n := pkg.test(ev.TestName)
+				n.output = append(n.output, ev.Message)
+			} else {
+				pkg.node.output = append(pkg.node.output, ev.Message)
+			}
+		case EventError:
+			pkg.node.output = append(pkg.node.output, ev.Message)
+		default:
+			return
+		}
+		u.changed()
+	}
+
+	// The arguments of the UI come last: the log would garble the view.
+	opts := u.exec
+	opts.Run = testPattern(test)
+	args := append(slices.Clone(u.args), pkg.dir, func(...any) {}, Quiet, events, opts)
+	err := RunTestsContext(ctx, args...)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	pkg.node.state = "pass"
+	if err != nil {
+		pkg.node.state = "fail"
+		if ctx.Err() != nil {
+			pkg.node.state = ""
+		} else if len(pkg.node.output) == 0 {
+			pkg.node.output = strings.Split(err.Error(), "\n")
+		}
+	}
+	u.changed()
+	return err
+}
+
+// testPattern returns the -run pattern selecting only test, a test or
+// subtest name, or "" for all the tests.
+func testPattern(test string) string {
+	if test == "" {
+		return ""
+	}
+	parts := strings.Split(test, "/")
+	for i, p := range parts {
+		parts[i] = "^" + regexp.QuoteMeta(p) + "$"
+	}
+	return strings.Join(parts, "/")
+}
+
+// stateMarks are the marks of the test states in the view.
+var stateMarks = map[string]string{
+	"":       "·",
+	"queued": "…",
+	"run":    "●",
+	"pass":   "✓",
+	"fail":   "✗",
+	"skip":   "↷",
+}
+
+// Render returns the view sized for a width x height terminal; zero sizes
+// don't limit it. The selected row is kept in sight.
+func (u *TestUI) Render(width, height int) string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	rows := u.visible()
+	u.selected = max(min(u.selected, len(rows)-1), 0)
+
+	var passed, failed, skipped int
+	for _, pkg := range u.pkgs {
+		for _, n := range pkg.tests {
+			switch n.state {
+			case "pass":
+				passed++
+			case "fail":
+				failed++
+			case "skip":
+				skipped++
+			}
+		}
+	}
+	header := fmt.Sprintf("%s  %s  %s",
+		u.paint(u.Theme.Pass, fmt.Sprintf("%d passed", passed)),
+		u.paint(u.Theme.Fail, fmt.Sprintf("%d failed", failed)),
+		u.paint(u.Theme.Skip, fmt.Sprintf("%d skipped", skipped)))
+	switch {
+	case u.running:
+		header += "  running…"
+	case u.status != "":
+		header += "  " + u.status
+	}
+	lines := []string{u.paint(u.Theme.Accent, "wasmtest") + "  " + header}
+	if u.filter != "" || u.editing {
+		cursor := ""
+		if u.editing {
+			cursor = "▏"
+		}
+		lines = append(lines, "filter: "+u.filter+cursor)
+	}
+
+	var tree []string
+	for i, n := range rows {
+		name := n.pkg.label
+		if n.name != "" {
+			_, name, _ = cutLast("/"+n.name, "/")
+		}
+		line := strings.Repeat("  ", n.depth()) + u.mark(n.state) + " " + name
+		if n.elapsed > 0 && (n.state == "pass" || n.state == "fail") {
+			line += u.paint(u.Theme.Dim, fmt.Sprintf(" (%v)", n.elapsed.Round(time.Millisecond)))
+		}
+		if i == u.selected {
+			line = "> " + line
+		} else {
+			line = "  " + line
+		}
+		tree = append(tree, line)
+	}
+
+	var output []string
+	if u.output && u.selected < len(rows) {
+		n := rows[u.selected]
+		title := n.pkg.label
+		if n.name != "" {
+			title = n.name
+		}
+		output = append([]string{u.paint(u.Theme.Accent, "output of "+title)}, n.output...)
+		if len(n.output) == 0 {
+			output = append(output, u.paint(u.Theme.Dim, "(no output)"))
+		}
+	}
+	footer := u.paint(u.Theme.Dim, "↑/↓ move  enter rerun  a run all  / filter  o output  esc stop  q quit")
+
+	// The output takes up to half of the rows left by the header and the
+	// footer, keeping its last lines, and the tree the rest, scrolled to
+	// the selection.
+	if height > 0 {
+		room := height - len(lines) - 1
+		if keep := max(room/2, 2); len(output) > keep {
+			output = append(output[:1], output[len(output)-keep+1:]...)
+		}
+		room = max(room-len(output), 1)
+		if len(tree) > room {
+			start := min(max(u.selected-room/2, 0), len(tree)-room)
+			tree = tree[start : start+room]
+		}
+	}
+	lines = append(append(append(lines, tree...), output...), footer)
+	if width > 0 {
+		for i, line := range lines {
+			lines[i] = truncateLine(line, width)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (u *TestUI) mark(state string) string {
+	mark := stateMarks[state]
+	switch state {
+	case "pass":
+		return u.paint(u.Theme.Pass, mark)
+	case "fail":
+		return u.paint(u.Theme.Fail, mark)
+	case "skip":
+		return u.paint(u.Theme.Skip, mark)
+	}
+	return mark
+}
+
+func (u *TestUI) paint(color, s string) string {
+	if color == "" {
+		return s
+	}
+	return color + s + "\x1b[0m"
+}
+
+// truncateLine cuts line to width visible characters, not counting the
+// ANSI escape sequences.
+func truncateLine(line string, width int) string {
+	var b strings.Builder
+	visible := 0
+	escape := false
+	for _, r := range line {
+		switch {
+		case escape:
+			escape = r != 'm'
+		case r == '\x1b':
+			escape = true
+		default:
+			if visible == width {
+				if strings.Contains(line, "\x1b[") {
+					b.WriteString("\x1b[0m")
+				}
+				return b.String()
+			}
+			visible++
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Name returns an identifier for the handler.
+func (u *TestUI) Name() string { return "WasmTestUI" }
+
+// Label returns the label of the handler.
+func (u *TestUI) Label() string { return "Run wasm tests" }
+
+// Execute reruns all the tests, reporting the result through progress.
+func (u *TestUI) Execute(progress func(msgs ...any)) {
+	if err := u.Run(context.Background(), ""); err != nil {
+		progress("exit", "error", err.Error())
+		return
+	}
+	progress("exit", "ok")
+}
+
+// Value returns the filter.
+func (u *TestUI) Value() string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.filter
+}
+
+// Change sets the filter to newValue.
+func (u *TestUI) Change(newValue string, progress func(msgs ...any)) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.filter, u.selected = newValue, 0
+	u.changed()
+	if progress != nil {
+		progress("info", fmt.Sprintf("filter set to %q", newValue))
+	}
+}
+
+// Content returns the view without size limits.
+func (u *TestUI) Content() string { return u.Render(0, 0) }
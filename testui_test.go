@@ -0,0 +1,70 @@
+package wasmtest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTestUI(t *testing.T) {
+	node := nodeExec(t)
+	dir := writeModule(t, map[string]string{
+		"p_test.go": "//go:build js && wasm\n\npackage p\n\nimport \"testing\"\n\n" +
+			"func TestPass(t *testing.T) {}\n\nfunc TestFail(t *testing.T) { t.Fatal(\"bad\") }\n\n" +
+			"func TestSub(t *testing.T) {\n\tt.Run(\"a\", func(t *testing.T) {})\n\tt.Run(\"b\", func(t *testing.T) {})\n}\n",
+	})
+	u, err := NewTestUI([]string{dir}, ExecOptions{Args: []string{"-exec", node}}, WithInstallDisabled())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view := u.Render(0, 0); !strings.Contains(view, "  · TestPass\n") || strings.Contains(view, "✓") {
+		t.Errorf("view before running:\n%s", view)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	if err := u.Run(ctx, ""); err == nil {
+		t.Error("Run() = nil, want the failure of TestFail")
+	}
+	view := u.Render(0, 0)
+	for _, want := range []string{"1 failed", "✗ " + dir, "✓ TestPass", "✗ TestFail", "✓ TestSub", "    ✓ a", "    ✓ b", "passed  "} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view after running lacks %q:\n%s", want, view)
+		}
+	}
+
+	// Filter, select TestFail and show its output.
+	for _, key := range []string{"/", "f", "a", "i", "l", "enter", "down", "o"} {
+		u.Key(key)
+	}
+	view = u.Render(80, 10)
+	if strings.Contains(view, "TestPass") || !strings.Contains(view, ">   ✗ TestFail") || !strings.Contains(view, "output of TestFail") || !strings.Contains(view, "p_test.go:9: bad") || strings.Contains(view, "--- FAIL") {
+		t.Errorf("filtered view:\n%s", view)
+	}
+	if len(strings.Split(view, "\n")) > 10 {
+		t.Errorf("view taller than 10 lines:\n%s", view)
+	}
+
+	// Rerun a single test from the keyboard.
+	u.Change("", nil)
+	u.Key("down")
+	u.Key("enter")
+	for !strings.Contains(u.Render(0, 0), "  passed") {
+		select {
+		case <-u.Updates():
+		case <-ctx.Done():
+			t.Fatalf("rerun didn't finish:\n%s", u.Render(0, 0))
+		}
+	}
+	if view := u.Render(0, 0); !strings.Contains(view, "✗ TestFail") {
+		t.Errorf("rerunning TestPass reset the other tests:\n%s", view)
+	}
+	if u.Key("q") {
+		t.Error(`Key("q") = true, want false`)
+	}
+
+	if got := testPattern("TestSub/a.b"); got != `^TestSub$/^a\.b$` {
+		t.Errorf("testPattern = %q", got)
+	}
+}
@@ -0,0 +1,96 @@
+package wasmtest
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// WithTinyGo makes go test runs compile the tests with `tinygo test -target
+// target` instead of the standard toolchain, for code built with TinyGo;
+// an empty target is "wasm". TinyGo runs the test binaries itself (under
+// node for wasm, wasmtime for wasip1), so the backend, browser and -exec
+// settings don't apply, and neither the compile stats nor the test list are
+// reported. tinygo is looked up in PATH, then in $TINYGOROOT/bin and the
+// default install directory. Its go test -v output is translated into the
+// usual "out" and "test" progress messages.
+func WithTinyGo(target string) Option {
+	return func(w *Wasmtest) {
+		if target == "" {
+			target = "wasm"
+		}
+		w.tinyGoTarget = target
+	}
+}
+
+// tinyGoInstallDirs are the directories the TinyGo packages install tinygo
+// to, by GOOS.
+var tinyGoInstallDirs = map[string]string{
+	"darwin":  "/usr/local/tinygo/bin",
+	"linux":   "/usr/local/tinygo/bin",
+	"windows": `C:\tinygo\bin`,
+}
+
+// findTinyGo returns the tinygo executable.
+func findTinyGo() (string, error) {
+	if path, err := exec.LookPath("tinygo"); err == nil {
+		return path, nil
+	}
+	name := "tinygo"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	for _, dir := range []string{os.Getenv("TINYGOROOT"), tinyGoInstallDirs[runtime.GOOS]} {
+		if dir == "" {
+			continue
+		}
+		if filepath.Base(dir) != "bin" {
+			dir = filepath.Join(dir, "bin")
+		}
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("wasmtest: tinygo not found in PATH, $TINYGOROOT/bin or %s", tinyGoInstallDirs[runtime.GOOS])
+}
+
+// tinyGoArgs returns the command line of the tinygo run of spec.
+func (w *Wasmtest) tinyGoArgs(spec execSpec) []string {
+	return append([]string{"test", "-target", w.tinyGoTarget, "-v"}, spec.args...)
+}
+
+var (
+	tinyGoRunLine    = regexp.MustCompile(`^=== RUN\s+(\S+)`)
+	tinyGoResultLine = regexp.MustCompile(`^\s*--- (PASS|FAIL|SKIP): (\S+) \(([0-9.]+)s\)`)
+)
+
+// tinyGoDecoder converts the go test -v output of tinygo test into the
+// progress messages of test2jsonDecoder: every line is an "out" line, and
+// the === RUN and --- PASS/FAIL/SKIP lines also give "test" messages.
+type tinyGoDecoder struct{}
+
+// decode returns the progress messages of one stdout line.
+func (tinyGoDecoder) decode(line string) [][]any {
+	if m := tinyGoRunLine.FindStringSubmatch(line); m != nil {
+		return [][]any{{"test", TestEvent{Time: time.Now(), Action: "run", Test: m[1]}}, {"out", line}}
+	}
+	if m := tinyGoResultLine.FindStringSubmatch(line); m != nil {
+		seconds, _ := strconv.ParseFloat(m[3], 64)
+		return [][]any{{"out", line}, {"test", TestEvent{
+			Time:    time.Now(),
+			Action:  map[string]string{"PASS": "pass", "FAIL": "fail", "SKIP": "skip"}[m[1]],
+			Test:    m[2],
+			Elapsed: time.Duration(seconds * float64(time.Second)),
+		}}}
+	}
+	return [][]any{{"out", line}}
+}
+
+// flush implements lineDecoder; tinygo output is read by whole lines.
+func (tinyGoDecoder) flush() [][]any { return nil }
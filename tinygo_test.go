@@ -0,0 +1,83 @@
+package wasmtest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTinyGoDecoder(t *testing.T) {
+	var dec tinyGoDecoder
+	var events []string
+	var out []string
+	for _, line := range []string{"=== RUN   TestA", "--- PASS: TestA (0.25s)", "=== RUN   TestB/sub", "    --- FAIL: TestB/sub (0.00s)", "        b_test.go:9: bad", "FAIL"} {
+		for _, msg := range dec.decode(line) {
+			switch msg[0] {
+			case "out":
+				out = append(out, msg[1].(string))
+			case "test":
+				ev := msg[1].(TestEvent)
+				events = append(events, ev.Action+" "+ev.Test)
+				if ev.Test == "TestA" && ev.Action == "pass" && ev.Elapsed != 250*time.Millisecond {
+					t.Errorf("TestA elapsed = %v", ev.Elapsed)
+				}
+			}
+		}
+	}
+	if want := "run TestA,pass TestA,run TestB/sub,fail TestB/sub"; strings.Join(events, ",") != want {
+		t.Errorf("events = %q; want %q", events, want)
+	}
+	if len(out) != 6 {
+		t.Errorf("out lines = %q", out)
+	}
+}
+
+func TestFindTinyGo(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	root := t.TempDir()
+	t.Setenv("TINYGOROOT", root)
+	tinyGoInstallDirs = map[string]string{}
+	defer func(dirs map[string]string) { tinyGoInstallDirs = dirs }(tinyGoInstallDirs)
+	if _, err := findTinyGo(); err == nil {
+		t.Errorf("findTinyGo found a tinygo in an empty PATH")
+	}
+	path := filepath.Join(root, "bin", "tinygo")
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	os.WriteFile(path, nil, 0o755)
+	if got, err := findTinyGo(); got != path {
+		t.Errorf("findTinyGo() = %q, %v; want %q", got, err, path)
+	}
+}
+
+func TestExecuteTinyGo(t *testing.T) {
+	dir := t.TempDir()
+	record := filepath.Join(dir, "args")
+	fake := "#!/bin/sh\necho \"$@\" > " + record + "\n" +
+		"echo '=== RUN   TestPass'\necho '--- PASS: TestPass (0.01s)'\necho '=== RUN   TestFail'\necho '    p_test.go:7: bad'\necho '--- FAIL: TestFail (0.00s)'\necho FAIL\nexit 1\n"
+	if err := os.WriteFile(filepath.Join(dir, "tinygo"), []byte(fake), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	w := New(WithInstallDisabled(), WithTinyGo(""), WithBackend(BackendNode))
+	progress, msgs := collectProgress()
+	err := w.execute(t.Context(), execSpec{dir: writeModule(t, map[string]string{"p_test.go": wasmPassTest}), args: []string{"-run", "Test"}, list: true}, progress)
+	if err == nil {
+		t.Errorf("the failing TinyGo run succeeded")
+	}
+	args, _ := os.ReadFile(record)
+	if got := strings.TrimSpace(string(args)); got != "test -target wasm -v -run Test" {
+		t.Errorf("tinygo arguments = %q", got)
+	}
+	var events []string
+	for _, msg := range msgs() {
+		if strings.HasPrefix(msg, "test ") || strings.HasPrefix(msg, "list ") || strings.HasPrefix(msg, "compile ") {
+			events = append(events, msg)
+		}
+	}
+	if len(events) != 4 || !strings.Contains(strings.Join(events, "\n"), "fail TestFail") {
+		t.Errorf("progress events:\n%s", strings.Join(msgs(), "\n"))
+	}
+}
@@ -0,0 +1,73 @@
+package wasmtest
+
+import (
+	"cmp"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// goToolchain is the Go installation running the go commands of wasmtest
+// (see WithGoToolchain). The zero value uses the go found in PATH.
+type goToolchain struct {
+	// bin is the go executable, root its GOROOT when known.
+	bin, root string
+}
+
+// newGoToolchain returns the toolchain of path, a go executable, looked up
+// in PATH when it is a bare name, or the GOROOT directory of a Go
+// installation.
+func newGoToolchain(path string) goToolchain {
+	exe := ""
+	if runtime.GOOS == "windows" {
+		exe = ".exe"
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path, _ = filepath.Abs(path)
+		return goToolchain{bin: filepath.Join(path, "bin", "go"+exe), root: path}
+	}
+	if !strings.ContainsRune(path, filepath.Separator) && !strings.ContainsRune(path, '/') {
+		if found, err := exec.LookPath(path); err == nil {
+			path = found
+		}
+	}
+	// The go commands run in the test directories.
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	tc := goToolchain{bin: path}
+	// A go executable of an installation lives in GOROOT/bin, e.g.
+	// /usr/bin/go links to /usr/lib/go/bin/go.
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		root := filepath.Dir(filepath.Dir(real))
+		if _, err := os.Stat(filepath.Join(root, "pkg", "tool")); err == nil {
+			tc.root = root
+		}
+	}
+	return tc
+}
+
+// command returns the go executable to run.
+func (tc goToolchain) command() string {
+	return cmp.Or(tc.bin, "go")
+}
+
+// env returns the KEY=VALUE entries making the programs started by the go
+// commands use the toolchain too: its directory comes first in PATH, for
+// go_js_wasm_exec and wasmbrowsertest looking up go, GOROOT is set to its
+// installation, where wasm_exec.js is found, and GOTOOLCHAIN=local keeps
+// the go command from switching to the release a go.mod asks for. The
+// zero value returns none.
+func (tc goToolchain) env() []string {
+	if tc.bin == "" {
+		return nil
+	}
+	// An empty GOROOT clears an inherited one, go then finds its own.
+	return []string{
+		"PATH=" + filepath.Dir(tc.bin) + string(os.PathListSeparator) + os.Getenv("PATH"),
+		"GOROOT=" + tc.root,
+		"GOTOOLCHAIN=local",
+	}
+}
@@ -0,0 +1,63 @@
+package wasmtest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestGoToolchain(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake go is a shell script")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go not in PATH")
+	}
+	out, err := exec.Command(goBin, "env", "GOROOT").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	goroot := strings.TrimSpace(string(out))
+	if tc := newGoToolchain(filepath.Join(goroot, "bin", "go")); tc.root == "" {
+		t.Errorf("GOROOT of %s not found: %+v", goroot, tc)
+	}
+
+	// The fake installation records how its go is run and hands over to
+	// the real one.
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "bin"), 0o755)
+	calls := filepath.Join(root, "calls")
+	script := `#!/bin/sh
+echo "$1 GOROOT=$GOROOT GOTOOLCHAIN=$GOTOOLCHAIN PATH=$PATH" >> "` + calls + `"
+GOROOT="` + goroot + `" exec "` + goBin + `" "$@"
+`
+	if err := os.WriteFile(filepath.Join(root, "bin", "go"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOROOT", "/elsewhere")
+	if tc := newGoToolchain(root); tc.bin != filepath.Join(root, "bin", "go") || tc.root != root {
+		t.Errorf("newGoToolchain(%s) = %+v", root, tc)
+	}
+
+	w := New(WithInstallDisabled(), WithGoToolchain(root), WithLogger(func(...any) {}))
+	if _, err := w.Bundle(t.Context(), "./example", t.TempDir()); err != nil {
+		t.Fatalf("Bundle failed: %v", err)
+	}
+	data, _ := os.ReadFile(calls)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var commands []string
+	for _, line := range lines {
+		commands = append(commands, strings.Fields(line)[0])
+		want := "GOROOT=" + root + " GOTOOLCHAIN=local PATH=" + filepath.Join(root, "bin") + string(os.PathListSeparator)
+		if !strings.Contains(line, want) {
+			t.Errorf("go run with %q, want %q", line, want)
+		}
+	}
+	if got := strings.Join(commands, " "); got != "test list env" {
+		t.Errorf("go commands run: %q", got)
+	}
+}
@@ -2,10 +2,18 @@ package wasmtest
 
 import (
 	"bufio"
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -23,70 +31,605 @@ func (w *Wasmtest) Execute(progress func(msgs ...any)) {
 		return
 	}
 
-	// Ensure go_js_wasm_exec is available for WASM test execution
-	if err := w.ensureWasmExecSymlink(progress); err != nil {
-		progress("error", "failed to setup WASM executor:", err)
-		return
+	_ = w.ExecuteWithOptions(ExecOptions{}, progress)
+}
+
+// execSpec describes a single `go test` invocation performed by execute.
+// The zero value runs the documented command in the current directory.
+type execSpec struct {
+	// dir is the working directory of the go test process; empty means the
+	// current process directory.
+	dir string
+	// exec is the program passed to `go test -exec`; empty relies on
+	// go_js_wasm_exec being resolvable from PATH.
+	exec string
+	// env holds extra KEY=VALUE entries overriding the inherited ones.
+	env []string
+	// args holds extra go test flags appended after -json.
+	args []string
+	// testArgs are passed to the test binary untouched, after -args.
+	testArgs []string
+	// tags are the extra build tags, also passed as -tags in args.
+	tags []string
+	// native builds and runs the tests for the host platform instead of
+	// js/wasm; exec is ignored.
+	native bool
+	// backend runs the test binaries (see WithBackend); execute resolves
+	// BackendAuto.
+	backend string
+	// target is the GOOS/GOARCH of the build, TargetJS or TargetWASIP1;
+	// empty is TargetJS. execute resolves it with runTarget.
+	target string
+	// binary is the prebuilt test binary run instead of compiling the
+	// tests (see RunBinary); args are then test binary flags.
+	binary *testBinary
+	// tinyGo is the tinygo executable compiling and running the tests
+	// instead of go test (see WithTinyGo); exec is ignored.
+	tinyGo string
+	// list reports the top level tests about to run, from go test -list,
+	// as a "list" progress message before running them.
+	list bool
+	// toolchain runs the go commands (see WithGoToolchain).
+	toolchain goToolchain
+}
+
+// environ returns the environment of the go commands run for spec. See
+// childEnv for how it is composed; the entries of spec.toolchain come
+// before spec.env, which overrides them.
+func (spec execSpec) environ() []string {
+	env := append(spec.toolchain.env(), spec.env...)
+	if spec.native {
+		return childEnv(os.Environ(), runtime.GOOS, runtime.GOARCH, env)
+	}
+	if spec.target == TargetWASIP1 {
+		return childEnv(os.Environ(), "wasip1", "wasm", env)
+	}
+	return childEnv(os.Environ(), "js", "wasm", env)
+}
+
+// execute runs `GOOS=js GOARCH=wasm go test -json` (or a native go test
+// when spec.native is set) as described by spec, streaming output through
+// progress: the test output as "out" lines, as with go test -v, and the
+// state changes of each test as "test" messages carrying a TestEvent.
+// Calls to progress are serialized so callers don't need their own
+// locking. The returned error is the one reported by the go test process
+// (nil on success).
+func (w *Wasmtest) execute(ctx context.Context, spec execSpec, progress func(msgs ...any)) error {
+	var mu sync.Mutex
+	var tracker testTracker
+	report := func(msgs ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		progress(msgs...)
+		if w.logFile != nil {
+			w.logFile.logProgress(spec.dir, msgs...)
+		}
+		if w.slog != nil {
+			w.slogProgress(&tracker, spec.dir, msgs...)
+		}
+	}
+	if w.optionErr != nil {
+		report("error", w.optionErr)
+		return w.optionErr
+	}
+
+	if !spec.native {
+		if err := w.prepareWasm(&spec, report); err != nil {
+			return err
+		}
+		remove, err := w.prepareRunner(ctx, &spec, report)
+		if err != nil {
+			return err
+		}
+		defer remove()
+	}
+	cpuProfile, cpuProfileArgs, remove, err := w.prepareCPUProfile(spec, report)
+	if err != nil {
+		return err
+	}
+	defer remove()
+	if err := precheckSpec(spec, report); err != nil {
+		return err
 	}
 
-	// create a background context with a short timeout for UI operations
-	ctx, cancel := contextWithTimeout(10 * time.Minute)
-	defer cancel()
+	cached := w.compileTests(ctx, spec, report)
 
-	// Run the documented command: GOOS=js GOARCH=wasm go test -v
-	cmd := exec.CommandContext(ctx, "go", "test", "-v")
-	// Set environment variables for the command copied from the parent's env
-	env := os.Environ()
-	// ensure GOOS and GOARCH are set to js/wasm
-	env = append(env, "GOOS=js", "GOARCH=wasm")
-	cmd.Env = env
+	// The test list lets callers show the progress of the run. Without it
+	// the run goes on, only the total is unknown.
+	if spec.list && spec.tinyGo == "" && spec.binary == nil {
+		names, err := w.listTests(ctx, spec)
+		if err == nil {
+			report("list", names)
+		} else {
+			w.debugf(report, "listing the tests failed: %v", err)
+		}
+	}
+	if cached != nil {
+		spec.binary = cached
+		spec.args = testFlagsOf(spec.args)
+	}
+
+	// The builtin backend runs a test binary of its own, with the go test
+	// flags as test binary flags.
+	if spec.builtin() && spec.binary == nil {
+		binary, remove, err := w.compileBuiltin(ctx, spec, report)
+		if err != nil {
+			report("error", err.Error())
+			report("exit", "error", err.Error())
+			return err
+		}
+		defer remove()
+		spec.binary = binary
+		spec.args = testFlagsOf(spec.args)
+	}
+
+	spec.args = slices.Concat(spec.args, cpuProfileArgs)
+	return w.runRetrying(ctx, spec, cpuProfile, report)
+}
+
+// prepareWasm completes the spec of a js/wasm or wasip1 run: it selects
+// the backend and the target, sets the environment of the options, drops
+// -race and looks TinyGo up. It rejects the options only RunBundle
+// honours.
+func (w *Wasmtest) prepareWasm(spec *execSpec, report func(msgs ...any)) error {
+	spec.backend = w.backend
+	if spec.backend == BackendAuto && spec.exec == "" && w.tinyGoTarget == "" {
+		backend, fallback, err := w.autoBackend(*spec)
+		if err != nil {
+			report("error", "backend selection failed:", err)
+			return err
+		}
+		spec.backend = backend
+		if fallback != nil {
+			report("warning", *fallback)
+		} else {
+			report("info", "auto backend: running the tests in a browser")
+		}
+	}
+	target, err := w.runTarget(*spec)
+	if err != nil {
+		report("error", "target selection failed:", err)
+		return err
+	}
+	spec.target = target
+
+	// Explicit env entries of the spec take precedence over the option.
+	if w.goWasm != "" {
+		spec.env = append([]string{"GOWASM=" + w.goWasm}, spec.env...)
+	}
+	if w.headful {
+		spec.env = append([]string{"WASM_HEADLESS=off"}, spec.env...)
+	}
+
+	if names := w.bundleOnlyOptions(); len(names) > 0 {
+		err := newRunError(ErrUsage, "❌💥 OPTION ERROR: %s only work with RunBundle\n💡 wasmbrowsertest serves its own page to go test runs; run the tests as a bundle (see Bundle)", strings.Join(names, ", "))
+		report("error", err)
+		return err
+	}
+
+	// -race can't be built for js/wasm: drop it and explain why.
+	if raceRequested(spec.args, append(os.Environ(), spec.env...)) {
+		report("warning", raceWarning)
+		spec.args = withoutRace(spec.args)
+	}
+
+	// TinyGo runs the test binaries itself.
+	if w.tinyGoTarget != "" && spec.binary == nil {
+		tinyGo, err := findTinyGo()
+		if err != nil {
+			report("error", "TinyGo setup failed:", err)
+			return err
+		}
+		spec.tinyGo = tinyGo
+		report("info", "compiling the tests with "+tinyGo+" -target "+w.tinyGoTarget)
+	}
+	return nil
+}
+
+// prepareRunner sets up what runs the test binaries of a js/wasm or wasip1
+// spec: the exec program of the backend, or go_js_wasm_exec and the
+// selected browser. The returned function removes what was set up.
+func (w *Wasmtest) prepareRunner(ctx context.Context, spec *execSpec, report func(msgs ...any)) (func(), error) {
+	var removes []func()
+	remove := func() {
+		for _, rm := range slices.Backward(removes) {
+			rm()
+		}
+	}
+
+	// Other backends replace wasmbrowsertest with their own exec program.
+	if spec.exec == "" && spec.tinyGo == "" {
+		rm, err := w.setupBackend(ctx, spec)
+		if err != nil {
+			report("error", "backend setup failed:", err)
+			return nil, err
+		}
+		removes = append(removes, rm)
+		if spec.exec != "" || spec.backend == BackendDocker {
+			backend := spec.backend
+			if !wasiBackend(backend) && spec.target == TargetWASIP1 {
+				backend = BackendWasmtime
+			}
+			report("info", "running the "+spec.target+" tests with the "+backend+" backend")
+			w.debugf(report, "%s backend: -exec %q", backend, spec.exec)
+		}
+	}
+
+	if spec.exec == "" && spec.tinyGo == "" && !spec.builtin() {
+		if err := w.checkWasmExec(ctx, spec, report); err != nil {
+			remove()
+			return nil, err
+		}
+	}
+
+	// wasmbrowsertest has no browser options: the selected browser, with
+	// its flags, is put first on its PATH. Other exec programs don't run a
+	// browser.
+	if spec.tinyGo == "" && spec.backend != BackendDocker && !spec.builtin() && (w.browser != "" || len(w.browserFlags) > 0) && (spec.exec == "" || strings.Contains(filepath.Base(spec.exec), "wasmbrowsertest")) {
+		browser, err := lookupBrowser(w.browser)
+		if err == nil {
+			var entry string
+			var rm func()
+			if entry, rm, err = browserShim(browser, w.browserFlags, lookupEnv(spec.environ(), "PATH")); err == nil {
+				removes = append(removes, rm)
+				spec.env = append(spec.env, entry)
+			}
+		}
+		if err != nil {
+			report("error", "browser selection failed:", err)
+			remove()
+			return nil, err
+		}
+		report("info", "using browser "+browser)
+		w.debugf(report, "linked %s as %s first on PATH, with flags %q", browser, browserShimName(), w.browserFlags)
+	}
+	return remove, nil
+}
+
+// checkWasmExec ensures go_js_wasm_exec is available to go test, and
+// reports a wasmbrowsertest that go test won't find or that doesn't match
+// the pinned version or the toolchain.
+func (w *Wasmtest) checkWasmExec(ctx context.Context, spec *execSpec, report func(msgs ...any)) error {
+	if err := w.ensureWasmExecSymlink(report); err != nil {
+		report("error", "failed to setup WASM executor:", err)
+		return err
+	}
+	// go_js_wasm_exec was linked in the install directory, which go
+	// test may not find on its PATH.
+	if dirs := filepath.SplitList(lookupEnv(spec.environ(), "PATH")); !runnerFound(dirs) {
+		if bin, err := w.installBinDir(ctx); err == nil && !inPath(dirs, bin) && runnerFound([]string{bin}) {
+			if w.pathFix {
+				spec.env = append(spec.env, "PATH="+lookupEnv(spec.environ(), "PATH")+string(os.PathListSeparator)+bin)
+				report("info", "added "+bin+" to the PATH of go test")
+			} else {
+				report("warning", installDirWarning(bin))
+			}
+		}
+	}
+	dirs := filepath.SplitList(lookupEnv(spec.environ(), "PATH"))
+	if path, stale := lookupRunner(dirs); w.offline && (path == "" || stale) {
+		err := newRunError(ErrRunnerMissing, "❌💥 RUNNER MISSING: go_js_wasm_exec was not found in PATH and offline mode (WithNoInstall) never installs it\n💡 Install wasmbrowsertest ahead of time with go install %s@%s and put it in PATH as go_js_wasm_exec", wasmBrowserTestModule, cmp.Or(w.wasmBrowserTestVersion, "latest"))
+		report("error", err)
+		return err
+	}
+	if warn := w.wasmBrowserTestMismatch(); warn != nil {
+		report("warning", *warn)
+	}
+	if path, _ := lookupRunner(dirs); path != "" {
+		if warn := w.runnerOutdated(ctx, *spec, path); warn != nil {
+			report("warning", *warn)
+		}
+	}
+	if warn := verifyRunner(dirs, wasmBrowserTestModule); warn != nil {
+		report("warning", *warn)
+	}
+	return nil
+}
+
+// prepareCPUProfile returns the path of the CPU profile of the run and the
+// go test flags writing it when WithCPUProfile is set. wasmbrowsertest
+// profiles the page when the test binary gets -test.cpuprofile. go test
+// then keeps the binary next to the profile unless -o puts it elsewhere.
+// Only the run itself is profiled. The returned function removes the
+// temporary files.
+func (w *Wasmtest) prepareCPUProfile(spec execSpec, report func(msgs ...any)) (string, []string, func(), error) {
+	if spec.native || !w.cpuProfile {
+		return "", nil, func() {}, nil
+	}
+	if spec.tinyGo != "" || spec.binary != nil || spec.builtin() || spec.exec != "" && !strings.Contains(filepath.Base(spec.exec), "wasmbrowsertest") {
+		report("warning", cpuProfileUnsupported)
+		return "", nil, func() {}, nil
+	}
+	profile, err := w.cpuProfilePath(dirProfileName(spec.dir))
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(profile), 0o755)
+	}
+	var tmp string
+	if err == nil {
+		tmp, err = makeTempDir("wasmtest-cpuprofile-")
+	}
+	if err != nil {
+		report("error", "failed to prepare the CPU profile:", err)
+		return "", nil, nil, err
+	}
+	args := []string{"-cpuprofile", profile, "-o", filepath.Join(tmp, "wasm.test")}
+	return profile, args, func() { os.RemoveAll(tmp) }, nil
+}
+
+// precheckSpec catches syscall/js misuse before spending a compile cycle on
+// it. A native build can't succeed when a host file imports syscall/js.
+// The wasip1 builds don't involve syscall/js.
+func precheckSpec(spec execSpec, report func(msgs ...any)) error {
+	warnings, err := Precheck(spec.dir, spec.tags...)
+	if err != nil || spec.target == TargetWASIP1 || spec.binary != nil {
+		return nil
+	}
+	for _, warn := range warnings {
+		if !spec.native {
+			report("warning", warn)
+		} else if warn.Code == "syscall-js-native" {
+			report("error", warn)
+			return errors.New(warn.Message)
+		}
+	}
+	return nil
+}
+
+// compileTests compiles the tests of spec first so build time and cache
+// usage can be reported on their own. A failed compilation is not fatal
+// here: go test reports the build errors in its usual format. With
+// WithBinaryCache, unchanged build inputs skip the compilation; the
+// returned binary, if any, is the cached one to run instead.
+func (w *Wasmtest) compileTests(ctx context.Context, spec execSpec, report func(msgs ...any)) *testBinary {
+	if spec.tinyGo != "" || spec.binary != nil {
+		return nil
+	}
+	start := time.Now()
+	entry, err := w.binaryCacheEntry(ctx, spec)
+	if err != nil {
+		w.debugf(report, "binary cache key failed: %v", err)
+	}
+	if cached := cachedBinary(entry); cached != nil {
+		report("compile", CompileStats{Package: cached.pkg, Duration: time.Since(start), Cached: true, Reused: true, GoWasm: lookupEnv(spec.environ(), "GOWASM")})
+		w.debugf(report, "running the test binary cached in %s", cached.path)
+		return cached
+	}
+	stats, err := w.compile(ctx, spec, entry)
+	if err == nil || errors.Is(err, errBinaryCache) {
+		report("compile", stats)
+	}
+	switch {
+	case stats.Binary != "":
+		report("info", "test binary kept in "+stats.Binary)
+	case errors.Is(err, errKeepBinary):
+		report("warning", Warning{
+			Code:    "keep-binary-failed",
+			Message: err.Error(),
+			Hint:    "check that the WithKeepBinary directory is writable",
+		})
+	case errors.Is(err, errBinaryCache):
+		report("warning", Warning{
+			Code:    "binary-cache-failed",
+			Message: err.Error(),
+			Hint:    "check that the WithBinaryCache directory is writable",
+		})
+	}
+	if err != nil {
+		return nil
+	}
+	return cachedBinary(entry)
+}
+
+// runRetrying runs the documented command, GOOS=js GOARCH=wasm go test
+// -json, and reports its exit. Browser launch failures known to be
+// transient are retried: no test ran yet. cpuProfile is the CPU profile the
+// run writes, if any.
+func (w *Wasmtest) runRetrying(ctx context.Context, spec execSpec, cpuProfile string, report func(msgs ...any)) error {
+	for attempt := 1; ; attempt++ {
+		retry := attempt <= w.launchRetries
+		launchErrs, err := w.run(ctx, spec, report, retry)
+		if err != nil && len(launchErrs) > 0 && retry && ctx.Err() == nil {
+			report("warning", Warning{
+				Code:    "browser-launch-retry",
+				Message: fmt.Sprintf("the browser failed to start (%s); retrying (%d/%d)", launchErrs[0], attempt, w.launchRetries),
+				Hint:    "WithLaunchRetries sets the number of retries",
+			})
+			continue
+		}
+		for _, line := range launchErrs {
+			report("err", line)
+		}
+		if cpuProfile != "" {
+			reportCPUProfile(cpuProfile, report)
+		}
+		if err != nil {
+			report("exit", "error", err.Error())
+			return err
+		}
+		report("exit", "ok")
+		return nil
+	}
+}
+
+// run starts go test once for spec and streams its output to report. When
+// holdLaunchErrors is set, stderr lines reporting a transient browser launch
+// failure written before any test started are returned instead of being
+// reported, so the caller can retry without surfacing them.
+func (w *Wasmtest) run(ctx context.Context, spec execSpec, report func(msgs ...any), holdLaunchErrors bool) ([]string, error) {
+	name, args := spec.toolchain.command(), []string{"test", "-json"}
+	if !spec.native && spec.exec != "" {
+		args = append(args, "-exec", spec.exec)
+	}
+	args = append(args, spec.args...)
+	if len(spec.testArgs) > 0 {
+		args = slices.Concat(args, []string{"-args"}, spec.testArgs)
+	}
+	// tinygo has no -json: its go test -v output is translated.
+	newDecoder := func() lineDecoder { return &test2jsonDecoder{} }
+	if !spec.native && spec.tinyGo != "" {
+		name, args = spec.tinyGo, w.tinyGoArgs(spec)
+		newDecoder = func() lineDecoder { return tinyGoDecoder{} }
+	}
+	// test2json runs a prebuilt binary with the exec program as go test
+	// does, go_js_wasm_exec from PATH by default.
+	if !spec.native && spec.binary != nil {
+		command := []string{"go_js_wasm_exec"}
+		if spec.exec != "" {
+			command = splitExecArgs(spec.exec)
+		}
+		args = slices.Concat([]string{"tool", "test2json", "-t", "-p", spec.binary.pkg}, command, []string{spec.binary.path, "-test.v=test2json", "-test.paniconexit0"}, spec.args, spec.testArgs)
+	}
+	// The builtin backend runs the binary itself: test2json converts the
+	// output it relays.
+	if spec.builtin() {
+		args = []string{"tool", "test2json", "-t", "-p", spec.binary.pkg}
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = spec.dir
+	cmd.Env = spec.environ()
+	killProcessTree(cmd)
+	w.debugf(report, "running %s %s in %q", filepath.Base(name), strings.Join(args, " "), cmd.Dir)
+	w.debugf(report, "environment: GOOS=%s GOARCH=%s GOFLAGS=%q, extra entries %q", lookupEnv(cmd.Env, "GOOS"), lookupEnv(cmd.Env, "GOARCH"), lookupEnv(cmd.Env, "GOFLAGS"), spec.env)
+	w.mu.Lock()
+	hook := w.envHook
+	w.mu.Unlock()
+	if hook != nil {
+		hook(cmd.Dir, cmd.Env)
+	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		progress("error", "stdout pipe error:", err)
-		return
+		report("error", "stdout pipe error:", err)
+		return nil, err
 	}
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		progress("error", "stderr pipe error:", err)
-		return
+		report("error", "stderr pipe error:", err)
+		return nil, err
+	}
+
+	var stdin io.WriteCloser
+	if spec.builtin() {
+		if stdin, err = cmd.StdinPipe(); err != nil {
+			report("error", "stdin pipe error:", err)
+			return nil, err
+		}
 	}
 
 	if err := cmd.Start(); err != nil {
-		progress("error", "failed to start go test:", err)
-		return
+		report("error", "failed to start "+filepath.Base(name)+" test:", err)
+		return nil, err
+	}
+
+	// browser receives the outcome of the builtin backend run.
+	type browserRun struct {
+		launchErrs []string
+		err        error
+	}
+	browser := make(chan browserRun, 1)
+	if spec.builtin() {
+		go func() {
+			launchErrs, err := w.runInBrowser(ctx, spec, stdin, report, holdLaunchErrors)
+			stdin.Close()
+			browser <- browserRun{launchErrs, err}
+		}()
 	}
 
 	// stream stdout and stderr lines to progress
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var started bool
+	var held []string
+	emit := func(msg []any) {
+		var line string
+		switch v := msg[1].(type) {
+		case string:
+			line = v
+		case OutputLine:
+			line = v.Line
+		default:
+			report(msg...)
+			return
+		}
+		mu.Lock()
+		started = started || strings.HasPrefix(line, "=== RUN")
+		hold := holdLaunchErrors && !started && isTransientLaunchError(line)
+		if hold {
+			held = append(held, line)
+		}
+		mu.Unlock()
+		if !hold {
+			report(msg...)
+		}
+		if seed, ok := shuffleSeed(line); ok {
+			report("info", fmt.Sprintf("tests shuffled with seed %s; replay this order with -shuffle=%s", seed, seed))
+		}
+	}
+	// stdout carries the test2json records of go test -json.
 	stream := func(r *bufio.Reader, tag string) {
+		defer wg.Done()
+		dec := newDecoder()
 		for {
 			line, err := r.ReadString('\n')
 			if line != "" {
-				progress(tag, strings.TrimRight(line, "\n"))
+				line = strings.TrimRight(line, "\n")
+				msgs := [][]any{{tag, line}}
+				if tag == "out" {
+					msgs = dec.decode(line)
+				}
+				for _, msg := range msgs {
+					emit(msg)
+				}
 			}
 			if err != nil {
+				for _, msg := range dec.flush() {
+					emit(msg)
+				}
 				return
 			}
 		}
 	}
 
+	wg.Add(2)
 	go stream(bufio.NewReader(stdout), "out")
 	go stream(bufio.NewReader(stderr), "err")
 
-	if err := cmd.Wait(); err != nil {
-		progress("exit", "error", err.Error())
-		return
+	// A canceled run must not hang on pipes still held open by processes
+	// that survived the kill.
+	drained := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			stdout.Close()
+			stderr.Close()
+		case <-drained:
+		}
+	}()
+
+	// Wait must not be called before the pipes are fully drained.
+	wg.Wait()
+	close(drained)
+	err = cmd.Wait()
+	if spec.builtin() {
+		run := <-browser
+		if run.err != nil {
+			return append(held, run.launchErrs...), run.err
+		}
 	}
-	progress("exit", "ok")
+	return held, err
 }
 
 // GetLastOperationID implements MessageTracker.
 func (w *Wasmtest) GetLastOperationID() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	return w.lastOpID
 }
 
 // SetLastOperationID implements MessageTracker.
 func (w *Wasmtest) SetLastOperationID(id string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	w.lastOpID = id
 }
 
@@ -97,33 +640,49 @@ func contextWithTimeout(d time.Duration) (context.Context, context.CancelFunc) {
 }
 
 // ensureWasmExecSymlink ensures that go_js_wasm_exec exists for WASM test execution.
-// It checks if go_js_wasm_exec exists, and if not, creates a symlink to wasmbrowsertest.
+// It checks if go_js_wasm_exec exists in the install directory (see
+// installBinDir), and if not, creates a symlink to wasmbrowsertest there.
 func (w *Wasmtest) ensureWasmExecSymlink(progress func(msgs ...any)) error {
-	// First check if go_js_wasm_exec already exists
-	gopath := os.Getenv("GOPATH")
-	if gopath == "" {
-		if cmd := exec.Command("go", "env", "GOPATH"); cmd != nil {
-			if output, err := cmd.Output(); err == nil {
-				gopath = strings.TrimSpace(string(output))
-			}
-		}
-	}
+	w.setupMu.Lock()
+	defer w.setupMu.Unlock()
 
-	if gopath == "" {
-		return nil // Skip if we can't determine GOPATH
+	bin, err := w.installBinDir(context.Background())
+	if err != nil {
+		w.debugf(progress, "install directory unknown (%v); skipping the go_js_wasm_exec setup", err)
+		return nil // Skip if we can't determine where wasmbrowsertest is
 	}
 
-	goWasmExec := gopath + "/bin/go_js_wasm_exec"
-	wasmBrowserTest := gopath + "/bin/wasmbrowsertest"
+	// Symlinks need elevated privileges or the developer mode on Windows:
+	// wasmbrowsertest is copied instead.
+	return w.linkWasmExec(bin, runtime.GOOS == "windows", progress)
+}
+
+// linkWasmExec makes the go_js_wasm_exec of bin run its wasmbrowsertest,
+// through a symlink, or a copy when copied is set. A copy older than
+// wasmbrowsertest, left by a previous version, is replaced.
+func (w *Wasmtest) linkWasmExec(bin string, copied bool, progress func(msgs ...any)) error {
+	goWasmExec := filepath.Join(bin, "go_js_wasm_exec")
+	wasmBrowserTest := filepath.Join(bin, "wasmbrowsertest")
+	if copied {
+		goWasmExec += ".exe"
+		wasmBrowserTest += ".exe"
+	}
 
 	// Check if go_js_wasm_exec already exists
-	if _, err := os.Stat(goWasmExec); err == nil {
-		progress("info", "go_js_wasm_exec already exists")
-		return nil
+	if info, err := os.Stat(goWasmExec); err == nil {
+		if src, err := os.Stat(wasmBrowserTest); !copied || err != nil || !src.ModTime().After(info.ModTime()) {
+			w.debugf(progress, "%s exists; no symlink needed", goWasmExec)
+			progress("info", "go_js_wasm_exec already exists")
+			return nil
+		}
 	}
 
 	// Check if wasmbrowsertest exists
 	if _, err := os.Stat(wasmBrowserTest); os.IsNotExist(err) {
+		w.debugf(progress, "neither %s nor %s exist", goWasmExec, wasmBrowserTest)
+		if w.offline {
+			return nil // execute fails with ErrRunnerMissing
+		}
 		if w.log != nil {
 			w.log("wasmbrowsertest not found, automatic installation may be in progress")
 		}
@@ -131,12 +690,23 @@ func (w *Wasmtest) ensureWasmExecSymlink(progress func(msgs ...any)) error {
 		return nil // Don't fail, let the test try anyway
 	}
 
+	if copied {
+		if err := copyFile(wasmBrowserTest, goWasmExec); err != nil {
+			progress("warning", "failed to copy wasmbrowsertest to go_js_wasm_exec:", err.Error())
+			return nil // Don't fail, let the test try anyway
+		}
+		w.debugf(progress, "copied %s to %s", wasmBrowserTest, goWasmExec)
+		progress("info", "copied wasmbrowsertest to go_js_wasm_exec")
+		return nil
+	}
+
 	// Create symlink from wasmbrowsertest to go_js_wasm_exec
 	if err := os.Symlink(wasmBrowserTest, goWasmExec); err != nil {
 		progress("warning", "failed to create go_js_wasm_exec symlink:", err.Error())
 		return nil // Don't fail, let the test try anyway
 	}
 
+	w.debugf(progress, "linked %s -> %s", goWasmExec, wasmBrowserTest)
 	progress("info", "created go_js_wasm_exec -> wasmbrowsertest symlink")
 	return nil
 }
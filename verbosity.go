@@ -0,0 +1,109 @@
+package wasmtest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Verbosity selects how much RunTests logs. It is also a RunTests argument
+// and can be set with the WASMTEST_VERBOSITY environment variable or the
+// verbosity setting of the configuration file, as quiet, normal, verbose or
+// debug.
+type Verbosity int
+
+const (
+	// Quiet logs the output of failed tests, errors, warnings and a
+	// summary line per package.
+	Quiet Verbosity = iota - 1
+	// Normal, the default, logs like go test without -v: the output of
+	// failed tests and the package result lines, plus the wasmtest
+	// messages (compile statistics, retries, summaries). RunTests streamed
+	// the whole output before the verbosity levels; pass Verbose for that.
+	Normal
+	// Verbose streams the whole test output as go test -v does.
+	Verbose
+	// Debug adds the internal traces of wasmtest: the commands executed,
+	// the environment composed for them and the go_js_wasm_exec setup
+	// decisions, as "debug" progress messages (see WithVerbosity).
+	Debug
+)
+
+var verbosityNames = map[Verbosity]string{Quiet: "quiet", Normal: "normal", Verbose: "verbose", Debug: "debug"}
+
+func (v Verbosity) String() string {
+	if name, ok := verbosityNames[v]; ok {
+		return name
+	}
+	return fmt.Sprintf("Verbosity(%d)", int(v))
+}
+
+// ParseVerbosity parses a Verbosity name: quiet, normal, verbose or debug.
+func ParseVerbosity(s string) (Verbosity, error) {
+	for v, name := range verbosityNames {
+		if strings.EqualFold(s, name) {
+			return v, nil
+		}
+	}
+	return Normal, fmt.Errorf("wasmtest: unknown verbosity %q (want quiet, normal, verbose or debug)", s)
+}
+
+// debugf reports a "debug" progress message when the verbosity is Debug or
+// the WithSlog logger records debug messages.
+func (w *Wasmtest) debugf(progress func(msgs ...any), format string, args ...any) {
+	if w.verbosity >= Debug || (w.slog != nil && w.slog.Enabled(context.Background(), slog.LevelDebug)) {
+		progress("debug", fmt.Sprintf(format, args...))
+	}
+}
+
+// logFilter selects the progress messages RunTests logs at a verbosity. The
+// output of a running test is held back below Verbose, and only logged if
+// the test fails.
+type logFilter struct {
+	level   Verbosity
+	tracker testTracker
+	held    map[string][][]any
+}
+
+// filter returns the messages to log for the progress message msgs.
+func (f *logFilter) filter(msgs ...any) [][]any {
+	if len(msgs) == 0 {
+		return nil
+	}
+	tag := fmt.Sprint(msgs[0])
+	if tag == "debug" {
+		if f.level >= Debug {
+			return [][]any{msgs}
+		}
+		return nil
+	}
+	if f.level >= Verbose {
+		return [][]any{msgs}
+	}
+
+	ev, ok := f.tracker.event(msgs...)
+	switch {
+	case ok && ev.parallelStep():
+		return nil
+	case ok && ev.Action != "output":
+		held := f.held[ev.Test]
+		delete(f.held, ev.Test)
+		if ev.Action == "fail" {
+			return held
+		}
+		return nil
+	case tag == "out" && ev.Test != "":
+		if f.held == nil {
+			f.held = map[string][][]any{}
+		}
+		f.held[ev.Test] = append(f.held[ev.Test], msgs)
+		return nil
+	case tag == "out", tag == "console", tag == "info", tag == "compile", tag == "exit":
+		if f.level >= Normal {
+			return [][]any{msgs}
+		}
+		return nil
+	}
+	return [][]any{msgs}
+}
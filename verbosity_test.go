@@ -0,0 +1,134 @@
+package wasmtest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseVerbosity(t *testing.T) {
+	for _, v := range []Verbosity{Quiet, Normal, Verbose, Debug} {
+		if got, err := ParseVerbosity(strings.ToUpper(v.String())); err != nil || got != v {
+			t.Errorf("ParseVerbosity(%q) = %v, %v", v, got, err)
+		}
+	}
+	if _, err := ParseVerbosity("loud"); err == nil {
+		t.Error("ParseVerbosity accepted an unknown name")
+	}
+}
+
+func TestLogFilter(t *testing.T) {
+	msgs := [][]any{
+		{"info", "compiling"},
+		{"test", TestEvent{Action: "run", Test: "TestOK"}},
+		{"out", "=== RUN   TestOK"},
+		{"out", "--- PASS: TestOK (0.00s)"},
+		{"test", TestEvent{Action: "pass", Test: "TestOK"}},
+		{"test", TestEvent{Action: "run", Test: "TestBad"}},
+		{"out", "=== RUN   TestBad"},
+		{"out", "    bad_test.go:9: boom"},
+		{"out", "--- FAIL: TestBad (0.00s)"},
+		{"test", TestEvent{Action: "fail", Test: "TestBad"}},
+		{"debug", "running go test"},
+		{"warning", "slow"},
+		{"out", "FAIL"},
+		{"exit", "error", "exit status 1"},
+	}
+	// The held output of a test is only logged when it fails, below
+	// Verbose.
+	failed := []string{"out === RUN   TestBad", "out     bad_test.go:9: boom", "out --- FAIL: TestBad (0.00s)"}
+	for _, tt := range []struct {
+		level Verbosity
+		want  []string
+	}{
+		{Quiet, append(failed, "warning slow")},
+		{Normal, append(append([]string{"info compiling"}, failed...), "warning slow", "out FAIL", "exit error exit status 1")},
+		{Verbose, []string{"info compiling", "test run TestOK", "out === RUN   TestOK", "out --- PASS: TestOK (0.00s)", "test pass TestOK (0s)",
+			"test run TestBad", "out === RUN   TestBad", "out     bad_test.go:9: boom", "out --- FAIL: TestBad (0.00s)", "test fail TestBad (0s)",
+			"warning slow", "out FAIL", "exit error exit status 1"}},
+	} {
+		f := logFilter{level: tt.level}
+		var got []string
+		for _, m := range msgs {
+			for _, logged := range f.filter(m...) {
+				s := fmt.Sprint(logged[0], " ", logged[1])
+				if len(logged) > 2 {
+					s += fmt.Sprint(" ", logged[2])
+				}
+				got = append(got, s)
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%v logged:\n%q\nwant\n%q", tt.level, got, tt.want)
+		}
+	}
+
+	f := logFilter{level: Debug}
+	if got := f.filter("debug", "trace"); len(got) != 1 {
+		t.Errorf("Debug dropped a debug message: %v", got)
+	}
+}
+
+func TestRunTestsVerbosity(t *testing.T) {
+	node := nodeExec(t)
+	dir := writeModule(t, map[string]string{"v_test.go": wasmPassTest})
+
+	run := func(v Verbosity) []string {
+		var logs []string
+		logger := func(a ...any) { logs = append(logs, fmt.Sprint(a...)) }
+		if err := RunTests(dir, logger, v, ExecOptions{Args: []string{"-exec", node}}, WithInstallDisabled()); err != nil {
+			t.Fatalf("RunTests(%v) failed: %v", v, err)
+		}
+		return logs
+	}
+	has := func(logs []string, s string) bool {
+		return slices.ContainsFunc(logs, func(l string) bool { return strings.Contains(l, s) })
+	}
+
+	if logs := run(Quiet); has(logs, "=== RUN") || !has(logs, "1 passed, 0 failed, 0 skipped") {
+		t.Errorf("Quiet logged %q", logs)
+	}
+	if logs := run(Normal); has(logs, "=== RUN") || !has(logs, "PASS") {
+		t.Errorf("Normal logged %q", logs)
+	}
+	if logs := run(Verbose); !has(logs, "=== RUN   TestPass") || has(logs, "debug") {
+		t.Errorf("Verbose logged %q", logs)
+	}
+	if logs := run(Debug); !has(logs, "debugrunning go test -json -exec") || !has(logs, "GOOS=js GOARCH=wasm") {
+		t.Errorf("Debug logged %q", logs)
+	}
+}
+
+func TestLogFilterParallel(t *testing.T) {
+	// Two parallel tests interleave their output: TestA fails while TestB,
+	// started last, passes.
+	msgs := [][]any{
+		{"test", TestEvent{Action: "run", Test: "TestA"}},
+		{"out", OutputLine{Line: "=== RUN   TestA", Test: "TestA"}},
+		{"test", TestEvent{Action: "pause", Test: "TestA"}},
+		{"test", TestEvent{Action: "run", Test: "TestB"}},
+		{"out", OutputLine{Line: "=== RUN   TestB", Test: "TestB"}},
+		{"test", TestEvent{Action: "pause", Test: "TestB"}},
+		{"test", TestEvent{Action: "cont", Test: "TestA"}},
+		{"test", TestEvent{Action: "cont", Test: "TestB"}},
+		{"out", OutputLine{Line: "    a_test.go:9: A failed", Test: "TestA"}},
+		{"out", OutputLine{Line: "    b_test.go:9: B log", Test: "TestB"}},
+		{"out", OutputLine{Line: "--- PASS: TestB (0.01s)", Test: "TestB"}},
+		{"test", TestEvent{Action: "pass", Test: "TestB"}},
+		{"out", OutputLine{Line: "--- FAIL: TestA (0.02s)", Test: "TestA"}},
+		{"test", TestEvent{Action: "fail", Test: "TestA"}},
+		{"out", OutputLine{Line: "FAIL"}},
+	}
+	f := logFilter{level: Normal}
+	var got []string
+	for _, m := range msgs {
+		for _, logged := range f.filter(m...) {
+			got = append(got, fmt.Sprint(logged[1]))
+		}
+	}
+	want := []string{"=== RUN   TestA", "    a_test.go:9: A failed", "--- FAIL: TestA (0.02s)", "FAIL"}
+	if !slices.Equal(got, want) {
+		t.Errorf("logged:\n%q\nwant\n%q", got, want)
+	}
+}
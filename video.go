@@ -0,0 +1,58 @@
+package wasmtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WithVideo records the browser session of RunBundle runs and keeps the
+// video, a webm file named after the package and the time of the run, in
+// dir when the tests fail, so flaky rendering issues can be watched instead
+// of guessed from logs. Passing runs leave nothing behind. It affects only
+// RunBundle, with the Playwright backend (see WithPlaywright): other
+// bundle runs report a "video-unsupported" warning, and go test runs fail
+// with ErrUsage.
+func WithVideo(dir string) Option {
+	return func(w *Wasmtest) { w.videoDir = dir }
+}
+
+// videoUnsupported is reported when WithVideo is set for a run that can't
+// be recorded.
+var videoUnsupported = Warning{
+	Code:    "video-unsupported",
+	Message: "only the Playwright backend of RunBundle records videos; this run is not recorded",
+	Hint:    "run the tests as a bundle with WithPlaywright to record them",
+}
+
+// saveVideo moves the videos recorded in tmp to the WithVideo directory,
+// named after pkg, and reports where they went.
+func (w *Wasmtest) saveVideo(tmp, pkg string, progress func(msgs ...any)) {
+	videos, _ := filepath.Glob(filepath.Join(tmp, "*.webm"))
+	if len(videos) == 0 {
+		progress("warning", Warning{Code: "video-missing", Message: "no video was recorded for the failed run"})
+		return
+	}
+	if err := os.MkdirAll(w.videoDir, 0o755); err != nil {
+		progress("warning", Warning{Code: "video-missing", Message: fmt.Sprintf("failed to save the video: %v", err)})
+		return
+	}
+	name := artifactName(pkg) + "-" + time.Now().Format("20060102-150405")
+	for i, video := range videos {
+		dst := filepath.Join(w.videoDir, name+".webm")
+		if i > 0 {
+			dst = filepath.Join(w.videoDir, fmt.Sprintf("%s-%d.webm", name, i+1))
+		}
+		// The temporary directory may be on another device.
+		err := os.Rename(video, dst)
+		if err != nil {
+			err = copyFile(video, dst)
+		}
+		if err != nil {
+			progress("warning", Warning{Code: "video-missing", Message: fmt.Sprintf("failed to save the video: %v", err)})
+			continue
+		}
+		progress("info", "🎥 video of the failed run saved to "+dst)
+	}
+}
@@ -0,0 +1,61 @@
+package wasmtest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunBundleVideo(t *testing.T) {
+	dir := fakePlaywrightDir(t)
+	videos := filepath.Join(t.TempDir(), "videos")
+	w := New(WithInstallDisabled(), WithPlaywright(PlaywrightOptions{Dir: dir, NoInstall: true}), WithVideo(videos), WithLaunchRetries(0))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	// A passing run keeps no video.
+	out := t.TempDir()
+	if _, err := w.Bundle(ctx, "./example", out, "-test.run=TestMathHelper"); err != nil {
+		t.Fatalf("Bundle failed: %v", err)
+	}
+	progress, msgs := collectProgress()
+	if err := w.RunBundle(ctx, out, RunBundleOptions{}, progress); err != nil {
+		t.Fatalf("RunBundle failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	if entries, _ := os.ReadDir(videos); len(entries) != 0 {
+		t.Errorf("videos of a passing run: %v", entries)
+	}
+
+	// A failing run keeps its video.
+	out = t.TempDir()
+	if _, err := w.Bundle(ctx, writeModule(t, map[string]string{"v_test.go": wasmFailTest}), out); err != nil {
+		t.Fatalf("Bundle failed: %v", err)
+	}
+	progress, msgs = collectProgress()
+	if err := w.RunBundle(ctx, out, RunBundleOptions{}, progress); err == nil {
+		t.Fatal("RunBundle of failing tests succeeded")
+	}
+	saved, _ := filepath.Glob(filepath.Join(videos, "*.webm"))
+	if len(saved) != 1 {
+		t.Fatalf("videos of a failing run: %q\n%s", saved, strings.Join(msgs(), "\n"))
+	}
+	if data, err := os.ReadFile(saved[0]); err != nil || !strings.HasPrefix(string(data), "http://127.0.0.1:") {
+		t.Errorf("video = %q, %v", data, err)
+	}
+	if !strings.Contains(strings.Join(msgs(), "\n"), "video of the failed run saved to "+saved[0]) {
+		t.Errorf("video not reported:\n%s", strings.Join(msgs(), "\n"))
+	}
+
+	// Other backends can't record.
+	w = New(WithInstallDisabled(), WithVideo(videos))
+	progress, msgs = collectProgress()
+	ctx, cancel = context.WithCancel(ctx)
+	cancel()
+	w.RunBundle(ctx, out, RunBundleOptions{NoLaunch: true, Addr: "127.0.0.1:0"}, progress)
+	if !strings.Contains(strings.Join(msgs(), "\n"), "video-unsupported") {
+		t.Errorf("no video-unsupported warning:\n%s", strings.Join(msgs(), "\n"))
+	}
+}
@@ -0,0 +1,167 @@
+package wasmtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// warmBrowserStartTimeout bounds how long a WarmBrowser may take to open
+// its DevTools endpoint.
+const warmBrowserStartTimeout = 30 * time.Second
+
+// WarmBrowser is a browser kept running across RunBundle calls and the
+// builtin backend's go test runs, so that successive packages or watch
+// iterations open their tests in a new tab instead of cold-starting the
+// browser each time, which dominates the run time of small suites. It is
+// started by the first run using it and restarted if it died; concurrent
+// runs share it, each in its own tab. The tabs share the browser profile, so
+// cookies and storage set by one run are seen by the next. Only Chrome
+// family browsers are supported, as the tabs are opened through their
+// DevTools endpoint. Call Close to stop it. A WarmBrowser is safe for
+// concurrent use and may be shared by several Wasmtest values.
+type WarmBrowser struct {
+	browser string
+	flags   []string
+
+	// mu protects the running browser: cmd, closed by exited, listening
+	// on endpoint.
+	mu       sync.Mutex
+	cmd      *exec.Cmd
+	exited   chan struct{}
+	endpoint string
+}
+
+// NewWarmBrowser returns a WarmBrowser running browser, a Browser name or
+// an executable (empty for the first one found), with the extra command
+// line flags.
+func NewWarmBrowser(browser string, flags ...string) *WarmBrowser {
+	return &WarmBrowser{browser: browser, flags: flags}
+}
+
+// WithWarmBrowser makes RunBundle and the builtin backend open the tests in
+// a new tab of b instead of launching a browser per run.
+// RunBundleOptions.Browser, the remote, WebDriver and Playwright backends
+// take precedence; the wasmbrowsertest backend ignores it, as it always
+// launches its own browser. Without it, a RunTests run of several packages
+// with the builtin backend shares one warm browser of its own.
+func WithWarmBrowser(b *WarmBrowser) Option {
+	return func(w *Wasmtest) { w.warmBrowser = b }
+}
+
+// devtools returns the DevTools endpoint of the browser, starting it when
+// it isn't running. started tells whether it had to.
+func (b *WarmBrowser) devtools(ctx context.Context) (endpoint string, started bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cmd != nil {
+		select {
+		case <-b.exited:
+			b.cmd = nil
+		default:
+			return b.endpoint, false, nil
+		}
+	}
+	path, err := lookupBrowser(b.browser)
+	if err != nil {
+		return "", false, err
+	}
+	if isFirefox(path) {
+		return "", false, fmt.Errorf("wasmtest: warm browser: Firefox has no DevTools endpoint to open tabs; use a Chrome family browser")
+	}
+	profile, err := makeTempDir("wasmtest-warm-")
+	if err != nil {
+		return "", false, err
+	}
+	args := []string{
+		"--headless=new", "--disable-gpu", "--no-first-run", "--no-default-browser-check",
+		"--user-data-dir=" + profile, "--remote-debugging-port=0",
+	}
+	if os.Geteuid() == 0 {
+		args = append(args, "--no-sandbox")
+	}
+	// The browser outlives ctx, which only bounds this run.
+	cmd := exec.Command(path, append(append(args, b.flags...), "about:blank")...)
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(profile)
+		return "", false, err
+	}
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		os.RemoveAll(profile)
+		close(exited)
+	}()
+
+	// The browser writes its port to DevToolsActivePort once listening.
+	timeout := time.After(warmBrowserStartTimeout)
+	for {
+		if data, err := os.ReadFile(filepath.Join(profile, "DevToolsActivePort")); err == nil {
+			if port, _, _ := strings.Cut(string(data), "\n"); port != "" {
+				b.cmd, b.exited = cmd, exited
+				b.endpoint = "http://127.0.0.1:" + strings.TrimSpace(port)
+				return b.endpoint, true, nil
+			}
+		}
+		select {
+		case <-exited:
+			return "", false, fmt.Errorf("wasmtest: warm browser %s exited before opening its DevTools endpoint", path)
+		case <-timeout:
+			cmd.Process.Kill()
+			return "", false, fmt.Errorf("wasmtest: warm browser %s did not open its DevTools endpoint within %v", path, warmBrowserStartTimeout)
+		case <-ctx.Done():
+			cmd.Process.Kill()
+			return "", false, ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// openTab opens page in a new tab of the browser, starting it when it isn't
+// running. The tab is closed when ctx is done. The returned channel
+// receives an error if the browser exits meanwhile.
+func (b *WarmBrowser) openTab(ctx context.Context, page string) (<-chan error, error) {
+	endpoint, _, err := b.devtools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	closeTab, err := openRemoteTab(ctx, endpoint, page)
+	if err != nil {
+		return nil, fmt.Errorf("wasmtest: failed to open the tests in the warm browser: %w", err)
+	}
+	b.mu.Lock()
+	browserExited := b.exited
+	b.mu.Unlock()
+	exited := make(chan error, 1)
+	go func() {
+		select {
+		case <-browserExited:
+			exited <- errors.New("the warm browser exited")
+		case <-ctx.Done():
+			closeTab()
+		}
+	}()
+	return exited, nil
+}
+
+// Close stops the browser, if running, and waits for it to exit.
+func (b *WarmBrowser) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cmd == nil {
+		return nil
+	}
+	err := b.cmd.Process.Kill()
+	<-b.exited
+	b.cmd = nil
+	if errors.Is(err, os.ErrProcessDone) {
+		err = nil
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package wasmtest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// nodeDevtools returns an executable standing in for a Chrome with a
+// DevTools endpoint (see testdata/nodedevtools.js) and the file counting
+// its starts.
+func nodeDevtools(t *testing.T) (browser, starts string) {
+	t.Helper()
+	nodeBrowser(t)
+	script, err := filepath.Abs("testdata/nodedevtools.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	browser = filepath.Join(dir, "nodedevtools")
+	if err := os.WriteFile(browser, []byte(fmt.Sprintf("#!/bin/sh\nexec node %q \"$@\"\n", script)), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	starts = filepath.Join(dir, "starts")
+	t.Setenv("NODE_DEVTOOLS_STARTS", starts)
+	return browser, starts
+}
+
+func TestRunBundleWarmBrowser(t *testing.T) {
+	browser, starts := nodeDevtools(t)
+	warm := NewWarmBrowser(browser)
+	defer warm.Close()
+	w := New(WithInstallDisabled(), WithWarmBrowser(warm))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	out := t.TempDir()
+	if _, err := w.Bundle(ctx, "./example", out, "-test.run=TestMathHelper"); err != nil {
+		t.Fatalf("Bundle failed: %v", err)
+	}
+
+	// Both runs use the browser started by the first one.
+	for i := range 2 {
+		progress, msgs := collectProgress()
+		if err := w.RunBundle(ctx, out, RunBundleOptions{}, progress); err != nil {
+			t.Fatalf("run %d failed: %v\n%s", i, err, strings.Join(msgs(), "\n"))
+		}
+		if started := strings.Contains(strings.Join(msgs(), "\n"), "started the warm browser"); started != (i == 0) {
+			t.Errorf("run %d: started = %v\n%s", i, started, strings.Join(msgs(), "\n"))
+		}
+	}
+	if data, _ := os.ReadFile(starts); strings.Count(string(data), "start") != 1 {
+		t.Errorf("browser starts = %q", data)
+	}
+
+	// A closed browser is started again.
+	if err := warm.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	progress, msgs := collectProgress()
+	if err := w.RunBundle(ctx, out, RunBundleOptions{}, progress); err != nil {
+		t.Fatalf("run after Close failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	if data, _ := os.ReadFile(starts); strings.Count(string(data), "start") != 2 {
+		t.Errorf("browser starts after Close = %q", data)
+	}
+}
+
+func TestRunTestsWarmBrowser(t *testing.T) {
+	browser, starts := nodeDevtools(t)
+	root := writeModule(t, map[string]string{
+		"a/a_test.go": wasmPassTest,
+		"b/b_test.go": wasmPassTest,
+	})
+
+	// The packages of a builtin run open their tests in tabs of one browser.
+	logger, log := collectProgress()
+	res, err := RunTestsResult(filepath.Join(root, "..."), logger, WithInstallDisabled(), WithBackend(BackendBuiltin), WithBrowser(browser))
+	if err != nil {
+		t.Fatalf("RunTestsResult failed: %v\n%s", err, strings.Join(log(), "\n"))
+	}
+	if len(res.Packages) != 2 || len(res.PassedTests) != 2 {
+		t.Errorf("unexpected result: %d packages, passed %q", len(res.Packages), res.PassedTests)
+	}
+	if data, _ := os.ReadFile(starts); strings.Count(string(data), "start") != 1 {
+		t.Errorf("browser starts = %q", data)
+	}
+}
@@ -1,90 +1,347 @@
 package wasmtest
 
 import (
+	"cmp"
 	"context"
+	"debug/buildinfo"
 	"errors"
+	"fmt"
+	"log/slog"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 )
 
+// Wasmtest runs Go js/wasm tests. It is safe for concurrent use: several
+// Execute, ExecuteWithOptions, RunBundle or Orchestrate calls may run in
+// parallel against different directories, e.g. from the panes of a TUI, and
+// the setters may be called at any time. The progress callback of a call is
+// never invoked concurrently with itself, but the callbacks of different
+// calls are, and so is the logger.
 type Wasmtest struct {
 	// Log is a simple logger function provided by the caller.
 	// It should accept variadic values similar to fmt.Println.
 	log func(...any)
-	// mutex to protect lastOpID
-	lastOpID string
 	// safeLog is a logger that won't panic in goroutines after test completion
 	safeLog func(...any)
+
+	// mu protects lastOpID and envHook.
+	mu       sync.Mutex
+	lastOpID string
+	// envHook, when set, inspects the environment of each go test process.
+	envHook func(dir string, env []string)
+	// setupMu serializes the go_js_wasm_exec setup of concurrent runs.
+	setupMu sync.Mutex
+	// installMu serializes the wasmbrowsertest installs.
+	installMu sync.Mutex
+	// installDone is closed when the background install of New is over,
+	// with its error in installErr (see Ready).
+	installDone chan struct{}
+	installErr  error
+	// optionErr is the invalid option setting found by New, returned by
+	// Install, Ready and the runs.
+	optionErr error
+
+	// timeout bounds each Execute call (WithTimeout).
+	timeout time.Duration
+	// testDir is the directory Execute runs in (WithTestDir).
+	testDir string
+	// goWasm is the GOWASM value of the js/wasm builds (see WithGoWasmFeatures).
+	goWasm string
+	// runFilter is the default -run regexp (see WithRun).
+	runFilter string
+	// skipFilter is the default -skip regexp (see WithSkip).
+	skipFilter string
+	// tags are the default extra build tags (see WithTags).
+	tags []string
+	// backend runs the test binaries of go test runs (see WithBackend).
+	backend string
+	// wasiRuntime is the wasmtime or wasmer executable of the WASI backends
+	// (see WithWASIRuntime).
+	wasiRuntime string
+	// cloudGrid, when set, runs bundles in a cloud browser (see
+	// WithCloudGrid).
+	cloudGrid *CloudGrid
+	// dockerImage and dockerBrowser are the image and browser of
+	// BackendDocker (see WithDockerImage).
+	dockerImage   string
+	dockerBrowser string
+	// target is the target of go test runs (see WithTarget).
+	target string
+	// tinyGoTarget, when set, compiles the tests with tinygo for this
+	// target (see WithTinyGo).
+	tinyGoTarget string
+	// keepBinary, when set, is the directory the compiled test binaries are
+	// kept in (see WithKeepBinary).
+	keepBinary string
+	// binaryCache, when set, is the directory the test binaries are cached
+	// in (see WithBinaryCache).
+	binaryCache string
+	// browser is the browser executable (see WithBrowser).
+	browser string
+	// browserFlags are extra browser command line flags (see
+	// WithBrowserFlags).
+	browserFlags []string
+	// remoteBrowser is the DevTools endpoint of the browser RunBundle uses
+	// (see WithRemoteBrowser).
+	remoteBrowser string
+	// warmBrowser is the browser RunBundle opens tabs in (see
+	// WithWarmBrowser).
+	warmBrowser *WarmBrowser
+	// webDriver and webDriverCaps are the WebDriver server RunBundle uses
+	// and the capabilities of its sessions (see WithWebDriver).
+	webDriver     string
+	webDriverCaps map[string]any
+	// managedDriver, when set, runs a local chromedriver or geckodriver
+	// instead (see WithManagedWebDriver).
+	managedDriver *DriverOptions
+	// cpuProfile collects CPU profiles in cpuProfileDir (see
+	// WithCPUProfile).
+	cpuProfile    bool
+	cpuProfileDir string
+	// jsCoverageDir receives the JS coverage of the bundle runs (see
+	// WithJSCoverage).
+	jsCoverageDir string
+	// memoryDir and memoryAt tell where and when memory is sampled (see
+	// WithMemoryProfile).
+	memoryDir string
+	memoryAt  MemoryPoint
+	// harPath is the HAR file of the bundle runs (see WithHAR).
+	harPath string
+	// videoDir receives the video of failed runs (see WithVideo).
+	videoDir string
+	// playwright runs the bundles with Playwright (see WithPlaywright).
+	playwright *PlaywrightOptions
+	// headful shows the browser window (see WithHeadful).
+	headful bool
+	// failurePause bounds how long a headful browser stays open after a
+	// failure (see WithFailurePause).
+	failurePause time.Duration
+	// launchRetries is the number of retries of transient browser launch
+	// failures (see WithLaunchRetries).
+	launchRetries int
+	// slog receives a structured record of every progress message (see
+	// WithSlog).
+	slog *slog.Logger
+	// logFile receives a copy of every progress message (see WithLogFile).
+	logFile *rotatingFile
+	// verbosity enables the debug traces at Debug (see WithVerbosity).
+	verbosity Verbosity
+	// installDisabled skips the background install in New
+	// (WithInstallDisabled).
+	installDisabled bool
+	// installEnv holds extra KEY=VALUE entries for go install (see
+	// WithInstallEnv).
+	installEnv []string
+	// installDir is where wasmbrowsertest is installed and linked, empty
+	// for the go install directory (see WithInstallDir).
+	installDir string
+	// offline never installs wasmbrowsertest and fails the runs missing it
+	// (see WithNoInstall).
+	offline bool
+	// wasmBrowserTestVersion is the pinned wasmbrowsertest version, empty
+	// for the latest (see WithWasmBrowserTestVersion).
+	wasmBrowserTestVersion string
+	// runnerUpgrade is the WithRunnerUpgrade policy, empty for
+	// RunnerUpgradeWarn.
+	runnerUpgrade string
+	// pathFix adds the install directory to the PATH of go test when it is
+	// missing (see WithPathFix).
+	pathFix bool
+	// toolchain runs the go commands (see WithGoToolchain).
+	toolchain goToolchain
 }
 
-// New returns a Wasmtest configured with the provided logger.
-// The logger must not be nil; if nil is passed a no-op logger is used.
-func New(logger func(...any)) *Wasmtest {
+// New returns a Wasmtest configured with the provided options. Without
+// WithLogger a println based logger is used. The WASMTEST_DIR,
+// WASMTEST_TIMEOUT, WASMTEST_BROWSER, WASMTEST_RUN and WASMTEST_SKIP_INSTALL
+// environment variables set defaults that the options override (see Config).
+//
+// Example:
+//
+//	w := New(WithLogger(log.Println), WithTestDir("./wasm_tests"), WithTimeout(5*time.Minute))
+func New(opts ...Option) *Wasmtest {
+	w := &Wasmtest{timeout: 10 * time.Minute, launchRetries: defaultLaunchRetries}
+	env := &Config{}
+	envErr := env.applyEnv(os.Getenv)
+	for _, opt := range append(env.Options(), opts...) {
+		opt(w)
+	}
 
+	logger := w.log
+	if logger == nil && w.slog != nil {
+		logger = func(args ...any) {
+			w.slog.Info(strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+		}
+		w.log = logger
+	}
 	if logger == nil {
 		logger = func(args ...any) {
 			println(args)
 		}
+		w.log = logger
 	}
 
 	// Create a safe logger for background operations that won't panic
 	// after test completion
-	safeLogger := func(args ...any) {
+	w.safeLog = func(args ...any) {
 		defer func() {
 			// Recover from panic if logging after test completion
 			recover()
 		}()
 		logger(args...)
 	}
+	if envErr != nil {
+		w.safeLog("ignoring invalid environment configuration:", envErr)
+	}
 
-	w := &Wasmtest{log: logger, safeLog: safeLogger}
+	w.installDone = make(chan struct{})
+	if w.optionErr = w.checkOptions(); w.optionErr != nil {
+		w.safeLog(w.optionErr)
+		w.installErr = w.optionErr
+		close(w.installDone)
+		return w
+	}
+	// The builtin backend needs no wasmbrowsertest.
+	if w.installDisabled || w.backend == BackendBuiltin {
+		close(w.installDone)
+		return w
+	}
 
-	// Perform a synchronous verification/install of wasmbrowsertest so callers
-	// (and integrations like TUI) don't need to call it explicitly.
+	// Verify/install wasmbrowsertest in the background so New doesn't
+	// block; Ready tells when it is over.
 	go func() {
-		// Run in background but wait a short period so New doesn't block too long.
+		defer close(w.installDone)
 		// Use a timeout context to bound the operation.
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 		defer cancel()
-		if err := w.ensureWasmBrowserTestInstalled(ctx); err != nil {
+		if w.installErr = w.Install(ctx); w.installErr != nil {
 			// Log the error via safe logger (won't panic after test completion)
-			w.safeLog("ensure wasmbrowsertest failed:", err)
+			w.safeLog("ensure wasmbrowsertest failed:", w.installErr)
 		}
 	}()
 
 	return w
 }
 
+// Install verifies that wasmbrowsertest is available, installing it with
+// go install when it is not (see WithWasmBrowserTestVersion and
+// WithInstallDir), unless WithNoInstall is set. New calls it in the
+// background unless WithAutoInstall(false) is set; call it explicitly to
+// prepare the environment at a chosen time. Concurrent calls install once.
+func (w *Wasmtest) Install(ctx context.Context) error {
+	if w.optionErr != nil {
+		return w.optionErr
+	}
+	w.installMu.Lock()
+	defer w.installMu.Unlock()
+	return w.ensureWasmBrowserTestInstalled(ctx)
+}
+
+// Ready returns a channel receiving the result of the background install of
+// New once it is over, then closed; each call returns its own channel. With
+// the install disabled it receives nil at once.
+func (w *Wasmtest) Ready() <-chan error {
+	ready := make(chan error, 1)
+	go func() {
+		if w.installDone != nil {
+			<-w.installDone
+		}
+		ready <- w.installErr
+		close(ready)
+	}()
+	return ready
+}
+
+// wasmBrowserTestModule is the module path of wasmbrowsertest.
+const wasmBrowserTestModule = "github.com/agnivade/wasmbrowsertest"
+
+// wasmBrowserTestNames are the names wasmbrowsertest is found under: the
+// original tool is `wasmbrowsertest` but the README suggests renaming it to
+// `go_js_wasm_exec`.
+var wasmBrowserTestNames = []string{"wasmbrowsertest", "go_js_wasm_exec"}
+
 // ensureWasmBrowserTestInstalled verifies that a binary named
 // `wasmbrowsertest` (or `go_js_wasm_exec`) is available in PATH. If not
-// present it will attempt to install `github.com/agnivade/wasmbrowsertest@latest`
-// using `go install`. Errors are returned and also reported through the
-// configured logger.
+// present it will attempt to install `github.com/agnivade/wasmbrowsertest`
+// at the pinned version, or @latest, using `go install`. A binary found at
+// another version than the pinned one is reported, not replaced; one built
+// with an older Go release is reinstalled under RunnerUpgradeAuto. Errors are
+// returned and also reported through the configured logger.
 func (w *Wasmtest) ensureWasmBrowserTestInstalled(ctx context.Context) error {
 	if w == nil {
 		return errors.New("wasmtest: receiver is nil")
 	}
 
-	// Check common names: the original tool is `wasmbrowsertest` but the
-	// README suggests renaming to `go_js_wasm_exec`. We'll accept either.
-	probes := []string{"wasmbrowsertest", "go_js_wasm_exec"}
-
-	for _, p := range probes {
-		if _, err := exec.LookPath(p); err == nil {
+	for _, p := range wasmBrowserTestNames {
+		if path, err := exec.LookPath(p); err == nil {
 			w.safeLog("found", p)
+			if warn := w.wasmBrowserTestMismatch(); warn != nil {
+				w.safeLog(warn.Message+";", warn.Hint)
+			}
+			if warn := w.runnerOutdated(ctx, execSpec{toolchain: w.toolchain}, path); warn != nil {
+				if w.runnerUpgrade != RunnerUpgradeAuto || w.offline {
+					w.safeLog(warn.Message+";", warn.Hint)
+					return nil
+				}
+				w.safeLog(warn.Message + "; upgrading it")
+				return w.installWasmBrowserTest(ctx)
+			}
 			return nil
 		}
 	}
 
+	// Installing again would not help a binary outside of PATH.
+	if path, dir := w.offPathRunner(ctx); path != "" {
+		return w.offPathInstalled(path, dir)
+	}
+
+	if w.offline {
+		return newRunError(ErrRunnerMissing, "wasmtest: wasmbrowsertest not found in PATH and offline mode never installs it")
+	}
+
 	w.safeLog("wasmbrowsertest not found in PATH; attempting to install via go install")
+	return w.installWasmBrowserTest(ctx)
+}
 
+// installWasmBrowserTest installs wasmbrowsertest at the pinned version, or
+// @latest, with go install, logging its stages, and records the installed
+// binary.
+func (w *Wasmtest) installWasmBrowserTest(ctx context.Context) error {
 	// Prepare install command with a timeout to avoid hanging indefinitely.
-	// Use the module path from the docs.
-	installCmd := exec.CommandContext(ctx, "go", "install", "github.com/agnivade/wasmbrowsertest@latest")
+	// Use the module path from the docs. The -x trace tells the stages.
+	module := wasmBrowserTestModule + "@" + cmp.Or(w.wasmBrowserTestVersion, "latest")
+	installCmd := exec.CommandContext(ctx, w.toolchain.command(), "install", "-x", module)
+	// The proxy settings of the environment (GOPROXY, HTTPS_PROXY, ...) are
+	// inherited; WithInstallEnv overrides them.
+	installCmd.Env = append(append(os.Environ(), w.toolchain.env()...), w.installEnv...)
+	if w.installDir != "" {
+		installCmd.Env = append(installCmd.Env, "GOBIN="+w.installDir)
+	}
+	stderr, err := installCmd.StderrPipe()
+	if err == nil {
+		err = installCmd.Start()
+	}
+	if err != nil {
+		w.safeLog("go install failed:", err)
+		return err
+	}
+	w.safeLog("install", InstallProgress{Stage: InstallResolving, Detail: module})
 	// Set a reasonable timeout if the provided context has none.
 	done := make(chan error, 1)
 	go func() {
-		done <- installCmd.Run()
+		failure := w.logInstallProgress(stderr)
+		err := installCmd.Wait()
+		if err != nil {
+			proxy := cmp.Or(lookupEnv(installCmd.Env, "GOPROXY"), "the default GOPROXY")
+			err = fmt.Errorf("wasmtest: go install %s through %s failed: %w\n%s", module, proxy, err, strings.Join(failure, "\n"))
+		}
+		done <- err
 	}()
 
 	select {
@@ -105,13 +362,122 @@ func (w *Wasmtest) ensureWasmBrowserTestInstalled(ctx context.Context) error {
 	}
 
 	// After install, re-check PATH
-	for _, p := range probes {
-		if _, err := exec.LookPath(p); err == nil {
-			w.safeLog("installed and found", p)
+	for _, p := range wasmBrowserTestNames {
+		if path, err := exec.LookPath(p); err == nil {
+			w.safeLog("install", InstallProgress{Stage: InstallInstalled, Detail: path})
+			// Later runs check the runner against this record.
+			if err := recordRunner(path); err != nil {
+				w.safeLog("recording the wasmbrowsertest hash failed:", err)
+			}
 			return nil
 		}
 	}
 
+	// GOBIN or GOPATH/bin may not be in PATH.
+	if path, dir := w.offPathRunner(ctx); path != "" {
+		w.safeLog("install", InstallProgress{Stage: InstallInstalled, Detail: path})
+		if err := recordRunner(path); err != nil {
+			w.safeLog("recording the wasmbrowsertest hash failed:", err)
+		}
+		return w.offPathInstalled(path, dir)
+	}
+
 	w.safeLog("installed but binary still not found in PATH; ensure GOBIN or GOPATH/bin is on PATH")
 	return errors.New("wasmtest: installed but binary not found in PATH")
 }
+
+// offPathRunner returns the wasmbrowsertest of the install directory (see
+// installBinDir) with the directory, when the directory is not in PATH;
+// empty strings otherwise.
+func (w *Wasmtest) offPathRunner(ctx context.Context) (path, dir string) {
+	dir, err := w.installBinDir(ctx)
+	if err != nil || inPath(filepath.SplitList(os.Getenv("PATH")), dir) {
+		return "", ""
+	}
+	path = filepath.Join(dir, "wasmbrowsertest")
+	if runtime.GOOS == "windows" {
+		path += ".exe"
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", ""
+	}
+	return path, dir
+}
+
+// offPathInstalled handles the wasmbrowsertest at path, installed in dir,
+// which is not in PATH: go test runs find it with WithPathFix, otherwise
+// the error names the directory to add.
+func (w *Wasmtest) offPathInstalled(path, dir string) error {
+	if w.pathFix {
+		w.safeLog("found", path+"; go test runs get "+dir+" added to their PATH")
+		return nil
+	}
+	warn := installDirWarning(dir)
+	w.safeLog(warn.Message+";", warn.Hint)
+	return fmt.Errorf("wasmtest: wasmbrowsertest is installed in %s, which is not in PATH; add it to PATH, or use WithPathFix", dir)
+}
+
+// inPath reports whether dir is one of the PATH entries dirs.
+func inPath(dirs []string, dir string) bool {
+	return slices.ContainsFunc(dirs, func(d string) bool {
+		return d != "" && filepath.Clean(d) == filepath.Clean(dir)
+	})
+}
+
+// installDirWarning is the warning of an install directory missing from
+// PATH, where go test looks for go_js_wasm_exec.
+func installDirWarning(dir string) Warning {
+	return Warning{
+		Code:    "install-dir-not-in-path",
+		Message: fmt.Sprintf("%s, where wasmbrowsertest is installed, is not in PATH: go test won't find go_js_wasm_exec", dir),
+		Hint:    "add " + dir + " to PATH, or let wasmtest add it to the PATH of go test with WithPathFix",
+	}
+}
+
+// installBinDir returns the directory wasmbrowsertest is installed in, and
+// go_js_wasm_exec linked in: the WithInstallDir one, else GOBIN, else the
+// bin directory of the first GOPATH entry, as go install does.
+func (w *Wasmtest) installBinDir(ctx context.Context) (string, error) {
+	if w.installDir != "" {
+		return w.installDir, nil
+	}
+	vars, err := goEnvVars(ctx, execSpec{toolchain: w.toolchain}, "GOBIN", "GOPATH")
+	if err != nil {
+		return "", err
+	}
+	if vars["GOBIN"] != "" {
+		return vars["GOBIN"], nil
+	}
+	if paths := filepath.SplitList(vars["GOPATH"]); len(paths) > 0 && paths[0] != "" {
+		return filepath.Join(paths[0], "bin"), nil
+	}
+	return "", errors.New("wasmtest: neither GOBIN nor GOPATH is set")
+}
+
+// wasmBrowserTestMismatch returns a warning when the wasmbrowsertest found
+// in PATH is not the version pinned by WithWasmBrowserTestVersion, nil when
+// it is, none is pinned or none is found.
+func (w *Wasmtest) wasmBrowserTestMismatch() *Warning {
+	if w.wasmBrowserTestVersion == "" {
+		return nil
+	}
+	for _, name := range wasmBrowserTestNames {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			continue
+		}
+		version := "an unknown version"
+		if info, err := buildinfo.ReadFile(path); err == nil && info.Main.Version != "" {
+			if info.Main.Version == w.wasmBrowserTestVersion {
+				return nil
+			}
+			version = info.Main.Version
+		}
+		return &Warning{
+			Code:    "wasmbrowsertest-version",
+			Message: fmt.Sprintf("%s is wasmbrowsertest %s, not the pinned %s", path, version, w.wasmBrowserTestVersion),
+			Hint:    "install the pinned version with go install " + wasmBrowserTestModule + "@" + w.wasmBrowserTestVersion,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package wasmtest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestConcurrentExecute runs parallel executions against different
+// directories while the setters are used; run it with -race.
+func TestConcurrentExecute(t *testing.T) {
+	node := nodeExec(t)
+	other := writeModule(t, map[string]string{
+		"other_test.go": "//go:build js && wasm\n\npackage tmp\n\nimport \"testing\"\n\nfunc TestOther(t *testing.T) {}\n",
+	})
+
+	w := New(WithInstallDisabled(), WithLogger(func(...any) {}))
+	var wg sync.WaitGroup
+	for i, dir := range []string{"./example", other, "./example", other} {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			progress, msgs := collectProgress()
+			opts := ExecOptions{Dir: dir, Args: []string{"-exec", node}}
+			if err := w.ExecuteWithOptions(opts, progress); err != nil {
+				t.Errorf("ExecuteWithOptions(%s) failed: %v\n%s", dir, err, strings.Join(msgs(), "\n"))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			w.SetLastOperationID(fmt.Sprint(i))
+			_ = w.GetLastOperationID()
+			w.SetEnvHook(func(string, []string) {})
+		}()
+	}
+	wg.Wait()
+}
+
+func TestWasmBrowserTestMismatch(t *testing.T) {
+	for in, want := range map[string]string{"0.8.0": "v0.8.0", "v0.8.0": "v0.8.0", "latest": "", "": ""} {
+		if got := New(WithInstallDisabled(), WithWasmBrowserTestVersion(in)).wasmBrowserTestVersion; got != want {
+			t.Errorf("WithWasmBrowserTestVersion(%q) pinned %q, want %q", in, got, want)
+		}
+	}
+
+	// The test binary stands in for an installed wasmbrowsertest: it is not
+	// a tagged release.
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Symlink(exe, filepath.Join(dir, "wasmbrowsertest")); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+
+	if warn := New(WithInstallDisabled()).wasmBrowserTestMismatch(); warn != nil {
+		t.Errorf("unpinned version reported: %+v", warn)
+	}
+	warn := New(WithInstallDisabled(), WithWasmBrowserTestVersion("v0.8.0")).wasmBrowserTestMismatch()
+	if warn == nil || warn.Code != "wasmbrowsertest-version" || !strings.Contains(warn.Message, "not the pinned v0.8.0") || !strings.Contains(warn.Hint, "wasmbrowsertest@v0.8.0") {
+		t.Errorf("mismatch not reported: %+v", warn)
+	}
+}
+
+func TestInstallBinDir(t *testing.T) {
+	t.Setenv("GOENV", "off")
+	gopath := t.TempDir()
+	t.Setenv("GOPATH", gopath+string(os.PathListSeparator)+t.TempDir())
+	t.Setenv("GOBIN", "")
+	w := New(WithInstallDisabled())
+	if got, err := w.installBinDir(t.Context()); err != nil || got != filepath.Join(gopath, "bin") {
+		t.Errorf("installBinDir() with GOPATH = %q, %v", got, err)
+	}
+
+	gobin := t.TempDir()
+	t.Setenv("GOBIN", gobin)
+	if got, err := w.installBinDir(t.Context()); err != nil || got != gobin {
+		t.Errorf("installBinDir() with GOBIN = %q, %v; want %q", got, err, gobin)
+	}
+	// go_js_wasm_exec is linked next to the wasmbrowsertest of GOBIN.
+	if err := os.WriteFile(filepath.Join(gobin, "wasmbrowsertest"), nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.ensureWasmExecSymlink(func(...any) {}); err != nil {
+		t.Fatal(err)
+	}
+	if dest, err := os.Readlink(filepath.Join(gobin, "go_js_wasm_exec")); err != nil || dest != filepath.Join(gobin, "wasmbrowsertest") {
+		t.Errorf("go_js_wasm_exec links to %q, %v", dest, err)
+	}
+
+	if got, _ := New(WithInstallDisabled(), WithInstallDir("/opt/wasm/bin")).installBinDir(t.Context()); got != "/opt/wasm/bin" {
+		t.Errorf("installBinDir() with WithInstallDir = %q", got)
+	}
+}
+
+func TestLinkWasmExec(t *testing.T) {
+	// The copies made on Windows are checked on every platform.
+	bin := t.TempDir()
+	src := filepath.Join(bin, "wasmbrowsertest.exe")
+	dst := filepath.Join(bin, "go_js_wasm_exec.exe")
+	if err := os.WriteFile(src, []byte("v1"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	w := New(WithInstallDisabled())
+	progress, msgs := collectProgress()
+	if err := w.linkWasmExec(bin, true, progress); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(dst); err != nil || string(data) != "v1" {
+		t.Fatalf("copy = %q, %v\n%s", data, err, strings.Join(msgs(), "\n"))
+	}
+	if info, err := os.Lstat(dst); err != nil || info.Mode()&os.ModeSymlink != 0 {
+		t.Errorf("go_js_wasm_exec.exe is not a copy: %v", err)
+	}
+
+	// A newer wasmbrowsertest replaces the copy.
+	if err := os.WriteFile(src, []byte("v2"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(src, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.linkWasmExec(bin, true, progress); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "v2" {
+		t.Errorf("stale copy kept: %q", data)
+	}
+}
+
+func TestInstallReady(t *testing.T) {
+	// A wasmbrowsertest in PATH makes the background install a check.
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "wasmbrowsertest"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+	var logs []string
+	w := New(WithLogger(func(args ...any) { logs = append(logs, fmt.Sprint(args...)) }))
+	select {
+	case err := <-w.Ready():
+		if err != nil {
+			t.Errorf("Ready() = %v", err)
+		}
+	case <-time.After(time.Minute):
+		t.Fatal("the background install never finished")
+	}
+	if len(logs) == 0 || !strings.Contains(logs[0], "wasmbrowsertest") {
+		t.Errorf("logs = %q", logs)
+	}
+	// Every caller gets the result.
+	if err := <-w.Ready(); err != nil {
+		t.Errorf("second Ready() = %v", err)
+	}
+
+	w = New(WithAutoInstall(false), WithNoInstall())
+	if err := <-w.Ready(); err != nil || !w.installDisabled {
+		t.Errorf("Ready() without auto install = %v", err)
+	}
+	t.Setenv("PATH", t.TempDir())
+	if err := w.Install(t.Context()); !errors.Is(err, ErrRunnerMissing) {
+		t.Errorf("offline Install() = %v, want ErrRunnerMissing", err)
+	}
+}
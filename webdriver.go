@@ -0,0 +1,136 @@
+package wasmtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WithWebDriver makes RunBundle open the tests through a W3C WebDriver
+// server, such as a Selenium Grid hub (http://grid:4444) or a chromedriver,
+// instead of launching a browser, so existing grids can run WASM tests
+// without wasmbrowsertest. A session is created with capabilities, opened
+// on the harness page and deleted at the end of the run. Nil capabilities
+// request the browser named by RunBundleOptions.Browser or WithBrowser
+// (Chrome by default), with the WithBrowserFlags flags. As with
+// WithRemoteBrowser, the grid nodes must reach the served bundle (see
+// RunBundleOptions.Addr and URL), and go test runs ignore it.
+func WithWebDriver(endpoint string, capabilities map[string]any) Option {
+	return func(w *Wasmtest) {
+		w.webDriver = endpoint
+		w.webDriverCaps = capabilities
+	}
+}
+
+// webDriverBrowserNames maps the Browser names to WebDriver browserName
+// capabilities.
+var webDriverBrowserNames = map[string]string{
+	BrowserChrome:   "chrome",
+	BrowserChromium: "chrome",
+	BrowserEdge:     "MicrosoftEdge",
+	BrowserFirefox:  "firefox",
+}
+
+// webDriverCapabilities returns the capabilities of the WebDriver sessions
+// of w running the tests in browser, a Browser name.
+func (w *Wasmtest) webDriverCapabilities(browser string) map[string]any {
+	if w.webDriverCaps != nil {
+		return w.webDriverCaps
+	}
+	name := webDriverBrowserNames[strings.ToLower(browser)]
+	if name == "" {
+		name = "chrome"
+	}
+	caps := map[string]any{"browserName": name}
+	if len(w.browserFlags) > 0 {
+		switch name {
+		case "chrome":
+			caps["goog:chromeOptions"] = map[string]any{"args": w.browserFlags}
+		case "MicrosoftEdge":
+			caps["ms:edgeOptions"] = map[string]any{"args": w.browserFlags}
+		case "firefox":
+			caps["moz:firefoxOptions"] = map[string]any{"args": w.browserFlags}
+		}
+	}
+	return caps
+}
+
+// webDriverSession is a session of a WebDriver server.
+type webDriverSession struct {
+	endpoint string
+	id       string
+}
+
+// newWebDriverSession creates a session with capabilities on the WebDriver
+// server at endpoint.
+func newWebDriverSession(ctx context.Context, endpoint string, capabilities map[string]any) (*webDriverSession, error) {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	var created struct {
+		SessionID string `json:"sessionId"`
+	}
+	body := map[string]any{"capabilities": map[string]any{"alwaysMatch": capabilities}}
+	if err := webDriverRequest(ctx, http.MethodPost, endpoint+"/session", body, &created); err != nil {
+		return nil, err
+	}
+	if created.SessionID == "" {
+		return nil, fmt.Errorf("wasmtest: webdriver: no session id in the new session response")
+	}
+	return &webDriverSession{endpoint: endpoint, id: created.SessionID}, nil
+}
+
+// navigate opens url in the session.
+func (s *webDriverSession) navigate(ctx context.Context, url string) error {
+	return webDriverRequest(ctx, http.MethodPost, s.endpoint+"/session/"+s.id+"/url", map[string]string{"url": url}, nil)
+}
+
+// quit deletes the session, closing its browser.
+func (s *webDriverSession) quit() {
+	// The run context may already be done: the session is deleted anyway.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	webDriverRequest(ctx, http.MethodDelete, s.endpoint+"/session/"+s.id, nil, nil)
+}
+
+// webDriverRequest sends a WebDriver command and decodes the "value" of its
+// response into v, when not nil.
+func webDriverRequest(ctx context.Context, method, url string, body, v any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("wasmtest: webdriver: %w", err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("wasmtest: webdriver: %s %s: %s", method, redactURL(url), resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Error   string `json:"error"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(result.Value, &failure)
+		return fmt.Errorf("wasmtest: webdriver: %s %s: %s: %s", method, redactURL(url), failure.Error, failure.Message)
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(result.Value, v)
+}
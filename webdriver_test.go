@@ -0,0 +1,90 @@
+package wasmtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebDriverCapabilities(t *testing.T) {
+	w := New(WithInstallDisabled(), WithBrowserFlags("--lang=es"))
+	want := map[string]any{"browserName": "firefox", "moz:firefoxOptions": map[string]any{"args": []string{"--lang=es"}}}
+	if got := w.webDriverCapabilities("Firefox"); !reflect.DeepEqual(got, want) {
+		t.Errorf("capabilities = %v, want %v", got, want)
+	}
+	if got := w.webDriverCapabilities("/opt/custom/chrome")["browserName"]; got != "chrome" {
+		t.Errorf("browserName of a path = %v", got)
+	}
+	caps := map[string]any{"browserName": "chrome", "platformName": "linux"}
+	if got := New(WithInstallDisabled(), WithWebDriver("http://grid:4444", caps)).webDriverCapabilities(""); !reflect.DeepEqual(got, caps) {
+		t.Errorf("explicit capabilities = %v", got)
+	}
+}
+
+func TestRunBundleWebDriver(t *testing.T) {
+	browser := nodeBrowser(t)
+	// The fake WebDriver server runs each session in a node browser.
+	var mu sync.Mutex
+	var cmd *exec.Cmd
+	var commands []string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		commands = append(commands, r.Method+" "+r.URL.Path)
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/session":
+			rw.Write([]byte(`{"value": {"sessionId": "s1", "capabilities": {}}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/session/s1/url":
+			cmd = exec.Command(browser, body["url"].(string))
+			if err := cmd.Start(); err != nil {
+				rw.WriteHeader(http.StatusInternalServerError)
+				rw.Write([]byte(`{"value": {"error": "unknown error", "message": "` + err.Error() + `"}}`))
+				return
+			}
+			rw.Write([]byte(`{"value": null}`))
+		case r.Method == http.MethodDelete && r.URL.Path == "/session/s1":
+			if cmd != nil {
+				cmd.Process.Kill()
+				cmd.Wait()
+			}
+			rw.Write([]byte(`{"value": null}`))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+			rw.Write([]byte(`{"value": {"error": "unknown command", "message": "` + r.URL.Path + `"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	w := New(WithInstallDisabled(), WithWebDriver(srv.URL+"/", nil))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	out := t.TempDir()
+	if _, err := w.Bundle(ctx, "./example", out, "-test.run=TestMathHelper"); err != nil {
+		t.Fatalf("Bundle failed: %v", err)
+	}
+	progress, msgs := collectProgress()
+	if err := w.RunBundle(ctx, out, RunBundleOptions{}, progress); err != nil {
+		t.Fatalf("RunBundle failed: %v\n%s", err, strings.Join(msgs(), "\n"))
+	}
+	mu.Lock()
+	got := commands
+	mu.Unlock()
+	if want := []string{"POST /session", "POST /session/s1/url", "DELETE /session/s1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("WebDriver commands = %q, want %q", got, want)
+	}
+
+	// WebDriver errors are reported with their code and message.
+	err := webDriverRequest(ctx, http.MethodGet, srv.URL+"/status", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "unknown command: /status") {
+		t.Errorf("error = %v", err)
+	}
+}